@@ -13,6 +13,8 @@ import (
 	"github.com/coralproject/shelf/cmd/askd/handlers"
 	"github.com/coralproject/shelf/cmd/askd/midware"
 	"github.com/coralproject/shelf/internal/ask/form/submission"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/coralproject/shelf/internal/platform/metrics"
 )
 
 // Environmental variables.
@@ -78,7 +80,13 @@ func API() http.Handler {
 		}
 	*/
 
-	a := app.New(midware.Mongo, midware.Auth)
+	authm, err := auth.Midware()
+	if err != nil {
+		log.Error("startup", "Init", err, "Initializing Auth")
+		os.Exit(1)
+	}
+
+	a := app.New(metrics.Midware, midware.Mongo, authm)
 	//		a.Ctx["anvil"] = anv
 
 	// Load in the recaptcha secret from the config.
@@ -94,6 +102,10 @@ func API() http.Handler {
 	//oldRoutes(a) // FIXME: remove on next API release
 	routes(a)
 
+	// Metrics are served outside of the middleware so they can be scraped
+	// without a token and don't count themselves.
+	a.TreeMux.Handle("GET", "/metrics", metrics.Handler)
+
 	log.Dev("startup", "Init", "Initalizing CORS")
 	a.CORS()
 
@@ -133,38 +145,42 @@ func oldRoutes(a *app.App) {
 }
 
 func routes(a *app.App) {
+	read := auth.Scope(auth.ScopeFormRead)
+	write := auth.Scope(auth.ScopeFormWrite)
+	submit := auth.Scope(auth.ScopeFormSubmit, auth.ScopeFormWrite)
+
 	// global
 	a.Handle("GET", "/v1/version", handlers.Version.List)
 
 	// forms
-	a.Handle("POST", "/v1/form", handlers.Form.Upsert)
-	a.Handle("GET", "/v1/form", handlers.Form.List)
-	a.Handle("PUT", "/v1/form/:id", handlers.Form.Upsert)
-	a.Handle("PUT", "/v1/form/:id/status/:status", handlers.Form.UpdateStatus)
-	a.Handle("GET", "/v1/form/:id", handlers.Form.Retrieve)
-	a.Handle("DELETE", "/v1/form/:id", handlers.Form.Delete)
+	a.Handle("POST", "/v1/form", write(handlers.Form.Upsert))
+	a.Handle("GET", "/v1/form", read(handlers.Form.List))
+	a.Handle("PUT", "/v1/form/:id", write(handlers.Form.Upsert))
+	a.Handle("PUT", "/v1/form/:id/status/:status", write(handlers.Form.UpdateStatus))
+	a.Handle("GET", "/v1/form/:id", read(handlers.Form.Retrieve))
+	a.Handle("DELETE", "/v1/form/:id", write(handlers.Form.Delete))
 
 	// form form submissions
-	a.Handle("POST", "/v1/form/:form_id/submission", handlers.FormSubmission.Create)
-	a.Handle("GET", "/v1/form/:form_id/submission", handlers.FormSubmission.Search)
-	a.Handle("GET", "/v1/form/:form_id/submission/:id", handlers.FormSubmission.Retrieve)
-	a.Handle("PUT", "/v1/form/:form_id/submission/:id/status/:status", handlers.FormSubmission.UpdateStatus)
-	a.Handle("POST", "/v1/form/:form_id/submission/:id/flag/:flag", handlers.FormSubmission.AddFlag)
-	a.Handle("DELETE", "/v1/form/:form_id/submission/:id/flag/:flag", handlers.FormSubmission.RemoveFlag)
-	a.Handle("PUT", "/v1/form/:form_id/submission/:id/answer/:answer_id", handlers.FormSubmission.UpdateAnswer)
-	a.Handle("DELETE", "/v1/form/:form_id/submission/:id", handlers.FormSubmission.Delete)
+	a.Handle("POST", "/v1/form/:form_id/submission", submit(handlers.FormSubmission.Create))
+	a.Handle("GET", "/v1/form/:form_id/submission", read(handlers.FormSubmission.Search))
+	a.Handle("GET", "/v1/form/:form_id/submission/:id", read(handlers.FormSubmission.Retrieve))
+	a.Handle("PUT", "/v1/form/:form_id/submission/:id/status/:status", write(handlers.FormSubmission.UpdateStatus))
+	a.Handle("POST", "/v1/form/:form_id/submission/:id/flag/:flag", write(handlers.FormSubmission.AddFlag))
+	a.Handle("DELETE", "/v1/form/:form_id/submission/:id/flag/:flag", write(handlers.FormSubmission.RemoveFlag))
+	a.Handle("PUT", "/v1/form/:form_id/submission/:id/answer/:answer_id", write(handlers.FormSubmission.UpdateAnswer))
+	a.Handle("DELETE", "/v1/form/:form_id/submission/:id", write(handlers.FormSubmission.Delete))
 
 	// temporal route to get CSV file - TO DO : move into a different service
-	a.Handle("GET", "/v1/form/:form_id/submission/export", handlers.FormSubmission.Download)
+	a.Handle("GET", "/v1/form/:form_id/submission/export", read(handlers.FormSubmission.Download))
 
 	// form form galleries
-	a.Handle("GET", "/v1/form/:form_id/gallery", handlers.FormGallery.RetrieveForForm)
+	a.Handle("GET", "/v1/form/:form_id/gallery", read(handlers.FormGallery.RetrieveForForm))
 
 	// form galleries
-	a.Handle("GET", "/v1/form_gallery/:id", handlers.FormGallery.Retrieve)
-	a.Handle("PUT", "/v1/form_gallery/:id", handlers.FormGallery.Update)
-	a.Handle("POST", "/v1/form_gallery/:id/submission/:submission_id/:answer_id", handlers.FormGallery.AddAnswer)
-	a.Handle("DELETE", "/v1/form_gallery/:id/submission/:submission_id/:answer_id", handlers.FormGallery.RemoveAnswer)
+	a.Handle("GET", "/v1/form_gallery/:id", read(handlers.FormGallery.Retrieve))
+	a.Handle("PUT", "/v1/form_gallery/:id", write(handlers.FormGallery.Update))
+	a.Handle("POST", "/v1/form_gallery/:id/submission/:submission_id/:answer_id", write(handlers.FormGallery.AddAnswer))
+	a.Handle("DELETE", "/v1/form_gallery/:id/submission/:submission_id/:answer_id", write(handlers.FormGallery.RemoveAnswer))
 }
 
 func ensureDBIndexes() error {
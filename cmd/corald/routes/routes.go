@@ -9,7 +9,8 @@ import (
 	"github.com/ardanlabs/kit/web/app"
 	"github.com/coralproject/shelf/cmd/corald/fixtures"
 	"github.com/coralproject/shelf/cmd/corald/handlers"
-	"github.com/coralproject/shelf/cmd/corald/midware"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/coralproject/shelf/internal/platform/metrics"
 )
 
 const (
@@ -30,17 +31,21 @@ func init() {
 
 // API returns a handler for a set of routes.
 func API(testing ...bool) http.Handler {
-	auth, err := midware.Auth()
+	authm, err := auth.Midware()
 	if err != nil {
 		log.Error("startup", "Init", err, "Initializing Auth")
 		os.Exit(1)
 	}
 
-	a := app.New(auth)
+	a := app.New(metrics.Midware, authm)
 
 	log.Dev("startup", "Init", "Initalizing routes")
 	routes(a)
 
+	// Metrics are served outside of the middleware so they can be scraped
+	// without a token and don't count themselves.
+	a.TreeMux.Handle("GET", "/metrics", metrics.Handler)
+
 	log.Dev("startup", "Init", "Initalizing CORS")
 	a.CORS()
 
@@ -62,7 +67,7 @@ func routes(a *app.App) {
 	a.Handle("GET", "/v1/item/:view_name/:item_key/:query_set",
 		handlers.Proxy(xeniadURL,
 			func(c *app.Context) string {
-				return "/v1/exec/" + c.Params["query_set"]
+				return "/v1/exec/" + c.Params["query_set"] + "/view/" + c.Params["view_name"] + "/" + c.Params["item_key"]
 			}))
 
 	a.Handle("POST", "/v1/item", fixtures.Handler("items/itemid", http.StatusCreated))
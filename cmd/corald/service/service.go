@@ -11,7 +11,12 @@ import (
 func Rewrite(c *app.Context) func(*http.Request) {
 
 	f := func(r *http.Request) {
-		// TODO: Add authentication headers here
+
+		// Forward the token of the caller so the services can verify it and
+		// enforce the scopes of their routes.
+		if token := c.Request.Header.Get("Authorization"); token != "" {
+			r.Header.Set("Authorization", token)
+		}
 	}
 
 	return f
@@ -12,6 +12,7 @@ var itemCmd = &cobra.Command{
 func GetCommands() *cobra.Command {
 	addUpsert()
 	addGet()
+	addList()
 	addDel()
 	return itemCmd
 }
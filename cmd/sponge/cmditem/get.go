@@ -9,11 +9,14 @@ var getLong = `Retrieves item records from the system having one of the supplied
 
 Example:
 	item get -i ids
+
+	item get -i id -v version
 `
 
 // get contains the state for this command.
 var get struct {
-	IDs string
+	IDs     string
+	Version string
 }
 
 // addGet handles the retrival of item records, displayed in json formatted response.
@@ -26,6 +29,7 @@ func addGet() {
 	}
 
 	cmd.Flags().StringVarP(&get.IDs, "IDs", "i", "", "Item IDs.")
+	cmd.Flags().StringVarP(&get.Version, "version", "v", "", "Version of the item.")
 
 	itemCmd.AddCommand(cmd)
 }
@@ -41,6 +45,10 @@ func runGet(cmd *cobra.Command, args []string) {
 	}
 
 	url += "/" + get.IDs
+	if get.Version != "" {
+		url += "/version/" + get.Version
+	}
+
 	resp, err := web.Request(cmd, verb, url, nil)
 	if err != nil {
 		cmd.Println("Getting Items : ", err)
@@ -0,0 +1,106 @@
+package cmditem
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/coralproject/shelf/cmd/sponge/web"
+	"github.com/spf13/cobra"
+)
+
+var listLong = `Lists the items in the system that match the supplied filters.
+
+Example:
+	item list -t comment
+
+	item list -t comment -d asset_id=1 -d likes[gte]=10 -s=-updated
+
+	item list -t user -a 2016-06-01T00:00:00Z -l 100 -c cursor
+`
+
+// list contains the state for this command.
+var list struct {
+	Type   string
+	Data   []string
+	After  string
+	Before string
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// addList handles the listing of item records, displayed in json formatted response.
+func addList() {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the item records matching the supplied filters.",
+		Long:  listLong,
+		Run:   runList,
+	}
+
+	cmd.Flags().StringVarP(&list.Type, "type", "t", "", "Type of the items.")
+	cmd.Flags().StringSliceVarP(&list.Data, "data", "d", nil, "Data conditions like field=value or field[gte]=value.")
+	cmd.Flags().StringVarP(&list.After, "after", "a", "", "Only items updated after the RFC3339 time.")
+	cmd.Flags().StringVarP(&list.Before, "before", "b", "", "Only items updated before the RFC3339 time.")
+	cmd.Flags().StringVarP(&list.Sort, "sort", "s", "", "Field to sort by, prefixed with - for descending.")
+	cmd.Flags().IntVarP(&list.Limit, "limit", "l", 0, "Number of items in the page.")
+	cmd.Flags().StringVarP(&list.Cursor, "cursor", "c", "", "Cursor of the next page.")
+
+	itemCmd.AddCommand(cmd)
+}
+
+// runList issues the command talking to the web service.
+func runList(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/1.0/item"
+
+	q, err := listQuery()
+	if err != nil {
+		cmd.Println("Listing Items : ", err)
+		return
+	}
+
+	if len(q) > 0 {
+		url += "?" + q.Encode()
+	}
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Listing Items : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
+
+// listQuery returns the query string for the flags of the command.
+func listQuery() (url.Values, error) {
+	q := make(url.Values)
+
+	set := func(key string, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+
+	set("type", list.Type)
+	set("updated_after", list.After)
+	set("updated_before", list.Before)
+	set("sort", list.Sort)
+	set("cursor", list.Cursor)
+
+	if list.Limit > 0 {
+		q.Set("limit", strconv.Itoa(list.Limit))
+	}
+
+	for _, cond := range list.Data {
+		i := strings.Index(cond, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("Invalid data condition %q", cond)
+		}
+		q.Add("data."+cond[:i], cond[i+1:])
+	}
+
+	return q, nil
+}
@@ -18,6 +18,9 @@ Example:
 	item upsert -p item.json
 
 	item upsert -p ./items
+
+The items of a directory are sent together and the status of each
+item is displayed.
 `
 
 // upsert contains the state for this command.
@@ -78,16 +81,14 @@ func runUpsert(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	var items []item.Item
 	f := func(path string) error {
 		item, err := disk.LoadItem("", path)
 		if err != nil {
 			return err
 		}
 
-		if err := runUpsertWeb(cmd, *item); err != nil {
-			return err
-		}
-
+		items = append(items, *item)
 		return nil
 	}
 
@@ -96,6 +97,16 @@ func runUpsert(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if len(items) == 0 {
+		cmd.Println("\n", "Upserting Items : No items found")
+		return
+	}
+
+	if err := runBulkWeb(cmd, items); err != nil {
+		cmd.Println("Upserting Items : ", err)
+		return
+	}
+
 	cmd.Println("\n", "Upserting Items : Upserted")
 }
 
@@ -117,3 +128,22 @@ func runUpsertWeb(cmd *cobra.Command, item item.Item) error {
 
 	return nil
 }
+
+// runBulkWeb sends the items to the web service in a single request.
+func runBulkWeb(cmd *cobra.Command, items []item.Item) error {
+	verb := "POST"
+	url := "/1.0/item/_bulk"
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	resp, err := web.Request(cmd, verb, url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+	return nil
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"unicode"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/pborman/uuid"
+)
+
+// bulkChunk is the number of items written with a single Mongo bulk
+// operation and Cayley transaction.
+const bulkChunk = 500
+
+// bulkMaxBytes is the largest body of a bulk request that is read. The items
+// sent before the limit is reached are still written.
+const bulkMaxBytes = 64 << 20
+
+// Set of statuses reported for each item of a bulk request.
+const (
+	bulkCreated   = "created"
+	bulkUpdated   = "updated"
+	bulkUnchanged = "unchanged"
+	bulkFailed    = "failed"
+)
+
+// errRepeated is reported for an item whose ID is repeated later in the same
+// chunk of a bulk request. Only the last copy of the item is written.
+var errRepeated = errors.New("Item is repeated later in the request")
+
+// bulkResult contains the status of an item of a bulk request. The graph
+// error is reported for an item that was stored when its relationships could
+// not be applied to the graph.
+type bulkResult struct {
+	ID         string              `json:"item_id,omitempty"`
+	Version    int                 `json:"version,omitempty"`
+	Status     string              `json:"status"`
+	Reason     string              `json:"reason,omitempty"`
+	Fields     []schema.FieldError `json:"fields,omitempty"`
+	GraphError string              `json:"graph_error,omitempty"`
+}
+
+//==============================================================================
+
+// Bulk inserts or updates the posted Item documents, sent as a JSON array or
+// newline delimited JSON, and responds with the status of each item in the
+// order they were sent.
+// 200 Success, 400 Bad Request, 500 Internal
+func (itemHandle) Bulk(c *app.Context) error {
+	w := bulkWriter{c: c}
+
+	f := func(doc json.RawMessage) {
+		var it item.Item
+		if err := json.Unmarshal(doc, &it); err != nil {
+			w.fail("", err)
+			return
+		}
+
+		// Give a new item its ID so its relationships can be inferred.
+		if it.ID == "" {
+			it.ID = uuid.New()
+		}
+
+		w.add(it)
+	}
+
+	return w.respond(decodeBulk(http.MaxBytesReader(c, c.Request.Body, bulkMaxBytes), f))
+}
+
+// Bulk itemizes the posted data documents of the type, sent as a JSON array
+// or newline delimited JSON, and upserts them. It responds with the status of
+// each item in the order they were sent.
+// 200 Success, 400 Bad Request, 500 Internal
+func (dataHandle) Bulk(c *app.Context) error {
+	s, err := idstrategy.ForType(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"])
+	if err != nil {
+		return err
+	}
+
+	w := bulkWriter{c: c}
+
+	f := func(doc json.RawMessage) {
+		var dat map[string]interface{}
+		if err := json.Unmarshal(doc, &dat); err != nil {
+			w.fail("", err)
+			return
+		}
+
+		it := item.Item{
+			Type: c.Params["type"],
+			Data: dat,
+		}
+
+		// Item.ID must be inferred from the data with the ID strategy of
+		// the type.
+		if err := it.InferID(s); err != nil {
+			w.fail("", err)
+			return
+		}
+
+		w.add(it)
+	}
+
+	return w.respond(decodeBulk(http.MaxBytesReader(c, c.Request.Body, bulkMaxBytes), f))
+}
+
+//==============================================================================
+
+// bulkWriter writes the items of a bulk request in chunks and records the
+// status of each item.
+type bulkWriter struct {
+	c       *app.Context
+	items   []item.Item
+	index   []int
+	results []bulkResult
+}
+
+// add queues the item and writes the queued items once a chunk is full.
+func (w *bulkWriter) add(it item.Item) {
+	w.index = append(w.index, len(w.results))
+	w.items = append(w.items, it)
+	w.results = append(w.results, bulkResult{ID: it.ID})
+
+	if len(w.items) >= bulkChunk {
+		w.flush()
+	}
+}
+
+// fail records an item that can't be written.
+func (w *bulkWriter) fail(id string, err error) {
+	w.results = append(w.results, bulkResult{ID: id, Status: bulkFailed, Reason: err.Error()})
+}
+
+// respond writes the queued items and responds with the status of each item.
+// A request that can't be read is rejected unless items were already read,
+// then the error is reported as the status of the last item.
+func (w *bulkWriter) respond(err error) error {
+	if err != nil && len(w.results) == 0 {
+		w.c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	w.flush()
+
+	if err != nil {
+		w.fail("", err)
+	}
+
+	w.c.Respond(w.results, http.StatusOK)
+	return nil
+}
+
+// flush writes the queued items to Mongo with a bulk operation and applies
+// their relationships to the graph with a single transaction.
+func (w *bulkWriter) flush() {
+	if len(w.items) == 0 {
+		return
+	}
+
+	its, index := w.items, w.index
+	w.items, w.index = nil, nil
+
+	mgoDB := w.c.Ctx["DB"].(*db.DB)
+	store := w.c.Ctx["Graph"].(*cayley.Handle)
+
+	// setStatus records the status of the queued item at position i.
+	setStatus := func(i int, status string, err error) {
+		r := &w.results[index[i]]
+		r.Status = status
+		if err != nil {
+			r.Reason = err.Error()
+		}
+		if verr, ok := err.(*schema.ValidationError); ok {
+			r.Fields = verr.Errors
+		}
+	}
+
+	// Find the items that already exist and the last copy of each item.
+	ids := make([]string, len(its))
+	last := make(map[string]int, len(its))
+	for i, it := range its {
+		ids[i] = it.ID
+		last[it.ID] = i
+	}
+
+	existing := make(map[string]item.Item)
+	found, err := item.GetByIDs(w.c.SessionID, mgoDB, ids)
+	if err != nil && err != item.ErrNotFound {
+		for i := range its {
+			setStatus(i, bulkFailed, err)
+		}
+		return
+	}
+	for _, it := range found {
+		existing[it.ID] = it
+	}
+
+	// Collect the items that changed and the changes to their relationships.
+	batch := wire.NewBatch(mgoDB)
+
+	var write []item.Item
+	var written []int
+	for i, it := range its {
+		if last[it.ID] != i {
+			setStatus(i, bulkFailed, errRepeated)
+			continue
+		}
+
+		old, exists := existing[it.ID]
+		if exists && old.SameContent(&it) {
+			w.results[index[i]].Version = old.Version
+			setStatus(i, bulkUnchanged, nil)
+			continue
+		}
+
+		var stale map[string]interface{}
+		if exists {
+			stale = itemMap(old)
+		}
+
+		// Items whose relationships are not allowed are rejected.
+		if err := batch.Upsert(w.c.SessionID, itemMap(it), stale); err != nil {
+			setStatus(i, bulkFailed, err)
+			continue
+		}
+
+		write = append(write, it)
+		written = append(written, i)
+	}
+
+	if len(write) == 0 {
+		return
+	}
+
+	// Write the items and report the ones that were rejected.
+	failed, err := item.UpsertBulk(w.c.SessionID, mgoDB, write, existing)
+	if err != nil {
+		for _, i := range written {
+			setStatus(i, bulkFailed, err)
+		}
+		return
+	}
+
+	// Keep the relationship changes of the items that were stored.
+	var stored []int
+	var maps []map[string]interface{}
+	for j, i := range written {
+		if err := failed[j]; err != nil {
+			batch.Drop(write[j].ID)
+			setStatus(i, bulkFailed, err)
+			continue
+		}
+
+		status := bulkCreated
+		if old, exists := existing[write[j].ID]; exists {
+			status = bulkUpdated
+			maps = append(maps, itemMap(old))
+		}
+		w.results[index[i]].Version = write[j].Version
+		setStatus(i, status, nil)
+
+		stored = append(stored, i)
+		maps = append(maps, itemMap(write[j]))
+	}
+
+	// The items are stored so they keep their status and the failure to
+	// update the graph is reported for each of them. Writing them again
+	// leaves them unchanged so the graph must be repaired with a check.
+	if err := batch.Apply(w.c.SessionID, store); err != nil {
+		for _, i := range stored {
+			w.results[index[i]].GraphError = err.Error()
+		}
+	}
+
+	// Update the live views the items are or were in.
+	syncLive(w.c, maps...)
+}
+
+//==============================================================================
+
+// decodeBulk reads the documents of a bulk request, either a JSON array or
+// newline delimited JSON, calling f for each document as it is read so the
+// whole body is never held in memory.
+func decodeBulk(r io.Reader, f func(doc json.RawMessage)) error {
+	br := bufio.NewReader(r)
+
+	// Skip the leading white space to find how the documents are sent.
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if !unicode.IsSpace(rune(b)) {
+			br.UnreadByte()
+			break
+		}
+	}
+
+	first, err := br.Peek(1)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(br)
+
+	if first[0] == '[' {
+
+		// Read the opening bracket so the elements are decoded one by one.
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			var doc json.RawMessage
+			if err := dec.Decode(&doc); err != nil {
+				return err
+			}
+
+			f(doc)
+		}
+
+		// Read the closing bracket.
+		_, err := dec.Token()
+		return err
+	}
+
+	for {
+		var doc json.RawMessage
+		if err := dec.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		f(doc)
+	}
+}
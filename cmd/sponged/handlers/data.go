@@ -7,9 +7,8 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/web/app"
-	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
 	"github.com/coralproject/shelf/internal/sponge/item"
-	"github.com/coralproject/shelf/internal/wire"
 )
 
 // dataHandle maintains the set of handlers for the data api, which is responsible
@@ -21,13 +20,10 @@ var Data dataHandle
 
 //==============================================================================
 
-// defaultVersion is set to item.Version when no version is provided.
-const defaultVersion = 1
-
-//==============================================================================
-
-// Upsert receives POSTed data, itemizes it then Upserts it via the item service
-// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 500 Internal.
+// Upsert receives POSTed data, itemizes it then Upserts it via the item service.
+// An If-Match header with the version the update is based on makes the update
+// conditional.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal.
 func (dataHandle) Upsert(c *app.Context) error {
 
 	// Unmarshall the data packet from the Request Body.
@@ -36,37 +32,34 @@ func (dataHandle) Upsert(c *app.Context) error {
 		return err
 	}
 
-	// Create a new item with known Type, Version and Data.
-	it := item.Item{
-		Type:    c.Params["type"],
-		Version: defaultVersion,
-		Data:    dat,
+	version, err := ifMatch(c)
+	if err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
 	}
 
-	// Item.ID must be inferred from the source_id in the data.
-	if err := it.InferIDFromData(); err != nil {
-		return err
+	// Create a new item with known Type and Data. The version is set when
+	// the item is stored.
+	it := item.Item{
+		Type: c.Params["type"],
+		Data: dat,
 	}
 
-	// Upsert the item.
-	if err := item.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), &it); err != nil {
+	// Item.ID must be inferred from the data with the ID strategy of the type.
+	s, err := idstrategy.ForType(c.SessionID, c.Ctx["DB"].(*db.DB), it.Type)
+	if err != nil {
 		return err
 	}
 
-	// Prepare the generic item data map.
-	itMap := map[string]interface{}{
-		"item_id": it.ID,
-		"type":    it.Type,
-		"version": it.Version,
-		"data":    it.Data,
+	if err := it.InferID(s); err != nil {
+		return err
 	}
 
-	// Infer relationships and add them to the graph.
-	if err := wire.AddToGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), itMap); err != nil {
-		return err
+	// Reject the item if its relationships are not allowed.
+	if err := validateItem(c, &it); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
 	}
 
-	// Respond with no content success.
-	c.Respond(nil, http.StatusNoContent)
-	return nil
+	return upsertItem(c, &it, version)
 }
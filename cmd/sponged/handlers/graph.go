@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/cmd/sponged/midware"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+)
+
+// cfgMongoDB is the name of the database which is also the name of the
+// master session.
+const cfgMongoDB = "MONGO_DB"
+
+// graphHandle maintains the set of handlers for the graph api.
+type graphHandle struct{}
+
+// Graph fronts the access to the graph service functionality.
+var Graph graphHandle
+
+//==============================================================================
+
+// Backfill starts re-inferring the relationships of the items of the type
+// after its pattern changed and responds with the backfill. The backfill runs
+// in the background. The from query string variable is the pattern revision
+// the items were inferred with and defaults to the revision before the
+// newest. An interrupted backfill is resumed when called again. The batch
+// query string variable is the number of items processed at a time.
+// 202 Accepted, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal
+func (graphHandle) Backfill(c *app.Context) error {
+	from := -1
+	if v := c.Request.URL.Query().Get("from"); v != "" {
+		var err error
+		if from, err = strconv.Atoi(v); err != nil || from < 0 {
+			return app.ErrValidation
+		}
+	}
+
+	var batch int
+	if v := c.Request.URL.Query().Get("batch"); v != "" {
+		var err error
+		if batch, err = strconv.Atoi(v); err != nil || batch <= 0 {
+			return app.ErrValidation
+		}
+	}
+
+	bf, err := wire.StartBackfill(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"], from)
+	if err != nil {
+		switch err {
+		case pattern.ErrNotFound:
+			return app.ErrNotFound
+		case wire.ErrBackfillRevision:
+			return app.ErrValidation
+		case wire.ErrBackfillRunning:
+			c.RespondError(err.Error(), http.StatusConflict)
+			return nil
+		}
+		return err
+	}
+
+	go backfill(*bf, batch)
+
+	c.Respond(bf, http.StatusAccepted)
+	return nil
+}
+
+// Progress returns the progress of the backfill for the type.
+// 200 Success, 404 Not Found, 500 Internal
+func (graphHandle) Progress(c *app.Context) error {
+	bf, err := wire.GetBackfill(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"])
+	if err != nil {
+		if err == wire.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(bf, http.StatusOK)
+	return nil
+}
+
+// Check compares the items with the quads in the graph and returns a report
+// of the orphaned and missing quads and the quads whose predicate has no
+// relationship. Posting to it also repairs the graph by adding the missing
+// quads and removing the orphaned ones. The limit query string variable is
+// the number of quads listed for each kind of issue.
+// 200 Success, 400 Bad Request, 500 Internal
+func (graphHandle) Check(c *app.Context) error {
+	var limit int
+	if v := c.Request.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return app.ErrValidation
+		}
+	}
+
+	repair := c.Request.Method == "POST"
+
+	report, err := wire.Check(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), repair, limit)
+	if err != nil {
+		return err
+	}
+
+	c.Respond(report, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// backfill runs the claimed backfill with its own sessions since it outlives
+// the request that started it.
+func backfill(bf wire.Backfill, batch int) {
+	context := "backfill:" + bf.Type
+
+	mgoDB, err := db.NewMGO(context, cfg.MustString(cfgMongoDB))
+	if err != nil {
+		log.Error(context, "backfill", err, "Getting Mongo session")
+		return
+	}
+	defer mgoDB.CloseMGO(context)
+
+	store, err := midware.NewGraph()
+	if err != nil {
+		log.Error(context, "backfill", err, "Getting Cayley handle")
+		return
+	}
+	defer store.Close()
+
+	wire.RunBackfill(context, mgoDB, store, &bf, batch, nil)
+}
@@ -3,15 +3,24 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"reflect"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/kit/web/app"
 	"github.com/cayleygraph/cayley"
 	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/schema"
 	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/mask"
+	"github.com/pborman/uuid"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // itemHandle maintains the set of handlers for theitem api.
@@ -20,6 +29,13 @@ type itemHandle struct{}
 // Item fronts the access to the item service functionality.
 var Item itemHandle
 
+// schemaError is the response for an item whose data doesn't match the
+// schema of its type, with the errors of each field.
+type schemaError struct {
+	Error  string              `json:"error"`
+	Fields []schema.FieldError `json:"fields"`
+}
+
 //==============================================================================
 
 // Retrieve returns the items, specified by IDs, from the system.
@@ -41,72 +57,118 @@ func (itemHandle) Retrieve(c *app.Context) error {
 
 //==============================================================================
 
-// Upsert inserts or updates the posted Item document into the database.
-// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 500 Internal
-func (itemHandle) Upsert(c *app.Context) error {
+// List returns a page of the items that match the filters in the query
+// string, like type=comment&data.asset_id=1&data.likes[gte]=10, with the
+// cursor for the next page. The fields masked for the items collection are
+// masked and can't be sorted or filtered on.
+// 200 Success, 400 Bad Request, 500 Internal
+func (itemHandle) List(c *app.Context) error {
+	opts, err := listOptions(c.Request.URL.Query())
+	if err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
 
-	// Decode the item.
-	var it item.Item
-	if err := json.NewDecoder(c.Request.Body).Decode(&it); err != nil {
+	masks, err := mask.GetByCollection(c.SessionID, c.Ctx["DB"].(*db.DB), item.Collection)
+	if err != nil && err != mask.ErrNotFound {
 		return err
 	}
+	opts.Masks = masks
 
-	// See if the item already exists.
-	if it.ID != "" {
-		items, err := item.GetByIDs(c.SessionID, c.Ctx["DB"].(*db.DB), []string{it.ID})
-		if err != nil {
-			if err != item.ErrNotFound {
-				return err
-			}
+	items, next, err := item.List(c.SessionID, c.Ctx["DB"].(*db.DB), opts)
+	if err != nil {
+		if err == item.ErrInvalidCursor || err == item.ErrMaskedField {
+			c.RespondError(err.Error(), http.StatusBadRequest)
+			return nil
 		}
+		return err
+	}
 
-		// If the item is identical, we don't have to do anything.
-		if len(items) > 0 {
-			if reflect.DeepEqual(items[0], it) {
-				c.Respond(nil, http.StatusNoContent)
-				return nil
-			}
-
-			// If the item is not identical, remove the stale relationships by
-			// preparing an item map.
-			itMap := map[string]interface{}{
-				"item_id": items[0].ID,
-				"type":    items[0].Type,
-				"version": items[0].Version,
-				"data":    items[0].Data,
-			}
-
-			// Remove the corresponding relationships from the graph.
-			if err := wire.RemoveFromGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), itMap); err != nil {
-				return err
-			}
-		}
-	}
-
-	// Add the item to the items collection.
-	if err := item.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), &it); err != nil {
+	docs := make([]bson.M, len(items))
+	for i, it := range items {
+		docs[i] = bson.M{
+			"item_id": it.ID,
+			"type":    it.Type,
+			"version": it.Version,
+			"updated": it.Updated,
+			"data":    it.Data,
+		}
+	}
+
+	if err := xenia.ApplyMasks(c.SessionID, c.Ctx["DB"].(*db.DB), item.Collection, docs); err != nil {
 		return err
 	}
 
-	// Prepare the generic item data map.
-	itMap := map[string]interface{}{
-		"item_id": it.ID,
-		"type":    it.Type,
-		"version": it.Version,
-		"data":    it.Data,
+	result := struct {
+		Items []bson.M `json:"items"`
+		Next  string   `json:"next,omitempty"`
+	}{
+		Items: docs,
+		Next:  next,
 	}
 
-	// Infer relationships and add them to the graph.
-	if err := wire.AddToGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), itMap); err != nil {
+	c.Respond(result, http.StatusOK)
+	return nil
+}
+
+// RetrieveVersion returns the item, specified by ID, at the specified version.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (itemHandle) RetrieveVersion(c *app.Context) error {
+	version, err := strconv.Atoi(c.Params["version"])
+	if err != nil || version < 1 {
+		c.RespondError("Invalid version "+c.Params["version"], http.StatusBadRequest)
+		return nil
+	}
+
+	it, err := item.GetByVersion(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["id"], version)
+	if err != nil {
+		if err == item.ErrNotFound {
+			err = app.ErrNotFound
+		}
 		return err
 	}
 
-	c.Respond(nil, http.StatusNoContent)
+	c.Respond(it, http.StatusOK)
 	return nil
 }
 
 //==============================================================================
 
+// Upsert inserts or updates the posted Item document into the database. The
+// version of the item is set by the service. An If-Match header with the
+// version the update is based on makes the update conditional.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal
+func (itemHandle) Upsert(c *app.Context) error {
+
+	// Decode the item.
+	var it item.Item
+	if err := json.NewDecoder(c.Request.Body).Decode(&it); err != nil {
+		return err
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	// Give a new item its ID so its relationships can be checked.
+	if it.ID == "" {
+		it.ID = uuid.New()
+	}
+
+	// Reject the item if its relationships are not allowed.
+	if err := validateItem(c, &it); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	return upsertItem(c, &it, version)
+}
+
+//==============================================================================
+//==============================================================================
+
 // Delete removes the specified Item from the system.
 // 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
 func (itemHandle) Delete(c *app.Context) error {
@@ -129,18 +191,182 @@ func (itemHandle) Delete(c *app.Context) error {
 	}
 
 	// Prepare the item map data.
-	itMap := map[string]interface{}{
-		"item_id": items[0].ID,
-		"type":    items[0].Type,
-		"version": items[0].Version,
-		"data":    items[0].Data,
-	}
+	itMap := itemMap(items[0])
 
 	// Remove the corresponding relationships from the graph.
 	if err := wire.RemoveFromGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), itMap); err != nil {
 		return err
 	}
 
+	// Remove the item from the live views it was in.
+	syncLive(c, itMap)
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
+
+//==============================================================================
+
+// upsertItem stores the item at the next version if the stored item is at the
+// specified version, or any version for 0, and updates its relationships in
+// the graph and the live views it is or was in.
+func upsertItem(c *app.Context, it *item.Item, version int) error {
+
+	// See if the item already exists. The stale item is kept so its
+	// relationships can be removed and the live views it was in updated.
+	var stale map[string]interface{}
+	items, err := item.GetByIDs(c.SessionID, c.Ctx["DB"].(*db.DB), []string{it.ID})
+	if err != nil {
+		if err != item.ErrNotFound {
+			return err
+		}
+	}
+
+	if len(items) > 0 {
+
+		// If the item is identical, we don't have to do anything.
+		if (version == 0 || version == items[0].Version) && items[0].SameContent(it) {
+			setETag(c, items[0].Version)
+			c.Respond(nil, http.StatusNoContent)
+			return nil
+		}
+
+		stale = itemMap(items[0])
+	}
+
+	// Add the item to the items collection. The write is rejected if the
+	// item was changed since the version the update is based on.
+	if err := item.UpsertVersion(c.SessionID, c.Ctx["DB"].(*db.DB), it, version); err != nil {
+		if err == item.ErrConflict {
+			c.RespondError(err.Error(), http.StatusConflict)
+			return nil
+		}
+		if verr, ok := err.(*schema.ValidationError); ok {
+			c.Respond(schemaError{Error: verr.Error(), Fields: verr.Errors}, http.StatusBadRequest)
+			return nil
+		}
+		return err
+	}
+
+	// Remove the stale relationships from the graph.
+	if stale != nil {
+		if err := wire.RemoveFromGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), stale); err != nil {
+			return err
+		}
+	}
+
+	// Infer relationships and add them to the graph.
+	itMap := itemMap(*it)
+	if err := wire.AddToGraph(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), itMap); err != nil {
+		return err
+	}
+
+	// Update the live views the item is or was in.
+	if stale != nil {
+		syncLive(c, stale, itMap)
+	} else {
+		syncLive(c, itMap)
+	}
+
+	setETag(c, it.Version)
 	c.Respond(nil, http.StatusNoContent)
 	return nil
 }
+
+// listOptions returns the options of a list of items from the query string.
+// The data conditions are data.<field>=<value> for equality and
+// data.<field>[<op>]=<value> for the gt, gte, lt and lte ranges.
+func listOptions(query url.Values) (item.ListOptions, error) {
+	opts := item.ListOptions{
+		Type:   query.Get("type"),
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("Invalid limit %s", v)
+		}
+		opts.Limit = limit
+	}
+
+	for key, t := range map[string]*time.Time{"updated_after": &opts.UpdatedAfter, "updated_before": &opts.UpdatedBefore} {
+		v := query.Get(key)
+		if v == "" {
+			continue
+		}
+
+		var err error
+		if *t, err = time.Parse(time.RFC3339, v); err != nil {
+			return opts, fmt.Errorf("Invalid %s time %s, must be RFC3339", key, v)
+		}
+	}
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, "data.") {
+			continue
+		}
+
+		field, op := key[len("data."):], item.OpEq
+		if i := strings.Index(field, "["); i != -1 && strings.HasSuffix(field, "]") {
+			field, op = field[:i], field[i+1:len(field)-1]
+		}
+
+		for _, v := range values {
+			opts.Data = append(opts.Data, item.Condition{Field: field, Op: op, Value: v})
+		}
+	}
+
+	return opts, nil
+}
+
+// ifMatch returns the version in the If-Match header of the request or 0 if
+// the header is not set. The version may be quoted like an entity tag.
+func ifMatch(c *app.Context) (int, error) {
+	h := c.Request.Header.Get("If-Match")
+	if h == "" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(h, `"`))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("Invalid If-Match version %s", h)
+	}
+
+	return version, nil
+}
+
+// setETag sets the version of the item as the entity tag of the response.
+func setETag(c *app.Context, version int) {
+	c.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// validateItem checks the relationships inferred for the item are allowed by
+// the relationship metadata before the item is stored. Other problems with the
+// item are reported when its relationships are added to the graph.
+func validateItem(c *app.Context, it *item.Item) *wire.ConstraintError {
+	if err, ok := wire.ValidateItem(c.SessionID, c.Ctx["DB"].(*db.DB), itemMap(*it)).(*wire.ConstraintError); ok {
+		return err
+	}
+
+	return nil
+}
+
+// itemMap returns the generic item data map used to infer the relationships
+// of the item.
+func itemMap(it item.Item) map[string]interface{} {
+	return map[string]interface{}{
+		"item_id": it.ID,
+		"type":    it.Type,
+		"version": it.Version,
+		"data":    it.Data,
+	}
+}
+
+// syncLive queues the written items so the live views affected by them are
+// updated in the background.
+func syncLive(c *app.Context, items ...map[string]interface{}) {
+	log.Dev(c.SessionID, "syncLive", "Queueing : Items[%d]", len(items))
+	lives.queue(items)
+}
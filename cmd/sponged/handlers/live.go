@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/cmd/sponged/midware"
+	"github.com/coralproject/shelf/internal/wire"
+)
+
+// liveQueueSize is the number of writes that can wait for their live views
+// to be updated. Writes wait for room in the queue once it is full.
+const liveQueueSize = 1024
+
+// liveWrite contains the items of a write whose live views are updated.
+type liveWrite struct {
+	items  []map[string]interface{}
+	queued time.Time
+}
+
+// liveQueue updates the live views affected by writes in the background so
+// writes don't wait for the views to be traversed. The writes are handled one
+// at a time in the order they were queued.
+type liveQueue struct {
+	once    sync.Once
+	writes  chan liveWrite
+	mu      sync.Mutex
+	pending int
+	current time.Time
+	failed  int
+}
+
+// lives is the queue of the writes whose live views are not updated yet.
+var lives = liveQueue{writes: make(chan liveWrite, liveQueueSize)}
+
+// liveStatus contains the state of the queue of live view updates. The lag
+// is how long the oldest write that is not done has been queued for.
+type liveStatus struct {
+	Pending int     `json:"pending"`
+	Lag     float64 `json:"lag_seconds"`
+	Failed  int     `json:"failed"`
+}
+
+// liveHandle maintains the set of handlers for the live api.
+type liveHandle struct{}
+
+// Live fronts the access to the live service functionality.
+var Live liveHandle
+
+//==============================================================================
+
+// Status returns how far the live views are behind the writes.
+// 200 Success
+func (liveHandle) Status(c *app.Context) error {
+	c.Respond(lives.status(), http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// queue adds the written items to the queue. The worker is started on the
+// first write.
+func (q *liveQueue) queue(items []map[string]interface{}) {
+	q.once.Do(func() { go q.run() })
+
+	q.mu.Lock()
+	q.pending++
+	q.mu.Unlock()
+
+	q.writes <- liveWrite{items: items, queued: time.Now()}
+}
+
+// status returns the state of the queue.
+func (q *liveQueue) status() liveStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := liveStatus{Pending: q.pending, Failed: q.failed}
+	if !q.current.IsZero() {
+		s.Lag = time.Since(q.current).Seconds()
+	}
+
+	return s
+}
+
+// run updates the live views for the queued writes. The sessions are shared
+// by the writes queued behind each other.
+func (q *liveQueue) run() {
+	for w := range q.writes {
+		q.drain(w)
+	}
+}
+
+// drain updates the live views for the write and the writes queued behind
+// it with its own sessions since it outlives the requests of the writes.
+func (q *liveQueue) drain(w liveWrite) {
+	const context = "syncLive"
+
+	mgoDB, err := db.NewMGO(context, cfg.MustString(cfgMongoDB))
+	if err != nil {
+		log.Error(context, "drain", err, "Getting Mongo session")
+		q.done(err)
+		return
+	}
+	defer mgoDB.CloseMGO(context)
+
+	store, err := midware.NewGraph()
+	if err != nil {
+		log.Error(context, "drain", err, "Getting Cayley handle")
+		q.done(err)
+		return
+	}
+	defer store.Close()
+
+	for {
+		q.sync(context, mgoDB, store, w)
+
+		select {
+		case w = <-q.writes:
+		default:
+			return
+		}
+	}
+}
+
+// sync updates the live views affected by the items of the write. The items
+// are already stored so a live view that can't be updated is only logged and
+// can be refreshed later.
+func (q *liveQueue) sync(context interface{}, mgoDB *db.DB, store *cayley.Handle, w liveWrite) {
+	q.mu.Lock()
+	q.current = w.queued
+	q.mu.Unlock()
+
+	err := wire.SyncLive(context, mgoDB, store, w.items...)
+	if err != nil {
+		log.Error(context, "sync", err, "Updating live views")
+	}
+
+	q.done(err)
+}
+
+// done removes the write from the pending writes.
+func (q *liveQueue) done(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending--
+	q.current = time.Time{}
+	if err != nil {
+		q.failed++
+	}
+}
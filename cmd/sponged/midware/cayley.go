@@ -1,10 +1,13 @@
 package midware
 
 import (
+	"time"
+
 	"github.com/ardanlabs/kit/cfg"
 	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/kit/web/app"
 	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/platform/metrics"
 
 	// mongo is needed to utilize mongoDB as the backend store for cayley.
 	_ "github.com/cayleygraph/cayley/graph/mongo"
@@ -20,8 +23,7 @@ const (
 func Cayley(h app.Handler) app.Handler {
 
 	// Check if mongodb is configured.
-	mongoHost, err := cfg.String(cfgMongoHost)
-	if err != nil {
+	if _, err := cfg.String(cfgMongoHost); err != nil {
 		return func(c *app.Context) error {
 			log.Dev(c.SessionID, "Cayley", "******> Cayley Not Configured")
 			return h(c)
@@ -30,12 +32,10 @@ func Cayley(h app.Handler) app.Handler {
 
 	// Wrap the handlers inside a session copy/close.
 	return func(c *app.Context) error {
-		opts := map[string]interface{}{
-			"database_name": cfg.MustString(cfgMongoDB),
-			"username":      cfg.MustString(cfgMongoUser),
-			"password":      cfg.MustString(cfgMongoPassword),
-		}
-		store, err := cayley.NewGraph("mongo", mongoHost, opts)
+		start := time.Now()
+		store, err := NewGraph()
+		metrics.Since(metrics.CayleyOpen, start)
+
 		if err != nil {
 			return app.ErrDBNotConfigured
 		}
@@ -50,3 +50,15 @@ func Cayley(h app.Handler) app.Handler {
 		return h(c)
 	}
 }
+
+// NewGraph returns a Cayley handle for the graph stored in Mongo. The handle
+// must be closed.
+func NewGraph() (*cayley.Handle, error) {
+	opts := map[string]interface{}{
+		"database_name": cfg.MustString(cfgMongoDB),
+		"username":      cfg.MustString(cfgMongoUser),
+		"password":      cfg.MustString(cfgMongoPassword),
+	}
+
+	return cayley.NewGraph("mongo", cfg.MustString(cfgMongoHost), opts)
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/ardanlabs/kit/web/app"
 	"github.com/coralproject/shelf/cmd/sponged/handlers"
 	"github.com/coralproject/shelf/cmd/sponged/midware"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/coralproject/shelf/internal/platform/metrics"
+	"github.com/coralproject/shelf/internal/wire"
 )
 
 // Environmental variables.
@@ -22,6 +25,7 @@ const (
 	cfgMongoUser     = "MONGO_USER"
 	cfgMongoPassword = "MONGO_PASS"
 	cfgAnvilHost     = "ANVIL_HOST"
+	cfgStrict        = "STRICT_RELATIONSHIPS"
 )
 
 func init() {
@@ -48,18 +52,34 @@ func init() {
 			os.Exit(1)
 		}
 	}
+
+	// Set if items with relationships the relationship metadata doesn't
+	// allow are rejected.
+	if on, err := cfg.Bool(cfgStrict); err == nil {
+		wire.SetStrict(on)
+		log.Dev("startup", "Init", "Strict Relationships : %v", on)
+	}
 }
 
 //==============================================================================
 
 // API returns a handler for a set of routes.
 func API() http.Handler {
+	authm, err := auth.Midware()
+	if err != nil {
+		log.Error("startup", "Init", err, "Initializing Auth")
+		os.Exit(1)
+	}
 
-	a := app.New(midware.Mongo, midware.Cayley, midware.Auth)
+	a := app.New(metrics.Midware, midware.Mongo, midware.Cayley, authm)
 
 	log.Dev("startup", "Init", "Initalizing routes")
 	routes(a)
 
+	// Metrics are served outside of the middleware so they can be scraped
+	// without a token and don't count themselves.
+	a.TreeMux.Handle("GET", "/metrics", metrics.Handler)
+
 	log.Dev("startup", "Init", "Initalizing CORS")
 	a.CORS()
 
@@ -68,11 +88,28 @@ func API() http.Handler {
 
 // routes manages the handling of the API endpoints.
 func routes(a *app.App) {
+	read := auth.Scope(auth.ScopeItemRead)
+	write := auth.Scope(auth.ScopeItemWrite)
+
 	a.Handle("GET", "/1.0/version", handlers.Version.List)
 
-	a.Handle("GET", "/1.0/item/:id", handlers.Item.Retrieve)
-	a.Handle("PUT", "/1.0/item", handlers.Item.Upsert)
-	a.Handle("DELETE", "/1.0/item/:id", handlers.Item.Delete)
+	a.Handle("GET", "/1.0/item", read(handlers.Item.List))
+	a.Handle("GET", "/1.0/item/:id", read(handlers.Item.Retrieve))
+	a.Handle("GET", "/1.0/item/:id/version/:version", read(handlers.Item.RetrieveVersion))
+	a.Handle("PUT", "/1.0/item", write(handlers.Item.Upsert))
+	a.Handle("POST", "/1.0/item/_bulk", write(handlers.Item.Bulk))
+	a.Handle("DELETE", "/1.0/item/:id", write(handlers.Item.Delete))
+
+	a.Handle("POST", "/1.0/data/:type", write(handlers.Data.Upsert))
+	a.Handle("POST", "/1.0/data/:type/_bulk", write(handlers.Data.Bulk))
+
+	a.Handle("POST", "/1.0/graph/backfill/:type", write(handlers.Graph.Backfill))
+	a.Handle("GET", "/1.0/graph/backfill/:type", read(handlers.Graph.Progress))
+
+	// The check scans every item and quad so even the report needs the write
+	// scope.
+	a.Handle("GET", "/1.0/graph/check", write(handlers.Graph.Check))
+	a.Handle("POST", "/1.0/graph/check", write(handlers.Graph.Check))
 
-	a.Handle("POST", "/1.0/data/:type", handlers.Data.Upsert)
+	a.Handle("GET", "/1.0/live/status", read(handlers.Live.Status))
 }
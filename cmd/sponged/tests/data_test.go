@@ -8,9 +8,12 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/tests"
 	"github.com/coralproject/shelf/internal/sponge/item"
 	"github.com/coralproject/shelf/internal/sponge/item/itemfix"
+	"github.com/coralproject/shelf/internal/sponge/schema"
 )
 
 // TestUpsertData tests the upsert of data into an item.
@@ -104,3 +107,99 @@ func TestUpsertData(t *testing.T) {
 
 	}
 }
+
+// TestUpsertDataSchema tests data is validated against the schema of its type.
+func TestUpsertDataSchema(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	dbName, err := cfg.String("MONGO_DB")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get the database name : %v", tests.Failed, err)
+	}
+
+	mgoDB, err := db.NewMGO("context", dbName)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer mgoDB.CloseMGO("context")
+
+	sch := schema.Schema{
+		Type:     itemPrefix + "schema",
+		Required: []string{"id", "body"},
+		Properties: map[string]schema.Property{
+			"body": {Type: schema.TypeString, MaxLength: 5},
+		},
+	}
+
+	if err := schema.Upsert("context", mgoDB, &sch); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the schema : %v", tests.Failed, err)
+	}
+	defer schema.Delete("context", mgoDB, sch.Type)
+
+	t.Log("Given the need to validate data against the schema of its type.")
+	{
+		//----------------------------------------------------------------------
+		// Insert invalid data.
+
+		url := "/1.0/data/" + sch.Type
+		r := tests.NewRequest("POST", url, bytes.NewBufferString(`{"id": 1, "body": "too long"}`))
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert invalid data : %s", url)
+		{
+			if w.Code != 400 {
+				t.Fatalf("\t%s\tShould not be able to insert the data : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to insert the data.", tests.Success)
+
+			var res struct {
+				Fields []schema.FieldError `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if len(res.Fields) != 1 || res.Fields[0].Field != "body" {
+				t.Log(w.Body.String())
+				t.Fatalf("\t%s\tShould get the error of the body field.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get the error of the body field.", tests.Success)
+		}
+
+		//----------------------------------------------------------------------
+		// Insert valid and invalid data in bulk.
+
+		url = "/1.0/data/" + sch.Type + "/_bulk"
+		r = tests.NewRequest("POST", url, bytes.NewBufferString(`[{"id": 1, "body": "hi"}, {"id": 2}]`))
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert data in bulk : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to insert the data : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to insert the data.", tests.Success)
+
+			var results []struct {
+				Status string              `json:"status"`
+				Fields []schema.FieldError `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || len(results) != 2 {
+				t.Fatalf("\t%s\tShould get a status for each item : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get a status for each item.", tests.Success)
+
+			if results[0].Status != "created" || results[1].Status != "failed" || len(results[1].Fields) != 1 || results[1].Fields[0].Field != "body" {
+				t.Log(w.Body.String())
+				t.Fatalf("\t%s\tShould only fail the item without a body.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould only fail the item without a body.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/wire"
+)
+
+// TestBackfillNotFound tests a backfill can't be started or retrieved for a
+// type without a pattern.
+func TestBackfillNotFound(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to backfill the graph for a type.")
+	{
+		for _, verb := range []string{"POST", "GET"} {
+			url := "/1.0/graph/backfill/" + patternPrefix + "unknown"
+			r := tests.NewRequest(verb, url, nil)
+			w := httptest.NewRecorder()
+
+			a.ServeHTTP(w, r)
+
+			t.Logf("\tWhen calling url with %s for a type without a pattern : %s", verb, url)
+			{
+				if w.Code != 404 {
+					t.Fatalf("\t%s\tShould get a not found : %v", tests.Failed, w.Code)
+				}
+				t.Logf("\t%s\tShould get a not found.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestCheck tests the consistency of the graph can be checked.
+func TestCheck(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to check the consistency of the graph.")
+	{
+		url := "/1.0/graph/check?limit=10"
+		r := tests.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to check the graph : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to check the graph.", tests.Success)
+
+			var report wire.CheckReport
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the report : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the report.", tests.Success)
+
+			if report.Added != 0 || report.Removed != 0 {
+				t.Fatalf("\t%s\tShould not repair the graph : %+v", tests.Failed, report)
+			}
+			t.Logf("\t%s\tShould not repair the graph.", tests.Success)
+		}
+	}
+}
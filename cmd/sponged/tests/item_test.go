@@ -180,7 +180,7 @@ func TestUpsertItem(t *testing.T) {
 		//----------------------------------------------------------------------
 		// Update the Item.
 
-		items[0].Version = 2
+		items[0].Data["body"] = "An updated comment."
 
 		itemStrData, err = json.Marshal(items[0])
 		if err != nil {
@@ -202,6 +202,24 @@ func TestUpsertItem(t *testing.T) {
 			t.Logf("\t%s\tShould be able to update the item.", tests.Success)
 		}
 
+		//----------------------------------------------------------------------
+		// Update the Item from a stale version.
+
+		url = "/1.0/item"
+		r = tests.NewRequest("PUT", url, bytes.NewBuffer(itemStrData))
+		r.Header.Set("If-Match", `"1"`)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to update a stale version : %s", url)
+		{
+			if w.Code != 409 {
+				t.Fatalf("\t%s\tShould get a conflict for the stale version : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould get a conflict for the stale version.", tests.Success)
+		}
+
 		//----------------------------------------------------------------------
 		// Retrieve the Item.
 
@@ -230,6 +248,35 @@ func TestUpsertItem(t *testing.T) {
 			}
 			t.Logf("\t%s\tShould get the expected result.", tests.Success)
 		}
+
+		//----------------------------------------------------------------------
+		// Retrieve the first version of the Item.
+
+		url = "/1.0/item/" + items[0].ID + "/version/1"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get a version : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the version : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the version.", tests.Success)
+
+			var itOld item.Item
+			if err := json.Unmarshal(w.Body.Bytes(), &itOld); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if itOld.Version != 1 || itOld.Data["body"] == "An updated comment." {
+				t.Log(w.Body.String())
+				t.Fatalf("\t%s\tShould get the item as it was before the update.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get the item as it was before the update.", tests.Success)
+		}
 	}
 }
 
@@ -296,3 +343,119 @@ func TestDeleteItem(t *testing.T) {
 
 	}
 }
+
+// TestBulkItems tests the insert and update of items with a bulk request.
+func TestBulkItems(t *testing.T) {
+	store := setup(t)
+	defer teardown(t, store)
+
+	t.Log("Given the need to upsert many items with one request.")
+	{
+		//----------------------------------------------------------------------
+		// Get the fixture.
+
+		items, err := itemfix.Get()
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to retrieve the fixture : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to retrieve the fixture.", tests.Success)
+
+		var ndjson bytes.Buffer
+		enc := json.NewEncoder(&ndjson)
+		for i := range items {
+			if err := enc.Encode(&items[i]); err != nil {
+				t.Fatalf("\t%s\tShould be able to marshal the fixture : %v", tests.Failed, err)
+			}
+		}
+
+		// Repeat the first item with an invalid version.
+		bad := items[0]
+		bad.Version = 0
+		if err := enc.Encode(&bad); err != nil {
+			t.Fatalf("\t%s\tShould be able to marshal the fixture : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to marshal the fixture.", tests.Success)
+
+		type result struct {
+			ID     string `json:"item_id"`
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+		}
+
+		//----------------------------------------------------------------------
+		// Upsert the items as newline delimited JSON.
+
+		url := "/1.0/item/_bulk"
+		r := tests.NewRequest("POST", url, &ndjson)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to upsert newline delimited JSON : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to upsert the items : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to upsert the items.", tests.Success)
+
+			var results []result
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if len(results) != len(items)+1 {
+				t.Fatalf("\t%s\tShould get a status for each item : %d", tests.Failed, len(results))
+			}
+			t.Logf("\t%s\tShould get a status for each item.", tests.Success)
+
+			if results[0].Status != "failed" || results[len(items)].Status != "failed" {
+				t.Log(w.Body.String())
+				t.Fatalf("\t%s\tShould fail the repeated and the invalid item.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould fail the repeated and the invalid item.", tests.Success)
+
+			for _, res := range results[1:len(items)] {
+				if res.Status == "failed" {
+					t.Fatalf("\t%s\tShould write the other items : %s : %s", tests.Failed, res.ID, res.Reason)
+				}
+			}
+			t.Logf("\t%s\tShould write the other items.", tests.Success)
+		}
+
+		//----------------------------------------------------------------------
+		// Upsert the same items as a JSON array.
+
+		data, err := json.Marshal(items[1:])
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to marshal the fixture : %v", tests.Failed, err)
+		}
+
+		r = tests.NewRequest("POST", url, bytes.NewBuffer(data))
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to upsert a JSON array : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to upsert the items : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to upsert the items.", tests.Success)
+
+			var results []result
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			for _, res := range results {
+				if res.Status != "unchanged" {
+					t.Log(w.Body.String())
+					t.Fatalf("\t%s\tShould report the items as unchanged : %s", tests.Failed, res.ID)
+				}
+			}
+			t.Logf("\t%s\tShould report the items as unchanged.", tests.Success)
+		}
+	}
+}
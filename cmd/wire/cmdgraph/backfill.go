@@ -0,0 +1,112 @@
+package cmdgraph
+
+import (
+	"encoding/json"
+
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/spf13/cobra"
+)
+
+var backfillLong = `Use backfill to re-infer the relationships of the items of a type after
+its pattern changed. The relationships inferred with the from revision of the
+pattern are replaced with the ones inferred with the newest revision. The from
+revision defaults to the revision before the newest. Running backfill again
+resumes an interrupted backfill.
+
+Example:
+	graph backfill -t comment
+
+	graph backfill -t comment -f 2 -b 1000
+`
+
+var progressLong = `Use progress to retrieve the progress of the backfill for a type.
+
+Example:
+	graph progress -t comment
+`
+
+// backfill contains the state for these commands.
+var backfill struct {
+	itemType string
+	from     int
+	batch    int
+}
+
+// addBackfill handles re-inferring the relationships of the items of a type.
+func addBackfill() {
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Backfill re-infers the relationships of the items of a type.",
+		Long:  backfillLong,
+		Run:   runBackfill,
+	}
+
+	cmd.Flags().StringVarP(&backfill.itemType, "type", "t", "", "Item type")
+	cmd.Flags().IntVarP(&backfill.from, "from", "f", -1, "Pattern revision the items were inferred with")
+	cmd.Flags().IntVarP(&backfill.batch, "batch", "b", wire.DefBackfillBatch, "Items processed at a time")
+
+	graphCmd.AddCommand(cmd)
+}
+
+// addProgress handles the retrieval of the progress of a backfill.
+func addProgress() {
+	cmd := &cobra.Command{
+		Use:   "progress",
+		Short: "Progress retrieves the progress of the backfill for a type.",
+		Long:  progressLong,
+		Run:   runProgress,
+	}
+
+	cmd.Flags().StringVarP(&backfill.itemType, "type", "t", "", "Item type")
+
+	graphCmd.AddCommand(cmd)
+}
+
+// runBackfill is the code that implements the backfill command.
+func runBackfill(cmd *cobra.Command, args []string) {
+	cmd.Printf("Backfilling Graph : Type[%s]\n", backfill.itemType)
+
+	// Validate the input parameters.
+	if backfill.itemType == "" {
+		cmd.Help()
+		return
+	}
+
+	bf, err := wire.StartBackfill("", mgoDB, backfill.itemType, backfill.from)
+	if err != nil {
+		cmd.Println("Backfilling Graph : ", err)
+		return
+	}
+
+	cmd.Printf("Backfilling Graph : From[%d] To[%d] Total[%d] Processed[%d]\n", bf.From, bf.To, bf.Total, bf.Processed)
+
+	progress := func(bf wire.Backfill) {
+		cmd.Printf("Backfilling Graph : Processed[%d/%d] Added[%d] Removed[%d]\n", bf.Processed, bf.Total, bf.Added, bf.Removed)
+	}
+
+	if err := wire.RunBackfill("", mgoDB, graphDB, bf, backfill.batch, progress); err != nil {
+		cmd.Println("Backfilling Graph : ", err)
+		return
+	}
+
+	cmd.Println("\n", "Backfilling Graph : Completed")
+}
+
+// runProgress is the code that implements the progress command.
+func runProgress(cmd *cobra.Command, args []string) {
+	cmd.Printf("Getting Backfill : Type[%s]\n", backfill.itemType)
+
+	bf, err := wire.GetBackfill("", mgoDB, backfill.itemType)
+	if err != nil {
+		cmd.Println("Getting Backfill : ", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(bf, "", "    ")
+	if err != nil {
+		cmd.Println("Getting Backfill : ", err)
+		return
+	}
+
+	cmd.Printf("\n%s\n\n", string(data))
+}
@@ -0,0 +1,61 @@
+package cmdgraph
+
+import (
+	"encoding/json"
+
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/spf13/cobra"
+)
+
+var checkLong = `Use check to compare the items with the relationships in the graph. It
+reports relationships whose subject or object has no item, relationships the
+patterns of the items imply that are missing and relationships whose predicate
+is not defined. With repair the missing relationships are added and the
+orphaned ones are removed. Relationships with an undefined predicate are only
+reported.
+
+Example:
+	graph check
+
+	graph check --repair -l 10
+`
+
+// check contains the state for this command.
+var check struct {
+	repair bool
+	limit  int
+}
+
+// addCheck handles checking and repairing the consistency of the graph.
+func addCheck() {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check reports and repairs differences between the items and the graph.",
+		Long:  checkLong,
+		Run:   runCheck,
+	}
+
+	cmd.Flags().BoolVar(&check.repair, "repair", false, "Add missing and remove orphaned relationships")
+	cmd.Flags().IntVarP(&check.limit, "limit", "l", wire.DefCheckLimit, "Relationships listed for each issue")
+
+	graphCmd.AddCommand(cmd)
+}
+
+// runCheck is the code that implements the check command.
+func runCheck(cmd *cobra.Command, args []string) {
+	cmd.Printf("Checking Graph : Repair[%v]\n", check.repair)
+
+	report, err := wire.Check("", mgoDB, graphDB, check.repair, check.limit)
+	if err != nil {
+		cmd.Println("Checking Graph : ", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		cmd.Println("Checking Graph : ", err)
+		return
+	}
+
+	cmd.Printf("\n%s\n\n", string(data))
+}
@@ -0,0 +1,32 @@
+package cmdgraph
+
+import (
+	"github.com/ardanlabs/kit/db"
+	"github.com/cayleygraph/cayley"
+	"github.com/spf13/cobra"
+)
+
+// graphCmd represents the parent for all graph cli commands.
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "graph provides a CLI for maintaining the relationships in the graph.",
+}
+
+var (
+	// mgoDB holds the session for the DB access.
+	mgoDB *db.DB
+
+	// graphDB holds the graph handle for graph access.
+	graphDB *cayley.Handle
+)
+
+// GetCommands returns the graph commands.
+func GetCommands(conn *db.DB, store *cayley.Handle) *cobra.Command {
+	mgoDB = conn
+	graphDB = store
+
+	addBackfill()
+	addProgress()
+	addCheck()
+	return graphCmd
+}
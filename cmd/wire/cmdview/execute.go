@@ -11,6 +11,10 @@ var executeLong = `Use execute to execute a view.
 
 Example:
 	view execute -n viewname -i itemkey -c resultscollection -b bufferlimit
+
+	view execute -n viewname -i itemkey -s=-data.created -f body,author -l 20
+
+	view execute -n viewname -i itemkey --count
 `
 
 // execute contains the state for this command.
@@ -19,6 +23,12 @@ var execute struct {
 	itemKey           string
 	resultsCollection string
 	bufferLimit       int
+	sort              []string
+	fields            []string
+	skip              int
+	limit             int
+	cursor            string
+	count             bool
 }
 
 // addExecute handles the execution of a view.
@@ -34,6 +44,12 @@ func addExecute() {
 	cmd.Flags().StringVarP(&execute.itemKey, "key", "i", "", "Item key")
 	cmd.Flags().StringVarP(&execute.resultsCollection, "collection", "c", "", "Results collection")
 	cmd.Flags().IntVarP(&execute.bufferLimit, "buffer", "b", 0, "Buffer Limit")
+	cmd.Flags().StringSliceVarP(&execute.sort, "sort", "s", nil, "Item fields to sort by, - for descending")
+	cmd.Flags().StringSliceVarP(&execute.fields, "fields", "f", nil, "Data fields to return")
+	cmd.Flags().IntVar(&execute.skip, "skip", 0, "Number of items to skip")
+	cmd.Flags().IntVarP(&execute.limit, "limit", "l", 0, "Number of items per page")
+	cmd.Flags().StringVar(&execute.cursor, "cursor", "", "Cursor of the page to read")
+	cmd.Flags().BoolVar(&execute.count, "count", false, "Only count the items")
 
 	viewCmd.AddCommand(cmd)
 }
@@ -54,6 +70,12 @@ func runExecute(cmd *cobra.Command, args []string) {
 		ItemKey:           execute.itemKey,
 		ResultsCollection: execute.resultsCollection,
 		BufferLimit:       execute.bufferLimit,
+		Sort:              execute.sort,
+		Fields:            execute.fields,
+		Skip:              execute.skip,
+		Limit:             execute.limit,
+		Cursor:            execute.cursor,
+		Count:             execute.count,
 	}
 
 	// Execute the view.
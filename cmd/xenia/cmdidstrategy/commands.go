@@ -0,0 +1,23 @@
+package cmdidstrategy
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
+
+// idstrategyCmd represents the parent for all ID strategy cli commands.
+var idstrategyCmd = &cobra.Command{
+	Use:   "idstrategy",
+	Short: "idstrategy provides a xenia CLI for managing the ID strategies of item types.",
+}
+
+// GetCommands returns the ID strategy commands.
+func GetCommands() *cobra.Command {
+	addUpsert()
+	addGet()
+	addDel()
+	history.AddCommands(idstrategyCmd, "idstrategy", "ID Strategy",
+		history.Key{Name: "type", Shorthand: "t", Usage: "Item type of the ID Strategy.", Example: "comment"},
+	)
+	return idstrategyCmd
+}
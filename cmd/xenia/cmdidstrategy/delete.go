@@ -0,0 +1,45 @@
+package cmdidstrategy
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var deleteLong = `Removes an ID Strategy from the system using the item type. Items of the
+type use the default strategy afterwards.
+
+Example:
+	idstrategy delete -t comment
+`
+
+// delete contains the state for this command.
+var delete struct {
+	itype string
+}
+
+// addDel handles the deletion of ID Strategy records.
+func addDel() {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Removes an ID Strategy record by item type.",
+		Long:  deleteLong,
+		Run:   runDelete,
+	}
+
+	cmd.Flags().StringVarP(&delete.itype, "type", "t", "", "Item type of the ID Strategy.")
+
+	idstrategyCmd.AddCommand(cmd)
+}
+
+// runDelete issues the command talking to the web service.
+func runDelete(cmd *cobra.Command, args []string) {
+	verb := "DELETE"
+	url := "/v1/idstrategy/" + delete.itype
+
+	if _, err := web.Request(cmd, verb, url, nil); err != nil {
+		cmd.Println("Deleting ID Strategy : ", err)
+		return
+	}
+
+	cmd.Println("Deleting ID Strategy : Deleted")
+}
@@ -0,0 +1,115 @@
+package cmdidstrategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/coralproject/shelf/cmd/xenia/disk"
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+	"github.com/spf13/cobra"
+)
+
+var upsertLong = `Use upsert to add or update the ID strategy of an item type in the system.
+
+Example:
+	idstrategy upsert -p comment.json
+
+	idstrategy upsert -p ./strategies
+`
+
+// upsert contains the state for this command.
+var upsert struct {
+	path string
+}
+
+// addUpsert handles the add or update of ID strategy records into the db.
+func addUpsert() {
+	cmd := &cobra.Command{
+		Use:   "upsert",
+		Short: "Upsert adds or updates an ID strategy from a file or directory.",
+		Long:  upsertLong,
+		Run:   runUpsert,
+	}
+
+	cmd.Flags().StringVarP(&upsert.path, "path", "p", "", "Path of ID strategy file or directory.")
+
+	idstrategyCmd.AddCommand(cmd)
+}
+
+// runUpsert is the code that implements the upsert command.
+func runUpsert(cmd *cobra.Command, args []string) {
+	cmd.Printf("Upserting ID Strategy : Path[%s]\n", upsert.path)
+
+	if upsert.path == "" {
+		cmd.Help()
+		return
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		cmd.Println("Upserting ID Strategy : ", err)
+		return
+	}
+
+	file := filepath.Join(pwd, upsert.path)
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		cmd.Println("Upserting ID Strategy : ", err)
+		return
+	}
+
+	if !stat.IsDir() {
+		s, err := disk.LoadIDStrategy("", file)
+		if err != nil {
+			cmd.Println("Upserting ID Strategy : ", err)
+			return
+		}
+
+		if err := runUpsertWeb(cmd, s); err != nil {
+			cmd.Println("Upserting ID Strategy : ", err)
+			return
+		}
+
+		cmd.Println("\n", "Upserting ID Strategy : Upserted")
+		return
+	}
+
+	f := func(path string) error {
+		s, err := disk.LoadIDStrategy("", path)
+		if err != nil {
+			return err
+		}
+
+		return runUpsertWeb(cmd, s)
+	}
+
+	if err := disk.LoadDir(file, f); err != nil {
+		cmd.Println("Upserting ID Strategy : ", err)
+		return
+	}
+
+	cmd.Println("\n", "Upserting ID Strategy : Upserted")
+}
+
+// runUpsertWeb issues the command talking to the web service.
+func runUpsertWeb(cmd *cobra.Command, s idstrategy.Strategy) error {
+	verb := "PUT"
+	url := "/v1/idstrategy"
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("\n%s\n\n", string(data))
+
+	if _, err := web.Request(cmd, verb, url, bytes.NewBuffer(data)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package cmdlive
+
+import "github.com/spf13/cobra"
+
+// liveCmd represents the parent for all live view cli commands.
+var liveCmd = &cobra.Command{
+	Use:   "live",
+	Short: "live provides a xenia CLI for managing the views kept up to date in a collection.",
+}
+
+// GetCommands returns the live view commands.
+func GetCommands() *cobra.Command {
+	addUpsert()
+	addGet()
+	addDel()
+	addRefresh()
+	return liveCmd
+}
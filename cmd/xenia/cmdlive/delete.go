@@ -0,0 +1,45 @@
+package cmdlive
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var deleteLong = `Removes a live view from the system using its name. The items already saved
+in its collection are kept.
+
+Example:
+	live delete -n name
+`
+
+// delete contains the state for this command.
+var delete struct {
+	name string
+}
+
+// addDel handles the deletion of live view records.
+func addDel() {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Removes a live view record by name.",
+		Long:  deleteLong,
+		Run:   runDelete,
+	}
+
+	cmd.Flags().StringVarP(&delete.name, "name", "n", "", "Name of the live view.")
+
+	liveCmd.AddCommand(cmd)
+}
+
+// runDelete issues the command talking to the web service.
+func runDelete(cmd *cobra.Command, args []string) {
+	verb := "DELETE"
+	url := "/v1/live/" + delete.name
+
+	if _, err := web.Request(cmd, verb, url, nil); err != nil {
+		cmd.Println("Deleting Live View : ", err)
+		return
+	}
+
+	cmd.Println("Deleting Live View : Deleted")
+}
@@ -0,0 +1,50 @@
+package cmdlive
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var getLong = `Retrieves live view records from the system with the optional supplied name.
+
+Example:
+	live get
+
+	live get -n name
+`
+
+// get contains the state for this command.
+var get struct {
+	name string
+}
+
+// addGet handles the retrival of live view records, displayed in json formatted response.
+func addGet() {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Retrieves all live view records, or those matching an optional name.",
+		Long:  getLong,
+		Run:   runGet,
+	}
+
+	cmd.Flags().StringVarP(&get.name, "name", "n", "", "Name of the live view.")
+
+	liveCmd.AddCommand(cmd)
+}
+
+// runGet issues the command talking to the web service.
+func runGet(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/live"
+
+	if get.name != "" {
+		url += "/" + get.name
+	}
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Live View : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
@@ -0,0 +1,46 @@
+package cmdlive
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var refreshLong = `Saves all the items of a live view into its collection again and removes
+the items that are no longer in the view.
+
+Example:
+	live refresh -n name
+`
+
+// refresh contains the state for this command.
+var refresh struct {
+	name string
+}
+
+// addRefresh handles refreshing the collection of a live view.
+func addRefresh() {
+	cmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Refreshes the collection of a live view by name.",
+		Long:  refreshLong,
+		Run:   runRefresh,
+	}
+
+	cmd.Flags().StringVarP(&refresh.name, "name", "n", "", "Name of the live view.")
+
+	liveCmd.AddCommand(cmd)
+}
+
+// runRefresh issues the command talking to the web service.
+func runRefresh(cmd *cobra.Command, args []string) {
+	verb := "POST"
+	url := "/v1/live/" + refresh.name + "/refresh"
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Refreshing Live View : ", err)
+		return
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
@@ -0,0 +1,66 @@
+package cmdlive
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/coralproject/shelf/internal/wire/live"
+	"github.com/spf13/cobra"
+)
+
+var upsertLong = `Use upsert to register a view whose items are kept up to date in a
+collection as items are written. The items of the view are saved into the
+collection when it is registered.
+
+Example:
+	live upsert -n user_comments_live -v user_comments -i 80aa936a -c user_comments_80aa936a
+`
+
+// upsert contains the state for this command.
+var upsert live.Live
+
+// addUpsert handles the add or update of live view records.
+func addUpsert() {
+	cmd := &cobra.Command{
+		Use:   "upsert",
+		Short: "Upsert registers a live view for a view and item.",
+		Long:  upsertLong,
+		Run:   runUpsert,
+	}
+
+	cmd.Flags().StringVarP(&upsert.Name, "name", "n", "", "Name of the live view.")
+	cmd.Flags().StringVarP(&upsert.View, "view", "v", "", "Name of the view.")
+	cmd.Flags().StringVarP(&upsert.ItemKey, "key", "i", "", "Item the view starts from.")
+	cmd.Flags().StringVarP(&upsert.Collection, "collection", "c", "", "Collection the items are kept in.")
+
+	liveCmd.AddCommand(cmd)
+}
+
+// runUpsert issues the command talking to the web service.
+func runUpsert(cmd *cobra.Command, args []string) {
+	cmd.Printf("Upserting Live View : Name[%s]\n", upsert.Name)
+
+	if upsert.Name == "" {
+		cmd.Help()
+		return
+	}
+
+	verb := "PUT"
+	url := "/v1/live"
+
+	data, err := json.Marshal(upsert)
+	if err != nil {
+		cmd.Println("Upserting Live View : ", err)
+		return
+	}
+
+	resp, err := web.Request(cmd, verb, url, bytes.NewBuffer(data))
+	if err != nil {
+		cmd.Println("Upserting Live View : ", err)
+		return
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+	cmd.Println("Upserting Live View : Upserted")
+}
@@ -1,6 +1,9 @@
 package cmdmask
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // maskCmd represents the parent for all mask cli commands.
 var maskCmd = &cobra.Command{
@@ -13,5 +16,9 @@ func GetCommands() *cobra.Command {
 	addUpsert()
 	addGet()
 	addDel()
+	history.AddCommands(maskCmd, "mask", "Mask",
+		history.Key{Name: "collection", Shorthand: "c", Usage: "Name of the Collection.", Example: "comments"},
+		history.Key{Name: "field", Shorthand: "f", Usage: "Name of the Field.", Example: "email"},
+	)
 	return maskCmd
 }
@@ -1,6 +1,9 @@
 package cmdpattern
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // patternCmd represents the parent for all pattern cli commands.
 var patternCmd = &cobra.Command{
@@ -13,5 +16,8 @@ func GetCommands() *cobra.Command {
 	addUpsert()
 	addGet()
 	addDel()
+	history.AddCommands(patternCmd, "pattern", "Pattern",
+		history.Key{Name: "type", Shorthand: "t", Usage: "Type of the Pattern.", Example: "comment"},
+	)
 	return patternCmd
 }
@@ -1,6 +1,9 @@
 package cmdquery
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // queryCmd represents the parent for all query cli commands.
 var queryCmd = &cobra.Command{
@@ -16,5 +19,9 @@ func GetCommands() *cobra.Command {
 	addExec()
 	addList()
 	addIndex()
+	addStats()
+	history.AddCommands(queryCmd, "query", "Set",
+		history.Key{Name: "name", Shorthand: "n", Usage: "Name of the Set.", Example: "user_advice"},
+	)
 	return queryCmd
 }
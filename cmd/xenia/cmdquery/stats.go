@@ -0,0 +1,52 @@
+package cmdquery
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var statsLong = `Retrieves the execution statistics for a Set with the supplied name.
+The statistics can be limited to the executions within a recent period.
+
+Example:
+	query stats -n user_advice
+
+	query stats -n user_advice -s 24h
+`
+
+// stats contains the state for this command.
+var stats struct {
+	name  string
+	since string
+}
+
+// addStats handles the retrival of the execution statistics for a Set.
+func addStats() {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Retrieves the execution statistics for a Set by name.",
+		Long:  statsLong,
+		Run:   runStats,
+	}
+
+	cmd.Flags().StringVarP(&stats.name, "name", "n", "", "Name of the Set.")
+	cmd.Flags().StringVarP(&stats.since, "since", "s", "", "Duration of the recent period, e.g. 24h.")
+
+	queryCmd.AddCommand(cmd)
+}
+
+// runStats issues the command talking to the web service.
+func runStats(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/stats/query/" + stats.name
+	if stats.since != "" {
+		url += "?since=" + stats.since
+	}
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Set Stats : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
@@ -1,6 +1,9 @@
 package cmdregex
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // regexCmd represents the parent for all regex cli commands.
 var regexCmd = &cobra.Command{
@@ -14,5 +17,8 @@ func GetCommands() *cobra.Command {
 	addGet()
 	addDel()
 	addList()
+	history.AddCommands(regexCmd, "regex", "Regex",
+		history.Key{Name: "name", Shorthand: "n", Usage: "Name of the Regex.", Example: "email"},
+	)
 	return regexCmd
 }
@@ -1,6 +1,9 @@
 package cmdrelationship
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // relationshipCmd represents the parent for all relationship cli commands.
 var relationshipCmd = &cobra.Command{
@@ -13,5 +16,8 @@ func GetCommands() *cobra.Command {
 	addUpsert()
 	addGet()
 	addDel()
+	history.AddCommands(relationshipCmd, "relationship", "Relationship",
+		history.Key{Name: "predicate", Shorthand: "p", Usage: "Predicate of the Relationship.", Example: "authored"},
+	)
 	return relationshipCmd
 }
@@ -0,0 +1,19 @@
+package cmdschedule
+
+import "github.com/spf13/cobra"
+
+// scheduleCmd represents the parent for all schedule cli commands.
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "schedule provides a xenia CLI for managing query set schedules.",
+}
+
+// GetCommands returns the schedule commands.
+func GetCommands() *cobra.Command {
+	addUpsert()
+	addGet()
+	addDel()
+	addRuns()
+	addRun()
+	return scheduleCmd
+}
@@ -0,0 +1,45 @@
+package cmdschedule
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var deleteLong = `Removes a Schedule from the system using the Schedule name.
+The record of its runs is kept.
+
+Example:
+	schedule delete -n nightly_top_commenters
+`
+
+// delete contains the state for this command.
+var delete struct {
+	name string
+}
+
+// addDel handles the removal of Schedule records.
+func addDel() {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Removes a Schedule record by name.",
+		Long:  deleteLong,
+		Run:   runDelete,
+	}
+
+	cmd.Flags().StringVarP(&delete.name, "name", "n", "", "Name of the Schedule record.")
+
+	scheduleCmd.AddCommand(cmd)
+}
+
+// runDelete issues the command talking to the web service.
+func runDelete(cmd *cobra.Command, args []string) {
+	verb := "DELETE"
+	url := "/v1/schedule/" + delete.name
+
+	if _, err := web.Request(cmd, verb, url, nil); err != nil {
+		cmd.Println("Deleting Schedule : ", err)
+		return
+	}
+
+	cmd.Println("Deleting Schedule : Deleted")
+}
@@ -0,0 +1,50 @@
+package cmdschedule
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var getLong = `Retrieves schedule records from the system with the optional supplied name.
+
+Example:
+	schedule get
+
+	schedule get -n nightly_top_commenters
+`
+
+// get contains the state for this command.
+var get struct {
+	name string
+}
+
+// addGet handles the retrival of schedule records, displayed in json formatted response.
+func addGet() {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Retrieves all schedule records, or those matching an optional name.",
+		Long:  getLong,
+		Run:   runGet,
+	}
+
+	cmd.Flags().StringVarP(&get.name, "name", "n", "", "Schedule name.")
+
+	scheduleCmd.AddCommand(cmd)
+}
+
+// runGet issues the command talking to the web service.
+func runGet(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/schedule"
+
+	if get.name != "" {
+		url += "/" + get.name
+	}
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Schedule : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
@@ -0,0 +1,83 @@
+package cmdschedule
+
+import (
+	"strconv"
+
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var runsLong = `Retrieves the most recent runs of a Schedule with the newest first.
+
+Example:
+	schedule runs -n nightly_top_commenters
+
+	schedule runs -n nightly_top_commenters -l 5
+`
+
+var runLong = `Executes the set of a Schedule now and records the run.
+
+Example:
+	schedule run -n nightly_top_commenters
+`
+
+// runs contains the state for these commands.
+var runs struct {
+	name  string
+	limit int
+}
+
+// addRuns handles the retrival of the runs of a schedule.
+func addRuns() {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Retrieves the recent runs of a Schedule by name.",
+		Long:  runsLong,
+		Run:   runRuns,
+	}
+
+	cmd.Flags().StringVarP(&runs.name, "name", "n", "", "Name of the Schedule.")
+	cmd.Flags().IntVarP(&runs.limit, "limit", "l", 20, "Number of runs to retrieve.")
+
+	scheduleCmd.AddCommand(cmd)
+}
+
+// addRun handles executing a schedule now.
+func addRun() {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Executes the set of a Schedule now.",
+		Long:  runLong,
+		Run:   runRun,
+	}
+
+	cmd.Flags().StringVarP(&runs.name, "name", "n", "", "Name of the Schedule.")
+
+	scheduleCmd.AddCommand(cmd)
+}
+
+// runRuns issues the command talking to the web service.
+func runRuns(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/schedule/" + runs.name + "/runs?limit=" + strconv.Itoa(runs.limit)
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Schedule Runs : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
+
+// runRun issues the command talking to the web service.
+func runRun(cmd *cobra.Command, args []string) {
+	verb := "POST"
+	url := "/v1/schedule/" + runs.name + "/run"
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Running Schedule : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
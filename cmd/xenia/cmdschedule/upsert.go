@@ -0,0 +1,115 @@
+package cmdschedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/coralproject/shelf/cmd/xenia/disk"
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+	"github.com/spf13/cobra"
+)
+
+var upsertLong = `Use upsert to add or update a schedule in the system.
+
+Example:
+	schedule upsert -p nightly.json
+
+	schedule upsert -p ./schedules
+`
+
+// upsert contains the state for this command.
+var upsert struct {
+	path string
+}
+
+// addUpsert handles the add or update of schedule records into the db.
+func addUpsert() {
+	cmd := &cobra.Command{
+		Use:   "upsert",
+		Short: "Upsert adds or updates a schedule from a file or directory.",
+		Long:  upsertLong,
+		Run:   runUpsert,
+	}
+
+	cmd.Flags().StringVarP(&upsert.path, "path", "p", "", "Path of schedule file or directory.")
+
+	scheduleCmd.AddCommand(cmd)
+}
+
+// runUpsert is the code that implements the upsert command.
+func runUpsert(cmd *cobra.Command, args []string) {
+	cmd.Printf("Upserting Schedule : Path[%s]\n", upsert.path)
+
+	if upsert.path == "" {
+		cmd.Help()
+		return
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		cmd.Println("Upserting Schedule : ", err)
+		return
+	}
+
+	file := filepath.Join(pwd, upsert.path)
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		cmd.Println("Upserting Schedule : ", err)
+		return
+	}
+
+	if !stat.IsDir() {
+		sch, err := disk.LoadSchedule("", file)
+		if err != nil {
+			cmd.Println("Upserting Schedule : ", err)
+			return
+		}
+
+		if err := runUpsertWeb(cmd, sch); err != nil {
+			cmd.Println("Upserting Schedule : ", err)
+			return
+		}
+
+		cmd.Println("\n", "Upserting Schedule : Upserted")
+		return
+	}
+
+	f := func(path string) error {
+		sch, err := disk.LoadSchedule("", path)
+		if err != nil {
+			return err
+		}
+
+		return runUpsertWeb(cmd, sch)
+	}
+
+	if err := disk.LoadDir(file, f); err != nil {
+		cmd.Println("Upserting Schedule : ", err)
+		return
+	}
+
+	cmd.Println("\n", "Upserting Schedule : Upserted")
+}
+
+// runUpsertWeb issues the command talking to the web service.
+func runUpsertWeb(cmd *cobra.Command, sch schedule.Schedule) error {
+	verb := "PUT"
+	url := "/v1/schedule"
+
+	data, err := json.Marshal(sch)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("\n%s\n\n", string(data))
+
+	if _, err := web.Request(cmd, verb, url, bytes.NewBuffer(data)); err != nil {
+		return err
+	}
+
+	return nil
+}
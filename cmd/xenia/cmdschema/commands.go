@@ -0,0 +1,24 @@
+package cmdschema
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the parent for all schema cli commands.
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "schema provides a xenia CLI for managing the schemas of item types.",
+}
+
+// GetCommands returns the schema commands.
+func GetCommands() *cobra.Command {
+	addUpsert()
+	addGet()
+	addDel()
+	addViolations()
+	history.AddCommands(schemaCmd, "schema", "Schema",
+		history.Key{Name: "type", Shorthand: "t", Usage: "Item type of the Schema.", Example: "comment"},
+	)
+	return schemaCmd
+}
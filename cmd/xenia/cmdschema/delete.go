@@ -0,0 +1,45 @@
+package cmdschema
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var deleteLong = `Removes a Schema from the system using the item type. Items of the
+type are no longer validated afterwards.
+
+Example:
+	schema delete -t comment
+`
+
+// delete contains the state for this command.
+var delete struct {
+	itype string
+}
+
+// addDel handles the deletion of Schema records.
+func addDel() {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Removes a Schema record by item type.",
+		Long:  deleteLong,
+		Run:   runDelete,
+	}
+
+	cmd.Flags().StringVarP(&delete.itype, "type", "t", "", "Item type of the Schema.")
+
+	schemaCmd.AddCommand(cmd)
+}
+
+// runDelete issues the command talking to the web service.
+func runDelete(cmd *cobra.Command, args []string) {
+	verb := "DELETE"
+	url := "/v1/schema/" + delete.itype
+
+	if _, err := web.Request(cmd, verb, url, nil); err != nil {
+		cmd.Println("Deleting Schema : ", err)
+		return
+	}
+
+	cmd.Println("Deleting Schema : Deleted")
+}
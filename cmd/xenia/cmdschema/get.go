@@ -0,0 +1,50 @@
+package cmdschema
+
+import (
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var getLong = `Retrieves schema records from the system with the optional supplied item type.
+
+Example:
+	schema get
+
+	schema get -t comment
+`
+
+// get contains the state for this command.
+var get struct {
+	itype string
+}
+
+// addGet handles the retrival of schema records, displayed in json formatted response.
+func addGet() {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Retrieves all schema records, or the one for an optional item type.",
+		Long:  getLong,
+		Run:   runGet,
+	}
+
+	cmd.Flags().StringVarP(&get.itype, "type", "t", "", "Item type of the Schema.")
+
+	schemaCmd.AddCommand(cmd)
+}
+
+// runGet issues the command talking to the web service.
+func runGet(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/schema"
+
+	if get.itype != "" {
+		url += "/" + get.itype
+	}
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Schema : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
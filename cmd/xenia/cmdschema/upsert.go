@@ -0,0 +1,117 @@
+package cmdschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/coralproject/shelf/cmd/xenia/disk"
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+	"github.com/spf13/cobra"
+)
+
+var upsertLong = `Use upsert to add or update the schema of an item type in the system. Items
+that don't match a schema with the mode "report" are stored and recorded as
+violations instead of being rejected.
+
+Example:
+	schema upsert -p comment.json
+
+	schema upsert -p ./schemas
+`
+
+// upsert contains the state for this command.
+var upsert struct {
+	path string
+}
+
+// addUpsert handles the add or update of schema records into the db.
+func addUpsert() {
+	cmd := &cobra.Command{
+		Use:   "upsert",
+		Short: "Upsert adds or updates a schema from a file or directory.",
+		Long:  upsertLong,
+		Run:   runUpsert,
+	}
+
+	cmd.Flags().StringVarP(&upsert.path, "path", "p", "", "Path of schema file or directory.")
+
+	schemaCmd.AddCommand(cmd)
+}
+
+// runUpsert is the code that implements the upsert command.
+func runUpsert(cmd *cobra.Command, args []string) {
+	cmd.Printf("Upserting Schema : Path[%s]\n", upsert.path)
+
+	if upsert.path == "" {
+		cmd.Help()
+		return
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		cmd.Println("Upserting Schema : ", err)
+		return
+	}
+
+	file := filepath.Join(pwd, upsert.path)
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		cmd.Println("Upserting Schema : ", err)
+		return
+	}
+
+	if !stat.IsDir() {
+		s, err := disk.LoadSchema("", file)
+		if err != nil {
+			cmd.Println("Upserting Schema : ", err)
+			return
+		}
+
+		if err := runUpsertWeb(cmd, s); err != nil {
+			cmd.Println("Upserting Schema : ", err)
+			return
+		}
+
+		cmd.Println("\n", "Upserting Schema : Upserted")
+		return
+	}
+
+	f := func(path string) error {
+		s, err := disk.LoadSchema("", path)
+		if err != nil {
+			return err
+		}
+
+		return runUpsertWeb(cmd, s)
+	}
+
+	if err := disk.LoadDir(file, f); err != nil {
+		cmd.Println("Upserting Schema : ", err)
+		return
+	}
+
+	cmd.Println("\n", "Upserting Schema : Upserted")
+}
+
+// runUpsertWeb issues the command talking to the web service.
+func runUpsertWeb(cmd *cobra.Command, s schema.Schema) error {
+	verb := "PUT"
+	url := "/v1/schema"
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("\n%s\n\n", string(data))
+
+	if _, err := web.Request(cmd, verb, url, bytes.NewBuffer(data)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmdschema
+
+import (
+	"strconv"
+
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var violationsLong = `Retrieves the most recent violations recorded for the items of a type whose
+Schema is in report mode, with the newest first.
+
+Example:
+	schema violations -t comment
+
+	schema violations -t comment -l 5
+`
+
+// violations contains the state for this command.
+var violations struct {
+	itype string
+	limit int
+}
+
+// addViolations handles the retrival of the violations of a schema.
+func addViolations() {
+	cmd := &cobra.Command{
+		Use:   "violations",
+		Short: "Retrieves the recent violations of a Schema by item type.",
+		Long:  violationsLong,
+		Run:   runViolations,
+	}
+
+	cmd.Flags().StringVarP(&violations.itype, "type", "t", "", "Item type of the Schema.")
+	cmd.Flags().IntVarP(&violations.limit, "limit", "l", 100, "Number of violations to retrieve.")
+
+	schemaCmd.AddCommand(cmd)
+}
+
+// runViolations issues the command talking to the web service.
+func runViolations(cmd *cobra.Command, args []string) {
+	verb := "GET"
+	url := "/v1/schema/" + violations.itype + "/violations?limit=" + strconv.Itoa(violations.limit)
+
+	resp, err := web.Request(cmd, verb, url, nil)
+	if err != nil {
+		cmd.Println("Getting Schema Violations : ", err)
+	}
+
+	cmd.Printf("\n%s\n\n", resp)
+}
@@ -1,6 +1,9 @@
 package cmdscript
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // scriptCmd represents the parent for all script cli commands.
 var scriptCmd = &cobra.Command{
@@ -14,5 +17,8 @@ func GetCommands() *cobra.Command {
 	addGet()
 	addDel()
 	addList()
+	history.AddCommands(scriptCmd, "script", "Script",
+		history.Key{Name: "name", Shorthand: "n", Usage: "Name of the Script.", Example: "QTEST_basic_script_pre"},
+	)
 	return scriptCmd
 }
@@ -1,6 +1,9 @@
 package cmdview
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/coralproject/shelf/cmd/xenia/history"
+	"github.com/spf13/cobra"
+)
 
 // viewCmd represents the parent for all view cli commands.
 var viewCmd = &cobra.Command{
@@ -13,5 +16,8 @@ func GetCommands() *cobra.Command {
 	addUpsert()
 	addGet()
 	addDel()
+	history.AddCommands(viewCmd, "view", "View",
+		history.Key{Name: "name", Shorthand: "n", Usage: "Name of the View.", Example: "user_comments"},
+	)
 	return viewCmd
 }
@@ -7,12 +7,15 @@ import (
 	"path/filepath"
 
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+	"github.com/coralproject/shelf/internal/sponge/schema"
 	"github.com/coralproject/shelf/internal/wire/pattern"
 	"github.com/coralproject/shelf/internal/wire/relationship"
 	"github.com/coralproject/shelf/internal/wire/view"
 	"github.com/coralproject/shelf/internal/xenia/mask"
 	"github.com/coralproject/shelf/internal/xenia/query"
 	"github.com/coralproject/shelf/internal/xenia/regex"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
 	"github.com/coralproject/shelf/internal/xenia/script"
 )
 
@@ -170,6 +173,72 @@ func LoadView(context interface{}, path string) (view.View, error) {
 	return v, nil
 }
 
+// LoadSchedule serializes the content of a Schedule from a file using the
+// given file path. Returns the serialized Schedule value.
+func LoadSchedule(context interface{}, path string) (schedule.Schedule, error) {
+	log.Dev(context, "LoadSchedule", "Started : File %s", path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error(context, "LoadSchedule", err, "Completed")
+		return schedule.Schedule{}, err
+	}
+	defer file.Close()
+
+	var sch schedule.Schedule
+	if err = json.NewDecoder(file).Decode(&sch); err != nil {
+		log.Error(context, "LoadSchedule", err, "Completed")
+		return schedule.Schedule{}, err
+	}
+
+	log.Dev(context, "LoadSchedule", "Completed")
+	return sch, nil
+}
+
+// LoadIDStrategy serializes the content of an ID strategy from a file using
+// the given file path. Returns the serialized Strategy value.
+func LoadIDStrategy(context interface{}, path string) (idstrategy.Strategy, error) {
+	log.Dev(context, "LoadIDStrategy", "Started : File %s", path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error(context, "LoadIDStrategy", err, "Completed")
+		return idstrategy.Strategy{}, err
+	}
+	defer file.Close()
+
+	var s idstrategy.Strategy
+	if err = json.NewDecoder(file).Decode(&s); err != nil {
+		log.Error(context, "LoadIDStrategy", err, "Completed")
+		return idstrategy.Strategy{}, err
+	}
+
+	log.Dev(context, "LoadIDStrategy", "Completed")
+	return s, nil
+}
+
+// LoadSchema serializes the content of a Schema from a file using the
+// given file path. Returns the serialized Schema value.
+func LoadSchema(context interface{}, path string) (schema.Schema, error) {
+	log.Dev(context, "LoadSchema", "Started : File %s", path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error(context, "LoadSchema", err, "Completed")
+		return schema.Schema{}, err
+	}
+	defer file.Close()
+
+	var s schema.Schema
+	if err = json.NewDecoder(file).Decode(&s); err != nil {
+		log.Error(context, "LoadSchema", err, "Completed")
+		return schema.Schema{}, err
+	}
+
+	log.Dev(context, "LoadSchema", "Completed")
+	return s, nil
+}
+
 // LoadDir loadsup a given directory, calling a load function for each valid
 // json file found.
 func LoadDir(dir string, loader func(string) error) error {
@@ -0,0 +1,116 @@
+// Package history provides the history and restore commands shared by the
+// metadata commands for working with the revisions kept for each document.
+package history
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coralproject/shelf/cmd/xenia/web"
+	"github.com/spf13/cobra"
+)
+
+var historyLong = `Retrieves the revisions of a %[1]s with the newest first, a single
+revision or the fields that changed between two revisions. Revisions are
+numbered from the oldest, which is revision 1.
+
+Example:
+	%[2]s history %[3]s
+
+	%[2]s history %[3]s -r 2
+
+	%[2]s history %[3]s --from 1 --to 2
+`
+
+var restoreLong = `Makes a revision of a %[1]s the current %[1]s. The restored %[1]s
+is added to the history as the newest revision.
+
+Example:
+	%[2]s restore %[3]s -r 2
+`
+
+// Key describes a flag whose value identifies the document. The values of
+// the flags are joined with a dot to form the key used by the web service.
+type Key struct {
+	Name      string // Name of the flag.
+	Shorthand string // Single letter for the flag.
+	Usage     string // Description of the flag.
+	Example   string // Value of the flag in the examples.
+}
+
+// AddCommands adds the history and restore commands for the kind of metadata
+// to the parent command. The noun is used to describe the metadata.
+func AddCommands(parent *cobra.Command, kind string, noun string, keys ...Key) {
+
+	// state contains the state for these commands.
+	var state struct {
+		keys     []string
+		revision int
+		from     int
+		to       int
+	}
+	state.keys = make([]string, len(keys))
+
+	var example []string
+	for _, k := range keys {
+		example = append(example, "-"+k.Shorthand+" "+k.Example)
+	}
+
+	history := &cobra.Command{
+		Use:   "history",
+		Short: "Retrieves the revisions of a " + noun + ".",
+		Long:  fmt.Sprintf(historyLong, noun, kind, strings.Join(example, " ")),
+		Run: func(cmd *cobra.Command, args []string) {
+			verb := "GET"
+			url := "/v1/history/" + kind + "/" + strings.Join(state.keys, ".")
+
+			switch {
+			case state.from > 0:
+				url += "/diff?from=" + strconv.Itoa(state.from)
+				if state.to > 0 {
+					url += "&to=" + strconv.Itoa(state.to)
+				}
+
+			case state.revision > 0:
+				url += "/" + strconv.Itoa(state.revision)
+			}
+
+			resp, err := web.Request(cmd, verb, url, nil)
+			if err != nil {
+				cmd.Println("Getting "+noun+" History : ", err)
+			}
+
+			cmd.Printf("\n%s\n\n", resp)
+		},
+	}
+
+	restore := &cobra.Command{
+		Use:   "restore",
+		Short: "Restores a revision of a " + noun + ".",
+		Long:  fmt.Sprintf(restoreLong, noun, kind, strings.Join(example, " ")),
+		Run: func(cmd *cobra.Command, args []string) {
+			verb := "POST"
+			url := "/v1/history/" + kind + "/" + strings.Join(state.keys, ".") + "/" + strconv.Itoa(state.revision) + "/restore"
+
+			if _, err := web.Request(cmd, verb, url, nil); err != nil {
+				cmd.Println("Restoring "+noun+" : ", err)
+				return
+			}
+
+			cmd.Println("Restoring " + noun + " : Restored")
+		},
+	}
+
+	for i, k := range keys {
+		history.Flags().StringVarP(&state.keys[i], k.Name, k.Shorthand, "", k.Usage)
+		restore.Flags().StringVarP(&state.keys[i], k.Name, k.Shorthand, "", k.Usage)
+	}
+
+	history.Flags().IntVarP(&state.revision, "revision", "r", 0, "Number of the revision to retrieve.")
+	history.Flags().IntVar(&state.from, "from", 0, "Number of the revision to compare from.")
+	history.Flags().IntVar(&state.to, "to", 0, "Number of the revision to compare to. Defaults to the newest.")
+	restore.Flags().IntVarP(&state.revision, "revision", "r", 0, "Number of the revision to restore.")
+
+	parent.AddCommand(history, restore)
+}
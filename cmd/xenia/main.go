@@ -9,11 +9,15 @@ import (
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
 	"github.com/coralproject/shelf/cmd/xenia/cmddb"
+	"github.com/coralproject/shelf/cmd/xenia/cmdidstrategy"
+	"github.com/coralproject/shelf/cmd/xenia/cmdlive"
 	"github.com/coralproject/shelf/cmd/xenia/cmdmask"
 	"github.com/coralproject/shelf/cmd/xenia/cmdpattern"
 	"github.com/coralproject/shelf/cmd/xenia/cmdquery"
 	"github.com/coralproject/shelf/cmd/xenia/cmdregex"
 	"github.com/coralproject/shelf/cmd/xenia/cmdrelationship"
+	"github.com/coralproject/shelf/cmd/xenia/cmdschedule"
+	"github.com/coralproject/shelf/cmd/xenia/cmdschema"
 	"github.com/coralproject/shelf/cmd/xenia/cmdscript"
 	"github.com/coralproject/shelf/cmd/xenia/cmdview"
 	"github.com/spf13/cobra"
@@ -88,6 +92,10 @@ func main() {
 		cmdrelationship.GetCommands(),
 		cmdview.GetCommands(),
 		cmdpattern.GetCommands(),
+		cmdschedule.GetCommands(),
+		cmdlive.GetCommands(),
+		cmdidstrategy.GetCommands(),
+		cmdschema.GetCommands(),
 	)
 	xenia.Execute()
 }
@@ -5,11 +5,41 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/view"
 	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+	"github.com/coralproject/shelf/internal/xenia/policy"
 	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/pborman/uuid"
+	mgo "gopkg.in/mgo.v2"
+)
+
+// Set of query string variables used to page the results.
+const (
+	varPageSize  = "_page_size"
+	varPageToken = "_page_token"
+)
+
+// Set of query string variables used to chain a view into a Set.
+const (
+	varCollection = "_collection" // Named collection to keep the view results in.
+	varView       = "view"        // Collection of the view results for the Set.
+)
+
+// Set of media types a client can accept to have the results streamed.
+const (
+	mediaStreamJSON = "application/stream+json"
+	mediaNDJSON     = "application/x-ndjson"
 )
 
 // execHandle maintains the set of handlers for the exec api.
@@ -31,7 +61,97 @@ func (execHandle) Name(c *app.Context) error {
 		return err
 	}
 
-	return execute(c, set)
+	return execute(c, set, queryVars(c))
+}
+
+// View runs the view for the item into a results collection and then runs
+// the specified Set against it. The collection is provided to the Set in the
+// view variable so queries can use "#string:view" as their collection. The
+// results are saved into the collection named by the _collection variable or
+// into a temporary collection that is dropped once the Set has run. Saving
+// into a named collection replaces what it held and needs the write grant for
+// it. The masks of the collection the view reads apply to the results.
+// 200 Success, 400 Bad Request, 403 Forbidden, 404 Not Found, 500 Internal
+func (execHandle) View(c *app.Context) error {
+	mgoDB := c.Ctx["DB"].(*db.DB)
+
+	set, err := query.GetByName(c.SessionID, mgoDB, c.Params["name"])
+	if err != nil {
+		if err == query.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	v, err := view.GetByName(c.SessionID, mgoDB, c.Params["view_name"])
+	if err != nil {
+		if err == view.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	// Results are cached by the name of the set and its variables, which
+	// don't identify the view and item the results were read from.
+	set.Cache = nil
+
+	vars := queryVars(c)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+
+	name := vars[varCollection]
+	delete(vars, varCollection)
+
+	temporary := name == ""
+	if temporary {
+		name = "view_" + strings.Replace(uuid.New(), "-", "", -1)
+		defer dropCollection(c.SessionID, mgoDB, name)
+	}
+
+	if err := collections.Writable(name); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	vars[varView] = name
+
+	// Check the set before the view is run into the collection.
+	if ok := check(c, set, vars); !ok {
+		return nil
+	}
+
+	if !temporary {
+
+		// A named collection outlives the call so the caller must be allowed
+		// to write into it.
+		if p, ok := c.App.Ctx["policy"].(*policy.Policy); ok {
+			if err := p.CheckWrite(set, roles(c), name); err != nil {
+				forbidden(c, set, err)
+				return nil
+			}
+		}
+
+		// Items saved by an earlier run must not be read with this one.
+		if err := emptyCollection(c.SessionID, mgoDB, name); err != nil {
+			return err
+		}
+	}
+
+	viewParams := wire.ViewParams{
+		ViewName:          c.Params["view_name"],
+		ItemKey:           c.Params["item_key"],
+		ResultsCollection: name,
+	}
+
+	if _, err := wire.Execute(c.SessionID, mgoDB, c.Ctx["Graph"].(*cayley.Handle), &viewParams); err != nil {
+		if err == view.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	return run(c, set, vars, map[string]string{name: v.Collection})
 }
 
 // Custom runs the provided Set and return results.
@@ -42,26 +162,206 @@ func (execHandle) Custom(c *app.Context) error {
 		return err
 	}
 
-	return execute(c, set)
+	if set == nil {
+		return app.ErrValidation
+	}
+
+	// Results are cached by the name of the set, which the caller controls
+	// here, so a custom set is never cached.
+	set.Cache = nil
+
+	return execute(c, set, queryVars(c))
+}
+
+// Invalidate removes the cached results for the specified Set.
+// 204 SuccessNoContent, 500 Internal
+func (execHandle) Invalidate(c *app.Context) error {
+	if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+		if err := xenia.InvalidateCache(c.SessionID, store, c.Params["name"]); err != nil {
+			return err
+		}
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
 }
 
 //==============================================================================
 
-// execute takes a context and Set and executes the set returning
+// execute takes a context, Set and variables and executes the set returning
 // any possible response.
-func execute(c *app.Context, set *query.Set) error {
-	var vars map[string]string
-	if c.Request.URL.RawQuery != "" {
-		if m, err := url.ParseQuery(c.Request.URL.RawQuery); err == nil {
-			vars = make(map[string]string)
-			for k, v := range m {
-				vars[k] = v[0]
-			}
+func execute(c *app.Context, set *query.Set, vars map[string]string) error {
+	if ok := check(c, set, vars); !ok {
+		return nil
+	}
+
+	return run(c, set, vars, nil)
+}
+
+// run executes the set that was checked returning any possible response.
+// The masks name the collection whose masks apply by the collection read.
+func run(c *app.Context, set *query.Set, vars map[string]string, masks map[string]string) error {
+
+	call := xenia.Call{
+		Caller: auth.Subject(c),
+		Masks:  masks,
+	}
+
+	// Take the page out of the variables for the set.
+	if v, exists := vars[varPageSize]; exists {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			n = -1
 		}
+
+		call.Page.Size = n
+		delete(vars, varPageSize)
+	}
+
+	if v, exists := vars[varPageToken]; exists {
+		call.Page.Token = v
+		delete(vars, varPageToken)
 	}
 
-	result := xenia.Exec(c.SessionID, c.Ctx["DB"].(*db.DB), set, vars)
+	// Does the client want the results streamed.
+	switch c.Request.Header.Get("Accept") {
+	case mediaStreamJSON:
+		return stream(c, set, vars, call, "application/json", xenia.NewJSONStreamer(c))
+
+	case mediaNDJSON:
+		return stream(c, set, vars, call, mediaNDJSON, xenia.NewNDJSONStreamer(c))
+	}
+
+	store, _ := c.App.Ctx["cache"].(cache.Store)
+
+	result, status := xenia.ExecCache(c.SessionID, c.Ctx["DB"].(*db.DB), store, set, vars, call)
+	if status != "" {
+		c.Header().Set("X-Cache", status)
+	}
 
 	c.Respond(result, http.StatusOK)
 	return nil
 }
+
+// check resolves the collections the queries of the set name with variables
+// and checks the set only uses the stages and collections the caller is
+// allowed to before anything is executed. The response is written when the
+// set can't be executed.
+func check(c *app.Context, set *query.Set, vars map[string]string) bool {
+
+	// The defaults of the parameters are used when no value is provided.
+	resolve := make(map[string]string, len(vars)+len(set.Params))
+	for _, prm := range set.Params {
+		if prm.Default != "" {
+			resolve[prm.Name] = prm.Default
+		}
+	}
+	for k, v := range vars {
+		resolve[k] = v
+	}
+
+	if err := xenia.ProcessCollections(c.SessionID, set, resolve); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	p, ok := c.App.Ctx["policy"].(*policy.Policy)
+	if !ok {
+		return true
+	}
+
+	if err := p.Check(set, roles(c), vars); err != nil {
+		forbidden(c, set, err)
+		return false
+	}
+
+	return true
+}
+
+// forbidden responds with the policy violation that stops the set from being
+// executed.
+func forbidden(c *app.Context, set *query.Set, err error) {
+	log.Error(c.SessionID, "check", err, "Checking policy : Name[%s]", set.Name)
+
+	resp := struct {
+		Error  string      `json:"error"`
+		Policy interface{} `json:"policy,omitempty"`
+	}{
+		Error:  err.Error(),
+		Policy: err,
+	}
+
+	c.Respond(resp, http.StatusForbidden)
+}
+
+// stream executes the set writing the documents to the response as they
+// are read so large results don't need to be held in memory.
+func stream(c *app.Context, set *query.Set, vars map[string]string, call xenia.Call, contentType string, s xenia.Streamer) error {
+	c.Header().Set("Access-Control-Allow-Origin", "*")
+	c.Header().Set("Content-Type", contentType)
+
+	c.Status = http.StatusOK
+	c.WriteHeader(c.Status)
+
+	// The status code has been written so errors can only be logged.
+	if err := xenia.ExecStream(c.SessionID, c.Ctx["DB"].(*db.DB), set, vars, call, s); err != nil {
+		log.Error(c.SessionID, "stream", err, "Writing response")
+	}
+
+	return nil
+}
+
+// queryVars returns the variables provided in the query string.
+func queryVars(c *app.Context) map[string]string {
+	if c.Request.URL.RawQuery == "" {
+		return nil
+	}
+
+	m, err := url.ParseQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		return nil
+	}
+
+	vars := make(map[string]string)
+	for k, v := range m {
+		vars[k] = v[0]
+	}
+
+	return vars
+}
+
+// dropCollection removes the temporary collection holding view results.
+func dropCollection(context interface{}, db *db.DB, name string) {
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "dropCollection", "MGO : db.%s.drop()", c.Name)
+		return c.DropCollection()
+	}
+
+	// The collection doesn't exist when the view has no items.
+	if err := db.ExecuteMGO(context, name, f); err != nil && err.Error() != "ns not found" {
+		log.Error(context, "dropCollection", err, "Dropping view results : %s", name)
+	}
+}
+
+// emptyCollection removes the results of an earlier run from a named
+// collection holding view results.
+func emptyCollection(context interface{}, db *db.DB, name string) error {
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "emptyCollection", "MGO : db.%s.remove({})", c.Name)
+		_, err := c.RemoveAll(nil)
+		return err
+	}
+
+	return db.ExecuteMGO(context, name, f)
+}
+
+// roles returns the roles and scopes granted to the caller. There are none
+// when authentication is off.
+func roles(c *app.Context) []string {
+	claims, ok := auth.Claims(c)
+	if !ok {
+		return nil
+	}
+
+	return auth.Granted(claims)
+}
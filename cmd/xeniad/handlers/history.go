@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/platform/history"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+	"github.com/coralproject/shelf/internal/wire/view"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+	"github.com/coralproject/shelf/internal/xenia/mask"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/coralproject/shelf/internal/xenia/regex"
+	"github.com/coralproject/shelf/internal/xenia/script"
+)
+
+// kinds contains the history kept for each type of metadata by the name
+// used in the url.
+var kinds = map[string]history.Kind{
+	"query":        query.History,
+	"script":       script.History,
+	"regex":        regex.History,
+	"mask":         mask.History,
+	"pattern":      pattern.History,
+	"relationship": relationship.History,
+	"view":         view.History,
+	"idstrategy":   idstrategy.History,
+	"schema":       schema.History,
+}
+
+// historyHandle maintains the set of handlers for the history api of a
+// type of metadata.
+type historyHandle struct {
+	kind       history.Kind
+	invalidate func(context interface{}, store cache.Store, key string) error
+}
+
+// History fronts the access to the history service functionality for the
+// type of metadata. The key param identifies the document whose history is
+// used. Masks are identified by the collection and field separated by a dot.
+func History(kind string) historyHandle {
+	k, exists := kinds[kind]
+	if !exists {
+		panic("handlers: no history for " + kind)
+	}
+
+	h := historyHandle{kind: k}
+
+	// The results of query sets are cached by the name of the set. Scripts,
+	// regexes and masks are used by any set so all the results are removed.
+	switch kind {
+	case "query":
+		h.invalidate = xenia.InvalidateCache
+
+	case "script", "regex", "mask":
+		h.invalidate = func(context interface{}, store cache.Store, key string) error {
+			return xenia.InvalidateAllCache(context, store)
+		}
+	}
+
+	return h
+}
+
+//==============================================================================
+
+// List returns the revisions of the document with the newest first.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (h historyHandle) List(c *app.Context) error {
+	revs, err := history.List(c.SessionID, c.Ctx["DB"].(*db.DB), h.kind, c.Params["key"])
+	if err != nil {
+		return historyErr(err)
+	}
+
+	c.Respond(revs, http.StatusOK)
+	return nil
+}
+
+// Retrieve returns the specified revision of the document.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (h historyHandle) Retrieve(c *app.Context) error {
+	number, err := strconv.Atoi(c.Params["revision"])
+	if err != nil {
+		return app.ErrValidation
+	}
+
+	rev, err := history.Get(c.SessionID, c.Ctx["DB"].(*db.DB), h.kind, c.Params["key"], number)
+	if err != nil {
+		return historyErr(err)
+	}
+
+	c.Respond(rev, http.StatusOK)
+	return nil
+}
+
+// Diff returns the fields that changed between the from and to revisions of
+// the document provided in the query string. The to revision defaults to the
+// newest revision.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (h historyHandle) Diff(c *app.Context) error {
+	db := c.Ctx["DB"].(*db.DB)
+	key := c.Params["key"]
+
+	from, err := strconv.Atoi(c.Request.URL.Query().Get("from"))
+	if err != nil {
+		return app.ErrValidation
+	}
+
+	var to int
+	if v := c.Request.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			return app.ErrValidation
+		}
+	} else {
+		revs, err := history.List(c.SessionID, db, h.kind, key)
+		if err != nil {
+			return historyErr(err)
+		}
+		to = revs[0].Number
+	}
+
+	changes, err := history.Diff(c.SessionID, db, h.kind, key, from, to)
+	if err != nil {
+		return historyErr(err)
+	}
+
+	c.Respond(changes, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Restore makes the specified revision the current document.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal
+func (h historyHandle) Restore(c *app.Context) error {
+	number, err := strconv.Atoi(c.Params["revision"])
+	if err != nil {
+		return app.ErrValidation
+	}
+
+	if err := history.Restore(c.SessionID, c.Ctx["DB"].(*db.DB), h.kind, c.Params["key"], number); err != nil {
+		if err == idstrategy.ErrInUse {
+			c.RespondError(err.Error(), http.StatusConflict)
+			return nil
+		}
+		return historyErr(err)
+	}
+
+	// Cached results may no longer match the restored version.
+	if h.invalidate != nil {
+		if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+			h.invalidate(c.SessionID, store, c.Params["key"])
+		}
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
+
+//==============================================================================
+
+// historyErr maps the errors of the history service to the web errors.
+func historyErr(err error) error {
+	switch err {
+	case history.ErrNotFound:
+		return app.ErrNotFound
+	case history.ErrInvalidKey:
+		return app.ErrValidation
+	}
+
+	return err
+}
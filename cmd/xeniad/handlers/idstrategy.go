@@ -0,0 +1,98 @@
+// Package handlers contains the handler logic for processing requests.
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+)
+
+// idStrategyHandle maintains the set of handlers for the ID strategy api.
+type idStrategyHandle struct{}
+
+// IDStrategy fronts the access to the ID strategy service functionality.
+var IDStrategy idStrategyHandle
+
+//==============================================================================
+
+// List returns all the existing ID strategies in the system.
+// 200 Success, 404 Not Found, 500 Internal
+func (idStrategyHandle) List(c *app.Context) error {
+	ss, err := idstrategy.GetAll(c.SessionID, c.Ctx["DB"].(*db.DB))
+	if err != nil {
+		if err == idstrategy.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(ss, http.StatusOK)
+	return nil
+}
+
+// Retrieve returns the ID strategy of the specified type from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (idStrategyHandle) Retrieve(c *app.Context) error {
+	s, err := idstrategy.GetByType(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"])
+	if err != nil {
+		if err == idstrategy.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(s, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Upsert inserts or updates the posted ID strategy document into the database.
+// The strategy of a type that has items can't be changed.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal
+func (idStrategyHandle) Upsert(c *app.Context) error {
+	var s idstrategy.Strategy
+	if err := json.NewDecoder(c.Request.Body).Decode(&s); err != nil {
+		return err
+	}
+
+	if err := s.Validate(); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if err := idstrategy.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), &s); err != nil {
+		if err == idstrategy.ErrInUse {
+			c.RespondError(err.Error(), http.StatusConflict)
+			return nil
+		}
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
+
+//==============================================================================
+
+// Delete removes the ID strategy of the specified type from the system. The
+// strategy of a type that has items can't be removed.
+// 200 Success, 400 Bad Request, 404 Not Found, 409 Conflict, 500 Internal
+func (idStrategyHandle) Delete(c *app.Context) error {
+	if err := idstrategy.Delete(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"]); err != nil {
+		if err == idstrategy.ErrInUse {
+			c.RespondError(err.Error(), http.StatusConflict)
+			return nil
+		}
+		if err == idstrategy.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
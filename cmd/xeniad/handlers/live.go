@@ -0,0 +1,112 @@
+// Package handlers contains the handler logic for processing requests.
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/live"
+	"github.com/coralproject/shelf/internal/wire/view"
+)
+
+// liveHandle maintains the set of handlers for the live view api.
+type liveHandle struct{}
+
+// Live fronts the access to the live view service functionality.
+var Live liveHandle
+
+//==============================================================================
+
+// List returns all the registered live views in the system.
+// 200 Success, 404 Not Found, 500 Internal
+func (liveHandle) List(c *app.Context) error {
+	ls, err := live.GetAll(c.SessionID, c.Ctx["DB"].(*db.DB))
+	if err != nil {
+		if err == live.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(ls, http.StatusOK)
+	return nil
+}
+
+// Retrieve returns the specified live view from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (liveHandle) Retrieve(c *app.Context) error {
+	l, err := live.GetByName(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"])
+	if err != nil {
+		if err == live.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(l, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Upsert registers the posted live view and saves the items of the view
+// into its collection.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (liveHandle) Upsert(c *app.Context) error {
+	var l live.Live
+	if err := json.NewDecoder(c.Request.Body).Decode(&l); err != nil {
+		return err
+	}
+
+	n, err := wire.UpsertLive(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), &l)
+	if err != nil {
+		if err == live.ErrCollectionInUse {
+			c.RespondError(err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		if err == view.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(map[string]int{"number_of_results": n}, http.StatusOK)
+	return nil
+}
+
+// Refresh saves all the items of the specified live view into its
+// collection again.
+// 200 Success, 404 Not Found, 500 Internal
+func (liveHandle) Refresh(c *app.Context) error {
+	n, err := wire.RefreshLive(c.SessionID, c.Ctx["DB"].(*db.DB), c.Ctx["Graph"].(*cayley.Handle), c.Params["name"])
+	if err != nil {
+		if err == live.ErrNotFound || err == view.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(map[string]int{"number_of_results": n}, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Delete removes the specified live view from the system. The items already
+// saved in its collection are kept.
+// 204 SuccessNoContent, 404 Not Found, 500 Internal
+func (liveHandle) Delete(c *app.Context) error {
+	if err := live.Delete(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"]); err != nil {
+		if err == live.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
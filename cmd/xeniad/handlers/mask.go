@@ -6,6 +6,8 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
 	"github.com/coralproject/shelf/internal/xenia/mask"
 )
 
@@ -81,6 +83,11 @@ func (maskHandle) Upsert(c *app.Context) error {
 		return err
 	}
 
+	// Cached results may hold the fields the mask now hides.
+	if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+		xenia.InvalidateAllCache(c.SessionID, store)
+	}
+
 	c.Respond(nil, http.StatusNoContent)
 	return nil
 }
@@ -97,6 +104,11 @@ func (maskHandle) Delete(c *app.Context) error {
 		return err
 	}
 
+	// Cached results were masked with the removed mask.
+	if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+		xenia.InvalidateAllCache(c.SessionID, store)
+	}
+
 	c.Respond(nil, http.StatusNoContent)
 	return nil
 }
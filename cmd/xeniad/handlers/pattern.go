@@ -7,6 +7,7 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/wire"
 	"github.com/coralproject/shelf/internal/wire/pattern"
 )
 
@@ -58,6 +59,15 @@ func (patternHandle) Upsert(c *app.Context) error {
 		return err
 	}
 
+	// Check the inferences against the relationships.
+	if err := wire.ValidatePattern(c.SessionID, c.Ctx["DB"].(*db.DB), &p); err != nil {
+		if _, ok := err.(*wire.ConstraintError); ok {
+			c.RespondError(err.Error(), http.StatusBadRequest)
+			return nil
+		}
+		return err
+	}
+
 	if err := pattern.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), &p); err != nil {
 		return err
 	}
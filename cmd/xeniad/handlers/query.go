@@ -7,6 +7,8 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
 	"github.com/coralproject/shelf/internal/xenia/query"
 )
 
@@ -62,6 +64,11 @@ func (queryHandle) Upsert(c *app.Context) error {
 		return err
 	}
 
+	// Cached results may no longer match the new version of the set.
+	if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+		xenia.InvalidateCache(c.SessionID, store, set.Name)
+	}
+
 	c.Respond(nil, http.StatusNoContent)
 	return nil
 }
@@ -99,6 +106,10 @@ func (queryHandle) Delete(c *app.Context) error {
 		return err
 	}
 
+	if store, ok := c.App.Ctx["cache"].(cache.Store); ok {
+		xenia.InvalidateCache(c.SessionID, store, c.Params["name"])
+	}
+
 	c.Respond(nil, http.StatusNoContent)
 	return nil
 }
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+)
+
+// defRunsLimit is the number of runs returned when no limit is provided.
+const defRunsLimit = 20
+
+// scheduleHandle maintains the set of handlers for the schedule api.
+type scheduleHandle struct{}
+
+// Schedule fronts the access to the schedule service functionality.
+var Schedule scheduleHandle
+
+//==============================================================================
+
+// List returns all the existing schedules in the system.
+// 200 Success, 404 Not Found, 500 Internal
+func (scheduleHandle) List(c *app.Context) error {
+	schs, err := schedule.GetAll(c.SessionID, c.Ctx["DB"].(*db.DB))
+	if err != nil {
+		if err == schedule.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(schs, http.StatusOK)
+	return nil
+}
+
+// Retrieve returns the specified schedule from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (scheduleHandle) Retrieve(c *app.Context) error {
+	sch, err := schedule.GetByName(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"])
+	if err != nil {
+		if err == schedule.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(sch, http.StatusOK)
+	return nil
+}
+
+// Runs returns the most recent runs of the specified schedule.
+// 200 Success, 400 Bad Request, 500 Internal
+func (scheduleHandle) Runs(c *app.Context) error {
+	limit := defRunsLimit
+	if v := c.Request.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return app.ErrValidation
+		}
+		limit = n
+	}
+
+	runs, err := schedule.GetRuns(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"], limit)
+	if err != nil {
+		return err
+	}
+
+	c.Respond(runs, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Upsert inserts or updates the posted Schedule document into the database.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 500 Internal
+func (scheduleHandle) Upsert(c *app.Context) error {
+	var sch schedule.Schedule
+	if err := json.NewDecoder(c.Request.Body).Decode(&sch); err != nil {
+		return err
+	}
+
+	if err := schedule.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), sch); err != nil {
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
+
+// Run executes the set of the specified schedule now and returns the run.
+// 200 Success, 404 Not Found, 500 Internal
+func (scheduleHandle) Run(c *app.Context) error {
+	db := c.Ctx["DB"].(*db.DB)
+
+	sch, err := schedule.GetByName(c.SessionID, db, c.Params["name"])
+	if err != nil {
+		if err == schedule.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	run := schedule.Execute(c.SessionID, db, sch, "api")
+
+	c.Respond(run, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Delete removes the specified Schedule from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (scheduleHandle) Delete(c *app.Context) error {
+	if err := schedule.Delete(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"]); err != nil {
+		if err == schedule.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
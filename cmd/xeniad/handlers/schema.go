@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+)
+
+// defViolationsLimit is the number of violations returned when no limit is
+// provided.
+const defViolationsLimit = 100
+
+// schemaHandle maintains the set of handlers for the schema api.
+type schemaHandle struct{}
+
+// Schema fronts the access to the schema service functionality.
+var Schema schemaHandle
+
+//==============================================================================
+
+// List returns all the existing schemas in the system.
+// 200 Success, 404 Not Found, 500 Internal
+func (schemaHandle) List(c *app.Context) error {
+	ss, err := schema.GetAll(c.SessionID, c.Ctx["DB"].(*db.DB))
+	if err != nil {
+		if err == schema.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(ss, http.StatusOK)
+	return nil
+}
+
+// Retrieve returns the schema of the specified type from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (schemaHandle) Retrieve(c *app.Context) error {
+	s, err := schema.GetByType(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"])
+	if err != nil {
+		if err == schema.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(s, http.StatusOK)
+	return nil
+}
+
+// Violations returns the most recent violations recorded for the items of
+// the specified type by a schema in report mode.
+// 200 Success, 400 Bad Request, 500 Internal
+func (schemaHandle) Violations(c *app.Context) error {
+	limit := defViolationsLimit
+	if v := c.Request.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return app.ErrValidation
+		}
+		limit = n
+	}
+
+	vs, err := schema.GetViolations(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"], limit)
+	if err != nil {
+		return err
+	}
+
+	c.Respond(vs, http.StatusOK)
+	return nil
+}
+
+//==============================================================================
+
+// Upsert inserts or updates the posted schema document into the database.
+// 204 SuccessNoContent, 400 Bad Request, 404 Not Found, 500 Internal
+func (schemaHandle) Upsert(c *app.Context) error {
+	var s schema.Schema
+	if err := json.NewDecoder(c.Request.Body).Decode(&s); err != nil {
+		return err
+	}
+
+	if err := s.Validate(); err != nil {
+		c.RespondError(err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if err := schema.Upsert(c.SessionID, c.Ctx["DB"].(*db.DB), &s); err != nil {
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
+
+//==============================================================================
+
+// Delete removes the schema of the specified type from the system.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (schemaHandle) Delete(c *app.Context) error {
+	if err := schema.Delete(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["type"]); err != nil {
+		if err == schema.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(nil, http.StatusNoContent)
+	return nil
+}
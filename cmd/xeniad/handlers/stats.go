@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/xenia/audit"
+)
+
+// statsHandle maintains the set of handlers for the stats api.
+type statsHandle struct{}
+
+// Stats fronts the access to the stats service functionality.
+var Stats statsHandle
+
+//==============================================================================
+
+// Query returns the aggregated execution statistics for the specified Set.
+// The since query string variable is a duration that limits the statistics
+// to the executions within that period.
+// 200 Success, 400 Bad Request, 404 Not Found, 500 Internal
+func (statsHandle) Query(c *app.Context) error {
+	var since time.Time
+	if v := c.Request.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return app.ErrValidation
+		}
+		since = time.Now().Add(-d)
+	}
+
+	st, err := audit.Stats(c.SessionID, c.Ctx["DB"].(*db.DB), c.Params["name"], since)
+	if err != nil {
+		if err == audit.ErrNotFound {
+			err = app.ErrNotFound
+		}
+		return err
+	}
+
+	c.Respond(st, http.StatusOK)
+	return nil
+}
@@ -48,7 +48,19 @@ func main() {
 		writeTimeout = 30 * time.Second
 	)
 
-	if err := app.Run(":16181", routes.API(), readTimeout, writeTimeout); err != nil {
+	// Start executing the query set schedules.
+	sched := routes.Scheduler()
+	if sched != nil {
+		sched.Start("scheduler")
+	}
+
+	err := app.Run(":16181", routes.API(), readTimeout, writeTimeout)
+
+	if sched != nil {
+		sched.Stop("shutdown")
+	}
+
+	if err != nil {
 		log.Error("shutdown", "Init", err, "App Shutdown")
 		os.Exit(1)
 	}
@@ -0,0 +1,64 @@
+package midware
+
+import (
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/log"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/platform/metrics"
+
+	// mongo is needed to utilize mongoDB as the backend store for cayley.
+	_ "github.com/cayleygraph/cayley/graph/mongo"
+)
+
+const (
+	cfgMongoHost     = "MONGO_HOST"
+	cfgMongoUser     = "MONGO_USER"
+	cfgMongoPassword = "MONGO_PASS"
+)
+
+// Cayley handles session management.
+func Cayley(h app.Handler) app.Handler {
+
+	// Check if mongodb is configured.
+	if _, err := cfg.String(cfgMongoHost); err != nil {
+		return func(c *app.Context) error {
+			log.Dev(c.SessionID, "Cayley", "******> Cayley Not Configured")
+			return h(c)
+		}
+	}
+
+	// Wrap the handlers inside a session copy/close.
+	return func(c *app.Context) error {
+		start := time.Now()
+		store, err := NewGraph()
+		metrics.Since(metrics.CayleyOpen, start)
+
+		if err != nil {
+			return app.ErrDBNotConfigured
+		}
+
+		log.Dev(c.SessionID, "Cayley", "******> Capture Cayley Session")
+		c.Ctx["Graph"] = store
+		defer func() {
+			log.Dev(c.SessionID, "Cayley", "******> Release Cayley Session")
+			store.Close()
+		}()
+
+		return h(c)
+	}
+}
+
+// NewGraph returns a Cayley handle for the graph stored in Mongo. The handle
+// must be closed.
+func NewGraph() (*cayley.Handle, error) {
+	opts := map[string]interface{}{
+		"database_name": cfg.MustString(cfgMongoDB),
+		"username":      cfg.MustString(cfgMongoUser),
+		"password":      cfg.MustString(cfgMongoPassword),
+	}
+
+	return cayley.NewGraph("mongo", cfg.MustString(cfgMongoHost), opts)
+}
@@ -1,10 +1,13 @@
 package midware
 
 import (
+	"time"
+
 	"github.com/ardanlabs/kit/cfg"
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/platform/metrics"
 )
 
 // cfgMongoDB config environmental variables.
@@ -24,7 +27,10 @@ func Mongo(h app.Handler) app.Handler {
 
 	// Wrap the handlers inside a session copy/close.
 	return func(c *app.Context) error {
+		start := time.Now()
 		mgoDB, err := db.NewMGO("Mongo", dbName)
+		metrics.Since(metrics.MongoAcquire, start)
+
 		if err != nil {
 			log.Error(c.SessionID, "Mongo", err, "Method[%s] URL[%s] RADDR[%s]", c.Request.Method, c.Request.URL.Path, c.Request.RemoteAddr)
 			return app.ErrDBNotConfigured
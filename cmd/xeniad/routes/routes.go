@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ardanlabs/kit/cfg"
@@ -15,6 +16,18 @@ import (
 	"github.com/ardanlabs/kit/web/app"
 	"github.com/coralproject/shelf/cmd/xeniad/handlers"
 	"github.com/coralproject/shelf/cmd/xeniad/midware"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/coralproject/shelf/internal/platform/metrics"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+	"github.com/coralproject/shelf/internal/xenia/policy"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+
+	// The collections of the forms are protected from the results of sets
+	// although xenia doesn't use the packages.
+	_ "github.com/coralproject/shelf/internal/ask/form"
+	_ "github.com/coralproject/shelf/internal/ask/form/gallery"
 )
 
 // Environmental variables.
@@ -25,8 +38,18 @@ const (
 	cfgMongoUser     = "MONGO_USER"
 	cfgMongoPassword = "MONGO_PASS"
 	cfgAnvilHost     = "ANVIL_HOST"
+	cfgCacheRedis    = "CACHE_REDIS_HOST"
+	cfgExecWorkers   = "EXEC_WORKERS"
+	cfgSchedInterval = "SCHEDULER_INTERVAL"
+	cfgSchedHosts    = "SCHEDULER_OUTPUT_HOSTS"
+	cfgAudit         = "AUDIT"
+	cfgExecPolicy    = "EXEC_POLICY"
+	cfgStrict        = "STRICT_RELATIONSHIPS"
 )
 
+// execPolicy is the policy sets are checked against before they execute.
+var execPolicy = policy.Default()
+
 func init() {
 	// Initialize the configuration and logging systems. Plus anything
 	// else the web app layer needs.
@@ -51,18 +74,104 @@ func init() {
 			os.Exit(1)
 		}
 	}
+
+	// Set the number of queries of a set that can run concurrently.
+	if workers, err := cfg.Int(cfgExecWorkers); err == nil {
+		xenia.SetWorkers(workers)
+		log.Dev("startup", "Init", "Exec Workers : %d", workers)
+	}
+
+	// Set if the executions of sets are recorded in the audit collection.
+	if on, err := cfg.Bool(cfgAudit); err == nil {
+		xenia.SetAudit(on)
+		log.Dev("startup", "Init", "Audit : %v", on)
+	}
+
+	// Set if patterns with inferences the relationship metadata doesn't
+	// allow are rejected.
+	if on, err := cfg.Bool(cfgStrict); err == nil {
+		wire.SetStrict(on)
+		log.Dev("startup", "Init", "Strict Relationships : %v", on)
+	}
+
+	// Configure the policy sets are checked against before they execute,
+	// including the sets of schedules.
+	if path, err := cfg.String(cfgExecPolicy); err == nil {
+		p, err := policy.Load(path)
+		if err != nil {
+			log.Error("startup", "Init", err, "Loading Exec Policy : %s", path)
+			os.Exit(1)
+		}
+		execPolicy = p
+		log.Dev("startup", "Init", "Exec Policy : %s", path)
+	} else {
+		log.Dev("startup", "Init", "Exec Policy : Default")
+	}
+	schedule.SetPolicy(execPolicy)
+
+	// Set the hosts the results of schedules can be posted to.
+	if hosts, err := cfg.String(cfgSchedHosts); err == nil {
+		schedule.SetOutputHosts(strings.Split(hosts, ","))
+		log.Dev("startup", "Init", "Scheduler Output Hosts : %s", hosts)
+	}
 }
 
 //==============================================================================
 
+// Scheduler returns the scheduler for executing the query set schedules or
+// nil if Mongo is not configured or the scheduler is turned off with an
+// interval of zero.
+func Scheduler() *schedule.Scheduler {
+	if _, err := cfg.String(cfgMongoHost); err != nil {
+		return nil
+	}
+
+	interval := time.Minute
+	if d, err := cfg.Duration(cfgSchedInterval); err == nil {
+		interval = d
+	}
+
+	if interval <= 0 {
+		log.Dev("startup", "Scheduler", "Scheduler : Off")
+		return nil
+	}
+
+	// The master session is registered under the name of the database.
+	log.Dev("startup", "Scheduler", "Scheduler : Interval[%v]", interval)
+	return schedule.New(cfg.MustString(cfgMongoDB), interval)
+}
+
 // API returns a handler for a set of routes.
 func API(testing ...bool) http.Handler {
 
-	a := app.New(midware.Mongo, midware.Auth)
+	authm, err := auth.Midware()
+	if err != nil {
+		log.Error("startup", "Init", err, "Initializing Auth")
+		os.Exit(1)
+	}
+
+	a := app.New(metrics.Midware, midware.Mongo, authm)
+
+	// Configure the store for caching query set results. Use Redis when
+	// configured so the cache is shared between instances.
+	if host, err := cfg.String(cfgCacheRedis); err == nil {
+		a.Ctx["cache"] = cache.NewRedis(host, 5*time.Second)
+		log.Dev("startup", "Init", "Cache Redis : %s", host)
+	} else {
+		a.Ctx["cache"] = cache.NewMemory(time.Minute)
+		log.Dev("startup", "Init", "Cache Memory")
+	}
+
+	// Sets are checked against the policy before they execute.
+	a.Ctx["policy"] = execPolicy
 
 	log.Dev("startup", "Init", "Initalizing routes")
 	routes(a)
 
+	// Metrics are served outside of the middleware so they can be scraped
+	// without a token and don't count themselves.
+	a.TreeMux.Handle("GET", "/metrics", metrics.Handler)
+
 	log.Dev("startup", "Init", "Initalizing CORS")
 	a.CORS()
 
@@ -77,49 +186,107 @@ func API(testing ...bool) http.Handler {
 
 // routes manages the handling of the API endpoints.
 func routes(a *app.App) {
-	a.Handle("GET", "/v1/version", handlers.Version.List)
+	read := auth.Scope(auth.ScopeQueryRead)
+	write := auth.Scope(auth.ScopeQueryWrite)
+	exec := auth.Scope(auth.ScopeQueryExec)
+	custom := auth.Scope(auth.ScopeExecCustom)
+	mask := auth.Scope(auth.ScopeMaskAdmin)
+	wireRead := auth.Scope(auth.ScopeWireRead)
+	wireWrite := auth.Scope(auth.ScopeWireWrite)
 
-	a.Handle("GET", "/v1/script", handlers.Script.List)
-	a.Handle("PUT", "/v1/script", handlers.Script.Upsert)
-	a.Handle("GET", "/v1/script/:name", handlers.Script.Retrieve)
-	a.Handle("DELETE", "/v1/script/:name", handlers.Script.Delete)
-
-	a.Handle("GET", "/v1/query", handlers.Query.List)
-	a.Handle("PUT", "/v1/query", handlers.Query.Upsert)
-	a.Handle("GET", "/v1/query/:name", handlers.Query.Retrieve)
-	a.Handle("DELETE", "/v1/query/:name", handlers.Query.Delete)
-
-	a.Handle("PUT", "/v1/index/:name", handlers.Query.EnsureIndexes)
-
-	a.Handle("GET", "/v1/regex", handlers.Regex.List)
-	a.Handle("PUT", "/v1/regex", handlers.Regex.Upsert)
-	a.Handle("GET", "/v1/regex/:name", handlers.Regex.Retrieve)
-	a.Handle("DELETE", "/v1/regex/:name", handlers.Regex.Delete)
-
-	a.Handle("GET", "/v1/mask", handlers.Mask.List)
-	a.Handle("PUT", "/v1/mask", handlers.Mask.Upsert)
-	a.Handle("GET", "/v1/mask/:collection/:field", handlers.Mask.Retrieve)
-	a.Handle("GET", "/v1/mask/:collection", handlers.Mask.Retrieve)
-	a.Handle("DELETE", "/v1/mask/:collection/:field", handlers.Mask.Delete)
-
-	a.Handle("POST", "/v1/exec", handlers.Exec.Custom)
-	a.Handle("GET", "/v1/exec/:name", handlers.Exec.Name)
-
-	a.Handle("GET", "/v1/relationship", handlers.Relationship.List)
-	a.Handle("PUT", "/v1/relationship", handlers.Relationship.Upsert)
-	a.Handle("GET", "/v1/relationship/:predicate", handlers.Relationship.Retrieve)
-	a.Handle("DELETE", "/v1/relationship/:predicate", handlers.Relationship.Delete)
-
-	a.Handle("GET", "/v1/view", handlers.View.List)
-	a.Handle("PUT", "/v1/view", handlers.View.Upsert)
-	a.Handle("GET", "/v1/view/:name", handlers.View.Retrieve)
-	a.Handle("DELETE", "/v1/view/:name", handlers.View.Delete)
+	a.Handle("GET", "/v1/version", handlers.Version.List)
 
-	a.Handle("GET", "/v1/pattern", handlers.Pattern.List)
-	a.Handle("PUT", "/v1/pattern", handlers.Pattern.Upsert)
-	a.Handle("GET", "/v1/pattern/:type", handlers.Pattern.Retrieve)
-	a.Handle("DELETE", "/v1/pattern/:type", handlers.Pattern.Delete)
+	a.Handle("GET", "/v1/script", read(handlers.Script.List))
+	a.Handle("PUT", "/v1/script", write(handlers.Script.Upsert))
+	a.Handle("GET", "/v1/script/:name", read(handlers.Script.Retrieve))
+	a.Handle("DELETE", "/v1/script/:name", write(handlers.Script.Delete))
+
+	a.Handle("GET", "/v1/query", read(handlers.Query.List))
+	a.Handle("PUT", "/v1/query", write(handlers.Query.Upsert))
+	a.Handle("GET", "/v1/query/:name", read(handlers.Query.Retrieve))
+	a.Handle("DELETE", "/v1/query/:name", write(handlers.Query.Delete))
+
+	a.Handle("PUT", "/v1/index/:name", write(handlers.Query.EnsureIndexes))
+
+	a.Handle("GET", "/v1/regex", read(handlers.Regex.List))
+	a.Handle("PUT", "/v1/regex", write(handlers.Regex.Upsert))
+	a.Handle("GET", "/v1/regex/:name", read(handlers.Regex.Retrieve))
+	a.Handle("DELETE", "/v1/regex/:name", write(handlers.Regex.Delete))
+
+	a.Handle("GET", "/v1/mask", mask(handlers.Mask.List))
+	a.Handle("PUT", "/v1/mask", mask(handlers.Mask.Upsert))
+	a.Handle("GET", "/v1/mask/:collection/:field", mask(handlers.Mask.Retrieve))
+	a.Handle("GET", "/v1/mask/:collection", mask(handlers.Mask.Retrieve))
+	a.Handle("DELETE", "/v1/mask/:collection/:field", mask(handlers.Mask.Delete))
+
+	a.Handle("POST", "/v1/exec", custom(handlers.Exec.Custom))
+	a.Handle("GET", "/v1/exec/:name", exec(handlers.Exec.Name))
+	a.Handle("DELETE", "/v1/exec/:name/cache", write(handlers.Exec.Invalidate))
+	a.Handle("GET", "/v1/exec/:name/view/:view_name/:item_key", exec(midware.Cayley(handlers.Exec.View)))
+
+	a.Handle("GET", "/v1/stats/query/:name", read(handlers.Stats.Query))
+
+	a.Handle("GET", "/v1/schedule", read(handlers.Schedule.List))
+	a.Handle("PUT", "/v1/schedule", write(handlers.Schedule.Upsert))
+	a.Handle("GET", "/v1/schedule/:name", read(handlers.Schedule.Retrieve))
+	a.Handle("DELETE", "/v1/schedule/:name", write(handlers.Schedule.Delete))
+	a.Handle("GET", "/v1/schedule/:name/runs", read(handlers.Schedule.Runs))
+	a.Handle("POST", "/v1/schedule/:name/run", write(handlers.Schedule.Run))
+
+	a.Handle("GET", "/v1/relationship", wireRead(handlers.Relationship.List))
+	a.Handle("PUT", "/v1/relationship", wireWrite(handlers.Relationship.Upsert))
+	a.Handle("GET", "/v1/relationship/:predicate", wireRead(handlers.Relationship.Retrieve))
+	a.Handle("DELETE", "/v1/relationship/:predicate", wireWrite(handlers.Relationship.Delete))
+
+	a.Handle("GET", "/v1/view", wireRead(handlers.View.List))
+	a.Handle("PUT", "/v1/view", wireWrite(handlers.View.Upsert))
+	a.Handle("GET", "/v1/view/:name", wireRead(handlers.View.Retrieve))
+	a.Handle("DELETE", "/v1/view/:name", wireWrite(handlers.View.Delete))
+
+	a.Handle("GET", "/v1/live", wireRead(handlers.Live.List))
+	a.Handle("PUT", "/v1/live", wireWrite(midware.Cayley(handlers.Live.Upsert)))
+	a.Handle("GET", "/v1/live/:name", wireRead(handlers.Live.Retrieve))
+	a.Handle("DELETE", "/v1/live/:name", wireWrite(handlers.Live.Delete))
+	a.Handle("POST", "/v1/live/:name/refresh", wireWrite(midware.Cayley(handlers.Live.Refresh)))
+
+	a.Handle("GET", "/v1/pattern", wireRead(handlers.Pattern.List))
+	a.Handle("PUT", "/v1/pattern", wireWrite(handlers.Pattern.Upsert))
+	a.Handle("GET", "/v1/pattern/:type", wireRead(handlers.Pattern.Retrieve))
+	a.Handle("DELETE", "/v1/pattern/:type", wireWrite(handlers.Pattern.Delete))
+
+	a.Handle("GET", "/v1/idstrategy", wireRead(handlers.IDStrategy.List))
+	a.Handle("PUT", "/v1/idstrategy", wireWrite(handlers.IDStrategy.Upsert))
+	a.Handle("GET", "/v1/idstrategy/:type", wireRead(handlers.IDStrategy.Retrieve))
+	a.Handle("DELETE", "/v1/idstrategy/:type", wireWrite(handlers.IDStrategy.Delete))
+
+	a.Handle("GET", "/v1/schema", wireRead(handlers.Schema.List))
+	a.Handle("PUT", "/v1/schema", wireWrite(handlers.Schema.Upsert))
+	a.Handle("GET", "/v1/schema/:type", wireRead(handlers.Schema.Retrieve))
+	a.Handle("DELETE", "/v1/schema/:type", wireWrite(handlers.Schema.Delete))
+	a.Handle("GET", "/v1/schema/:type/violations", wireRead(handlers.Schema.Violations))
+
+	history(a, "query", read, write)
+	history(a, "script", read, write)
+	history(a, "regex", read, write)
+	history(a, "mask", mask, mask)
+	history(a, "relationship", wireRead, wireWrite)
+	history(a, "view", wireRead, wireWrite)
+	history(a, "pattern", wireRead, wireWrite)
+	history(a, "idstrategy", wireRead, wireWrite)
+	history(a, "schema", wireRead, wireWrite)
+}
 
+// history manages the handling of the API endpoints for the revisions of a
+// type of metadata. The revisions are read and restored with the same scopes
+// the metadata is read and written with.
+func history(a *app.App, kind string, read app.Middleware, write app.Middleware) {
+	h := handlers.History(kind)
+	url := "/v1/history/" + kind + "/:key"
+
+	a.Handle("GET", url, read(h.List))
+	a.Handle("GET", url+"/diff", read(h.Diff))
+	a.Handle("GET", url+"/:revision", read(h.Retrieve))
+	a.Handle("POST", url+"/:revision/restore", write(h.Restore))
 }
 
 // website manages the serving of web files for the project.
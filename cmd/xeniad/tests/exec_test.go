@@ -184,3 +184,67 @@ func TestExecExplain(t *testing.T) {
 		}
 	}
 }
+
+// TestExecStream tests the execution of a specific query with the results
+// streamed as newline delimited JSON.
+func TestExecStream(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to execute a specific query with streamed output.")
+	{
+		url := "/v1/exec/" + qPrefix + "_basic?station_id=42021"
+		r := tests.NewRequest("GET", url, nil)
+		r.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the query : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the query.", tests.Success)
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Fatalf("\t%s\tShould get the ndjson content type : %s", tests.Failed, ct)
+			}
+			t.Logf("\t%s\tShould get the ndjson content type.", tests.Success)
+
+			recv := strings.TrimSpace(w.Body.String())
+			resp := `{"name":"Basic","doc":{"name":"C14 - Pasco County Buoy, FL"}}`
+
+			if resp != recv {
+				t.Log(resp)
+				t.Log(recv)
+				t.Fatalf("\t%s\tShould get the expected result.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get the expected result.", tests.Success)
+		}
+	}
+}
+
+// TestExecView tests the execution of a specific query against the results
+// of a view that doesn't exist.
+func TestExecView(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to execute a specific query against the results of a view.")
+	{
+		url := "/v1/exec/" + qPrefix + "_basic/view/" + qPrefix + "_missing/1?station_id=42021"
+		r := tests.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not be able to find the view : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to find the view.", tests.Success)
+		}
+	}
+}
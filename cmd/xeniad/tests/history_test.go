@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/platform/history"
+)
+
+// TestHistory tests the retrieval of the revisions of a pattern.
+func TestHistory(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to get the revisions of a pattern.")
+	{
+		url := "/v1/history/pattern/" + patternPrefix + "comment"
+		r := tests.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the revisions : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the revisions.", tests.Success)
+
+			var revs []history.Revision
+			if err := json.Unmarshal(w.Body.Bytes(), &revs); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if len(revs) == 0 || revs[0].Number != len(revs) {
+				t.Fatalf("\t%s\tShould have the newest revision first : %+v", tests.Failed, revs)
+			}
+			t.Logf("\t%s\tShould have the newest revision first.", tests.Success)
+		}
+
+		url = "/v1/history/pattern/" + patternPrefix + "comment/1000"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not find the revision : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not find the revision.", tests.Success)
+		}
+
+		url = "/v1/history/mask/" + patternPrefix + "comment"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url without a field : %s", url)
+		{
+			if w.Code != 400 {
+				t.Fatalf("\t%s\tShould get a bad request : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould get a bad request.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+)
+
+// idsPrefix is the base name for the ID strategies.
+const idsPrefix = "IDSTEST_"
+
+// TestIDStrategyCRUD tests an ID strategy can be managed through the API.
+func TestIDStrategyCRUD(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	s := idstrategy.Strategy{
+		Type:     idsPrefix + "asset",
+		Fields:   []string{"site", "external.id"},
+		Template: "{type}:{site}:{external.id}",
+		Hash:     idstrategy.HashSHA1,
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to marshal the ID strategy : %v", tests.Failed, err)
+	}
+
+	t.Log("Given the need to manage an ID strategy.")
+	{
+		url := "/v1/idstrategy"
+		r := tests.NewRequest("PUT", url, bytes.NewBuffer(data))
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to insert the ID strategy : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to insert the ID strategy.", tests.Success)
+		}
+
+		url = "/v1/idstrategy/" + s.Type
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the ID strategy : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the ID strategy.", tests.Success)
+
+			var got idstrategy.Strategy
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if !reflect.DeepEqual(s, got) {
+				t.Logf("\t%+v", s)
+				t.Logf("\t%+v", got)
+				t.Fatalf("\t%s\tShould be able to get back the same ID strategy.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be able to get back the same ID strategy.", tests.Success)
+		}
+
+		invalid := s
+		invalid.Template = "{type}:{site}"
+
+		data, err := json.Marshal(invalid)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to marshal the invalid ID strategy : %v", tests.Failed, err)
+		}
+
+		url = "/v1/idstrategy"
+		r = tests.NewRequest("PUT", url, bytes.NewBuffer(data))
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert an invalid ID strategy : %s", url)
+		{
+			if w.Code != 400 {
+				t.Fatalf("\t%s\tShould not be able to insert the ID strategy : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to insert the ID strategy.", tests.Success)
+		}
+
+		url = "/v1/idstrategy/" + s.Type
+		r = tests.NewRequest("DELETE", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to delete : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to delete the ID strategy : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to delete the ID strategy.", tests.Success)
+		}
+
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not be able to retrieve the ID strategy : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to retrieve the ID strategy.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+)
+
+// TestMetrics tests the requests handled are reported by the metrics.
+func TestMetrics(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to get the metrics.")
+	{
+		r := tests.NewRequest("GET", "/v1/version", nil)
+		a.ServeHTTP(httptest.NewRecorder(), r)
+
+		url := "/metrics"
+		r = tests.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the metrics : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the metrics.", tests.Success)
+
+			body := w.Body.String()
+			if !strings.Contains(body, `http_requests_total{method="GET",route="/v1/version",status="200"}`) {
+				t.Fatalf("\t%s\tShould have the request counted :\n%s", tests.Failed, body)
+			}
+			t.Logf("\t%s\tShould have the request counted.", tests.Success)
+
+			if !strings.Contains(body, "mongo_session_acquire_seconds_count") {
+				t.Fatalf("\t%s\tShould have the Mongo session acquire time.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have the Mongo session acquire time.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+)
+
+// TestExecPolicy tests a custom set that breaks the policy is rejected.
+func TestExecPolicy(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	set := `{
+		"name": "QTEST_O_policy",
+		"enabled": true,
+		"queries": [{
+			"name": "copy",
+			"type": "pipeline",
+			"collection": "test_xenia_data",
+			"return": true,
+			"commands": [{"$match": {}}, {"$out": "query_sets"}]
+		}]
+	}`
+
+	t.Log("Given the need to reject sets that break the policy.")
+	{
+		url := "/v1/exec"
+		r := tests.NewRequest("POST", url, bytes.NewBufferString(set))
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url with a $out stage : %s", url)
+		{
+			if w.Code != 403 {
+				t.Fatalf("\t%s\tShould not be allowed to execute the set : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be allowed to execute the set.", tests.Success)
+
+			var resp struct {
+				Policy struct {
+					Stage string `json:"stage"`
+				} `json:"policy"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+
+			if resp.Policy.Stage != "$out" {
+				t.Fatalf("\t%s\tShould name the $out stage : %s", tests.Failed, w.Body.String())
+			}
+			t.Logf("\t%s\tShould name the $out stage.", tests.Success)
+		}
+	}
+}
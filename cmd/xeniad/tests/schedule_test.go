@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+)
+
+// schPrefix is the base name for the schedules.
+const schPrefix = "SCHTEST_O"
+
+// TestScheduleCRUD tests a schedule can be managed and run through the API.
+func TestScheduleCRUD(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	sch := schedule.Schedule{
+		Name:    schPrefix + "_basic",
+		Set:     "QTEST_O_basic",
+		Cron:    "@daily",
+		Enabled: true,
+	}
+
+	data, err := json.Marshal(sch)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to marshal the schedule : %v", tests.Failed, err)
+	}
+
+	t.Log("Given the need to manage a schedule.")
+	{
+		url := "/v1/schedule"
+		r := tests.NewRequest("PUT", url, bytes.NewBuffer(data))
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to insert the schedule : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to insert the schedule.", tests.Success)
+		}
+
+		url = "/v1/schedule/" + sch.Name
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the schedule : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the schedule.", tests.Success)
+
+			var got schedule.Schedule
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if got.Set != sch.Set || got.NextRun.IsZero() {
+				t.Fatalf("\t%s\tShould have the set and next run : %+v", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould have the set and next run.", tests.Success)
+		}
+
+		url = "/v1/schedule/" + sch.Name + "/run"
+		r = tests.NewRequest("POST", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to run : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to run the schedule : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to run the schedule.", tests.Success)
+
+			var run schedule.Run
+			if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if run.Error != "" {
+				t.Fatalf("\t%s\tShould run without an error : %s", tests.Failed, run.Error)
+			}
+			t.Logf("\t%s\tShould run without an error.", tests.Success)
+		}
+
+		url = "/v1/schedule/" + sch.Name + "/runs"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get the runs : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the runs : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the runs.", tests.Success)
+
+			var runs []schedule.Run
+			if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil || len(runs) == 0 {
+				t.Fatalf("\t%s\tShould have at least one run : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have at least one run.", tests.Success)
+		}
+
+		url = "/v1/schedule/" + sch.Name
+		r = tests.NewRequest("DELETE", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to delete : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to delete the schedule : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to delete the schedule.", tests.Success)
+		}
+
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not be able to retrieve the schedule : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to retrieve the schedule.", tests.Success)
+		}
+	}
+}
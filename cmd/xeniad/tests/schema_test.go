@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+)
+
+// schemaPrefix is the base name for the schemas.
+const schemaPrefix = "SCTEST_"
+
+// TestSchemaCRUD tests a schema can be managed through the API.
+func TestSchemaCRUD(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	s := schema.Schema{
+		Type:     schemaPrefix + "comment",
+		Mode:     schema.ModeReport,
+		Required: []string{"body"},
+		Properties: map[string]schema.Property{
+			"body":   {Type: schema.TypeString, MinLength: 1},
+			"status": {Type: schema.TypeString, Enum: []interface{}{"approved", "rejected"}},
+			"author": {
+				Type:     schema.TypeObject,
+				Required: []string{"name"},
+				Properties: map[string]schema.Property{
+					"name": {Type: schema.TypeString},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to marshal the schema : %v", tests.Failed, err)
+	}
+
+	t.Log("Given the need to manage an schema.")
+	{
+		url := "/v1/schema"
+		r := tests.NewRequest("PUT", url, bytes.NewBuffer(data))
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to insert the schema : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to insert the schema.", tests.Success)
+		}
+
+		url = "/v1/schema/" + s.Type
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the schema : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the schema.", tests.Success)
+
+			var got schema.Schema
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if !reflect.DeepEqual(s, got) {
+				t.Logf("\t%+v", s)
+				t.Logf("\t%+v", got)
+				t.Fatalf("\t%s\tShould be able to get back the same schema.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be able to get back the same schema.", tests.Success)
+		}
+
+		invalid := s
+		invalid.Mode = "strict"
+
+		data, err := json.Marshal(invalid)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to marshal the invalid schema : %v", tests.Failed, err)
+		}
+
+		url = "/v1/schema"
+		r = tests.NewRequest("PUT", url, bytes.NewBuffer(data))
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to insert an invalid schema : %s", url)
+		{
+			if w.Code != 400 {
+				t.Fatalf("\t%s\tShould not be able to insert the schema : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to insert the schema.", tests.Success)
+		}
+
+		url = "/v1/schema/" + s.Type + "/violations"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get the violations : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the violations : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the violations.", tests.Success)
+
+			var vs []schema.Violation
+			if err := json.Unmarshal(w.Body.Bytes(), &vs); err != nil || len(vs) != 0 {
+				t.Fatalf("\t%s\tShould have no violations : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have no violations.", tests.Success)
+		}
+
+		url = "/v1/schema/" + s.Type
+		r = tests.NewRequest("DELETE", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to delete : %s", url)
+		{
+			if w.Code != 204 {
+				t.Fatalf("\t%s\tShould be able to delete the schema : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to delete the schema.", tests.Success)
+		}
+
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not be able to retrieve the schema : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to retrieve the schema.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/audit"
+)
+
+// TestStatsQuery tests the executions of a set are reported in the stats.
+func TestStatsQuery(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Log("Given the need to get the statistics for a set.")
+	{
+		url := "/v1/exec/" + qPrefix + "_basic?station_id=42021"
+		r := tests.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to execute : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to execute the query : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to execute the query.", tests.Success)
+		}
+
+		url = "/v1/stats/query/" + qPrefix + "_basic?since=1h"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url to get the stats : %s", url)
+		{
+			if w.Code != 200 {
+				t.Fatalf("\t%s\tShould be able to retrieve the stats : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the stats.", tests.Success)
+
+			var st audit.SetStats
+			if err := json.Unmarshal(w.Body.Bytes(), &st); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to unmarshal the results.", tests.Success)
+
+			if st.Executions < 1 || len(st.Queries) == 0 {
+				t.Fatalf("\t%s\tShould have the execution recorded : %+v", tests.Failed, st)
+			}
+			t.Logf("\t%s\tShould have the execution recorded.", tests.Success)
+		}
+
+		url = "/v1/stats/query/" + qPrefix + "_missing"
+		r = tests.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+
+		a.ServeHTTP(w, r)
+
+		t.Logf("\tWhen calling url for a set with no executions : %s", url)
+		{
+			if w.Code != 404 {
+				t.Fatalf("\t%s\tShould not be able to retrieve the stats : %v", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould not be able to retrieve the stats.", tests.Success)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
 	"github.com/coralproject/shelf/internal/ask/form/submission"
+	"github.com/coralproject/shelf/internal/platform/collections"
 	validator "gopkg.in/bluesuncorp/validator.v8"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -32,6 +33,10 @@ var ErrInvalidID = errors.New("ID is not in it's proper form")
 // Collection is the mongo collection where Form documents are saved.
 const Collection = "forms"
 
+func init() {
+	collections.Protect(Collection)
+}
+
 // Widget describes a specific question being asked by the Form which is
 // contained within a Step.
 type Widget struct {
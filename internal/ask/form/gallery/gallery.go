@@ -8,6 +8,7 @@ import (
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
 	"github.com/coralproject/shelf/internal/ask/form/submission"
+	"github.com/coralproject/shelf/internal/platform/collections"
 	validator "gopkg.in/bluesuncorp/validator.v8"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -33,6 +34,10 @@ var ErrInvalidID = errors.New("ID is not in it's proper form")
 // saved.
 const Collection = "form_galleries"
 
+func init() {
+	collections.Protect(Collection)
+}
+
 // Answer describes an answer from a form which has been added to a
 // Gallery.
 type Answer struct {
@@ -9,6 +9,7 @@ import (
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
 
+	"github.com/coralproject/shelf/internal/platform/collections"
 	validator "gopkg.in/bluesuncorp/validator.v8"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -34,6 +35,10 @@ var ErrInvalidID = errors.New("ID is not in it's proper form")
 // documents are saved.
 const Collection = "form_submissions"
 
+func init() {
+	collections.Protect(Collection)
+}
+
 // EnsureIndexes perform index create commands against Mongo for the indexes
 // needed for the ask package to run.
 func EnsureIndexes(context interface{}, db *db.DB) error {
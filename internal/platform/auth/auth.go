@@ -0,0 +1,262 @@
+// Package auth provides the token authentication and scope authorization
+// middleware shared by the daemons.
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/log"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrInvalidToken is returned when the token provided is not valid.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrInvalidClaims is returned when the claims inside a valid token are not
+	// valid.
+	ErrInvalidClaims = errors.New("invalid claims")
+
+	// ErrForbidden is returned when the claims do not grant the scope a
+	// route requires.
+	ErrForbidden = errors.New("Forbidden")
+)
+
+// cfgAuthPublicKey is the key for which the actual base64 + PEM encoded public
+// RSA key is stored.
+const cfgAuthPublicKey = "AUTH_PUBLIC_KEY"
+
+// ctxClaims is the key the claims of a valid token are stored under.
+const ctxClaims = "claims"
+
+//==============================================================================
+
+// authOff is used when authentication is turned off by not providing a public
+// key in the environment.
+func authOff(h app.Handler) app.Handler {
+	f := func(c *app.Context) error {
+
+		// Log out the process for verbosity.
+		log.Dev(c.SessionID, "Auth", "Started")
+		log.Dev(c.SessionID, "Auth", "Authentication Off")
+		log.Dev(c.SessionID, "Auth", "Completed")
+		return h(c)
+	}
+
+	return f
+}
+
+// Midware returns the token authentication middleware. Authentication is
+// turned off when no public key is provided in the environment.
+func Midware() (app.Middleware, error) {
+
+	// Load in the public key to validate the JWT tokens.
+	publicKeyBase64Str, err := cfg.String(cfgAuthPublicKey)
+	if err != nil {
+		return authOff, nil
+	}
+
+	// Our public key has been encoded from a PEM encoded public RSA key into this
+	// publicKeyBase64Str. We need to decode the base64 string in order to get the
+	// PEM encoded certificate back out.
+	publicKeyPEM, err := base64.StdEncoding.DecodeString(publicKeyBase64Str)
+	if err != nil {
+		log.Error("startup", "Auth", err, "Can not setup Auth middleware")
+		return nil, err
+	}
+
+	return NewMidware(publicKeyPEM)
+}
+
+// NewMidware returns the token authentication middleware that validates RS256
+// tokens with the PEM encoded public RSA key. The claims of a valid token are
+// added to the context for the handlers and the Scope middleware.
+func NewMidware(publicKeyPEM []byte) (app.Middleware, error) {
+
+	// Now that we have our PEM encoded public RSA key, we can parse it using the
+	// methods built into the jwt librairy into something we can use to verify the
+	// incomming JWT's.
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyPEM)
+	if err != nil {
+		log.Error("startup", "Auth", err, "Can not setup Auth middleware")
+		return nil, err
+	}
+
+	log.Dev("startup", "Auth", "Initalizing Auth")
+
+	// Create the middleware to actually return.
+	m := func(h app.Handler) app.Handler {
+
+		// Create the handler that we should return as a part of the middleware
+		// chain.
+		f := func(c *app.Context) error {
+			log.Dev(c.SessionID, "Auth", "Started")
+
+			claims, err := validate(c, publicKey)
+			if err != nil {
+				return app.ErrNotAuthorized
+			}
+
+			// Add the claims to the context.
+			c.Ctx[ctxClaims] = claims
+
+			log.Dev(c.SessionID, "Auth", "Completed : Valid")
+			return h(c)
+		}
+
+		return f
+	}
+
+	return m, nil
+}
+
+// validate parses the token from the Authorization header and returns the
+// claims if the token and the claims are valid.
+func validate(c *app.Context, publicKey *rsa.PublicKey) (*jwt.MapClaims, error) {
+
+	// Extract the token from the Authorization header provided on the request.
+	// The bearer scheme is optional.
+	tokenString := c.Request.Header.Get("Authorization")
+	tokenString = strings.TrimSpace(strings.TrimPrefix(tokenString, "Bearer "))
+
+	if tokenString == "" {
+		log.Error(c.SessionID, "Auth", ErrInvalidToken, "No token on request")
+		return nil, ErrInvalidToken
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+
+		// Don't forget to validate the alg is what you expect.
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+
+		// Return with the public key that was provided in the config.
+		return publicKey, nil
+	})
+
+	// Return with the error if there was an issue parsing the token.
+	if err != nil {
+		log.Error(c.SessionID, "Auth", err, "Token could not be parsed")
+		return nil, ErrInvalidToken
+	}
+
+	// Return with an error if the token is not valid.
+	if !token.Valid {
+		log.Error(c.SessionID, "Auth", ErrInvalidToken, "Token not valid")
+		return nil, ErrInvalidToken
+	}
+
+	// Ensure that the claims that are inside the token are indeed the MapClaims
+	// that we expect.
+	claims, ok := token.Claims.(*jwt.MapClaims)
+	if !ok {
+		log.Error(c.SessionID, "Auth", ErrInvalidClaims, "Claims not valid")
+		return nil, ErrInvalidClaims
+	}
+
+	// Validate that all the parameters we expect are correct, noteably, the
+	// expiry date, and not before claims should be verified.
+	if err := claims.Valid(); err != nil {
+		log.Error(c.SessionID, "Auth", err, "Claims not valid")
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+//==============================================================================
+
+// Scope returns a middleware that only calls the handler when the claims
+// grant at least one of the scopes. It must run after the authentication
+// middleware so it is applied to the handler of a route. When authentication
+// is off there are no claims and every request is allowed.
+func Scope(scopes ...string) app.Middleware {
+	return func(h app.Handler) app.Handler {
+		return func(c *app.Context) error {
+			claims, ok := Claims(c)
+			if !ok {
+				return h(c)
+			}
+
+			granted := Granted(claims)
+			for _, scope := range scopes {
+				if Allows(granted, scope) {
+					return h(c)
+				}
+			}
+
+			log.Error(c.SessionID, "Scope", ErrForbidden, "Requires%v Granted%v", scopes, granted)
+			c.RespondError(ErrForbidden.Error(), http.StatusForbidden)
+			return nil
+		}
+	}
+}
+
+// Claims returns the claims of the token for the request. There are no
+// claims when authentication is off.
+func Claims(c *app.Context) (*jwt.MapClaims, bool) {
+	claims, ok := c.Ctx[ctxClaims].(*jwt.MapClaims)
+	return claims, ok
+}
+
+// Subject returns the subject of the token for the request which identifies
+// the caller. The subject is empty when authentication is off.
+func Subject(c *app.Context) string {
+	claims, ok := Claims(c)
+	if !ok {
+		return ""
+	}
+
+	sub, _ := (*claims)["sub"].(string)
+	return sub
+}
+
+// Granted returns the scopes granted by the claims. Scopes are read from the
+// space separated scope claim and the scopes and roles array claims.
+func Granted(claims *jwt.MapClaims) []string {
+	var granted []string
+
+	if s, ok := (*claims)["scope"].(string); ok {
+		granted = append(granted, strings.Fields(s)...)
+	}
+
+	for _, name := range []string{"scopes", "roles"} {
+		vs, ok := (*claims)[name].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, v := range vs {
+			if s, ok := v.(string); ok {
+				granted = append(granted, s)
+			}
+		}
+	}
+
+	return granted
+}
+
+// Allows reports if the granted scopes include the scope. A granted scope
+// ending in * allows every scope with that prefix, so query:* allows
+// query:read and * allows everything.
+func Allows(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope {
+			return true
+		}
+
+		if strings.HasSuffix(g, "*") && strings.HasPrefix(scope, strings.TrimSuffix(g, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/platform/auth"
+	"github.com/dgrijalva/jwt-go"
+)
+
+func init() {
+	tests.Init("AUTH")
+}
+
+//==============================================================================
+
+// newKey generates a private key and returns it with the PEM encoded public key.
+func newKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to generate a key : %v", tests.Failed, err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to marshal the public key : %v", tests.Failed, err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// newToken returns a signed token for the claims.
+func newToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to sign the token : %v", tests.Failed, err)
+	}
+
+	return token
+}
+
+// TestScope tests tokens are verified and the scopes of routes enforced.
+func TestScope(t *testing.T) {
+	key, publicKeyPEM := newKey(t)
+	other, _ := newKey(t)
+
+	m, err := auth.NewMidware(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create the middleware : %v", tests.Failed, err)
+	}
+
+	var subject string
+	ok := func(c *app.Context) error {
+		subject = auth.Subject(c)
+		c.Respond(nil, http.StatusNoContent)
+		return nil
+	}
+
+	a := app.New(m)
+	a.Handle("GET", "/test/read", auth.Scope(auth.ScopeQueryRead)(ok))
+	a.Handle("DELETE", "/test/mask", auth.Scope(auth.ScopeMaskAdmin)(ok))
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	reqs := []struct {
+		name   string
+		verb   string
+		url    string
+		token  string
+		status int
+	}{
+		{"no token", "GET", "/test/read", "", http.StatusUnauthorized},
+		{"wrong key", "GET", "/test/read", newToken(t, other, jwt.MapClaims{"sub": "bill", "scope": "query:read"}), http.StatusUnauthorized},
+		{"expired", "GET", "/test/read", newToken(t, key, jwt.MapClaims{"sub": "bill", "scope": "query:read", "exp": time.Now().Add(-time.Hour).Unix()}), http.StatusUnauthorized},
+		{"scope", "GET", "/test/read", "Bearer " + newToken(t, key, jwt.MapClaims{"sub": "bill", "scope": "query:read query:write", "exp": exp}), http.StatusNoContent},
+		{"missing scope", "DELETE", "/test/mask", newToken(t, key, jwt.MapClaims{"sub": "bill", "scope": "query:read"}), http.StatusForbidden},
+		{"role", "DELETE", "/test/mask", newToken(t, key, jwt.MapClaims{"sub": "bill", "roles": []string{"mask:admin"}}), http.StatusNoContent},
+		{"wildcard", "DELETE", "/test/mask", newToken(t, key, jwt.MapClaims{"sub": "bill", "scopes": []string{"mask:*"}}), http.StatusNoContent},
+	}
+
+	t.Log("Given the need to authorize requests with tokens.")
+	{
+		for _, req := range reqs {
+			t.Logf("\tWhen using a request with %s", req.name)
+			{
+				r := tests.NewRequest(req.verb, req.url, nil)
+				if req.token != "" {
+					r.Header.Set("Authorization", req.token)
+				}
+				w := httptest.NewRecorder()
+
+				subject = ""
+				a.ServeHTTP(w, r)
+
+				if w.Code != req.status {
+					t.Errorf("\t%s\tShould get status %d : got %d", tests.Failed, req.status, w.Code)
+					continue
+				}
+				t.Logf("\t%s\tShould get status %d.", tests.Success, req.status)
+
+				if w.Code == http.StatusNoContent && subject != "bill" {
+					t.Errorf("\t%s\tShould have the subject of the token : got %q", tests.Failed, subject)
+					continue
+				}
+			}
+		}
+	}
+}
+
+// TestAllows tests granted scopes are matched against required scopes.
+func TestAllows(t *testing.T) {
+	scopes := []struct {
+		granted []string
+		scope   string
+		allowed bool
+	}{
+		{[]string{"query:read"}, "query:read", true},
+		{[]string{"query:read"}, "query:write", false},
+		{[]string{"query:*"}, "query:write", true},
+		{[]string{"query:*"}, "mask:admin", false},
+		{[]string{"*"}, "mask:admin", true},
+		{nil, "query:read", false},
+	}
+
+	t.Log("Given the need to match granted scopes.")
+	{
+		for _, s := range scopes {
+			t.Logf("\tWhen granted %v and requiring %s", s.granted, s.scope)
+			{
+				if got := auth.Allows(s.granted, s.scope); got != s.allowed {
+					t.Errorf("\t%s\tShould get %v : got %v", tests.Failed, s.allowed, got)
+					continue
+				}
+				t.Logf("\t%s\tShould get %v.", tests.Success, s.allowed)
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+// Set of scopes required by the route groups of the daemons.
+const (
+	ScopeQueryRead  = "query:read"  // Read sets, scripts, regexs, schedules and stats.
+	ScopeQueryWrite = "query:write" // Manage sets, scripts, regexs and schedules.
+	ScopeQueryExec  = "query:exec"  // Execute saved sets.
+	ScopeExecCustom = "exec:custom" // Execute sets provided in the request.
+	ScopeMaskAdmin  = "mask:admin"  // Manage masks.
+	ScopeWireRead   = "wire:read"   // Read patterns, relationships and views.
+	ScopeWireWrite  = "wire:write"  // Manage patterns, relationships and views.
+	ScopeItemRead   = "item:read"   // Read items.
+	ScopeItemWrite  = "item:write"  // Manage items and import data.
+	ScopeFormRead   = "form:read"   // Read forms, submissions and galleries.
+	ScopeFormWrite  = "form:write"  // Manage forms, submissions and galleries.
+	ScopeFormSubmit = "form:submit" // Create submissions for a form.
+)
@@ -0,0 +1,47 @@
+// Package collections keeps the registry of the collections Shelf keeps its
+// own data in. Query results, views and schedule outputs are written into
+// collections named by callers, so those names are checked against the
+// registry first. The packages owning collections add them from their init
+// functions.
+package collections
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// protected contains the collections results can't be written into. Cayley
+// keeps the graph in the same database in the quads, nodes and log
+// collections.
+var protected = map[string]bool{
+	"quads": true,
+	"nodes": true,
+	"log":   true,
+}
+
+// Protect adds collections to the set results can't be written into. It is
+// called from the init functions of the packages owning the collections.
+func Protect(names ...string) {
+	for _, name := range names {
+		protected[name] = true
+	}
+}
+
+// Writable checks results can be written into the named collection. The
+// collections of the query metadata, the history collections and the system
+// collections are always protected.
+func Writable(name string) error {
+	switch {
+	case name == "":
+		return errors.New("Missing save collection name")
+
+	case strings.ContainsAny(name, "$\x00"), strings.HasPrefix(name, "system."):
+		return fmt.Errorf("Invalid save collection %q", name)
+
+	case protected[name], strings.HasPrefix(name, "query_"), strings.HasSuffix(name, "_history"):
+		return fmt.Errorf("Collection %q is protected and can't be saved to", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package collections_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/platform/collections"
+)
+
+// TestWritable tests results can't be written into the protected collections.
+func TestWritable(t *testing.T) {
+	collections.Protect("test_owned")
+
+	names := []string{"quads", "nodes", "log", "test_owned", "query_sets", "views_history", "system.users", "a$b", ""}
+
+	t.Log("Given the need to write results into collections.")
+	{
+		for _, name := range names {
+			t.Logf("\tWhen using collection %q", name)
+			{
+				if err := collections.Writable(name); err == nil {
+					t.Fatalf("\t%s\tShould not be able to write into the collection.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould not be able to write into the collection.", tests.Success)
+			}
+		}
+
+		t.Log("\tWhen using a collection nothing owns")
+		{
+			if err := collections.Writable("top_commenters"); err != nil {
+				t.Fatalf("\t%s\tShould be able to write into the collection : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to write into the collection.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package history provides support for listing, comparing and restoring the
+// revisions kept in the history collections of the metadata types. Each
+// history document holds the revisions of a single document in an array with
+// the newest revision first. Revisions are numbered from the oldest, which is
+// revision 1, so the numbers don't change as new revisions are added.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Set of error variables.
+var (
+	ErrNotFound   = errors.New("History not found")
+	ErrInvalidKey = errors.New("Invalid history key")
+)
+
+// Kind describes the history kept for a type of metadata.
+type Kind struct {
+	Collection string                                                    // Collection holding the history documents.
+	Field      string                                                    // Field of the history document holding the revisions.
+	Selector   func(key string) (bson.M, error)                          // Selects the history document for the key.
+	Decode     func(raw bson.Raw) (interface{}, error)                   // Decodes a revision so it is ready for use.
+	Restore    func(context interface{}, db *db.DB, v interface{}) error // Makes a decoded revision the current document.
+}
+
+// Revision is a version of a document kept in the history.
+type Revision struct {
+	Number   int         `json:"revision"`
+	Document interface{} `json:"document"`
+}
+
+// Change is a field whose value differs between two revisions. A field that
+// only exists in one of the revisions has no value for the other.
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from,omitempty"`
+	To    interface{} `json:"to,omitempty"`
+}
+
+//==============================================================================
+
+// List retrieves the revisions of the document for the key with the newest
+// revision first.
+func List(context interface{}, db *db.DB, k Kind, key string) ([]Revision, error) {
+	log.Dev(context, "List", "Started : Collection[%s] Key[%s]", k.Collection, key)
+
+	raws, err := load(context, db, k, key)
+	if err != nil {
+		log.Error(context, "List", err, "Completed")
+		return nil, err
+	}
+
+	revs := make([]Revision, len(raws))
+	for i, raw := range raws {
+		doc, err := k.Decode(raw)
+		if err != nil {
+			log.Error(context, "List", err, "Completed")
+			return nil, err
+		}
+
+		revs[i] = Revision{Number: len(raws) - i, Document: doc}
+	}
+
+	log.Dev(context, "List", "Completed : Revisions[%d]", len(revs))
+	return revs, nil
+}
+
+// Get retrieves the specified revision of the document for the key.
+func Get(context interface{}, db *db.DB, k Kind, key string, number int) (*Revision, error) {
+	log.Dev(context, "Get", "Started : Collection[%s] Key[%s] Revision[%d]", k.Collection, key, number)
+
+	raws, err := load(context, db, k, key)
+	if err != nil {
+		log.Error(context, "Get", err, "Completed")
+		return nil, err
+	}
+
+	rev, err := revision(k, raws, number)
+	if err != nil {
+		log.Error(context, "Get", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "Get", "Completed")
+	return rev, nil
+}
+
+// Diff compares two revisions of the document for the key and returns the
+// fields that changed sorted by name. Nested fields are named by their path,
+// like queries.0.collection.
+func Diff(context interface{}, db *db.DB, k Kind, key string, from int, to int) ([]Change, error) {
+	log.Dev(context, "Diff", "Started : Collection[%s] Key[%s] From[%d] To[%d]", k.Collection, key, from, to)
+
+	raws, err := load(context, db, k, key)
+	if err != nil {
+		log.Error(context, "Diff", err, "Completed")
+		return nil, err
+	}
+
+	var fields [2]map[string]interface{}
+	for i, number := range []int{from, to} {
+		rev, err := revision(k, raws, number)
+		if err != nil {
+			log.Error(context, "Diff", err, "Completed")
+			return nil, err
+		}
+
+		if fields[i], err = flatten(rev.Document); err != nil {
+			log.Error(context, "Diff", err, "Completed")
+			return nil, err
+		}
+	}
+
+	changes := compare(fields[0], fields[1])
+
+	log.Dev(context, "Diff", "Completed : Changes[%d]", len(changes))
+	return changes, nil
+}
+
+// Restore makes the specified revision of the document for the key the
+// current document. The restored document is written like any other change
+// so it becomes the newest revision.
+func Restore(context interface{}, db *db.DB, k Kind, key string, number int) error {
+	log.Dev(context, "Restore", "Started : Collection[%s] Key[%s] Revision[%d]", k.Collection, key, number)
+
+	raws, err := load(context, db, k, key)
+	if err != nil {
+		log.Error(context, "Restore", err, "Completed")
+		return err
+	}
+
+	rev, err := revision(k, raws, number)
+	if err != nil {
+		log.Error(context, "Restore", err, "Completed")
+		return err
+	}
+
+	if err := k.Restore(context, db, rev.Document); err != nil {
+		log.Error(context, "Restore", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Restore", "Completed")
+	return nil
+}
+
+//==============================================================================
+
+// load retrieves the raw revisions of the document for the key with the
+// newest revision first.
+func load(context interface{}, db *db.DB, k Kind, key string) ([]bson.Raw, error) {
+	q, err := k.Selector(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string][]bson.Raw
+	f := func(c *mgo.Collection) error {
+		proj := bson.M{k.Field: 1, "_id": 0}
+		log.Dev(context, "load", "MGO : db.%s.find(%s,%s)", c.Name, mongo.Query(q), mongo.Query(proj))
+		return c.Find(q).Select(proj).One(&result)
+	}
+
+	if err := db.ExecuteMGO(context, k.Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		return nil, err
+	}
+
+	if len(result[k.Field]) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return result[k.Field], nil
+}
+
+// revision decodes the revision with the specified number.
+func revision(k Kind, raws []bson.Raw, number int) (*Revision, error) {
+	if number < 1 || number > len(raws) {
+		return nil, ErrNotFound
+	}
+
+	doc, err := k.Decode(raws[len(raws)-number])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{Number: number, Document: doc}, nil
+}
+
+// flatten returns the fields of the document by their path using the names
+// of the fields in the JSON form of the document.
+func flatten(doc interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		join := func(name string) string {
+			if path == "" {
+				return name
+			}
+			return path + "." + name
+		}
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if len(v) == 0 && path != "" {
+				fields[path] = v
+				return
+			}
+			for name, sub := range v {
+				walk(join(name), sub)
+			}
+
+		case []interface{}:
+			if len(v) == 0 {
+				fields[path] = v
+				return
+			}
+			for i, sub := range v {
+				walk(join(strconv.Itoa(i)), sub)
+			}
+
+		default:
+			fields[path] = v
+		}
+	}
+
+	walk("", v)
+	return fields, nil
+}
+
+// compare returns the changes between two sets of flattened fields.
+func compare(from map[string]interface{}, to map[string]interface{}) []Change {
+	changes := []Change{}
+
+	for field, v := range from {
+		if nv, exists := to[field]; !exists || !reflect.DeepEqual(v, nv) {
+			changes = append(changes, Change{Field: field, From: v, To: nv})
+		}
+	}
+
+	for field, v := range to {
+		if _, exists := from[field]; !exists {
+			changes = append(changes, Change{Field: field, To: v})
+		}
+	}
+
+	sort.Sort(byField(changes))
+	return changes
+}
+
+// byField sorts changes by the name of the field.
+type byField []Change
+
+func (b byField) Len() int           { return len(b) }
+func (b byField) Less(i, j int) bool { return b[i].Field < b[j].Field }
+func (b byField) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
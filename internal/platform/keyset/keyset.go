@@ -0,0 +1,200 @@
+// Package keyset provides support for paging through sorted documents with
+// cursors. A cursor holds the values of the sort fields of the last document
+// of a page and the next page is read with a filter selecting the documents
+// that sort after them, so pages stay stable as documents are added.
+package keyset
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrInvalid is returned when a cursor can't be decoded.
+var ErrInvalid = errors.New("Invalid cursor")
+
+// Key is a field used to order the documents, with a direction of 1 for an
+// ascending and -1 for a descending sort.
+type Key struct {
+	Field string
+	Dir   int
+}
+
+//==============================================================================
+
+// Keys returns the keys for the sort fields, which are prefixed with - for a
+// descending sort, with the tie field added to break ties when it is not
+// already sorted on.
+func Keys(sort []string, tie string) []Key {
+	var keys []Key
+	var hasTie bool
+
+	for _, fld := range sort {
+		k := Key{Field: fld, Dir: 1}
+		switch {
+		case strings.HasPrefix(fld, "-"):
+			k = Key{Field: fld[1:], Dir: -1}
+		case strings.HasPrefix(fld, "+"):
+			k.Field = fld[1:]
+		}
+
+		if k.Field == tie {
+			hasTie = true
+		}
+		keys = append(keys, k)
+	}
+
+	if !hasTie {
+		keys = append(keys, Key{Field: tie, Dir: 1})
+	}
+
+	return keys
+}
+
+// Fields returns the keys as the sort fields of a find.
+func Fields(keys []Key) []string {
+	flds := make([]string, len(keys))
+	for i, k := range keys {
+		flds[i] = k.Field
+		if k.Dir < 0 {
+			flds[i] = "-" + k.Field
+		}
+	}
+
+	return flds
+}
+
+// Doc returns the keys as an ordered $sort document.
+func Doc(keys []Key) bson.D {
+	d := make(bson.D, len(keys))
+	for i, k := range keys {
+		d[i] = bson.DocElem{Name: k.Field, Value: k.Dir}
+	}
+
+	return d
+}
+
+//==============================================================================
+
+// After returns the filter that selects the documents that sort after the
+// values of the keys. Mongo sorts null and missing values before any other
+// value, so a nil value is matched as null and documents without a field
+// are not skipped.
+func After(keys []Key, values []interface{}) (bson.M, error) {
+	if len(keys) != len(values) {
+		return nil, ErrInvalid
+	}
+
+	// {"$or": [{"a": {"$gt": 1}}, {"a": 1, "_id": {"$gt": 2}}]}
+	var or []bson.M
+	for i, k := range keys {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[keys[j].Field] = values[j]
+		}
+
+		v := values[i]
+		switch {
+		case k.Dir > 0 && v == nil:
+			cond[k.Field] = bson.M{"$ne": nil}
+
+		case k.Dir > 0:
+			cond[k.Field] = bson.M{"$gt": v}
+
+		case v == nil:
+
+			// Nothing sorts after null in a descending sort.
+			continue
+
+		default:
+
+			// Null and missing values sort last in a descending sort.
+			cond["$or"] = []bson.M{
+				{k.Field: bson.M{"$lt": v}},
+				{k.Field: nil},
+			}
+		}
+
+		or = append(or, cond)
+	}
+
+	if len(or) == 0 {
+		return nil, ErrInvalid
+	}
+
+	return bson.M{"$or": or}, nil
+}
+
+// Values returns the values of the keys in the document. The value of a
+// field the document doesn't have is nil, which is how Mongo sorts it.
+func Values(doc map[string]interface{}, keys []Key) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = Lookup(doc, k.Field)
+	}
+
+	return values
+}
+
+// Lookup returns the value of the dotted field in the document or nil when
+// the document doesn't have the field.
+func Lookup(doc map[string]interface{}, field string) interface{} {
+	var v interface{} = doc
+	for _, name := range strings.Split(field, ".") {
+		switch m := v.(type) {
+		case map[string]interface{}:
+			v = m[name]
+		case bson.M:
+			v = m[name]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+//==============================================================================
+
+// Encode returns the cursor value as an opaque string.
+func Encode(cursor interface{}) (string, error) {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode reads the cursor value from the string returned by Encode.
+func Decode(s string, cursor interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	if err := bson.Unmarshal(data, cursor); err != nil {
+		return ErrInvalid
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// Masked returns the first field whose value is masked, or an empty string.
+// Masks apply to every field with the name of the mask whatever the document
+// it is in, so the last name of a dotted field is checked. Masked fields
+// can't be sorted on since the cursor holds their value, nor filtered on
+// since the filter would reveal it.
+func Masked(fields []string, masked func(name string) bool) string {
+	for _, fld := range fields {
+		if masked(fld[strings.LastIndex(fld, ".")+1:]) {
+			return fld
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,172 @@
+package keyset_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/platform/keyset"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TestKeys tests the keys of the sort fields get the tie field added.
+func TestKeys(t *testing.T) {
+	t.Log("Given the need to sort documents with a cursor.")
+	{
+		t.Log("\tWhen using sort fields without the tie field")
+		{
+			keys := keyset.Keys([]string{"-data.created", "+type"}, "item_id")
+			exp := []keyset.Key{{Field: "data.created", Dir: -1}, {Field: "type", Dir: 1}, {Field: "item_id", Dir: 1}}
+			if !reflect.DeepEqual(keys, exp) {
+				t.Fatalf("\t%s\tShould get the keys with the tie field : %v", tests.Failed, keys)
+			}
+			t.Logf("\t%s\tShould get the keys with the tie field.", tests.Success)
+
+			flds := keyset.Fields(keys)
+			if !reflect.DeepEqual(flds, []string{"-data.created", "type", "item_id"}) {
+				t.Fatalf("\t%s\tShould get back the sort fields : %v", tests.Failed, flds)
+			}
+			t.Logf("\t%s\tShould get back the sort fields.", tests.Success)
+		}
+
+		t.Log("\tWhen using sort fields with the tie field")
+		{
+			keys := keyset.Keys([]string{"-item_id"}, "item_id")
+			if !reflect.DeepEqual(keys, []keyset.Key{{Field: "item_id", Dir: -1}}) {
+				t.Fatalf("\t%s\tShould not add the tie field again : %v", tests.Failed, keys)
+			}
+			t.Logf("\t%s\tShould not add the tie field again.", tests.Success)
+		}
+	}
+}
+
+// TestAfter tests the filter selecting the documents after the values of a
+// cursor, including documents missing the sort field.
+func TestAfter(t *testing.T) {
+	asc := []keyset.Key{{Field: "a", Dir: 1}, {Field: "_id", Dir: 1}}
+	desc := []keyset.Key{{Field: "a", Dir: -1}, {Field: "_id", Dir: 1}}
+
+	filters := []struct {
+		name   string
+		keys   []keyset.Key
+		values []interface{}
+		exp    bson.M
+	}{
+		{
+			"an ascending value",
+			asc, []interface{}{1, 2},
+			bson.M{"$or": []bson.M{{"a": bson.M{"$gt": 1}}, {"a": 1, "_id": bson.M{"$gt": 2}}}},
+		},
+		{
+			"an ascending missing value",
+			asc, []interface{}{nil, 2},
+			bson.M{"$or": []bson.M{{"a": bson.M{"$ne": nil}}, {"a": nil, "_id": bson.M{"$gt": 2}}}},
+		},
+		{
+			"a descending value",
+			desc, []interface{}{1, 2},
+			bson.M{"$or": []bson.M{{"$or": []bson.M{{"a": bson.M{"$lt": 1}}, {"a": nil}}}, {"a": 1, "_id": bson.M{"$gt": 2}}}},
+		},
+		{
+			"a descending missing value",
+			desc, []interface{}{nil, 2},
+			bson.M{"$or": []bson.M{{"a": nil, "_id": bson.M{"$gt": 2}}}},
+		},
+	}
+
+	t.Log("Given the need to select the documents after a cursor.")
+	{
+		for _, f := range filters {
+			t.Logf("\tWhen using %s", f.name)
+			{
+				after, err := keyset.After(f.keys, f.values)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to get the filter : %v", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould be able to get the filter.", tests.Success)
+
+				if !reflect.DeepEqual(after, f.exp) {
+					t.Fatalf("\t%s\tShould get the expected filter : %v", tests.Failed, after)
+				}
+				t.Logf("\t%s\tShould get the expected filter.", tests.Success)
+			}
+		}
+
+		t.Log("\tWhen using values that don't match the keys")
+		{
+			if _, err := keyset.After(asc, []interface{}{1}); err != keyset.ErrInvalid {
+				t.Fatalf("\t%s\tShould get an invalid cursor error : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get an invalid cursor error.", tests.Success)
+		}
+	}
+}
+
+// TestEncode tests a cursor can be read back from its string.
+func TestEncode(t *testing.T) {
+	type cursor struct {
+		Name   string        `bson:"n"`
+		Values []interface{} `bson:"v"`
+	}
+
+	t.Log("Given the need to hand out cursors.")
+	{
+		t.Log("\tWhen encoding the values of a document")
+		{
+			doc := bson.M{"item_id": "a1", "data": bson.M{"count": 3}}
+			keys := keyset.Keys([]string{"-data.count", "data.missing"}, "item_id")
+
+			s, err := keyset.Encode(cursor{Name: "page", Values: keyset.Values(doc, keys)})
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to encode the cursor : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to encode the cursor.", tests.Success)
+
+			var c cursor
+			if err := keyset.Decode(s, &c); err != nil {
+				t.Fatalf("\t%s\tShould be able to decode the cursor : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to decode the cursor.", tests.Success)
+
+			if c.Name != "page" || !reflect.DeepEqual(c.Values, []interface{}{3, nil, "a1"}) {
+				t.Fatalf("\t%s\tShould get back the same values : %v", tests.Failed, c.Values)
+			}
+			t.Logf("\t%s\tShould get back the same values.", tests.Success)
+		}
+
+		t.Log("\tWhen decoding a string that is not a cursor")
+		{
+			var c cursor
+			if err := keyset.Decode("not a cursor", &c); err != keyset.ErrInvalid {
+				t.Fatalf("\t%s\tShould get an invalid cursor error : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get an invalid cursor error.", tests.Success)
+		}
+	}
+}
+
+// TestMasked tests the fields whose values are masked are found.
+func TestMasked(t *testing.T) {
+	masked := func(name string) bool {
+		return name == "email"
+	}
+
+	t.Log("Given the need to keep masked values out of cursors and filters.")
+	{
+		t.Log("\tWhen a nested field has the name of a mask")
+		{
+			if fld := keyset.Masked([]string{"type", "data.author.email"}, masked); fld != "data.author.email" {
+				t.Fatalf("\t%s\tShould find the masked field : %q", tests.Failed, fld)
+			}
+			t.Logf("\t%s\tShould find the masked field.", tests.Success)
+		}
+
+		t.Log("\tWhen no field has the name of a mask")
+		{
+			if fld := keyset.Masked([]string{"type", "data.emails"}, masked); fld != "" {
+				t.Fatalf("\t%s\tShould not find a masked field : %q", tests.Failed, fld)
+			}
+			t.Logf("\t%s\tShould not find a masked field.", tests.Success)
+		}
+	}
+}
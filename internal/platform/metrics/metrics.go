@@ -0,0 +1,285 @@
+// Package metrics provides support for recording operational metrics and
+// exposing them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default upper bounds in seconds used by histograms.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector is implemented by each type of metric so it can be written.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// registry contains the metrics in the order they were created.
+var registry struct {
+	sync.Mutex
+	collectors []collector
+}
+
+// register adds the collector to the registry.
+func register(c collector) {
+	registry.Lock()
+	registry.collectors = append(registry.collectors, c)
+	registry.Unlock()
+}
+
+// Write writes all the metrics to the writer in the text exposition format.
+func Write(w io.Writer) error {
+	registry.Lock()
+	collectors := make([]collector, len(registry.collectors))
+	copy(collectors, registry.collectors)
+	registry.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+//==============================================================================
+
+// desc contains the details shared by all types of metrics.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key returns the key a series is stored under for the label values.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s requires %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+
+	return strings.Join(values, "\xff")
+}
+
+// header writes the help and type lines for the metric.
+func (d *desc) header(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, d.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// pairs formats the labels with the values plus any extra pair.
+func (d *desc) pairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	ps := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		ps = append(ps, d.labels[i]+"="+strconv.Quote(v))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		ps = append(ps, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+
+	return "{" + strings.Join(ps, ",") + "}"
+}
+
+// sortedKeys returns the keys of the series in order.
+func sortedKeys(n int, each func(func(string))) []string {
+	keys := make([]string, 0, n)
+	each(func(k string) { keys = append(keys, k) })
+	sort.Strings(keys)
+	return keys
+}
+
+// splitKey returns the label values the key was created from.
+func splitKey(key string, labels int) []string {
+	if labels == 0 {
+		return nil
+	}
+
+	return strings.Split(key, "\xff")
+}
+
+// formatFloat formats a value the way the exposition format expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+//==============================================================================
+
+// Counter is a metric whose value only goes up.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+// NewCounter creates and registers a counter with the specified labels.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := Counter{
+		desc:   desc{name: name, help: help, labels: labels},
+		series: make(map[string]float64),
+	}
+
+	register(&c)
+	return &c
+}
+
+// Inc adds one to the counter for the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds the value to the counter for the label values.
+func (c *Counter) Add(v float64, values ...string) {
+	key := c.key(values)
+
+	c.mu.Lock()
+	c.series[key] += v
+	c.mu.Unlock()
+}
+
+// Value returns the current value of the counter for the label values.
+func (c *Counter) Value(values ...string) float64 {
+	key := c.key(values)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.series[key]
+}
+
+// write implements the collector interface.
+func (c *Counter) write(w *bufio.Writer) {
+	c.header(w, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := sortedKeys(len(c.series), func(f func(string)) {
+		for k := range c.series {
+			f(k)
+		}
+	})
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.pairs(splitKey(k, len(c.labels))), formatFloat(c.series[k]))
+	}
+}
+
+//==============================================================================
+
+// histogramSeries contains the observations for one set of label values.
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram is a metric that counts observations in buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// NewHistogram creates and registers a histogram with the specified bucket
+// upper bounds and labels. DefBuckets are used when no buckets are provided.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+
+	bs := make([]float64, len(buckets))
+	copy(bs, buckets)
+	sort.Float64s(bs)
+
+	h := Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: bs,
+		series:  make(map[string]*histogramSeries),
+	}
+
+	register(&h)
+	return &h
+}
+
+// Observe records the value for the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := h.key(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, exists := h.series[key]
+	if !exists {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+
+	s.count++
+	s.sum += v
+}
+
+// Count returns the number of observations for the label values.
+func (h *Histogram) Count(values ...string) uint64 {
+	key := h.key(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, exists := h.series[key]; exists {
+		return s.count
+	}
+
+	return 0
+}
+
+// write implements the collector interface.
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := sortedKeys(len(h.series), func(f func(string)) {
+		for k := range h.series {
+			f(k)
+		}
+	})
+
+	for _, k := range keys {
+		s := h.series[k]
+		values := splitKey(k, len(h.labels))
+
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(values, "le", formatFloat(b)), s.counts[i])
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.pairs(values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.pairs(values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.pairs(values), s.count)
+	}
+}
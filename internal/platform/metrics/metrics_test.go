@@ -0,0 +1,146 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/ardanlabs/kit/web/app"
+	"github.com/coralproject/shelf/internal/platform/metrics"
+)
+
+func init() {
+	tests.Init("METRICS")
+}
+
+//==============================================================================
+
+// TestWrite tests the metrics are written in the text exposition format.
+func TestWrite(t *testing.T) {
+	c := metrics.NewCounter("test_write_total", "Test counter.", "kind")
+	h := metrics.NewHistogram("test_write_seconds", "Test histogram.", []float64{1, 5})
+
+	c.Inc("a")
+	c.Add(2, "a")
+	c.Inc("b")
+	h.Observe(0.5)
+	h.Observe(3)
+
+	lines := []string{
+		"# TYPE test_write_total counter",
+		`test_write_total{kind="a"} 3`,
+		`test_write_total{kind="b"} 1`,
+		"# TYPE test_write_seconds histogram",
+		`test_write_seconds_bucket{le="1"} 1`,
+		`test_write_seconds_bucket{le="5"} 2`,
+		`test_write_seconds_bucket{le="+Inf"} 2`,
+		"test_write_seconds_sum 3.5",
+		"test_write_seconds_count 2",
+	}
+
+	t.Log("Given the need to write the metrics.")
+	{
+		t.Log("\tWhen using a counter and a histogram")
+		{
+			var buf bytes.Buffer
+			if err := metrics.Write(&buf); err != nil {
+				t.Fatalf("\t%s\tShould be able to write the metrics : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to write the metrics.", tests.Success)
+
+			for _, line := range lines {
+				if !strings.Contains(buf.String(), line+"\n") {
+					t.Errorf("\t%s\tShould have the line %q.", tests.Failed, line)
+					continue
+				}
+				t.Logf("\t%s\tShould have the line %q.", tests.Success, line)
+			}
+		}
+	}
+}
+
+// TestRoute tests the route parameters are replaced by their names.
+func TestRoute(t *testing.T) {
+	routes := []struct {
+		path   string
+		params map[string]string
+		route  string
+	}{
+		{"/v1/version", nil, "/v1/version"},
+		{"/v1/exec/top_users", map[string]string{"name": "top_users"}, "/v1/exec/:name"},
+		{"/v1/mask/users/email", map[string]string{"collection": "users", "field": "email"}, "/v1/mask/:collection/:field"},
+	}
+
+	t.Log("Given the need to count requests per route.")
+	{
+		for _, r := range routes {
+			t.Logf("\tWhen using path %s", r.path)
+			{
+				if got := metrics.Route(r.path, r.params); got != r.route {
+					t.Errorf("\t%s\tShould get route %s : got %s", tests.Failed, r.route, got)
+					continue
+				}
+				t.Logf("\t%s\tShould get route %s.", tests.Success, r.route)
+			}
+		}
+	}
+}
+
+// TestMidware tests requests and panics are counted by the middleware.
+func TestMidware(t *testing.T) {
+	a := app.New(metrics.Midware)
+	a.Handle("GET", "/test/ok/:id", func(c *app.Context) error {
+		c.Respond(nil, http.StatusNoContent)
+		return nil
+	})
+	a.Handle("GET", "/test/missing", func(c *app.Context) error {
+		return app.ErrNotFound
+	})
+	a.Handle("GET", "/test/panic", func(c *app.Context) error {
+		panic("boom")
+	})
+
+	t.Log("Given the need to measure requests.")
+	{
+		reqs := []struct {
+			url    string
+			route  string
+			status int
+		}{
+			{"/test/ok/42", "/test/ok/:id", http.StatusNoContent},
+			{"/test/missing", "/test/missing", http.StatusNotFound},
+			{"/test/panic", "/test/panic", http.StatusInternalServerError},
+		}
+
+		for _, req := range reqs {
+			t.Logf("\tWhen calling url : %s", req.url)
+			{
+				r := tests.NewRequest("GET", req.url, nil)
+				w := httptest.NewRecorder()
+
+				a.ServeHTTP(w, r)
+
+				if w.Code != req.status {
+					t.Errorf("\t%s\tShould get status %d : got %d", tests.Failed, req.status, w.Code)
+					continue
+				}
+				t.Logf("\t%s\tShould get status %d.", tests.Success, req.status)
+
+				if n := metrics.Requests.Value("GET", req.route, strconv.Itoa(req.status)); n != 1 {
+					t.Errorf("\t%s\tShould count the request : got %v", tests.Failed, n)
+					continue
+				}
+				t.Logf("\t%s\tShould count the request.", tests.Success)
+			}
+		}
+
+		if n := metrics.Panics.Value("GET", "/test/panic"); n != 1 {
+			t.Fatalf("\t%s\tShould count the panic : got %v", tests.Failed, n)
+		}
+		t.Logf("\t%s\tShould count the panic.", tests.Success)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/kit/log"
+	"github.com/ardanlabs/kit/web/app"
+)
+
+// Set of metrics shared by the daemons.
+var (
+	Requests     = NewCounter("http_requests_total", "Number of requests handled by route and status.", "method", "route", "status")
+	Latency      = NewHistogram("http_request_duration_seconds", "Time taken to handle requests by route.", nil, "method", "route")
+	Panics       = NewCounter("http_panics_recovered_total", "Number of panics recovered while handling requests.", "method", "route")
+	MongoAcquire = NewHistogram("mongo_session_acquire_seconds", "Time taken to acquire a Mongo session.", nil)
+	CayleyOpen   = NewHistogram("cayley_store_open_seconds", "Time taken to open the Cayley store.", nil)
+	ExecTimeouts = NewCounter("xenia_exec_timeouts_total", "Number of query executions that timed out by collection.", "collection")
+)
+
+// ErrPanic is returned to the client when a panic is recovered.
+var ErrPanic = errors.New("Internal error")
+
+// contentType is the media type of the text exposition format.
+const contentType = "text/plain; version=0.0.4"
+
+//==============================================================================
+
+// Midware records the count and latency of each request by route and
+// recovers from any panic so it is counted. It should be the first
+// middleware so the time spent in the others is included.
+func Midware(h app.Handler) app.Handler {
+	return func(c *app.Context) (err error) {
+		route := Route(c.Request.URL.Path, c.Params)
+
+		defer func() {
+			if r := recover(); r != nil {
+				Panics.Inc(c.Request.Method, route)
+				log.Error(c.SessionID, "Metrics", fmt.Errorf("%v", r), "Recovered : Method[%s] Route[%s]", c.Request.Method, route)
+				err = ErrPanic
+			}
+
+			// Respond here so the status of errors is recorded.
+			if err != nil {
+				c.Error(err)
+				err = nil
+			}
+
+			Requests.Inc(c.Request.Method, route, fmt.Sprint(c.Status))
+			Latency.Observe(time.Since(c.Now).Seconds(), c.Request.Method, route)
+		}()
+
+		return h(c)
+	}
+}
+
+// Handler writes the metrics in the text exposition format. It is mounted
+// on the router directly so requests for metrics are not measured or
+// authenticated.
+func Handler(w http.ResponseWriter, r *http.Request, p map[string]string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if err := Write(w); err != nil {
+		log.Error("metrics", "Handler", err, "Writing metrics")
+	}
+}
+
+// Route returns the path with the values of the route parameters replaced
+// by their names so requests are counted per route and not per URL.
+func Route(path string, params map[string]string) string {
+	if len(params) == 0 {
+		return path
+	}
+
+	names := make(map[string]string, len(params))
+	for k, v := range params {
+		names[v] = k
+	}
+
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if name, exists := names[seg]; exists && seg != "" {
+			segs[i] = ":" + name
+		}
+	}
+
+	return strings.Join(segs, "/")
+}
+
+// Since records the seconds elapsed since the start time in the histogram.
+func Since(h *Histogram, start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
@@ -0,0 +1,239 @@
+// Package idstrategy provides the service layer for managing the strategies
+// used to compose the IDs of items from their data. Items are itemized and
+// their relationships are inferred with the same strategies so the IDs match.
+package idstrategy
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Contains the name of Mongo collections.
+const (
+	Collection        = "id_strategies"         // Collection containing ID strategies.
+	CollectionHistory = "id_strategies_history" // Collection containing the history of each ID strategy.
+)
+
+func init() {
+	collections.Protect(Collection)
+}
+
+// itemCollection is the collection of the items. The item package uses the
+// strategies so its constant can't be imported.
+const itemCollection = "items"
+
+// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
+var ErrNotFound = errors.New("ID strategy Not found")
+
+// ErrInUse is returned when the strategy of a type that has items would be
+// changed. The IDs of the items and their relationships were composed with
+// the strategy.
+var ErrInUse = errors.New("ID strategy is used by items of the type")
+
+// Upsert upserts a strategy to the collection of currently utilized strategies.
+func Upsert(context interface{}, db *db.DB, s *Strategy) error {
+	log.Dev(context, "Upsert", "Started : Type[%s]", s.Type)
+
+	// Validate the strategy.
+	if err := s.Validate(); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// The strategy of a type with items can't change.
+	cur, err := ForType(context, db, s.Type)
+	if err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	if !cur.Same(s) {
+		if err := unused(context, db, s.Type); err != nil {
+			log.Error(context, "Upsert", err, "Completed")
+			return err
+		}
+	}
+
+	// Upsert the strategy.
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": s.Type}
+		log.Dev(context, "Upsert", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(s))
+		_, err := c.Upsert(q, s)
+		return err
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// Add this strategy to the beginning of the history. The history record is
+	// created by the upsert if this strategy is new.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"type": s.Type}
+		qu := bson.M{
+			"$push": bson.M{
+				"strategies": bson.M{
+					"$each":     []*Strategy{s},
+					"$position": 0,
+				},
+			},
+		}
+
+		log.Dev(context, "Upsert", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(qu))
+		_, err := c.Upsert(q, qu)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Upsert", "Completed")
+	return nil
+}
+
+// GetAll retrieves the current strategies from Mongo.
+func GetAll(context interface{}, db *db.DB) ([]Strategy, error) {
+	log.Dev(context, "GetAll", "Started")
+
+	// Get the strategies from Mongo.
+	var ss []Strategy
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "Find", "MGO : db.%s.find().sort([\"type\"])", c.Name)
+		return c.Find(nil).Sort("type").All(&ss)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetAll", err, "Completed")
+		return nil, err
+	}
+
+	if ss == nil {
+		log.Error(context, "GetAll", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetAll", "Completed : Strategies[%d]", len(ss))
+	return ss, nil
+}
+
+// GetByType retrieves a strategy by type from Mongo.
+func GetByType(context interface{}, db *db.DB, itemType string) (*Strategy, error) {
+	log.Dev(context, "GetByType", "Started : Type[%s]", itemType)
+
+	// Get the strategy from Mongo.
+	var s Strategy
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "Find", "MGO : db.%s.find(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&s)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetByType", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetByType", "Completed")
+	return &s, nil
+}
+
+// ForType retrieves the strategy used for the type. The default strategy is
+// returned when no strategy is registered for the type.
+func ForType(context interface{}, db *db.DB, itemType string) (*Strategy, error) {
+	s, err := GetByType(context, db, itemType)
+	if err != nil {
+		if err == ErrNotFound {
+			return Default(itemType), nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// History describes the history kept for strategies so their revisions can
+// be listed, compared and restored by type.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "strategies",
+	Selector: func(itemType string) (bson.M, error) {
+		return bson.M{"type": itemType}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var s Strategy
+		if err := raw.Unmarshal(&s); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*Strategy))
+	},
+}
+
+// Delete removes a strategy from Mongo. Items of the type use the default
+// strategy afterwards so a strategy that differs from the default can't be
+// removed once the type has items.
+func Delete(context interface{}, db *db.DB, itemType string) error {
+	log.Dev(context, "Delete", "Started : Type[%s]", itemType)
+
+	s, err := GetByType(context, db, itemType)
+	if err != nil {
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	if !s.Same(Default(itemType)) {
+		if err := unused(context, db, itemType); err != nil {
+			log.Error(context, "Delete", err, "Completed")
+			return err
+		}
+	}
+
+	// Remove the strategy.
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "Remove", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		return c.Remove(q)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Delete", "Completed")
+	return nil
+}
+
+// unused returns ErrInUse when there are items of the type.
+func unused(context interface{}, db *db.DB, itemType string) error {
+	var n int
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "unused", "MGO : db.%s.find(%s).limit(1).count()", c.Name, mongo.Query(q))
+		var err error
+		n, err = c.Find(q).Limit(1).Count()
+		return err
+	}
+	if err := db.ExecuteMGO(context, itemCollection, f); err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return ErrInUse
+	}
+
+	return nil
+}
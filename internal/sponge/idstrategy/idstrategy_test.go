@@ -0,0 +1,119 @@
+package idstrategy_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TestID tests the composition of IDs with strategies.
+func TestID(t *testing.T) {
+	data := map[string]interface{}{
+		"id":   1234.0,
+		"_id":  "abc",
+		"site": "nyt",
+		"external": bson.M{
+			"id": "x-1",
+		},
+	}
+
+	ss := []struct {
+		s  idstrategy.Strategy
+		id string
+	}{
+		{*idstrategy.Default("comment"), "comment_1234"},
+		{idstrategy.Strategy{Type: "comment", Fields: []string{"_id"}}, "comment_abc"},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}}, "asset_nyt_x-1"},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}, Template: "{site}:{external.id}"}, "nyt:x-1"},
+		{idstrategy.Strategy{Type: "user", Fields: []string{"_id"}, Hash: idstrategy.HashMD5}, "user_c95c3944b155ed4ca5f46902ea79a2d9"},
+	}
+
+	t.Log("Given the need to compose the IDs of items from their data.")
+	{
+		for _, tt := range ss {
+			t.Logf("\tWhen using fields %v with template %q and hash %q", tt.s.Fields, tt.s.Template, tt.s.Hash)
+			{
+				if err := tt.s.Validate(); err != nil {
+					t.Fatalf("\t%s\tShould be a valid strategy : %v", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould be a valid strategy.", tests.Success)
+
+				id, err := tt.s.ID(data)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to compose the ID : %v", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould be able to compose the ID.", tests.Success)
+
+				if id != tt.id {
+					t.Fatalf("\t%s\tShould get the ID %s : %s", tests.Failed, tt.id, id)
+				}
+				t.Logf("\t%s\tShould get the ID %s.", tests.Success, tt.id)
+			}
+		}
+
+		t.Log("\tWhen a field is missing from the data")
+		{
+			s := idstrategy.Strategy{Type: "asset", Fields: []string{"site", "missing"}}
+			if _, err := s.ID(data); err == nil {
+				t.Fatalf("\t%s\tShould not be able to compose the ID.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be able to compose the ID.", tests.Success)
+		}
+	}
+}
+
+// TestValidate tests the validation of strategies.
+func TestValidate(t *testing.T) {
+	ss := []idstrategy.Strategy{
+		{Type: "asset"},
+		{Type: "asset", Fields: []string{"site", ""}},
+		{Type: "asset", Fields: []string{"site"}, Hash: "crc"},
+		{Type: "asset", Fields: []string{"site", "id"}, Template: "{type}_{site}"},
+		{Type: "asset", Fields: []string{"site"}, Template: "{type}_{other}"},
+		{Type: "asset", Fields: []string{"site"}, Template: "{type}_{site"},
+	}
+
+	t.Log("Given the need to reject invalid strategies.")
+	{
+		for _, s := range ss {
+			t.Logf("\tWhen using fields %v with template %q and hash %q", s.Fields, s.Template, s.Hash)
+			{
+				if err := s.Validate(); err == nil {
+					t.Fatalf("\t%s\tShould not be a valid strategy.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould not be a valid strategy.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestSame tests if strategies that compose different IDs are told apart.
+func TestSame(t *testing.T) {
+	s := idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}}
+
+	ss := []struct {
+		o    idstrategy.Strategy
+		same bool
+	}{
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}}, true},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"external.id", "site"}}, false},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site"}}, false},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}, Template: "{site}:{external.id}"}, false},
+		{idstrategy.Strategy{Type: "asset", Fields: []string{"site", "external.id"}, Hash: idstrategy.HashMD5}, false},
+	}
+
+	t.Log("Given the need to know if a strategy changes the IDs of items.")
+	{
+		for _, tt := range ss {
+			t.Logf("\tWhen using fields %v with template %q and hash %q", tt.o.Fields, tt.o.Template, tt.o.Hash)
+			{
+				if s.Same(&tt.o) != tt.same {
+					t.Fatalf("\t%s\tShould report the strategies as the same : %v", tests.Failed, tt.same)
+				}
+				t.Logf("\t%s\tShould report the strategies as the same : %v", tests.Success, tt.same)
+			}
+		}
+	}
+}
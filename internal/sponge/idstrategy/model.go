@@ -0,0 +1,271 @@
+package idstrategy
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	validator "gopkg.in/bluesuncorp/validator.v8"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==============================================================================
+
+// validate is used to perform model field validation.
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New(&validator.Config{TagName: "validate"})
+}
+
+//==============================================================================
+
+// Set of hashes that can be applied to an ID.
+const (
+	HashMD5    = "md5"
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+)
+
+// typeField is the placeholder of the item type in a template.
+const typeField = "type"
+
+// defaultField is the field holding the source id of an item when no strategy
+// is registered for its type.
+const defaultField = "id"
+
+//==============================================================================
+
+// Strategy describes how the ID of an item of a type is composed from the
+// fields of its data. Nested fields are separated with dots. The template
+// refers to the type as {type} and to each field by name, like
+// {type}_{site}_{external.id}, and defaults to the type and the fields joined
+// with underscores. When a hash is set the ID is the type and the hash of
+// the composed value joined with an underscore.
+type Strategy struct {
+	Type     string   `bson:"type" json:"type" validate:"required,min=2"`
+	Fields   []string `bson:"fields" json:"fields" validate:"required,min=1"`
+	Template string   `bson:"template,omitempty" json:"template,omitempty"`
+	Hash     string   `bson:"hash,omitempty" json:"hash,omitempty"`
+}
+
+// Default returns the strategy used for a type without a registered strategy.
+// The ID is the type and the id field of the data joined with an underscore.
+func Default(itemType string) *Strategy {
+	return &Strategy{
+		Type:   itemType,
+		Fields: []string{defaultField},
+	}
+}
+
+// Validate checks the Strategy value for consistency.
+func (s *Strategy) Validate() error {
+	if err := validate.Struct(s); err != nil {
+		return err
+	}
+
+	fields := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if !validField(f) || f == typeField {
+			return fmt.Errorf("Invalid field %q", f)
+		}
+		fields[f] = true
+	}
+
+	switch s.Hash {
+	case "", HashMD5, HashSHA1, HashSHA256:
+	default:
+		return fmt.Errorf("Invalid hash %q", s.Hash)
+	}
+
+	if s.Template == "" {
+		return nil
+	}
+
+	used, err := placeholders(s.Template)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range used {
+		if name != typeField && !fields[name] {
+			return fmt.Errorf("Template refers to %q which is not a field", name)
+		}
+		delete(fields, name)
+	}
+
+	for f := range fields {
+		return fmt.Errorf("Template does not refer to field %q", f)
+	}
+
+	return nil
+}
+
+// Same reports if the strategies compose the same IDs from the same data.
+func (s *Strategy) Same(o *Strategy) bool {
+	if s.Template != o.Template || s.Hash != o.Hash || len(s.Fields) != len(o.Fields) {
+		return false
+	}
+
+	for i := range s.Fields {
+		if s.Fields[i] != o.Fields[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ID composes the ID of an item of the type from its data.
+func (s *Strategy) ID(data map[string]interface{}) (string, error) {
+	values := make([]interface{}, len(s.Fields))
+	for i, f := range s.Fields {
+		v, ok := Lookup(data, f)
+		if !ok {
+			return "", fmt.Errorf("Cannot Infer ID: Unable to find source id field: %s", f)
+		}
+		values[i] = v
+	}
+
+	return s.IDFromValues(values)
+}
+
+// IDFromValues composes an ID from the values of the fields of the strategy,
+// in the same order as the fields. It is used for the IDs of related items
+// whose values are found in the data of another item.
+func (s *Strategy) IDFromValues(values []interface{}) (string, error) {
+	if len(values) != len(s.Fields) {
+		return "", fmt.Errorf("Cannot Infer ID: Expected %d values for type %s, got %d", len(s.Fields), s.Type, len(values))
+	}
+
+	byField := map[string]string{typeField: s.Type}
+	for i, f := range s.Fields {
+		byField[f] = fmt.Sprintf("%v", values[i])
+	}
+
+	tmpl := s.Template
+	if tmpl == "" {
+		tmpl = "{" + typeField + "}_{" + strings.Join(s.Fields, "}_{") + "}"
+	}
+
+	id, err := render(tmpl, byField)
+	if err != nil {
+		return "", err
+	}
+
+	if s.Hash == "" {
+		return id, nil
+	}
+
+	var h hash.Hash
+	switch s.Hash {
+	case HashMD5:
+		h = md5.New()
+	case HashSHA1:
+		h = sha1.New()
+	case HashSHA256:
+		h = sha256.New()
+	default:
+		return "", fmt.Errorf("Invalid hash %q", s.Hash)
+	}
+
+	h.Write([]byte(id))
+	return s.Type + "_" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+//==============================================================================
+
+// Lookup returns the value of the field in the data. Nested fields are
+// separated with dots. Fields that are missing, null or empty are not found.
+func Lookup(data map[string]interface{}, field string) (interface{}, bool) {
+	var value interface{} = data
+	for _, name := range strings.Split(field, ".") {
+		switch doc := value.(type) {
+		case map[string]interface{}:
+			value = doc[name]
+		case bson.M:
+			value = doc[name]
+		default:
+			return nil, false
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return v, v != ""
+	case map[string]interface{}, bson.M, []interface{}:
+		return nil, false
+	}
+
+	return value, true
+}
+
+// validField checks the name of a field in the data of the items.
+func validField(field string) bool {
+	if field == "" || strings.ContainsAny(field, "{}$") {
+		return false
+	}
+
+	for _, name := range strings.Split(field, ".") {
+		if name == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// placeholders returns the names of the placeholders in the template.
+func placeholders(tmpl string) ([]string, error) {
+	var names []string
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i == -1 {
+			if strings.IndexByte(tmpl, '}') != -1 {
+				return nil, fmt.Errorf("Invalid template %q", tmpl)
+			}
+			return names, nil
+		}
+
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j == -1 || strings.IndexByte(tmpl[:i], '}') != -1 {
+			return nil, fmt.Errorf("Invalid template %q", tmpl)
+		}
+
+		names = append(names, tmpl[i+1:i+j])
+		tmpl = tmpl[i+j+1:]
+	}
+}
+
+// render replaces the placeholders in the template with their values.
+func render(tmpl string, values map[string]string) (string, error) {
+	var buf bytes.Buffer
+	for {
+		i := strings.IndexByte(tmpl, '{')
+		if i == -1 {
+			buf.WriteString(tmpl)
+			return buf.String(), nil
+		}
+
+		j := strings.IndexByte(tmpl[i:], '}')
+		if j == -1 {
+			return "", fmt.Errorf("Invalid template %q", tmpl)
+		}
+
+		v, ok := values[tmpl[i+1:i+j]]
+		if !ok {
+			return "", fmt.Errorf("Template refers to %q which is not a field", tmpl[i+1:i+j])
+		}
+
+		buf.WriteString(tmpl[:i])
+		buf.WriteString(v)
+		tmpl = tmpl[i+j+1:]
+	}
+}
@@ -3,10 +3,13 @@ package item
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/sponge/schema"
 	"github.com/pborman/uuid"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -15,40 +18,291 @@ import (
 // Collection is the Mongo collection containing item values.
 const Collection = "items"
 
-// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
-var ErrNotFound = errors.New("Set Not found")
+// HistoryCollection is the Mongo collection containing the revisions of items
+// that were superseded by a newer version.
+const HistoryCollection = "items_history"
 
-// Upsert upserts an item to the items collections.
+func init() {
+	collections.Protect(Collection, HistoryCollection)
+}
+
+// Set of error variables for the item service.
+var (
+	ErrNotFound = errors.New("Set Not found")
+	ErrConflict = errors.New("Item version conflict")
+)
+
+// Upsert upserts an item to the items collections. The version of the item is
+// set to 1 for a new item and incremented when a stored item changes, when the
+// update time is also set. The stored item is kept in the history collection.
 func Upsert(context interface{}, db *db.DB, item *Item) error {
-	log.Dev(context, "Upsert", "Started : ID[%s]", item.ID)
+	return UpsertVersion(context, db, item, 0)
+}
+
+// UpsertVersion upserts an item to the items collection if the stored item is
+// at the specified version. A version of 0 upserts the item whatever the
+// stored version is. ErrConflict is returned when the stored item is at a
+// different version or is changed by another write at the same time. A
+// *schema.ValidationError is returned when the data doesn't match the schema
+// of the type, unless the schema only reports the violations.
+func UpsertVersion(context interface{}, db *db.DB, item *Item, version int) error {
+	log.Dev(context, "UpsertVersion", "Started : ID[%s] Version[%d]", item.ID, version)
 
 	// If there is no ID, create one.
 	if item.ID == "" {
 		item.ID = uuid.New()
 	}
 
+	// Find the stored item.
+	var stored Item
+	exists := true
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"item_id": item.ID}
+		log.Dev(context, "UpsertVersion", "MGO : db.%s.findOne(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&stored)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err != mgo.ErrNotFound {
+			log.Error(context, "UpsertVersion", err, "Completed")
+			return err
+		}
+		exists = false
+	}
+
+	if version != 0 && (!exists || stored.Version != version) {
+		log.Error(context, "UpsertVersion", ErrConflict, "Completed")
+		return ErrConflict
+	}
+
+	// If the item is identical, we don't have to do anything.
+	if exists && stored.SameContent(item) {
+		item.Version = stored.Version
+		item.Updated = stored.Updated
+		log.Dev(context, "UpsertVersion", "Completed : Unchanged : Version[%d]", item.Version)
+		return nil
+	}
+
+	item.Version = 1
+	if exists {
+		item.Version = stored.Version + 1
+	}
+	item.Updated = time.Now().UTC()
+
 	// Validate the item.
 	if err := item.Validate(); err != nil {
-		log.Error(context, "Upsert", err, "Completed")
+		log.Error(context, "UpsertVersion", err, "Completed")
 		return err
 	}
 
-	// Upsert the item.
-	f := func(c *mgo.Collection) error {
-		q := bson.M{"item_id": item.ID}
-		log.Dev(context, "Upsert", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(item))
-		_, err := c.Upsert(q, item)
+	// Check the data against the schema of the type.
+	failed, reports, err := checkSchemas(context, db, []Item{*item}, []int{0})
+	if err != nil {
+		log.Error(context, "UpsertVersion", err, "Completed")
+		return err
+	}
+	if err := failed[0]; err != nil {
+		log.Error(context, "UpsertVersion", err, "Completed")
 		return err
 	}
+
+	// Keep the stored revision before it is replaced.
+	if exists {
+		if err := saveHistory(context, db, []Item{stored}); err != nil {
+			log.Error(context, "UpsertVersion", err, "Completed")
+			return err
+		}
+	}
+
+	// Write the item only if no other write changed it since it was read.
+	f = func(c *mgo.Collection) error {
+		if exists {
+			q := bson.M{"item_id": item.ID, "version": stored.Version}
+			log.Dev(context, "UpsertVersion", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(item))
+			if err := c.Update(q, item); err != nil {
+				if err == mgo.ErrNotFound {
+					return ErrConflict
+				}
+				return err
+			}
+			return nil
+		}
+
+		q := bson.M{"item_id": item.ID}
+		u := bson.M{"$setOnInsert": item}
+		log.Dev(context, "UpsertVersion", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(u))
+		info, err := c.Upsert(q, u)
+		if err != nil {
+			return err
+		}
+		if info.UpsertedId == nil {
+			return ErrConflict
+		}
+		return nil
+	}
 	if err := db.ExecuteMGO(context, Collection, f); err != nil {
-		log.Error(context, "Upsert", err, "Completed")
+		log.Error(context, "UpsertVersion", err, "Completed")
 		return err
 	}
 
-	log.Dev(context, "Upsert", "Completed")
+	saveViolations(context, db, reports, nil)
+
+	log.Dev(context, "UpsertVersion", "Completed : Version[%d]", item.Version)
 	return nil
 }
 
+// UpsertBulk upserts the items to the items collection with a single bulk
+// operation. The stored items the items replace are provided by ID and are
+// kept in the history collection. The versions of the items are set like
+// Upsert does. The errors for the items that could not be written, including
+// ErrConflict for items changed by another write and *schema.ValidationError
+// for items that don't match the schema of their type, are returned by their
+// position.
+func UpsertBulk(context interface{}, db *db.DB, items []Item, stored map[string]Item) (map[int]error, error) {
+	log.Dev(context, "UpsertBulk", "Started : Items[%d]", len(items))
+
+	failed := make(map[int]error)
+
+	// Set the versions and leave out the invalid items.
+	now := time.Now().UTC()
+
+	var valid []int
+	var revs []Item
+	for i := range items {
+		old, exists := stored[items[i].ID]
+
+		items[i].Version = 1
+		if exists {
+			items[i].Version = old.Version + 1
+		}
+		items[i].Updated = now
+
+		if err := items[i].Validate(); err != nil {
+			failed[i] = err
+			continue
+		}
+
+		valid = append(valid, i)
+		if exists {
+			revs = append(revs, old)
+		}
+	}
+
+	// Leave out the items that don't match the schema of their type.
+	rejected, reports, err := checkSchemas(context, db, items, valid)
+	if err != nil {
+		log.Error(context, "UpsertBulk", err, "Completed")
+		return nil, err
+	}
+
+	if len(rejected) > 0 {
+		checked := valid
+		valid, revs = nil, nil
+		for _, i := range checked {
+			if err := rejected[i]; err != nil {
+				failed[i] = err
+				continue
+			}
+
+			valid = append(valid, i)
+			if old, exists := stored[items[i].ID]; exists {
+				revs = append(revs, old)
+			}
+		}
+	}
+
+	if len(valid) == 0 {
+		log.Dev(context, "UpsertBulk", "Completed : Failed[%d]", len(failed))
+		return failed, nil
+	}
+
+	// Keep the stored revisions before they are replaced.
+	if err := saveHistory(context, db, revs); err != nil {
+		log.Error(context, "UpsertBulk", err, "Completed")
+		return nil, err
+	}
+
+	tx, err := db.BulkOperationMGO(context, Collection)
+	if err != nil {
+		log.Error(context, "UpsertBulk", err, "Completed")
+		return nil, err
+	}
+
+	// Stored items are only updated at the version they were read at and
+	// new items are only inserted if they still don't exist.
+	var updates int
+	for _, i := range valid {
+		if old, exists := stored[items[i].ID]; exists {
+			tx.Update(bson.M{"item_id": items[i].ID, "version": old.Version}, &items[i])
+			updates++
+			continue
+		}
+
+		tx.Upsert(bson.M{"item_id": items[i].ID}, bson.M{"$setOnInsert": &items[i]})
+	}
+
+	log.Dev(context, "UpsertBulk", "MGO : db.%s.bulk(write x %d)", Collection, len(valid))
+	res, err := tx.Run()
+	if err != nil {
+		berr, ok := err.(*mgo.BulkError)
+		if !ok {
+			log.Error(context, "UpsertBulk", err, "Completed")
+			return nil, err
+		}
+
+		// Report the items that failed when Mongo knows which ones did.
+		for _, ec := range berr.Cases() {
+			if ec.Index < 0 {
+				log.Error(context, "UpsertBulk", err, "Completed")
+				return nil, err
+			}
+			failed[valid[ec.Index]] = ec.Err
+		}
+	}
+
+	// Only the stored items should have matched. Otherwise find the items
+	// another write changed.
+	if res == nil || res.Matched != updates {
+		if err := bulkConflicts(context, db, items, valid, failed); err != nil {
+			log.Error(context, "UpsertBulk", err, "Completed")
+			return nil, err
+		}
+	}
+
+	saveViolations(context, db, reports, failed)
+
+	log.Dev(context, "UpsertBulk", "Completed : Failed[%d]", len(failed))
+	return failed, nil
+}
+
+// GetByVersion retrieves an item at the specified version from Mongo. Versions
+// older than the stored item are retrieved from the history collection.
+func GetByVersion(context interface{}, db *db.DB, id string, version int) (*Item, error) {
+	log.Dev(context, "GetByVersion", "Started : ID[%s] Version[%d]", id, version)
+
+	q := bson.M{"item_id": id, "version": version}
+
+	var it Item
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "GetByVersion", "MGO : db.%s.findOne(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&it)
+	}
+
+	err := db.ExecuteMGO(context, Collection, f)
+	if err == mgo.ErrNotFound {
+		err = db.ExecuteMGO(context, HistoryCollection, f)
+	}
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetByVersion", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetByVersion", "Completed")
+	return &it, nil
+}
+
 // GetByIDs retrieves items by ID from Mongo.
 func GetByIDs(context interface{}, db *db.DB, ids []string) ([]Item, error) {
 	log.Dev(context, "GetByIDs", "Started : IDs%v", ids)
@@ -77,7 +331,7 @@ func GetByIDs(context interface{}, db *db.DB, ids []string) ([]Item, error) {
 	return items, nil
 }
 
-// Delete removes an item from from Mongo.
+// Delete removes an item and its revisions from Mongo.
 func Delete(context interface{}, db *db.DB, id string) error {
 	log.Dev(context, "Delete", "Started : ID[%s]", id)
 
@@ -92,6 +346,135 @@ func Delete(context interface{}, db *db.DB, id string) error {
 		return err
 	}
 
+	// Remove the revisions of the item.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"item_id": id}
+		log.Dev(context, "Remove", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		_, err := c.RemoveAll(q)
+		return err
+	}
+	if err := db.ExecuteMGO(context, HistoryCollection, f); err != nil {
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	// Remove the schema violation recorded for the item.
+	if err := db.ExecuteMGO(context, schema.CollectionViolations, f); err != nil {
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
 	log.Dev(context, "Delete", "Completed")
 	return nil
 }
+
+//==============================================================================
+
+// saveHistory saves the revisions to the history collection. A revision that
+// is already saved is replaced so a failed write can be tried again.
+func saveHistory(context interface{}, db *db.DB, revs []Item) error {
+	if len(revs) == 0 {
+		return nil
+	}
+
+	tx, err := db.BulkOperationMGO(context, HistoryCollection)
+	if err != nil {
+		return err
+	}
+
+	for i := range revs {
+		tx.Upsert(bson.M{"item_id": revs[i].ID, "version": revs[i].Version}, &revs[i])
+	}
+
+	log.Dev(context, "saveHistory", "MGO : db.%s.bulk(upsert x %d)", HistoryCollection, len(revs))
+	_, err = tx.Run()
+	return err
+}
+
+// checkSchemas checks the data of the items at the positions against the
+// schemas of their types. The errors of the items a schema rejects are
+// returned by position, along with the violations to record for the items
+// of types whose schema only reports them.
+func checkSchemas(context interface{}, db *db.DB, items []Item, positions []int) (map[int]error, map[int]schema.Violation, error) {
+	failed := make(map[int]error)
+	reports := make(map[int]schema.Violation)
+
+	schemas := make(map[string]*schema.Schema)
+	for _, i := range positions {
+		it := &items[i]
+
+		s, ok := schemas[it.Type]
+		if !ok {
+			var err error
+			if s, err = schema.GetByType(context, db, it.Type); err != nil && err != schema.ErrNotFound {
+				return nil, nil, err
+			}
+			schemas[it.Type] = s
+		}
+
+		// Items of types without a schema are not validated.
+		if s == nil {
+			continue
+		}
+
+		errs := s.Check(it.Data)
+
+		if s.Mode == schema.ModeReport {
+			reports[i] = schema.Violation{ItemID: it.ID, Type: it.Type, Version: it.Version, Errors: errs}
+			continue
+		}
+
+		if len(errs) > 0 {
+			failed[i] = &schema.ValidationError{Type: it.Type, Errors: errs}
+		}
+	}
+
+	return failed, reports, nil
+}
+
+// saveViolations records the violations of the items that were written.
+// The items are already stored so a failure is only logged.
+func saveViolations(context interface{}, db *db.DB, reports map[int]schema.Violation, failed map[int]error) {
+	var vs []schema.Violation
+	for i, v := range reports {
+		if failed[i] == nil {
+			vs = append(vs, v)
+		}
+	}
+
+	if err := schema.SaveViolations(context, db, vs); err != nil {
+		log.Error(context, "saveViolations", err, "Completed")
+	}
+}
+
+// bulkConflicts reads back the items written by a bulk operation and records
+// ErrConflict for the items that are not stored as they were written.
+func bulkConflicts(context interface{}, db *db.DB, items []Item, written []int, failed map[int]error) error {
+	ids := make([]string, len(written))
+	for j, i := range written {
+		ids[j] = items[i].ID
+	}
+
+	current, err := GetByIDs(context, db, ids)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	byID := make(map[string]Item, len(current))
+	for _, it := range current {
+		byID[it.ID] = it
+	}
+
+	for _, i := range written {
+		if failed[i] != nil {
+			continue
+		}
+
+		it, ok := byID[items[i].ID]
+		if !ok || it.Version != items[i].Version || !it.SameContent(&items[i]) {
+			failed[i] = ErrConflict
+		}
+	}
+
+	return nil
+}
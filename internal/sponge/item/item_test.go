@@ -10,6 +10,8 @@ import (
 	"github.com/ardanlabs/kit/tests"
 	"github.com/coralproject/shelf/internal/sponge/item"
 	"github.com/coralproject/shelf/internal/sponge/item/itemfix"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+	"github.com/coralproject/shelf/internal/xenia/mask"
 )
 
 func init() {
@@ -161,6 +163,276 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+// TestVersions tests if items are versioned as they change.
+func TestVersions(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	defer func() {
+		if err := itemfix.Remove(tests.Context, db, prefix); err != nil {
+			t.Fatalf("\t%s\tShould be able to remove the items : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to remove the items.", tests.Success)
+	}()
+
+	t.Log("Given the need to keep the versions of an item.")
+	{
+		t.Log("\tWhen updating an item")
+		{
+			it := item.Item{
+				ID:   prefix + "versioned",
+				Type: "comment",
+				Data: map[string]interface{}{"body": "first"},
+			}
+
+			if err := item.Upsert(tests.Context, db, &it); err != nil || it.Version != 1 {
+				t.Fatalf("\t%s\tShould be able to insert the item at version 1 : %d : %v", tests.Failed, it.Version, err)
+			}
+			t.Logf("\t%s\tShould be able to insert the item at version 1.", tests.Success)
+
+			if err := item.Upsert(tests.Context, db, &it); err != nil || it.Version != 1 {
+				t.Fatalf("\t%s\tShould keep the version of an unchanged item : %d : %v", tests.Failed, it.Version, err)
+			}
+			t.Logf("\t%s\tShould keep the version of an unchanged item.", tests.Success)
+
+			it.Data = map[string]interface{}{"body": "second"}
+			if err := item.UpsertVersion(tests.Context, db, &it, 1); err != nil || it.Version != 2 {
+				t.Fatalf("\t%s\tShould be able to update the item to version 2 : %d : %v", tests.Failed, it.Version, err)
+			}
+			t.Logf("\t%s\tShould be able to update the item to version 2.", tests.Success)
+
+			it.Data = map[string]interface{}{"body": "third"}
+			if err := item.UpsertVersion(tests.Context, db, &it, 1); err != item.ErrConflict {
+				t.Fatalf("\t%s\tShould get a conflict updating a stale version : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get a conflict updating a stale version.", tests.Success)
+
+			old, err := item.GetByVersion(tests.Context, db, it.ID, 1)
+			if err != nil || old.Data["body"] != "first" {
+				t.Fatalf("\t%s\tShould be able to get the first version : %v : %v", tests.Failed, old, err)
+			}
+			t.Logf("\t%s\tShould be able to get the first version.", tests.Success)
+
+			cur, err := item.GetByVersion(tests.Context, db, it.ID, 2)
+			if err != nil || cur.Data["body"] != "second" {
+				t.Fatalf("\t%s\tShould be able to get the current version : %v : %v", tests.Failed, cur, err)
+			}
+			t.Logf("\t%s\tShould be able to get the current version.", tests.Success)
+
+			if _, err := item.GetByVersion(tests.Context, db, it.ID, 3); err != item.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find a version that does not exist : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find a version that does not exist.", tests.Success)
+		}
+	}
+}
+
+// TestList tests if we can list items by type and data fields.
+func TestList(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	defer func() {
+		if err := itemfix.Remove(tests.Context, db, prefix); err != nil {
+			t.Fatalf("\t%s\tShould be able to remove the items : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to remove the items.", tests.Success)
+	}()
+
+	t.Log("Given the need to list items by type and data fields.")
+	{
+		t.Log("\tWhen starting from the item fixture")
+		{
+			items, err := itemfix.Get()
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able retrieve item fixture : %s", tests.Failed, err)
+			}
+
+			if err := itemfix.Add(tests.Context, db, items); err != nil {
+				t.Fatalf("\t%s\tShould be able to add the items : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to add the items.", tests.Success)
+
+			opts := item.ListOptions{
+				Type: "PTEST_comment",
+				Data: []item.Condition{
+					{Field: "asset", Op: item.OpEq, Value: "ITEST_c1b2bbfe-af9f-4903-8777-bd47c4d5b20a"},
+					{Field: "author", Op: item.OpEq, Value: "ITEST_80aa936a-f618-4234-a7be-df59a14cf8de"},
+				},
+			}
+
+			list, next, err := item.List(tests.Context, db, opts)
+			if err != nil || len(list) != 2 || next != "" {
+				t.Fatalf("\t%s\tShould be able to list the comments of the author : %d : %v", tests.Failed, len(list), err)
+			}
+			t.Logf("\t%s\tShould be able to list the comments of the author.", tests.Success)
+
+			opts.Data = opts.Data[:1]
+			opts.Sort = "-item_id"
+			opts.Limit = 2
+
+			list, next, err = item.List(tests.Context, db, opts)
+			if err != nil || len(list) != 2 || next == "" {
+				t.Fatalf("\t%s\tShould be able to get the first page of comments : %d : %v", tests.Failed, len(list), err)
+			}
+			t.Logf("\t%s\tShould be able to get the first page of comments.", tests.Success)
+
+			if list[0].ID < list[1].ID {
+				t.Fatalf("\t%s\tShould get the comments in descending order : %s %s", tests.Failed, list[0].ID, list[1].ID)
+			}
+			t.Logf("\t%s\tShould get the comments in descending order.", tests.Success)
+
+			opts.Cursor = next
+
+			page, next, err := item.List(tests.Context, db, opts)
+			if err != nil || len(page) != 1 || next != "" {
+				t.Fatalf("\t%s\tShould be able to get the last page of comments : %d : %v", tests.Failed, len(page), err)
+			}
+			t.Logf("\t%s\tShould be able to get the last page of comments.", tests.Success)
+
+			if page[0].ID >= list[1].ID {
+				t.Fatalf("\t%s\tShould get the comment after the first page : %s", tests.Failed, page[0].ID)
+			}
+			t.Logf("\t%s\tShould get the comment after the first page.", tests.Success)
+
+			opts.Sort = "updated"
+			if _, _, err := item.List(tests.Context, db, opts); err != item.ErrInvalidCursor {
+				t.Fatalf("\t%s\tShould not be able to use the cursor with another sort : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to use the cursor with another sort.", tests.Success)
+
+			opts = item.ListOptions{
+				Type:  "PTEST_comment",
+				Data:  []item.Condition{{Field: "author", Op: item.OpEq, Value: "ITEST_80aa936a-f618-4234-a7be-df59a14cf8de"}},
+				Masks: map[string]mask.Mask{"author": {Collection: item.Collection, Field: "author", Type: mask.MaskRemove}},
+			}
+			if _, _, err := item.List(tests.Context, db, opts); err != item.ErrMaskedField {
+				t.Fatalf("\t%s\tShould not be able to filter on a masked field : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to filter on a masked field.", tests.Success)
+
+			opts.Data = nil
+			opts.Sort = "-data.author"
+			if _, _, err := item.List(tests.Context, db, opts); err != item.ErrMaskedField {
+				t.Fatalf("\t%s\tShould not be able to sort on a masked field : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to sort on a masked field.", tests.Success)
+		}
+	}
+}
+
+// TestSchemas tests if items are validated against the schema of their type.
+func TestSchemas(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	sch := schema.Schema{
+		Type:     prefix + "comment",
+		Required: []string{"body"},
+		Properties: map[string]schema.Property{
+			"body": {Type: schema.TypeString, MinLength: 1},
+		},
+	}
+
+	defer func() {
+		if err := schema.Delete(tests.Context, db, sch.Type); err != nil {
+			t.Fatalf("\t%s\tShould be able to remove the schema : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to remove the schema.", tests.Success)
+
+		if err := itemfix.Remove(tests.Context, db, prefix); err != nil {
+			t.Fatalf("\t%s\tShould be able to remove the items : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to remove the items.", tests.Success)
+	}()
+
+	t.Log("Given the need to validate items against the schema of their type.")
+	{
+		if err := schema.Upsert(tests.Context, db, &sch); err != nil {
+			t.Fatalf("\t%s\tShould be able to upsert the schema : %v", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould be able to upsert the schema.", tests.Success)
+
+		t.Log("\tWhen the schema is enforced")
+		{
+			it := item.Item{
+				ID:   prefix + "schema",
+				Type: sch.Type,
+				Data: map[string]interface{}{"body": 1},
+			}
+
+			err := item.Upsert(tests.Context, db, &it)
+			verr, ok := err.(*schema.ValidationError)
+			if !ok || len(verr.Errors) != 1 || verr.Errors[0].Field != "body" {
+				t.Fatalf("\t%s\tShould not be able to insert an invalid item : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to insert an invalid item.", tests.Success)
+
+			it.Data = map[string]interface{}{"body": "first"}
+			if err := item.Upsert(tests.Context, db, &it); err != nil {
+				t.Fatalf("\t%s\tShould be able to insert a valid item : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to insert a valid item.", tests.Success)
+		}
+
+		t.Log("\tWhen the schema only reports violations")
+		{
+			sch.Mode = schema.ModeReport
+			if err := schema.Upsert(tests.Context, db, &sch); err != nil {
+				t.Fatalf("\t%s\tShould be able to update the schema : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to update the schema.", tests.Success)
+
+			it := item.Item{
+				ID:   prefix + "schema",
+				Type: sch.Type,
+				Data: map[string]interface{}{"title": "second"},
+			}
+
+			if err := item.Upsert(tests.Context, db, &it); err != nil {
+				t.Fatalf("\t%s\tShould be able to update with an invalid item : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to update with an invalid item.", tests.Success)
+
+			vs, err := schema.GetViolations(tests.Context, db, sch.Type, 10)
+			if err != nil || len(vs) != 1 || vs[0].ItemID != it.ID || vs[0].Version != it.Version {
+				t.Fatalf("\t%s\tShould have a violation for the item : %v : %v", tests.Failed, vs, err)
+			}
+			t.Logf("\t%s\tShould have a violation for the item.", tests.Success)
+
+			it.Data = map[string]interface{}{"body": "third"}
+			if err := item.Upsert(tests.Context, db, &it); err != nil {
+				t.Fatalf("\t%s\tShould be able to update with a valid item : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to update with a valid item.", tests.Success)
+
+			vs, err = schema.GetViolations(tests.Context, db, sch.Type, 10)
+			if err != nil || len(vs) != 0 {
+				t.Fatalf("\t%s\tShould have no violations left : %v : %v", tests.Failed, vs, err)
+			}
+			t.Logf("\t%s\tShould have no violations left.", tests.Success)
+		}
+	}
+}
+
 // TestInferId tests the inference of an item_id from type and source id.
 func TestInferId(t *testing.T) {
 	tests.ResetLog()
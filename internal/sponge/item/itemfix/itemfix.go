@@ -6,6 +6,7 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/schema"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -44,7 +45,7 @@ func Add(context interface{}, db *db.DB, items []item.Item) error {
 	return nil
 }
 
-// Remove removes items in Mongo that match a given pattern.
+// Remove removes items and their revisions in Mongo that match a given pattern.
 func Remove(context interface{}, db *db.DB, pattern string) error {
 	f := func(c *mgo.Collection) error {
 		q := bson.M{"item_id": bson.RegEx{Pattern: pattern}}
@@ -56,6 +57,14 @@ func Remove(context interface{}, db *db.DB, pattern string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, item.HistoryCollection, f); err != nil {
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, schema.CollectionViolations, f); err != nil {
+		return err
+	}
+
 	return nil
 }
 
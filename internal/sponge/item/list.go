@@ -0,0 +1,305 @@
+package item
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/keyset"
+	"github.com/coralproject/shelf/internal/xenia/mask"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Set of operators for the data conditions of a list.
+const (
+	OpEq  = "eq"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpLt  = "lt"
+	OpLte = "lte"
+)
+
+// Set of limits for the number of items in a page of a list.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 1000
+)
+
+// ErrInvalidCursor is returned when a cursor can't be used for a list.
+var ErrInvalidCursor = errors.New("Invalid list cursor")
+
+// ErrMaskedField is returned when a list is sorted or filtered on a field
+// whose value is masked.
+var ErrMaskedField = errors.New("Can't sort or filter on a masked field")
+
+// Condition compares a field in the data of the items with a value. Values
+// that look like numbers or booleans also match data of those types.
+type Condition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// ListOptions contains the filters, sort and page of a list of items. The
+// masks are the masks of the items collection; masked fields can't be
+// sorted or filtered on since the cursor and the filter would reveal their
+// values.
+type ListOptions struct {
+	Type          string      `json:"type,omitempty"`
+	UpdatedAfter  time.Time   `json:"updated_after,omitempty"`
+	UpdatedBefore time.Time   `json:"updated_before,omitempty"`
+	Data          []Condition `json:"data,omitempty"`
+	Sort          string      `json:"sort,omitempty"`
+	Limit         int         `json:"limit,omitempty"`
+	Cursor        string      `json:"cursor,omitempty"`
+
+	Masks map[string]mask.Mask `json:"-"`
+}
+
+// cursor is the decoded form of a list cursor. It identifies the sort of the
+// list and the sort values of the last item returned.
+type cursor struct {
+	Sort   string        `bson:"s"`
+	Values []interface{} `bson:"v"`
+}
+
+//==============================================================================
+
+// List retrieves a page of the items that match the options from Mongo. The
+// items are sorted by the sort field, prefixed with - for a descending sort,
+// and then by ID. Items without the sort field sort first in an ascending
+// sort. The cursor for the next page is returned, or an empty
+// string when there are no more items.
+func List(context interface{}, db *db.DB, opts ListOptions) ([]Item, string, error) {
+	log.Dev(context, "List", "Started : Type[%s] Sort[%s] Limit[%d]", opts.Type, opts.Sort, opts.Limit)
+
+	keys, err := listSort(opts.Sort, opts.Masks)
+	if err != nil {
+		log.Error(context, "List", err, "Completed")
+		return nil, "", err
+	}
+
+	limit := opts.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultLimit
+	case limit < 0 || limit > MaxLimit:
+		err := fmt.Errorf("Invalid limit %d, must be between 1 and %d", limit, MaxLimit)
+		log.Error(context, "List", err, "Completed")
+		return nil, "", err
+	}
+
+	q, err := listFilter(opts)
+	if err != nil {
+		log.Error(context, "List", err, "Completed")
+		return nil, "", err
+	}
+
+	// Only read the items after the last item of the previous page.
+	if opts.Cursor != "" {
+		after, err := cursorFilter(opts.Cursor, opts.Sort, keys)
+		if err != nil {
+			log.Error(context, "List", err, "Completed")
+			return nil, "", err
+		}
+		q = bson.M{"$and": []bson.M{q, after}}
+	}
+
+	sort := keyset.Fields(keys)
+
+	// Ask for one more item to know if there is another page.
+	var items []Item
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "List", "MGO : db.%s.find(%s).sort(%v).limit(%d)", c.Name, mongo.Query(q), sort, limit+1)
+		return c.Find(q).Sort(sort...).Limit(limit + 1).All(&items)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "List", err, "Completed")
+		return nil, "", err
+	}
+
+	if len(items) <= limit {
+		log.Dev(context, "List", "Completed : Items[%d]", len(items))
+		return items, "", nil
+	}
+
+	items = items[:limit]
+	last := items[limit-1]
+
+	next, err := keyset.Encode(cursor{Sort: opts.Sort, Values: keyset.Values(sortDoc(&last), keys)})
+	if err != nil {
+		log.Error(context, "List", err, "Completed")
+		return nil, "", err
+	}
+
+	log.Dev(context, "List", "Completed : Items[%d] More", len(items))
+	return items, next, nil
+}
+
+//==============================================================================
+
+// listSort returns the keys of the sort of a list, ending with the item ID.
+// Items are sorted by ID when no sort is provided.
+func listSort(sort string, masks map[string]mask.Mask) ([]keyset.Key, error) {
+	if sort == "" {
+		return keyset.Keys(nil, "item_id"), nil
+	}
+
+	keys := keyset.Keys([]string{sort}, "item_id")
+	field := keys[0].Field
+
+	switch field {
+	case "item_id", "type", "version", "updated":
+	default:
+		if !strings.HasPrefix(field, "data.") {
+			return nil, fmt.Errorf("Invalid sort field %q", field)
+		}
+
+		if err := validDataField(field[len("data."):]); err != nil {
+			return nil, err
+		}
+	}
+
+	if masked(field, masks) {
+		return nil, ErrMaskedField
+	}
+
+	return keys, nil
+}
+
+// masked reports if the value of the field is masked.
+func masked(field string, masks map[string]mask.Mask) bool {
+	f := func(name string) bool {
+		_, exists := masks[name]
+		return exists
+	}
+
+	return keyset.Masked([]string{field}, f) != ""
+}
+
+// listFilter returns the Mongo filter for the conditions of a list.
+func listFilter(opts ListOptions) (bson.M, error) {
+	and := []bson.M{}
+
+	if opts.Type != "" {
+		if masked("type", opts.Masks) {
+			return nil, ErrMaskedField
+		}
+		and = append(and, bson.M{"type": opts.Type})
+	}
+
+	if (!opts.UpdatedAfter.IsZero() || !opts.UpdatedBefore.IsZero()) && masked("updated", opts.Masks) {
+		return nil, ErrMaskedField
+	}
+
+	if !opts.UpdatedAfter.IsZero() {
+		and = append(and, bson.M{"updated": bson.M{"$gt": opts.UpdatedAfter}})
+	}
+
+	if !opts.UpdatedBefore.IsZero() {
+		and = append(and, bson.M{"updated": bson.M{"$lt": opts.UpdatedBefore}})
+	}
+
+	for _, cond := range opts.Data {
+		if err := validDataField(cond.Field); err != nil {
+			return nil, err
+		}
+
+		field := "data." + cond.Field
+		if masked(field, opts.Masks) {
+			return nil, ErrMaskedField
+		}
+
+		values := conditionValues(cond.Value)
+
+		switch cond.Op {
+		case OpEq, "":
+			and = append(and, bson.M{field: bson.M{"$in": values}})
+
+		case OpGt, OpGte, OpLt, OpLte:
+
+			// A range only compares values of the same type so the most
+			// specific value is used.
+			and = append(and, bson.M{field: bson.M{"$" + cond.Op: values[len(values)-1]}})
+
+		default:
+			return nil, fmt.Errorf("Invalid operator %q for data field %q", cond.Op, cond.Field)
+		}
+	}
+
+	if len(and) == 0 {
+		return bson.M{}, nil
+	}
+
+	return bson.M{"$and": and}, nil
+}
+
+// validDataField checks the name of a field in the data of the items.
+func validDataField(field string) error {
+	if field == "" || strings.Contains(field, "$") {
+		return fmt.Errorf("Invalid data field %q", field)
+	}
+
+	for _, name := range strings.Split(field, ".") {
+		if name == "" {
+			return fmt.Errorf("Invalid data field %q", field)
+		}
+	}
+
+	return nil
+}
+
+// conditionValues returns the values a condition matches. The value is
+// always matched as a string and also as a number or boolean when it can be
+// parsed as one.
+func conditionValues(value string) []interface{} {
+	values := []interface{}{value}
+
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return append(values, n)
+	}
+
+	if b, err := strconv.ParseBool(value); err == nil {
+		return append(values, b)
+	}
+
+	return values
+}
+
+// cursorFilter returns the filter that selects the items that sort after
+// the item the cursor was issued for.
+func cursorFilter(token string, sort string, keys []keyset.Key) (bson.M, error) {
+	var c cursor
+	if err := keyset.Decode(token, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	after, err := keyset.After(keys, c.Values)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return after, nil
+}
+
+// sortDoc returns the fields of the item that can be sorted on as a
+// document.
+func sortDoc(it *Item) map[string]interface{} {
+	return map[string]interface{}{
+		"item_id": it.ID,
+		"type":    it.Type,
+		"version": it.Version,
+		"updated": it.Updated,
+		"data":    it.Data,
+	}
+}
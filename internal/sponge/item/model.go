@@ -1,8 +1,11 @@
 package item
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
+	"time"
 
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
 	validator "gopkg.in/bluesuncorp/validator.v8"
 )
 
@@ -17,11 +20,6 @@ func init() {
 
 //==============================================================================
 
-// sourceIDField is used to infer item.ID from type and data.
-const sourceIDField = "id"
-
-//==============================================================================
-
 // Item is data, properties and behavior associated with one of a comment,
 // asset, action, etc. Regardless of type (comment, asset, etc.), all Items
 // are formatted with an ID, Type, and Version, and asssociated data (which
@@ -30,6 +28,7 @@ type Item struct {
 	ID      string                 `bson:"item_id" json:"item_id" validate:"required,min=1"`
 	Type    string                 `bson:"type" json:"type" validate:"required,min=2"`
 	Version int                    `bson:"version" json:"version" validate:"required,min=1"`
+	Updated time.Time              `bson:"updated" json:"updated"`
 	Data    map[string]interface{} `bson:"data" json:"data"`
 }
 
@@ -42,17 +41,40 @@ func (item *Item) Validate() error {
 	return nil
 }
 
-// InferIDFromData infers an item_id from type and source id.
-func (item *Item) InferIDFromData() error {
+// SameContent reports if the item has the same type and data as the other
+// item. The data is compared as JSON since Mongo returns embedded documents
+// with different types than the JSON decoder.
+func (item *Item) SameContent(other *Item) bool {
+	if item.Type != other.Type {
+		return false
+	}
+
+	a, err := json.Marshal(item.Data)
+	if err != nil {
+		return false
+	}
 
-	// If a source id value is found, use it to compose the item_id for this item.
-	idValue, ok := item.Data[sourceIDField]
-	if !ok {
-		return fmt.Errorf("Cannot Infer ID: Unable to find source id field: %s", sourceIDField)
+	b, err := json.Marshal(other.Data)
+	if err != nil {
+		return false
 	}
 
-	// Set the ID via the template.
-	item.ID = fmt.Sprintf("%s_%v", item.Type, idValue)
+	return bytes.Equal(a, b)
+}
+
+// InferIDFromData infers an item_id from type and source id using the default
+// ID strategy.
+func (item *Item) InferIDFromData() error {
+	return item.InferID(idstrategy.Default(item.Type))
+}
+
+// InferID infers an item_id from the data using the ID strategy of the type.
+func (item *Item) InferID(s *idstrategy.Strategy) error {
+	id, err := s.ID(item.Data)
+	if err != nil {
+		return err
+	}
 
+	item.ID = id
 	return nil
 }
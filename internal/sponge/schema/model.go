@@ -0,0 +1,382 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	validator "gopkg.in/bluesuncorp/validator.v8"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==============================================================================
+
+// validate is used to perform model field validation.
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New(&validator.Config{TagName: "validate"})
+}
+
+//==============================================================================
+
+// Set of modes of a schema.
+const (
+	ModeEnforce = "enforce" // Items that don't match the schema are rejected.
+	ModeReport  = "report"  // Items are stored and what doesn't match is recorded.
+)
+
+// Set of types of the fields of the data.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+//==============================================================================
+
+// Property describes a field in the data of the items. A field without a
+// type accepts any value. The required fields and properties apply to
+// objects, the items to the values of arrays and the lengths to strings.
+type Property struct {
+	Type       string              `bson:"type,omitempty" json:"type,omitempty"`
+	Enum       []interface{}       `bson:"enum,omitempty" json:"enum,omitempty"`
+	MinLength  int                 `bson:"min_length,omitempty" json:"min_length,omitempty"`
+	MaxLength  int                 `bson:"max_length,omitempty" json:"max_length,omitempty"`
+	Required   []string            `bson:"required,omitempty" json:"required,omitempty"`
+	Properties map[string]Property `bson:"properties,omitempty" json:"properties,omitempty"`
+	Items      *Property           `bson:"items,omitempty" json:"items,omitempty"`
+}
+
+// Schema describes the data of the items of a type. Items are rejected when
+// their data doesn't match the schema unless the schema is in report mode.
+type Schema struct {
+	Type       string              `bson:"type" json:"type" validate:"required,min=2"`
+	Mode       string              `bson:"mode,omitempty" json:"mode,omitempty"`
+	Required   []string            `bson:"required,omitempty" json:"required,omitempty"`
+	Properties map[string]Property `bson:"properties,omitempty" json:"properties,omitempty"`
+}
+
+// Validate checks the Schema value for consistency.
+func (s *Schema) Validate() error {
+	if err := validate.Struct(s); err != nil {
+		return err
+	}
+
+	switch s.Mode {
+	case "", ModeEnforce, ModeReport:
+	default:
+		return fmt.Errorf("Invalid mode %q", s.Mode)
+	}
+
+	root := s.root()
+	return root.validate("")
+}
+
+// Check returns the errors of the fields of the data that don't match the
+// schema. The missing required fields of an object are reported first.
+func (s *Schema) Check(data map[string]interface{}) []FieldError {
+	var errs []FieldError
+	root := s.root()
+	root.check("", data, &errs)
+	return errs
+}
+
+// root returns the property describing the whole data.
+func (s *Schema) root() Property {
+	return Property{
+		Type:       TypeObject,
+		Required:   s.Required,
+		Properties: s.Properties,
+	}
+}
+
+//==============================================================================
+
+// FieldError describes a field of the data that doesn't match a schema.
+// Nested fields and the values of arrays are separated with dots.
+type FieldError struct {
+	Field   string `bson:"field" json:"field"`
+	Message string `bson:"message" json:"message"`
+}
+
+// ValidationError is returned when the data of an item doesn't match the
+// schema of its type.
+type ValidationError struct {
+	Type   string
+	Errors []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+
+	return fmt.Sprintf("Item does not match the schema of type %s : %s", e.Type, strings.Join(msgs, ", "))
+}
+
+// Violation records the errors of an item of a type whose schema is in
+// report mode, as of the version of the item that was checked.
+type Violation struct {
+	ItemID  string       `bson:"item_id" json:"item_id"`
+	Type    string       `bson:"type" json:"type"`
+	Version int          `bson:"version" json:"version"`
+	Errors  []FieldError `bson:"errors" json:"errors"`
+	Date    time.Time    `bson:"date" json:"date"`
+}
+
+//==============================================================================
+
+// validate checks the property for consistency. The field is the path of
+// the property used in errors.
+func (p *Property) validate(field string) error {
+	name := field
+	if name == "" {
+		name = "data"
+	}
+
+	switch p.Type {
+	case "", TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeObject, TypeArray:
+	default:
+		return fmt.Errorf("Invalid type %q for field %q", p.Type, name)
+	}
+
+	if p.MinLength < 0 || p.MaxLength < 0 || (p.MaxLength != 0 && p.MinLength > p.MaxLength) {
+		return fmt.Errorf("Invalid lengths for field %q", name)
+	}
+
+	if (p.MinLength != 0 || p.MaxLength != 0) && p.Type != TypeString {
+		return fmt.Errorf("Lengths only apply to strings for field %q", name)
+	}
+
+	if len(p.Enum) > 0 {
+		switch p.Type {
+		case TypeObject, TypeArray:
+			return fmt.Errorf("Enum does not apply to %s for field %q", p.Type, name)
+		}
+
+		for _, v := range p.Enum {
+			if !scalar(v) {
+				return fmt.Errorf("Invalid enum value %v for field %q : must be a string, number or boolean", v, name)
+			}
+
+			if msg := p.checkScalar(v); msg != "" {
+				return fmt.Errorf("Invalid enum value %v for field %q : %s", v, name, msg)
+			}
+		}
+	}
+
+	if (len(p.Required) > 0 || len(p.Properties) > 0) && p.Type != TypeObject {
+		return fmt.Errorf("Properties only apply to objects for field %q", name)
+	}
+
+	for _, r := range p.Required {
+		if !validName(r) {
+			return fmt.Errorf("Invalid required field %q for field %q", r, name)
+		}
+	}
+
+	for n, sub := range p.Properties {
+		if !validName(n) {
+			return fmt.Errorf("Invalid property %q for field %q", n, name)
+		}
+
+		if err := sub.validate(join(field, n)); err != nil {
+			return err
+		}
+	}
+
+	if p.Items != nil {
+		if p.Type != TypeArray {
+			return fmt.Errorf("Items only apply to arrays for field %q", name)
+		}
+
+		if err := p.Items.validate(join(field, "items")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check adds the errors of the value of the field to errs.
+func (p *Property) check(field string, value interface{}, errs *[]FieldError) {
+	add := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch p.Type {
+	case TypeObject:
+		doc, ok := object(value)
+		if !ok {
+			add("must be an object")
+			return
+		}
+
+		for _, r := range p.Required {
+			if _, ok := doc[r]; !ok {
+				*errs = append(*errs, FieldError{Field: join(field, r), Message: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(p.Properties))
+		for n := range p.Properties {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			if v, ok := doc[n]; ok {
+				sub := p.Properties[n]
+				sub.check(join(field, n), v, errs)
+			}
+		}
+
+	case TypeArray:
+		values, ok := value.([]interface{})
+		if !ok {
+			add("must be an array")
+			return
+		}
+
+		if p.Items != nil {
+			for i, v := range values {
+				p.Items.check(join(field, strconv.Itoa(i)), v, errs)
+			}
+		}
+
+	default:
+		if msg := p.checkScalar(value); msg != "" {
+			add("%s", msg)
+			return
+		}
+
+		if len(p.Enum) > 0 && !p.inEnum(value) {
+			add("must be one of %v", p.Enum)
+		}
+	}
+}
+
+// checkScalar returns why the value doesn't match the type and lengths of
+// the property, or an empty string when it does.
+func (p *Property) checkScalar(value interface{}) string {
+	switch p.Type {
+	case TypeString:
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+
+		n := utf8.RuneCountInString(s)
+		if n < p.MinLength {
+			return fmt.Sprintf("must be at least %d characters long", p.MinLength)
+		}
+		if p.MaxLength != 0 && n > p.MaxLength {
+			return fmt.Sprintf("must be at most %d characters long", p.MaxLength)
+		}
+
+	case TypeNumber:
+		if _, ok := number(value); !ok {
+			return "must be a number"
+		}
+
+	case TypeInteger:
+		if n, ok := number(value); !ok || n != float64(int64(n)) {
+			return "must be an integer"
+		}
+
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+	}
+
+	return ""
+}
+
+// inEnum reports if the value is one of the values of the enum. Numbers are
+// compared by value whatever their Go type.
+func (p *Property) inEnum(value interface{}) bool {
+	n, isNum := number(value)
+	for _, e := range p.Enum {
+		if isNum {
+			if en, ok := number(e); ok && en == n {
+				return true
+			}
+			continue
+		}
+
+		if e == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
+
+// object returns the value as a document. Mongo and the JSON decoder return
+// embedded documents with different types.
+func object(value interface{}) (map[string]interface{}, bool) {
+	switch doc := value.(type) {
+	case map[string]interface{}:
+		return doc, true
+	case bson.M:
+		return doc, true
+	}
+
+	return nil, false
+}
+
+// number returns the value as a float64 if it is a number.
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+// scalar reports if the value is a string, number or boolean.
+func scalar(value interface{}) bool {
+	switch value.(type) {
+	case string, bool:
+		return true
+	}
+
+	_, ok := number(value)
+	return ok
+}
+
+// validName checks the name of a field. Names are stored as keys in Mongo so
+// they can't contain dots or start with $.
+func validName(name string) bool {
+	return name != "" && !strings.Contains(name, ".") && !strings.HasPrefix(name, "$")
+}
+
+// join returns the path of the field in the parent field.
+func join(parent string, field string) string {
+	if parent == "" {
+		return field
+	}
+
+	return parent + "." + field
+}
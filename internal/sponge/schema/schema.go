@@ -0,0 +1,246 @@
+// Package schema provides the service layer for managing the schemas the
+// data of items of a type is validated against, and the violations recorded
+// for the types whose schema only reports them.
+package schema
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Contains the name of Mongo collections.
+const (
+	Collection           = "item_schemas"           // Collection containing schemas.
+	CollectionHistory    = "item_schemas_history"   // Collection containing the history of each schema.
+	CollectionViolations = "item_schema_violations" // Collection containing the violations of report mode schemas.
+)
+
+func init() {
+	collections.Protect(Collection, CollectionViolations)
+}
+
+// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
+var ErrNotFound = errors.New("Schema Not found")
+
+// Upsert upserts a schema to the collection of currently utilized schemas.
+func Upsert(context interface{}, db *db.DB, s *Schema) error {
+	log.Dev(context, "Upsert", "Started : Type[%s]", s.Type)
+
+	// Validate the schema.
+	if err := s.Validate(); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// Upsert the schema.
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": s.Type}
+		log.Dev(context, "Upsert", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(s))
+		_, err := c.Upsert(q, s)
+		return err
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// Add this schema to the beginning of the history. The history record is
+	// created by the upsert if this schema is new.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"type": s.Type}
+		qu := bson.M{
+			"$push": bson.M{
+				"schemas": bson.M{
+					"$each":     []*Schema{s},
+					"$position": 0,
+				},
+			},
+		}
+
+		log.Dev(context, "Upsert", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(qu))
+		_, err := c.Upsert(q, qu)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Upsert", "Completed")
+	return nil
+}
+
+// GetAll retrieves the current schemas from Mongo.
+func GetAll(context interface{}, db *db.DB) ([]Schema, error) {
+	log.Dev(context, "GetAll", "Started")
+
+	// Get the schemas from Mongo.
+	var ss []Schema
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "Find", "MGO : db.%s.find().sort([\"type\"])", c.Name)
+		return c.Find(nil).Sort("type").All(&ss)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetAll", err, "Completed")
+		return nil, err
+	}
+
+	if ss == nil {
+		log.Error(context, "GetAll", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetAll", "Completed : Schemas[%d]", len(ss))
+	return ss, nil
+}
+
+// GetByType retrieves a schema by type from Mongo.
+func GetByType(context interface{}, db *db.DB, itemType string) (*Schema, error) {
+	log.Dev(context, "GetByType", "Started : Type[%s]", itemType)
+
+	// Get the schema from Mongo.
+	var s Schema
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "Find", "MGO : db.%s.find(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&s)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetByType", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetByType", "Completed")
+	return &s, nil
+}
+
+// History describes the history kept for schemas so their revisions can be
+// listed, compared and restored by type.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "schemas",
+	Selector: func(itemType string) (bson.M, error) {
+		return bson.M{"type": itemType}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var s Schema
+		if err := raw.Unmarshal(&s); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*Schema))
+	},
+}
+
+// Delete removes a schema and the violations recorded for it from Mongo.
+// Items of the type are no longer validated afterwards.
+func Delete(context interface{}, db *db.DB, itemType string) error {
+	log.Dev(context, "Delete", "Started : Type[%s]", itemType)
+
+	// Remove the schema.
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "Remove", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		return c.Remove(q)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	// Remove the violations of the schema.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "Remove", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		_, err := c.RemoveAll(q)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionViolations, f); err != nil {
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Delete", "Completed")
+	return nil
+}
+
+//==============================================================================
+
+// SaveViolations records the violations of the items that were checked
+// against a report mode schema. Only the last violation of an item is kept
+// and the violation of an item that now matches its schema is removed.
+func SaveViolations(context interface{}, db *db.DB, vs []Violation) error {
+	log.Dev(context, "SaveViolations", "Started : Violations[%d]", len(vs))
+
+	if len(vs) == 0 {
+		log.Dev(context, "SaveViolations", "Completed")
+		return nil
+	}
+
+	tx, err := db.BulkOperationMGO(context, CollectionViolations)
+	if err != nil {
+		log.Error(context, "SaveViolations", err, "Completed")
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i := range vs {
+		q := bson.M{"item_id": vs[i].ItemID}
+		if len(vs[i].Errors) == 0 {
+			tx.RemoveAll(q)
+			continue
+		}
+
+		vs[i].Date = now
+		tx.Upsert(q, &vs[i])
+	}
+
+	log.Dev(context, "SaveViolations", "MGO : db.%s.bulk(write x %d)", CollectionViolations, len(vs))
+	if _, err := tx.Run(); err != nil {
+		log.Error(context, "SaveViolations", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "SaveViolations", "Completed")
+	return nil
+}
+
+// GetViolations retrieves the violations recorded for the items of a type,
+// the most recent first.
+func GetViolations(context interface{}, db *db.DB, itemType string, limit int) ([]Violation, error) {
+	log.Dev(context, "GetViolations", "Started : Type[%s] Limit[%d]", itemType, limit)
+
+	var vs []Violation
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "GetViolations", "MGO : db.%s.find(%s).sort([\"-date\"]).limit(%d)", c.Name, mongo.Query(q), limit)
+		return c.Find(q).Sort("-date", "item_id").Limit(limit).All(&vs)
+	}
+	if err := db.ExecuteMGO(context, CollectionViolations, f); err != nil {
+		log.Error(context, "GetViolations", err, "Completed")
+		return nil, err
+	}
+
+	if vs == nil {
+		vs = []Violation{}
+	}
+
+	log.Dev(context, "GetViolations", "Completed : Violations[%d]", len(vs))
+	return vs, nil
+}
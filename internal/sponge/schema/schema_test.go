@@ -0,0 +1,142 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+)
+
+// comment is the schema used to check the data of comments.
+const comment = `{
+	"type": "comment",
+	"required": ["body", "status", "author"],
+	"properties": {
+		"body": {"type": "string", "min_length": 1, "max_length": 10},
+		"status": {"type": "string", "enum": ["approved", "rejected"]},
+		"likes": {"type": "integer"},
+		"score": {"type": "number", "enum": [1, 2.5]},
+		"author": {
+			"type": "object",
+			"required": ["name"],
+			"properties": {
+				"name": {"type": "string"},
+				"staff": {"type": "boolean"}
+			}
+		},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	}
+}`
+
+// TestCheck tests the data of items is checked against a schema.
+func TestCheck(t *testing.T) {
+	var s schema.Schema
+	if err := json.Unmarshal([]byte(comment), &s); err != nil {
+		t.Fatalf("\t%s\tShould be able to unmarshal the schema : %v", tests.Failed, err)
+	}
+
+	ds := []struct {
+		data string
+		errs []schema.FieldError
+	}{
+		{
+			`{"body": "Hi", "status": "approved", "likes": 3, "score": 2.5, "author": {"name": "a", "staff": true}, "tags": ["a"], "other": 1}`,
+			nil,
+		},
+		{
+			`{"body": "", "status": "pending", "author": {}}`,
+			[]schema.FieldError{
+				{Field: "author.name", Message: "is required"},
+				{Field: "body", Message: "must be at least 1 characters long"},
+				{Field: "status", Message: "must be one of [approved rejected]"},
+			},
+		},
+		{
+			`{"body": "Hello there world", "status": "approved", "likes": 1.5, "score": 3, "author": "a", "tags": ["a", 1]}`,
+			[]schema.FieldError{
+				{Field: "author", Message: "must be an object"},
+				{Field: "body", Message: "must be at most 10 characters long"},
+				{Field: "likes", Message: "must be an integer"},
+				{Field: "score", Message: "must be one of [1 2.5]"},
+				{Field: "tags.1", Message: "must be a string"},
+			},
+		},
+		{
+			`{"status": null}`,
+			[]schema.FieldError{
+				{Field: "body", Message: "is required"},
+				{Field: "author", Message: "is required"},
+				{Field: "status", Message: "must be a string"},
+			},
+		},
+	}
+
+	t.Log("Given the need to check the data of items against a schema.")
+	{
+		for _, d := range ds {
+			t.Logf("\tWhen using data %s", d.data)
+			{
+				var data map[string]interface{}
+				if err := json.Unmarshal([]byte(d.data), &data); err != nil {
+					t.Fatalf("\t%s\tShould be able to unmarshal the data : %v", tests.Failed, err)
+				}
+
+				errs := s.Check(data)
+				if !reflect.DeepEqual(errs, d.errs) {
+					t.Logf("\t%+v", errs)
+					t.Fatalf("\t%s\tShould get the expected field errors.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould get the expected field errors.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestValidate tests the validation of schemas.
+func TestValidate(t *testing.T) {
+	ss := []string{
+		`{"type": "comment", "mode": "strict"}`,
+		`{"type": "comment", "properties": {"body": {"type": "text"}}}`,
+		`{"type": "comment", "properties": {"likes": {"type": "integer", "max_length": 2}}}`,
+		`{"type": "comment", "properties": {"body": {"type": "string", "min_length": 5, "max_length": 2}}}`,
+		`{"type": "comment", "properties": {"likes": {"type": "integer", "enum": [1, "two"]}}}`,
+		`{"type": "comment", "properties": {"tags": {"type": "array", "enum": [1]}}}`,
+		`{"type": "comment", "properties": {"a.b": {"type": "string"}}}`,
+		`{"type": "comment", "properties": {"body": {"type": "string", "required": ["x"]}}}`,
+		`{"type": "comment", "properties": {"body": {"type": "string", "items": {"type": "string"}}}}`,
+		`{"type": "comment", "properties": {"tags": {"type": "array", "items": {"type": "list"}}}}`,
+	}
+
+	t.Log("Given the need to reject invalid schemas.")
+	{
+		for _, str := range ss {
+			t.Logf("\tWhen using schema %s", str)
+			{
+				var s schema.Schema
+				if err := json.Unmarshal([]byte(str), &s); err != nil {
+					t.Fatalf("\t%s\tShould be able to unmarshal the schema : %v", tests.Failed, err)
+				}
+
+				if err := s.Validate(); err == nil {
+					t.Fatalf("\t%s\tShould not be a valid schema.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould not be a valid schema.", tests.Success)
+			}
+		}
+
+		t.Log("\tWhen using a valid schema")
+		{
+			var s schema.Schema
+			if err := json.Unmarshal([]byte(comment), &s); err != nil {
+				t.Fatalf("\t%s\tShould be able to unmarshal the schema : %v", tests.Failed, err)
+			}
+
+			if err := s.Validate(); err != nil {
+				t.Fatalf("\t%s\tShould be a valid schema : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be a valid schema.", tests.Success)
+		}
+	}
+}
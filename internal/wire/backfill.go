@@ -0,0 +1,409 @@
+package wire
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CollectionBackfill is the Mongo collection containing the progress of the
+// graph backfills, one document for each item type.
+const CollectionBackfill = "graph_backfills"
+
+func init() {
+	collections.Protect(CollectionBackfill)
+}
+
+// Set of statuses for a backfill.
+const (
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+// DefBackfillBatch is the number of items processed in a batch when no batch
+// size is provided.
+const DefBackfillBatch = 500
+
+// backfillLease is how long a running backfill can go without reporting
+// progress before it is considered interrupted and can be resumed.
+var backfillLease = 5 * time.Minute
+
+var (
+	// ErrBackfillRunning is returned when a backfill for the type is already
+	// running.
+	ErrBackfillRunning = errors.New("Backfill already running")
+
+	// ErrBackfillRevision is returned when the pattern revision to backfill
+	// from does not exist.
+	ErrBackfillRevision = errors.New("Invalid pattern revision")
+)
+
+// Backfill contains the progress of re-inferring the relationships of the
+// items of a type after its pattern changed. The items are processed in
+// item_id order so an interrupted backfill resumes after the last item.
+type Backfill struct {
+	Type      string    `bson:"_id" json:"type"`
+	From      int       `bson:"from" json:"from"` // Pattern revision the items were inferred with, 0 for none.
+	To        int       `bson:"to" json:"to"`     // Pattern revision the items are inferred with, 0 for the current pattern.
+	Status    string    `bson:"status" json:"status"`
+	LastID    string    `bson:"last_id" json:"last_id"`
+	Total     int       `bson:"total" json:"total"`
+	Processed int       `bson:"processed" json:"processed"`
+	Skipped   int       `bson:"skipped" json:"skipped"`
+	Added     int       `bson:"added" json:"added"`
+	Removed   int       `bson:"removed" json:"removed"`
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+	Started   time.Time `bson:"started" json:"started"`
+	Updated   time.Time `bson:"updated" json:"updated"`
+}
+
+//==============================================================================
+
+// StartBackfill claims the backfill for the item type so it can be run. An
+// interrupted or failed backfill is resumed unless a different from revision
+// is provided. Otherwise a new backfill is started from the provided pattern
+// revision to the newest one. A from revision of -1 uses the revision before
+// the newest one.
+func StartBackfill(context interface{}, db *db.DB, itemType string, from int) (*Backfill, error) {
+	log.Dev(context, "StartBackfill", "Started : Type[%s] From[%d]", itemType, from)
+
+	bf, err := GetBackfill(context, db, itemType)
+	if err != nil && err != ErrNotFound {
+		log.Error(context, "StartBackfill", err, "Completed")
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	if bf != nil && bf.Status == BackfillRunning && now.Sub(bf.Updated) < backfillLease {
+		log.Error(context, "StartBackfill", ErrBackfillRunning, "Completed")
+		return nil, ErrBackfillRunning
+	}
+
+	// The selector only matches the backfill we read so two callers can't
+	// claim it at the same time.
+	var q bson.M
+	if bf != nil {
+		q = bson.M{"_id": bf.Type, "updated": bf.Updated}
+	}
+
+	switch {
+	case bf != nil && bf.Status != BackfillCompleted && (from < 0 || from == bf.From):
+		log.Dev(context, "StartBackfill", "Resuming : LastID[%s] Processed[%d]", bf.LastID, bf.Processed)
+		bf.Status = BackfillRunning
+		bf.Error = ""
+		bf.Updated = now
+
+	default:
+		nbf, err := newBackfill(context, db, itemType, from)
+		if err != nil {
+			log.Error(context, "StartBackfill", err, "Completed")
+			return nil, err
+		}
+		bf = nbf
+	}
+
+	f := func(c *mgo.Collection) error {
+		if q == nil {
+			log.Dev(context, "StartBackfill", "MGO : db.%s.insert(%s)", c.Name, mongo.Query(bf))
+			return c.Insert(bf)
+		}
+
+		log.Dev(context, "StartBackfill", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(bf))
+		return c.Update(q, bf)
+	}
+
+	if err := db.ExecuteMGO(context, CollectionBackfill, f); err != nil {
+		if err == mgo.ErrNotFound || mgo.IsDup(err) {
+			err = ErrBackfillRunning
+		}
+		log.Error(context, "StartBackfill", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "StartBackfill", "Completed : From[%d] To[%d] Total[%d]", bf.From, bf.To, bf.Total)
+	return bf, nil
+}
+
+// RunBackfill re-infers the relationships of the items of the claimed
+// backfill in batches and applies the difference between the quads of the
+// old and new patterns to the graph. Progress is saved after each batch and
+// reported to the optional progress function.
+func RunBackfill(context interface{}, db *db.DB, store *cayley.Handle, bf *Backfill, batch int, progress func(Backfill)) error {
+	log.Dev(context, "RunBackfill", "Started : Type[%s] From[%d] To[%d] LastID[%s]", bf.Type, bf.From, bf.To, bf.LastID)
+
+	if batch <= 0 {
+		batch = DefBackfillBatch
+	}
+
+	if err := runBackfill(context, db, store, bf, batch, progress); err != nil {
+		bf.Status = BackfillFailed
+		bf.Error = err.Error()
+		saveBackfill(context, db, bf, bson.M{"status": bf.Status, "error": bf.Error})
+
+		log.Error(context, "RunBackfill", err, "Completed")
+		return err
+	}
+
+	bf.Status = BackfillCompleted
+	if err := saveBackfill(context, db, bf, bson.M{"status": bf.Status}); err != nil {
+		log.Error(context, "RunBackfill", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "RunBackfill", "Completed : Processed[%d] Added[%d] Removed[%d]", bf.Processed, bf.Added, bf.Removed)
+	return nil
+}
+
+// GetBackfill retrieves the progress of the backfill for the item type.
+func GetBackfill(context interface{}, db *db.DB, itemType string) (*Backfill, error) {
+	log.Dev(context, "GetBackfill", "Started : Type[%s]", itemType)
+
+	var bf Backfill
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"_id": itemType}
+		log.Dev(context, "GetBackfill", "MGO : db.%s.findOne(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&bf)
+	}
+
+	if err := db.ExecuteMGO(context, CollectionBackfill, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetBackfill", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetBackfill", "Completed : Status[%s] Processed[%d]", bf.Status, bf.Processed)
+	return &bf, nil
+}
+
+//==============================================================================
+
+// newBackfill returns a new backfill for the item type using the revisions
+// of its pattern.
+func newBackfill(context interface{}, db *db.DB, itemType string, from int) (*Backfill, error) {
+	now := time.Now().UTC()
+
+	bf := Backfill{
+		Type:    itemType,
+		Status:  BackfillRunning,
+		Started: now,
+		Updated: now,
+	}
+
+	revs, err := history.List(context, db, pattern.History, itemType)
+	switch {
+	case err == history.ErrNotFound:
+
+		// The pattern was saved before its history was kept so the items are
+		// inferred with the current pattern and nothing is removed.
+		if _, err := pattern.GetByType(context, db, itemType); err != nil {
+			return nil, err
+		}
+		if from > 0 {
+			return nil, ErrBackfillRevision
+		}
+
+	case err != nil:
+		return nil, err
+
+	default:
+		bf.To = revs[0].Number
+		bf.From = from
+		if from < 0 {
+			bf.From = bf.To - 1
+		}
+		if bf.From > bf.To {
+			return nil, ErrBackfillRevision
+		}
+	}
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		log.Dev(context, "newBackfill", "MGO : db.%s.find(%s).count()", c.Name, mongo.Query(q))
+		bf.Total, err = c.Find(q).Count()
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, item.Collection, f); err != nil {
+		return nil, err
+	}
+
+	return &bf, nil
+}
+
+// runBackfill processes the batches of items of the backfill.
+func runBackfill(context interface{}, db *db.DB, store *cayley.Handle, bf *Backfill, batch int, progress func(Backfill)) error {
+	oldP, err := backfillPattern(context, db, bf.Type, bf.From, false)
+	if err != nil {
+		return err
+	}
+
+	newP, err := backfillPattern(context, db, bf.Type, bf.To, true)
+	if err != nil {
+		return err
+	}
+
+	ids, err := strategies(context, db)
+	if err != nil {
+		return err
+	}
+
+	// Batches that were partly applied before an interruption are applied
+	// again so quads that already exist or are already gone are ignored.
+	qw, err := newWriter(store)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var items []item.Item
+		f := func(c *mgo.Collection) error {
+			q := bson.M{"type": bf.Type, "item_id": bson.M{"$gt": bf.LastID}}
+			log.Dev(context, "runBackfill", "MGO : db.%s.find(%s).sort([\"item_id\"]).limit(%d)", c.Name, mongo.Query(q), batch)
+			return c.Find(q).Sort("item_id").Limit(batch).All(&items)
+		}
+
+		if err := db.ExecuteMGO(context, item.Collection, f); err != nil {
+			return err
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		// Apply the difference between the old and new quads of each item.
+		tx := cayley.NewTransaction()
+		var added, removed, skipped int
+
+		for _, it := range items {
+			parsed, err := itemParse(map[string]interface{}{
+				"item_id": it.ID,
+				"type":    it.Type,
+				"data":    it.Data,
+			})
+			if err != nil {
+				skipped++
+				continue
+			}
+
+			var oldQuads map[QuadParam]bool
+			if oldP != nil {
+				oldQuads = quadSet(infer(parsed, oldP, ids))
+			}
+			newQuads := quadSet(infer(parsed, newP, ids))
+
+			for qp := range oldQuads {
+				if !newQuads[qp] {
+					tx.RemoveQuad(quad.Make(qp.Subject, qp.Predicate, qp.Object, ""))
+					removed++
+				}
+			}
+
+			for qp := range newQuads {
+				if !oldQuads[qp] {
+					tx.AddQuad(quad.Make(qp.Subject, qp.Predicate, qp.Object, ""))
+					added++
+				}
+			}
+		}
+
+		if len(tx.Deltas) > 0 {
+			if err := qw.ApplyTransaction(tx); err != nil {
+				return err
+			}
+		}
+
+		// Save the progress so the backfill can resume after this batch.
+		bf.LastID = items[len(items)-1].ID
+		bf.Processed += len(items)
+		bf.Skipped += skipped
+		bf.Added += added
+		bf.Removed += removed
+
+		if err := saveBackfill(context, db, bf, nil); err != nil {
+			return err
+		}
+
+		if progress != nil {
+			progress(*bf)
+		}
+	}
+}
+
+// saveBackfill saves the progress of the backfill plus the extra fields.
+func saveBackfill(context interface{}, db *db.DB, bf *Backfill, extra bson.M) error {
+	bf.Updated = time.Now().UTC()
+
+	set := bson.M{
+		"last_id":   bf.LastID,
+		"processed": bf.Processed,
+		"skipped":   bf.Skipped,
+		"added":     bf.Added,
+		"removed":   bf.Removed,
+		"updated":   bf.Updated,
+	}
+	for k, v := range extra {
+		set[k] = v
+	}
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"_id": bf.Type}
+		u := bson.M{"$set": set}
+		log.Dev(context, "saveBackfill", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(u))
+		return c.Update(q, u)
+	}
+
+	return db.ExecuteMGO(context, CollectionBackfill, f)
+}
+
+// backfillPattern returns the revision of the pattern for the item type. A
+// revision of 0 is no pattern, or the current pattern when current is set.
+func backfillPattern(context interface{}, db *db.DB, itemType string, number int, current bool) (*pattern.Pattern, error) {
+	if number == 0 {
+		if !current {
+			return nil, nil
+		}
+		return pattern.GetByType(context, db, itemType)
+	}
+
+	rev, err := history.Get(context, db, pattern.History, itemType, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return rev.Document.(*pattern.Pattern), nil
+}
+
+// newWriter returns a quad writer that ignores quads that already exist when
+// adding and quads that don't exist when removing.
+func newWriter(store *cayley.Handle) (graph.QuadWriter, error) {
+	return graph.NewQuadWriter("single", store.QuadStore, graph.Options{"ignore_duplicate": true, "ignore_missing": true})
+}
+
+// quadSet returns the valid quads as a set.
+func quadSet(qps []QuadParam) map[QuadParam]bool {
+	set := make(map[QuadParam]bool, len(qps))
+	for _, qp := range qps {
+		if err := qp.Validate(); err != nil {
+			continue
+		}
+		set[qp] = true
+	}
+
+	return set
+}
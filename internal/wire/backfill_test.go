@@ -0,0 +1,105 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/item/itemfix"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/wirefix"
+)
+
+// TestBackfill tests if the relationships of existing items are re-inferred
+// after their pattern changed.
+func TestBackfill(t *testing.T) {
+	db, _ := setup(t)
+	defer teardown(t, db)
+
+	const bfType = "BTEST_comment"
+	defer wirefix.Remove(tests.Context, db, "BTEST_")
+	defer itemfix.Remove(tests.Context, db, "BTEST_")
+
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a new Cayley graph : %v", tests.Failed, err)
+	}
+
+	p := pattern.Pattern{
+		Type: bfType,
+		Inferences: []pattern.Inference{
+			{RelIDField: "author", Predicate: "authored", Direction: "in"},
+		},
+	}
+
+	t.Log("Given the need to backfill the graph after a pattern changed.")
+	{
+		t.Log("\tWhen adding an inference to the pattern of existing items")
+		{
+			if err := pattern.Upsert(tests.Context, db, &p); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the pattern : %s", tests.Failed, err)
+			}
+
+			for _, id := range []string{"BTEST_1", "BTEST_2", "BTEST_3"} {
+				it := item.Item{
+					ID:      id,
+					Type:    bfType,
+					Version: 1,
+					Data:    map[string]interface{}{"author": "BTEST_user", "asset": "BTEST_asset"},
+				}
+				if err := item.Upsert(tests.Context, db, &it); err != nil {
+					t.Fatalf("\t%s\tShould be able to upsert the item : %s", tests.Failed, err)
+				}
+
+				itMap := map[string]interface{}{"item_id": it.ID, "type": it.Type, "data": it.Data}
+				if err := wire.AddToGraph(tests.Context, db, store, itMap); err != nil {
+					t.Fatalf("\t%s\tShould be able to add the item to the graph : %s", tests.Failed, err)
+				}
+			}
+			t.Logf("\t%s\tShould be able to add the items to the graph.", tests.Success)
+
+			p.Inferences = append(p.Inferences, pattern.Inference{RelIDField: "asset", Predicate: "on", Direction: "out"})
+			if err := pattern.Upsert(tests.Context, db, &p); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the changed pattern : %s", tests.Failed, err)
+			}
+
+			bf, err := wire.StartBackfill(tests.Context, db, bfType, -1)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to start the backfill : %s", tests.Failed, err)
+			}
+			if bf.From != 1 || bf.To != 2 || bf.Total != 3 {
+				t.Fatalf("\t%s\tShould backfill from revision 1 to 2 for 3 items : %+v", tests.Failed, bf)
+			}
+			t.Logf("\t%s\tShould backfill from revision 1 to 2 for 3 items.", tests.Success)
+
+			if _, err := wire.StartBackfill(tests.Context, db, bfType, -1); err != wire.ErrBackfillRunning {
+				t.Fatalf("\t%s\tShould not be able to start a running backfill : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to start a running backfill.", tests.Success)
+
+			var batches int
+			if err := wire.RunBackfill(tests.Context, db, store, bf, 2, func(wire.Backfill) { batches++ }); err != nil {
+				t.Fatalf("\t%s\tShould be able to run the backfill : %s", tests.Failed, err)
+			}
+			if batches != 2 || bf.Status != wire.BackfillCompleted || bf.Added != 3 || bf.Removed != 0 {
+				t.Fatalf("\t%s\tShould add a relationship for each item in two batches : %+v", tests.Failed, bf)
+			}
+			t.Logf("\t%s\tShould add a relationship for each item in two batches.", tests.Success)
+
+			var count int
+			it := cayley.StartPath(store, quad.String("BTEST_asset")).In(quad.String("on")).BuildIterator()
+			for it.Next() {
+				count++
+			}
+			it.Close()
+
+			if count != 3 {
+				t.Fatalf("\t%s\tShould have the new relationships in the graph : %d", tests.Failed, count)
+			}
+			t.Logf("\t%s\tShould have the new relationships in the graph.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package wire
+
+import (
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+)
+
+// Batch collects the changes to the relationships of many items so they are
+// applied to the graph in a single transaction. The patterns, ID strategies
+// and relationship metadata are retrieved once for all the items.
+type Batch struct {
+	db       *db.DB
+	loaded   bool
+	patterns map[string]*pattern.Pattern
+	ids      idStrategies
+	rels     map[string]relationship.Relationship
+	add      map[string][]QuadParam
+	remove   map[string][]QuadParam
+}
+
+// NewBatch returns an empty batch of relationship changes.
+func NewBatch(db *db.DB) *Batch {
+	return &Batch{
+		db:       db,
+		patterns: make(map[string]*pattern.Pattern),
+		add:      make(map[string][]QuadParam),
+		remove:   make(map[string][]QuadParam),
+	}
+}
+
+// Upsert adds the changes to the relationships of the item to the batch. The
+// stale item is the item as it was stored before, or nil for a new item. An
+// error is returned and nothing is added for an item AddToGraph rejects.
+func (b *Batch) Upsert(context interface{}, item map[string]interface{}, stale map[string]interface{}) error {
+	if err := b.load(context); err != nil {
+		return err
+	}
+
+	// Infer the relationships in the item.
+	added, err := b.infer(context, item, true)
+	if err != nil {
+		return err
+	}
+
+	for _, qp := range added {
+		if err := qp.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Infer the relationships the stale item had.
+	var removed []QuadParam
+	if stale != nil {
+		if removed, err = b.infer(context, stale, false); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := itemParse(item)
+	if err != nil {
+		return err
+	}
+
+	newQuads := quadSet(added)
+	for qp := range quadSet(removed) {
+		if !newQuads[qp] {
+			b.remove[parsed.itemID] = append(b.remove[parsed.itemID], qp)
+		}
+	}
+
+	for qp := range newQuads {
+		b.add[parsed.itemID] = append(b.add[parsed.itemID], qp)
+	}
+
+	return nil
+}
+
+// Drop removes the changes to the relationships of the item from the batch.
+// It is used for an item that could not be stored.
+func (b *Batch) Drop(itemID string) {
+	delete(b.add, itemID)
+	delete(b.remove, itemID)
+}
+
+// Apply applies the changes in the batch to the graph in a single
+// transaction. Relationships an item removes that another item adds are
+// kept.
+func (b *Batch) Apply(context interface{}, store *cayley.Handle) error {
+	log.Dev(context, "Apply", "Started : Items[%d]", len(b.add))
+
+	add := make(map[QuadParam]bool)
+	for _, qps := range b.add {
+		for _, qp := range qps {
+			add[qp] = true
+		}
+	}
+
+	remove := make(map[QuadParam]bool)
+	for _, qps := range b.remove {
+		for _, qp := range qps {
+			if !add[qp] {
+				remove[qp] = true
+			}
+		}
+	}
+
+	tx := cayley.NewTransaction()
+	for qp := range remove {
+		tx.RemoveQuad(quad.Make(qp.Subject, qp.Predicate, qp.Object, ""))
+	}
+
+	for qp := range add {
+		tx.AddQuad(quad.Make(qp.Subject, qp.Predicate, qp.Object, ""))
+	}
+
+	if len(tx.Deltas) == 0 {
+		log.Dev(context, "Apply", "Completed : No changes")
+		return nil
+	}
+
+	qw, err := newWriter(store)
+	if err != nil {
+		log.Error(context, "Apply", err, "Completed")
+		return err
+	}
+
+	if err := qw.ApplyTransaction(tx); err != nil {
+		log.Error(context, "Apply", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Apply", "Completed")
+	return nil
+}
+
+//==============================================================================
+
+// load retrieves the ID strategies and relationship metadata the first time
+// the batch is used.
+func (b *Batch) load(context interface{}) error {
+	if b.loaded {
+		return nil
+	}
+
+	ids, err := strategies(context, b.db)
+	if err != nil {
+		return err
+	}
+
+	rels, err := relationships(context, b.db)
+	if err != nil {
+		return err
+	}
+
+	b.ids, b.rels, b.loaded = ids, rels, true
+	return nil
+}
+
+// infer infers the relationships of the item like inferRelationships. The
+// patterns are only retrieved once for each type.
+func (b *Batch) infer(context interface{}, itemIn map[string]interface{}, enforce bool) ([]QuadParam, error) {
+	item, err := itemParse(itemIn)
+	if err != nil {
+		return nil, err
+	}
+
+	p, exists := b.patterns[item.itemType]
+	if !exists {
+		if p, err = pattern.GetByType(context, b.db, item.itemType); err != nil {
+			if err != pattern.ErrNotFound {
+				return nil, err
+			}
+			p = nil
+		}
+		b.patterns[item.itemType] = p
+	}
+
+	if p == nil {
+		return nil, nil
+	}
+
+	if enforce {
+		return constrain(context, item, p, b.ids, b.rels)
+	}
+
+	return infer(item, p, b.ids), nil
+}
@@ -0,0 +1,418 @@
+package wire
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/graph"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefCheckLimit is the number of quads listed for each kind of issue when no
+// limit is provided.
+const DefCheckLimit = 100
+
+// repairBatch is the number of quads added or removed in a transaction.
+const repairBatch = 1000
+
+// Issues contains the quads with a kind of issue. The number of quads listed
+// is limited but the count includes all of them.
+type Issues struct {
+	Count int         `json:"count"`
+	Quads []QuadParam `json:"quads"`
+}
+
+// add counts the quad and lists it if the limit is not reached.
+func (is *Issues) add(qp QuadParam, limit int) {
+	is.Count++
+	if len(is.Quads) < limit {
+		is.Quads = append(is.Quads, qp)
+	}
+}
+
+// CheckReport contains the differences found between the items and the quads
+// in the graph.
+type CheckReport struct {
+	Items     int    `json:"items"`     // Number of items scanned.
+	Quads     int    `json:"quads"`     // Number of quads scanned.
+	Orphaned  Issues `json:"orphaned"`  // Quads no item implies whose subject or object has no item.
+	Missing   Issues `json:"missing"`   // Quads the pattern of an item implies that are not in the graph.
+	Undefined Issues `json:"undefined"` // Quads whose predicate has no relationship.
+	Added     int    `json:"added"`     // Missing quads added by the repair.
+	Removed   int    `json:"removed"`   // Orphaned quads removed by the repair.
+}
+
+//==============================================================================
+
+// Check scans the items and the quads in the graph and reports the quads that
+// are orphaned, missing or use an undefined predicate. When repair is set the
+// missing quads are added and the orphaned quads are removed. Quads with an
+// undefined predicate are only reported since the relationship may not be
+// defined yet. The items are checked one at a time against their quads in the
+// graph and the quads are checked a page at a time against their items so
+// neither is held in memory.
+func Check(context interface{}, db *db.DB, store *cayley.Handle, repair bool, limit int) (*CheckReport, error) {
+	log.Dev(context, "Check", "Started : Repair[%v]", repair)
+
+	if limit <= 0 {
+		limit = DefCheckLimit
+	}
+
+	c, err := newChecker(context, db, store, repair, limit)
+	if err != nil {
+		log.Error(context, "Check", err, "Completed")
+		return nil, err
+	}
+
+	// Find the quads the patterns of the items imply that are missing.
+	if err := c.items(); err != nil {
+		log.Error(context, "Check", err, "Completed")
+		return nil, err
+	}
+
+	// Find the quads that are orphaned or use an undefined predicate.
+	if err := c.quads(); err != nil {
+		log.Error(context, "Check", err, "Completed")
+		return nil, err
+	}
+
+	report := c.report
+	log.Dev(context, "Check", "Completed : Items[%d] Quads[%d] Orphaned[%d] Missing[%d] Undefined[%d]", report.Items, report.Quads, report.Orphaned.Count, report.Missing.Count, report.Undefined.Count)
+	return &report, nil
+}
+
+//==============================================================================
+
+// checkPage is the number of quads of the graph checked together against
+// their items.
+const checkPage = 1000
+
+// checker contains the state of a check of the graph.
+type checker struct {
+	context  interface{}
+	db       *db.DB
+	store    *cayley.Handle
+	repair   bool
+	limit    int
+	rels     map[string]relationship.Relationship
+	strats   idStrategies
+	patterns map[string]*pattern.Pattern
+	report   CheckReport
+}
+
+// newChecker returns a checker with the metadata the items and quads are
+// checked against.
+func newChecker(context interface{}, db *db.DB, store *cayley.Handle, repair bool, limit int) (*checker, error) {
+	rels, err := relationships(context, db)
+	if err != nil {
+		return nil, err
+	}
+
+	strats, err := strategies(context, db)
+	if err != nil {
+		return nil, err
+	}
+
+	c := checker{
+		context:  context,
+		db:       db,
+		store:    store,
+		repair:   repair,
+		limit:    limit,
+		rels:     rels,
+		strats:   strats,
+		patterns: make(map[string]*pattern.Pattern),
+	}
+
+	return &c, nil
+}
+
+// items scans the items and reports the quads their patterns imply that are
+// not in the graph. The quads of an item are the ones it is the subject or
+// object of.
+func (c *checker) items() error {
+	var missing []QuadParam
+
+	f := func(col *mgo.Collection) error {
+		log.Dev(c.context, "items", "MGO : db.%s.find({})", col.Name)
+		iter := col.Find(nil).Select(bson.M{"item_id": 1, "type": 1, "data": 1}).Iter()
+
+		var it item.Item
+		for iter.Next(&it) {
+			c.report.Items++
+
+			implied, err := c.implied(it)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+
+			if len(implied) > 0 {
+				quads, err := itemQuads(c.store, it.ID)
+				if err != nil {
+					iter.Close()
+					return err
+				}
+
+				for _, qp := range sortQuads(implied) {
+					if !quads[qp] {
+						c.report.Missing.add(qp, c.limit)
+						missing = append(missing, qp)
+					}
+				}
+			}
+
+			// Add the missing quads in batches as they are found.
+			if c.repair && len(missing) >= repairBatch {
+				n, err := applyQuads(c.store, missing, graph.Add)
+				c.report.Added += n
+				if err != nil {
+					iter.Close()
+					return err
+				}
+				missing = missing[:0]
+			}
+
+			it = item.Item{}
+		}
+
+		return iter.Close()
+	}
+
+	if err := c.db.ExecuteMGO(c.context, item.Collection, f); err != nil {
+		return err
+	}
+
+	if c.repair {
+		n, err := applyQuads(c.store, missing, graph.Add)
+		c.report.Added += n
+		return err
+	}
+
+	return nil
+}
+
+// quads scans the quads in the graph a page at a time and reports the quads
+// whose predicate has no relationship and the quads no item implies whose
+// subject or object has no item.
+func (c *checker) quads() error {
+	it := c.store.QuadsAllIterator()
+	defer it.Close()
+
+	page := make([]QuadParam, 0, checkPage)
+	for it.Next() {
+		q := c.store.Quad(it.Result())
+		page = append(page, QuadParam{
+			Subject:   fmt.Sprint(quad.NativeOf(q.Subject)),
+			Predicate: fmt.Sprint(quad.NativeOf(q.Predicate)),
+			Object:    fmt.Sprint(quad.NativeOf(q.Object)),
+		})
+
+		if len(page) == checkPage {
+			if err := c.page(page); err != nil {
+				return err
+			}
+			page = page[:0]
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return c.page(page)
+}
+
+// page checks a page of quads against the items they relate. A quad is only
+// implied by the items it relates since the items infer the quads they are
+// the subject or object of.
+func (c *checker) page(page []QuadParam) error {
+	if len(page) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, 2*len(page))
+	for _, qp := range page {
+		ids = append(ids, qp.Subject, qp.Object)
+	}
+
+	var its []item.Item
+	f := func(col *mgo.Collection) error {
+		q := bson.M{"item_id": bson.M{"$in": ids}}
+		log.Dev(c.context, "page", "MGO : db.%s.find({item_id: {$in: [%d]}})", col.Name, len(ids))
+		return col.Find(q).Select(bson.M{"item_id": 1, "type": 1, "data": 1}).All(&its)
+	}
+
+	if err := c.db.ExecuteMGO(c.context, item.Collection, f); err != nil {
+		return err
+	}
+
+	exists := make(map[string]bool, len(its))
+	implied := make(map[QuadParam]bool)
+	for _, it := range its {
+		exists[it.ID] = true
+
+		qps, err := c.implied(it)
+		if err != nil {
+			return err
+		}
+		for qp := range qps {
+			implied[qp] = true
+		}
+	}
+
+	var orphaned []QuadParam
+	for _, qp := range page {
+		c.report.Quads++
+
+		if _, exists := c.rels[qp.Predicate]; !exists {
+			c.report.Undefined.add(qp, c.limit)
+		}
+
+		if !implied[qp] && (!exists[qp.Subject] || !exists[qp.Object]) {
+			c.report.Orphaned.add(qp, c.limit)
+			orphaned = append(orphaned, qp)
+		}
+	}
+
+	if c.repair {
+		n, err := applyQuads(c.store, orphaned, graph.Delete)
+		c.report.Removed += n
+		return err
+	}
+
+	return nil
+}
+
+// implied returns the quads the current pattern of the type of the item
+// implies. Patterns are only retrieved once for each type.
+func (c *checker) implied(it item.Item) (map[QuadParam]bool, error) {
+	parsed, err := itemParse(map[string]interface{}{
+		"item_id": it.ID,
+		"type":    it.Type,
+		"data":    it.Data,
+	})
+	if err != nil {
+		return nil, nil
+	}
+
+	p, exists := c.patterns[it.Type]
+	if !exists {
+		var err error
+		if p, err = pattern.GetByType(c.context, c.db, it.Type); err != nil {
+			if err != pattern.ErrNotFound {
+				return nil, err
+			}
+			p = nil
+		}
+		c.patterns[it.Type] = p
+	}
+
+	if p == nil {
+		return nil, nil
+	}
+
+	return quadSet(infer(parsed, p, c.strats)), nil
+}
+
+// itemQuads returns the quads of the graph the item is the subject or object
+// of.
+func itemQuads(store *cayley.Handle, itemID string) (map[QuadParam]bool, error) {
+	quads := make(map[QuadParam]bool)
+
+	v := store.ValueOf(quad.String(itemID))
+	if v == nil {
+		return quads, nil
+	}
+
+	for _, dir := range []quad.Direction{quad.Subject, quad.Object} {
+		it := store.QuadIterator(dir, v)
+		for it.Next() {
+			q := store.Quad(it.Result())
+			quads[QuadParam{
+				Subject:   fmt.Sprint(quad.NativeOf(q.Subject)),
+				Predicate: fmt.Sprint(quad.NativeOf(q.Predicate)),
+				Object:    fmt.Sprint(quad.NativeOf(q.Object)),
+			}] = true
+		}
+
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return quads, nil
+}
+
+// applyQuads adds or removes the quads in batches and returns the number of
+// quads applied.
+func applyQuads(store *cayley.Handle, qps []QuadParam, action graph.Procedure) (int, error) {
+	qw, err := newWriter(store)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+	for len(qps) > 0 {
+		n := repairBatch
+		if n > len(qps) {
+			n = len(qps)
+		}
+
+		tx := cayley.NewTransaction()
+		for _, qp := range qps[:n] {
+			q := quad.Make(qp.Subject, qp.Predicate, qp.Object, "")
+			if action == graph.Add {
+				tx.AddQuad(q)
+				continue
+			}
+			tx.RemoveQuad(q)
+		}
+
+		if err := qw.ApplyTransaction(tx); err != nil {
+			return applied, err
+		}
+
+		applied += n
+		qps = qps[n:]
+	}
+
+	return applied, nil
+}
+
+// sortQuads returns the quads of the set sorted by subject, predicate and
+// object so reports are stable.
+func sortQuads(set map[QuadParam]bool) []QuadParam {
+	qps := make([]QuadParam, 0, len(set))
+	for qp := range set {
+		qps = append(qps, qp)
+	}
+
+	sort.Sort(byQuad(qps))
+	return qps
+}
+
+// byQuad sorts quads by subject, predicate and object.
+type byQuad []QuadParam
+
+func (b byQuad) Len() int      { return len(b) }
+func (b byQuad) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byQuad) Less(i, j int) bool {
+	if b[i].Subject != b[j].Subject {
+		return b[i].Subject < b[j].Subject
+	}
+	if b[i].Predicate != b[j].Predicate {
+		return b[i].Predicate < b[j].Predicate
+	}
+	return b[i].Object < b[j].Object
+}
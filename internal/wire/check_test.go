@@ -0,0 +1,116 @@
+package wire_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/item/itemfix"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/wirefix"
+)
+
+// TestCheck tests if orphaned, missing and undefined relationships are
+// reported and repaired.
+func TestCheck(t *testing.T) {
+	db, _ := setup(t)
+	defer teardown(t, db)
+
+	const prefix = "CTEST_"
+	defer wirefix.Remove(tests.Context, db, prefix)
+	defer itemfix.Remove(tests.Context, db, prefix)
+
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a new Cayley graph : %v", tests.Failed, err)
+	}
+
+	// issues returns the number of quads of the issues about the test data.
+	issues := func(is wire.Issues) int {
+		var n int
+		for _, qp := range is.Quads {
+			if strings.HasPrefix(qp.Subject, prefix) || strings.HasPrefix(qp.Object, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Log("Given the need to check the consistency of the graph.")
+	{
+		t.Log("\tWhen an item has no relationships and a relationship has no item")
+		{
+			p := pattern.Pattern{
+				Type: prefix + "comment",
+				Inferences: []pattern.Inference{
+					{RelIDField: "author", Predicate: "authored", Direction: "in"},
+				},
+			}
+			if err := pattern.Upsert(tests.Context, db, &p); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the pattern : %s", tests.Failed, err)
+			}
+
+			it := item.Item{
+				ID:      prefix + "1",
+				Type:    p.Type,
+				Version: 1,
+				Data:    map[string]interface{}{"author": prefix + "user"},
+			}
+			if err := item.Upsert(tests.Context, db, &it); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the item : %s", tests.Failed, err)
+			}
+
+			if err := store.AddQuad(quad.Make(prefix+"gone", prefix+"flagged", prefix+"1", "")); err != nil {
+				t.Fatalf("\t%s\tShould be able to add the orphaned quad : %s", tests.Failed, err)
+			}
+
+			report, err := wire.Check(tests.Context, db, store, false, 1000)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to check the graph : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to check the graph.", tests.Success)
+
+			if issues(report.Missing) != 1 {
+				t.Fatalf("\t%s\tShould report the missing relationship : %+v", tests.Failed, report.Missing)
+			}
+			t.Logf("\t%s\tShould report the missing relationship.", tests.Success)
+
+			if issues(report.Orphaned) != 1 {
+				t.Fatalf("\t%s\tShould report the orphaned relationship : %+v", tests.Failed, report.Orphaned)
+			}
+			t.Logf("\t%s\tShould report the orphaned relationship.", tests.Success)
+
+			if issues(report.Undefined) != 1 {
+				t.Fatalf("\t%s\tShould report the undefined predicate : %+v", tests.Failed, report.Undefined)
+			}
+			t.Logf("\t%s\tShould report the undefined predicate.", tests.Success)
+
+			if report.Added != 0 || report.Removed != 0 {
+				t.Fatalf("\t%s\tShould not change the graph without repair : %+v", tests.Failed, report)
+			}
+			t.Logf("\t%s\tShould not change the graph without repair.", tests.Success)
+		}
+
+		t.Log("\tWhen repairing the graph")
+		{
+			if _, err := wire.Check(tests.Context, db, store, true, 1000); err != nil {
+				t.Fatalf("\t%s\tShould be able to repair the graph : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to repair the graph.", tests.Success)
+
+			report, err := wire.Check(tests.Context, db, store, false, 1000)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to check the graph : %s", tests.Failed, err)
+			}
+
+			if n := issues(report.Missing) + issues(report.Orphaned) + issues(report.Undefined); n != 0 {
+				t.Fatalf("\t%s\tShould have no issues after the repair : %+v", tests.Failed, report)
+			}
+			t.Logf("\t%s\tShould have no issues after the repair.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+)
+
+// ErrUndefinedPredicate is reported when a predicate has no relationship.
+var ErrUndefinedPredicate = errors.New("Predicate has no relationship")
+
+// strict reports if relationships that violate the relationship metadata
+// are rejected instead of being logged and skipped.
+var strict bool
+
+// SetStrict turns the rejection of relationships that violate the
+// relationship metadata on or off.
+func SetStrict(on bool) {
+	strict = on
+}
+
+// Violation is a relationship the relationship metadata doesn't allow. The
+// types are empty when they are not known and the subject and object are
+// empty when checking a pattern.
+type Violation struct {
+	Subject     string `json:"subject,omitempty"`
+	SubjectType string `json:"subject_type,omitempty"`
+	Predicate   string `json:"predicate"`
+	Object      string `json:"object,omitempty"`
+	ObjectType  string `json:"object_type,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// String returns the relationship and the reason it is not allowed.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s(%s) %s %s(%s) : %s", v.Subject, v.SubjectType, v.Predicate, v.Object, v.ObjectType, v.Reason)
+}
+
+// ConstraintError is returned in strict mode when relationships violate the
+// relationship metadata.
+type ConstraintError struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Error implements the error interface.
+func (e *ConstraintError) Error() string {
+	vs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		vs[i] = v.String()
+	}
+
+	return "Relationships not allowed : " + strings.Join(vs, ", ")
+}
+
+//==============================================================================
+
+// ValidateItem checks the relationships inferred for the item against the
+// relationship metadata so the item can be rejected before it is stored. In
+// strict mode a *ConstraintError is returned for any violation.
+func ValidateItem(context interface{}, db *db.DB, item map[string]interface{}) error {
+	log.Dev(context, "ValidateItem", "Started")
+
+	if _, err := inferRelationships(context, db, item, true); err != nil {
+		log.Error(context, "ValidateItem", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "ValidateItem", "Completed")
+	return nil
+}
+
+// ValidatePattern checks the inferences of the pattern against the
+// relationship metadata. In strict mode a *ConstraintError is returned for
+// any violation, otherwise the violations are only logged.
+func ValidatePattern(context interface{}, db *db.DB, p *pattern.Pattern) error {
+	log.Dev(context, "ValidatePattern", "Started : Type[%s]", p.Type)
+
+	rels, err := relationships(context, db)
+	if err != nil {
+		log.Error(context, "ValidatePattern", err, "Completed")
+		return err
+	}
+
+	var vs []Violation
+	for _, inf := range p.Inferences {
+		if v := violation(rels, p.Type, inf); v != nil {
+			vs = append(vs, *v)
+		}
+	}
+
+	if err := violated(context, "ValidatePattern", vs); err != nil {
+		log.Error(context, "ValidatePattern", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "ValidatePattern", "Completed")
+	return nil
+}
+
+//==============================================================================
+
+// constrain infers the relationships of a parsed item using the pattern and
+// checks each one against the relationship metadata.
+func constrain(context interface{}, item parsedItem, p *pattern.Pattern, ids idStrategies, rels map[string]relationship.Relationship) ([]QuadParam, error) {
+	var qps []QuadParam
+	var vs []Violation
+	for _, inf := range p.Inferences {
+		qp, ok := inferOne(item, inf, ids)
+		if !ok {
+			continue
+		}
+
+		// Invalid relationships are kept so they are reported as invalid.
+		if err := qp.Validate(); err != nil {
+			qps = append(qps, qp)
+			continue
+		}
+
+		if v := violation(rels, p.Type, inf); v != nil {
+			v.Subject = qp.Subject
+			v.Object = qp.Object
+			vs = append(vs, *v)
+			continue
+		}
+
+		qps = append(qps, qp)
+	}
+
+	if err := violated(context, "constrain", vs); err != nil {
+		return nil, err
+	}
+
+	return qps, nil
+}
+
+// violated returns a *ConstraintError for the violations in strict mode and
+// logs them otherwise.
+func violated(context interface{}, function string, vs []Violation) error {
+	if len(vs) == 0 {
+		return nil
+	}
+
+	if strict {
+		return &ConstraintError{Violations: vs}
+	}
+
+	for _, v := range vs {
+		log.Dev(context, function, "Skipping : %s", v)
+	}
+
+	return nil
+}
+
+// violation checks the relationship inferred for items of the type against
+// the relationship metadata. The type of the related item is the related type
+// of the inference when one is provided.
+func violation(rels map[string]relationship.Relationship, itemType string, inf pattern.Inference) *Violation {
+	subjectType, objectType := inf.RelType, itemType
+	if inf.Direction == outString {
+		subjectType, objectType = itemType, inf.RelType
+	}
+
+	v := Violation{
+		SubjectType: subjectType,
+		Predicate:   inf.Predicate,
+		ObjectType:  objectType,
+	}
+
+	rel, exists := rels[inf.Predicate]
+	if !exists {
+		v.Reason = ErrUndefinedPredicate.Error()
+		return &v
+	}
+
+	if err := rel.Allows(subjectType, objectType); err != nil {
+		v.Reason = err.Error()
+		return &v
+	}
+
+	return nil
+}
+
+// relationships returns the relationship metadata by predicate.
+func relationships(context interface{}, db *db.DB) (map[string]relationship.Relationship, error) {
+	rels, err := relationship.GetAll(context, db)
+	if err != nil && err != relationship.ErrNotFound {
+		return nil, err
+	}
+
+	m := make(map[string]relationship.Relationship, len(rels))
+	for _, rel := range rels {
+		m[rel.Predicate] = rel
+	}
+
+	return m, nil
+}
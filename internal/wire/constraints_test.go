@@ -0,0 +1,133 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+	"github.com/coralproject/shelf/internal/wire/wirefix"
+)
+
+// TestConstraints tests if inferred relationships are checked against the
+// relationship metadata.
+func TestConstraints(t *testing.T) {
+	db, _ := setup(t)
+	defer teardown(t, db)
+
+	const prefix = "STEST_"
+	defer wirefix.Remove(tests.Context, db, prefix)
+	defer wire.SetStrict(false)
+
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a new Cayley graph : %v", tests.Failed, err)
+	}
+
+	rel := relationship.Relationship{
+		SubjectTypes: []string{prefix + "user"},
+		Predicate:    prefix + "authored",
+		ObjectTypes:  []string{prefix + "comment"},
+	}
+	if err := relationship.Upsert(tests.Context, db, &rel); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the relationship : %s", tests.Failed, err)
+	}
+
+	p := pattern.Pattern{
+		Type: prefix + "comment",
+		Inferences: []pattern.Inference{
+			{RelIDField: "author", RelType: prefix + "user", Predicate: prefix + "authored", Direction: "in"},
+			{RelIDField: "asset", RelType: prefix + "asset", Predicate: prefix + "on", Direction: "out"},
+		},
+	}
+	if err := pattern.Upsert(tests.Context, db, &p); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the pattern : %s", tests.Failed, err)
+	}
+
+	itMap := map[string]interface{}{
+		"item_id": prefix + "1",
+		"type":    p.Type,
+		"data":    map[string]interface{}{"author": "1", "asset": "1"},
+	}
+
+	// count returns the number of quads with the predicate.
+	count := func(predicate string) int {
+		var n int
+		it := cayley.StartPath(store, quad.String(prefix+"1")).Both(quad.String(predicate)).BuildIterator()
+		for it.Next() {
+			n++
+		}
+		it.Close()
+		return n
+	}
+
+	t.Log("Given the need to check relationships against their metadata.")
+	{
+		t.Log("\tWhen a pattern infers a relationship that is not defined in lenient mode")
+		{
+			if err := wire.ValidatePattern(tests.Context, db, &p); err != nil {
+				t.Fatalf("\t%s\tShould be able to validate the pattern : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to validate the pattern.", tests.Success)
+
+			if err := wire.AddToGraph(tests.Context, db, store, itMap); err != nil {
+				t.Fatalf("\t%s\tShould be able to add the item to the graph : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to add the item to the graph.", tests.Success)
+
+			if count(prefix+"authored") != 1 || count(prefix+"on") != 0 {
+				t.Fatalf("\t%s\tShould only add the allowed relationship.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould only add the allowed relationship.", tests.Success)
+
+			if err := wire.RemoveFromGraph(tests.Context, db, store, itMap); err != nil {
+				t.Fatalf("\t%s\tShould be able to remove the item from the graph : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to remove the item from the graph.", tests.Success)
+
+			if count(prefix+"authored") != 0 {
+				t.Fatalf("\t%s\tShould remove the added relationship.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould remove the added relationship.", tests.Success)
+		}
+
+		t.Log("\tWhen a pattern infers a relationship that is not defined in strict mode")
+		{
+			wire.SetStrict(true)
+
+			err := wire.ValidatePattern(tests.Context, db, &p)
+			cerr, ok := err.(*wire.ConstraintError)
+			if !ok || len(cerr.Violations) != 1 || cerr.Violations[0].Reason != wire.ErrUndefinedPredicate.Error() {
+				t.Fatalf("\t%s\tShould reject the pattern for the undefined predicate : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject the pattern for the undefined predicate.", tests.Success)
+
+			if _, ok := wire.ValidateItem(tests.Context, db, itMap).(*wire.ConstraintError); !ok {
+				t.Fatalf("\t%s\tShould reject the item.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould reject the item.", tests.Success)
+		}
+
+		t.Log("\tWhen a pattern infers a relationship between types that are not allowed in strict mode")
+		{
+			wire.SetStrict(true)
+
+			bad := pattern.Pattern{
+				Type: prefix + "asset",
+				Inferences: []pattern.Inference{
+					{RelIDField: "author", RelType: prefix + "user", Predicate: prefix + "authored", Direction: "in"},
+				},
+			}
+
+			err := wire.ValidatePattern(tests.Context, db, &bad)
+			cerr, ok := err.(*wire.ConstraintError)
+			if !ok || len(cerr.Violations) != 1 || cerr.Violations[0].Reason != relationship.ErrObjectType.Error() {
+				t.Fatalf("\t%s\tShould reject the pattern for the object type : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject the pattern for the object type.", tests.Success)
+		}
+	}
+}
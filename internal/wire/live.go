@@ -0,0 +1,311 @@
+package wire
+
+import (
+	"fmt"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/wire/live"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/view"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// UpsertLive registers the live view and saves the items of the view into
+// its collection. The number of items in the view is returned.
+func UpsertLive(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, l *live.Live) (int, error) {
+	log.Dev(context, "UpsertLive", "Started : Name[%s]", l.Name)
+
+	// The view must exist and be valid before it is registered.
+	v, err := view.GetByName(context, mgoDB, l.View)
+	if err != nil {
+		log.Error(context, "UpsertLive", err, "Completed")
+		return 0, err
+	}
+
+	if err := validateStartType(context, mgoDB, v); err != nil {
+		log.Error(context, "UpsertLive", err, "Completed")
+		return 0, err
+	}
+
+	if err := live.Upsert(context, mgoDB, l); err != nil {
+		log.Error(context, "UpsertLive", err, "Completed")
+		return 0, err
+	}
+
+	n, err := liveSync(context, mgoDB, graphDB, l, v, nil)
+	if err != nil {
+		log.Error(context, "UpsertLive", err, "Completed")
+		return 0, err
+	}
+
+	log.Dev(context, "UpsertLive", "Completed : Items[%d]", n)
+	return n, nil
+}
+
+// RefreshLive saves all the items of the live view into its collection again
+// and removes the items that are no longer in the view. The number of items
+// in the view is returned.
+func RefreshLive(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, name string) (int, error) {
+	log.Dev(context, "RefreshLive", "Started : Name[%s]", name)
+
+	l, err := live.GetByName(context, mgoDB, name)
+	if err != nil {
+		log.Error(context, "RefreshLive", err, "Completed")
+		return 0, err
+	}
+
+	v, err := view.GetByName(context, mgoDB, l.View)
+	if err != nil {
+		log.Error(context, "RefreshLive", err, "Completed")
+		return 0, err
+	}
+
+	n, err := liveSync(context, mgoDB, graphDB, l, v, nil)
+	if err != nil {
+		log.Error(context, "RefreshLive", err, "Completed")
+		return 0, err
+	}
+
+	log.Dev(context, "RefreshLive", "Completed : Items[%d]", n)
+	return n, nil
+}
+
+// SyncLive updates the collections of the live views affected by the items
+// that were written. A live view is affected when its path follows a
+// predicate of the quads the write touched: the relationships inferred for
+// the items and the quads of the graph the items are the subject or object
+// of, which includes the relationships other items infer. Items that entered
+// the view are saved, items that left it are removed and the written items
+// that are in the view are saved again. The items must be provided as they
+// were before and after the write so relationships that were removed are
+// considered. Every affected view is updated even if one of them fails.
+func SyncLive(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, items ...map[string]interface{}) error {
+	log.Dev(context, "SyncLive", "Started : Items[%d]", len(items))
+
+	ls, err := live.GetAll(context, mgoDB)
+	if err != nil {
+		if err == live.ErrNotFound {
+			log.Dev(context, "SyncLive", "Completed : No live views")
+			return nil
+		}
+		log.Error(context, "SyncLive", err, "Completed")
+		return err
+	}
+
+	ids, preds, err := itemPredicates(context, mgoDB, graphDB, items)
+	if err != nil {
+		log.Error(context, "SyncLive", err, "Completed")
+		return err
+	}
+
+	var synced int
+	var lastErr error
+	for i := range ls {
+		v, err := view.GetByName(context, mgoDB, ls[i].View)
+		if err != nil {
+			log.Error(context, "SyncLive", err, "Getting view : Live[%s]", ls[i].Name)
+			lastErr = err
+			continue
+		}
+
+		if !pathFollows(v.Path, preds) {
+			continue
+		}
+
+		if _, err := liveSync(context, mgoDB, graphDB, &ls[i], v, ids); err != nil {
+			log.Error(context, "SyncLive", err, "Updating view : Live[%s]", ls[i].Name)
+			lastErr = err
+			continue
+		}
+		synced++
+	}
+
+	if lastErr != nil {
+		log.Error(context, "SyncLive", lastErr, "Completed")
+		return lastErr
+	}
+
+	log.Dev(context, "SyncLive", "Completed : Updated[%d]", synced)
+	return nil
+}
+
+//==============================================================================
+
+// liveSync brings the collection of the live view in line with the items in
+// the view. Only the items that entered the view and the changed items are
+// saved unless changed is nil, then all the items of the view are saved.
+func liveSync(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, l *live.Live, v *view.View, changed map[string]bool) (int, error) {
+
+	// Find the items that are in the view now.
+	graphPath, filters, err := viewPathToGraphPath(v, l.ItemKey, graphDB)
+	if err != nil {
+		return 0, err
+	}
+
+	ids, err := viewIDs(context, mgoDB, v, graphPath, filters, graphDB)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the items of the view that are already saved in the collection.
+	saved, err := liveIDs(context, mgoDB, l.Collection, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var save []string
+	for _, id := range ids {
+		if changed == nil || changed[id] || !saved[id] {
+			save = append(save, id)
+		}
+	}
+
+	// Remove the items that left the view.
+	var removed int
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"item_id": bson.M{"$nin": ids}}
+		log.Dev(context, "liveSync", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		info, err := c.RemoveAll(q)
+		if info != nil {
+			removed = info.Removed
+		}
+		return err
+	}
+	if err := mgoDB.ExecuteMGO(context, l.Collection, f); err != nil {
+		return 0, err
+	}
+
+	// Save the items that entered the view or changed.
+	if len(save) > 0 {
+		viewParams := ViewParams{
+			ViewName:          l.View,
+			ItemKey:           l.ItemKey,
+			ResultsCollection: l.Collection,
+		}
+
+		q, err := newViewQuery(&viewParams, save)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, _, err := viewSave(context, mgoDB, v, &viewParams, q); err != nil {
+			return 0, err
+		}
+	}
+
+	log.Dev(context, "liveSync", "Live[%s] Items[%d] Saved[%d] Removed[%d]", l.Name, len(ids), len(save), removed)
+	return len(ids), nil
+}
+
+// liveIDs returns which of the ids of the items are saved in the collection.
+func liveIDs(context interface{}, mgoDB *db.DB, collection string, itemIDs []string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"item_id": bson.M{"$in": itemIDs}}
+		log.Dev(context, "liveIDs", "MGO : db.%s.find(%s, {item_id: 1})", c.Name, mongo.Query(q))
+		iter := c.Find(q).Select(bson.M{"item_id": 1}).Iter()
+
+		var doc struct {
+			ID string `bson:"item_id"`
+		}
+		for iter.Next(&doc) {
+			ids[doc.ID] = true
+		}
+
+		return iter.Close()
+	}
+
+	if err := mgoDB.ExecuteMGO(context, collection, f); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// itemPredicates returns the ids of the items and the predicates of the
+// quads the write of the items touched. The relationships inferred for the
+// items include the ones that were removed from the graph, the quads of the
+// graph include the ones other items infer to the items.
+func itemPredicates(context interface{}, db *db.DB, graphDB *cayley.Handle, items []map[string]interface{}) (map[string]bool, map[string]bool, error) {
+	ids := make(map[string]bool)
+	preds := make(map[string]bool)
+
+	strats, err := strategies(context, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, itMap := range items {
+		parsed, err := itemParse(itMap)
+		if err != nil {
+			continue
+		}
+		ids[parsed.itemID] = true
+
+		if err := graphPredicates(graphDB, parsed.itemID, preds); err != nil {
+			return nil, nil, err
+		}
+
+		// Items without a pattern don't infer relationships.
+		p, err := pattern.GetByType(context, db, parsed.itemType)
+		if err != nil {
+			if err == pattern.ErrNotFound {
+				continue
+			}
+			return nil, nil, err
+		}
+
+		for _, qp := range infer(parsed, p, strats) {
+			preds[qp.Predicate] = true
+		}
+	}
+
+	return ids, preds, nil
+}
+
+// graphPredicates adds the predicates of the quads of the graph the item is
+// the subject or object of.
+func graphPredicates(graphDB *cayley.Handle, itemID string, preds map[string]bool) error {
+	v := graphDB.ValueOf(quad.String(itemID))
+	if v == nil {
+		return nil
+	}
+
+	for _, dir := range []quad.Direction{quad.Subject, quad.Object} {
+		it := graphDB.QuadIterator(dir, v)
+		for it.Next() {
+			q := graphDB.Quad(it.Result())
+			preds[fmt.Sprint(quad.NativeOf(q.Predicate))] = true
+		}
+
+		err := it.Err()
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pathFollows reports if a segment of the path or its branches follows one
+// of the predicates.
+func pathFollows(p view.Path, preds map[string]bool) bool {
+	for _, segment := range p {
+		if preds[segment.Predicate] {
+			return true
+		}
+		for _, branch := range segment.Branches {
+			if pathFollows(branch, preds) {
+				return true
+			}
+		}
+	}
+	return false
+}
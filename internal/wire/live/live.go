@@ -0,0 +1,140 @@
+// Package live provides the service layer for managing the registrations of
+// views that are kept up to date in a collection as items are written.
+package live
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Collection contains the name of the Mongo collection of registrations.
+const Collection = "live_views"
+
+func init() {
+	collections.Protect(Collection)
+}
+
+// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
+var ErrNotFound = errors.New("Live view Not found")
+
+// ErrCollectionInUse is returned when the collection of a live view is kept
+// by another live view.
+var ErrCollectionInUse = errors.New("Collection is used by another live view")
+
+// Upsert upserts a registration to the collection of live views.
+func Upsert(context interface{}, db *db.DB, l *Live) error {
+	log.Dev(context, "Upsert", "Started : Name[%s]", l.Name)
+
+	// Validate the registration.
+	if err := l.Validate(); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// Each live view owns its collection. Items saved by one view would be
+	// removed by the other.
+	var n int
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"collection": l.Collection, "name": bson.M{"$ne": l.Name}}
+		log.Dev(context, "Upsert", "MGO : db.%s.find(%s).count()", c.Name, mongo.Query(q))
+		var err error
+		n, err = c.Find(q).Count()
+		return err
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	if n > 0 {
+		log.Error(context, "Upsert", ErrCollectionInUse, "Completed")
+		return ErrCollectionInUse
+	}
+
+	// Upsert the registration.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"name": l.Name}
+		log.Dev(context, "Upsert", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(l))
+		_, err := c.Upsert(q, l)
+		return err
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Upsert", "Completed")
+	return nil
+}
+
+// GetAll retrieves the live views from Mongo.
+func GetAll(context interface{}, db *db.DB) ([]Live, error) {
+	log.Dev(context, "GetAll", "Started")
+
+	var ls []Live
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "GetAll", "MGO : db.%s.find({}).sort([\"name\"])", c.Name)
+		return c.Find(nil).Sort("name").All(&ls)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetAll", err, "Completed")
+		return nil, err
+	}
+
+	if ls == nil {
+		log.Error(context, "GetAll", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetAll", "Completed : Live[%d]", len(ls))
+	return ls, nil
+}
+
+// GetByName retrieves a live view by name from Mongo.
+func GetByName(context interface{}, db *db.DB, name string) (*Live, error) {
+	log.Dev(context, "GetByName", "Started : Name[%s]", name)
+
+	var l Live
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": name}
+		log.Dev(context, "GetByName", "MGO : db.%s.findOne(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&l)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetByName", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetByName", "Completed")
+	return &l, nil
+}
+
+// Delete removes a live view from Mongo. The collection of the view is kept.
+func Delete(context interface{}, db *db.DB, name string) error {
+	log.Dev(context, "Delete", "Started : Name[%s]", name)
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": name}
+		log.Dev(context, "Delete", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		return c.Remove(q)
+	}
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Delete", "Completed")
+	return nil
+}
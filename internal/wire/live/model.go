@@ -0,0 +1,37 @@
+package live
+
+import (
+	"github.com/coralproject/shelf/internal/platform/collections"
+	validator "gopkg.in/bluesuncorp/validator.v8"
+)
+
+//==============================================================================
+
+// validate is used to perform model field validation.
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New(&validator.Config{TagName: "validate"})
+}
+
+//==============================================================================
+
+// Live contains the registration of a view whose items are kept up to date
+// in a collection as items are written.
+type Live struct {
+	Name       string `bson:"name" json:"name" validate:"required,min=3"`             // Unique name of the registration.
+	View       string `bson:"view" json:"view" validate:"required,min=3"`             // Name of the view to materialize.
+	ItemKey    string `bson:"item_key" json:"item_key" validate:"required"`           // Item the view starts from.
+	Collection string `bson:"collection" json:"collection" validate:"required,min=2"` // Collection the items of the view are kept in.
+}
+
+// Validate checks the Live value for consistency.
+func (l *Live) Validate() error {
+	if err := validate.Struct(l); err != nil {
+		return err
+	}
+
+	// The items are written into the collection so it can't be one the
+	// services own.
+	return collections.Writable(l.Collection)
+}
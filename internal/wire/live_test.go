@@ -0,0 +1,179 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/cayleygraph/cayley"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/item/itemfix"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/live"
+	"github.com/coralproject/shelf/internal/wire/pattern"
+	"github.com/coralproject/shelf/internal/wire/relationship"
+	"github.com/coralproject/shelf/internal/wire/view"
+	"github.com/coralproject/shelf/internal/wire/wirefix"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TestLive tests if the collection of a live view is kept up to date as
+// items are written.
+func TestLive(t *testing.T) {
+	db, _ := setup(t)
+	defer teardown(t, db)
+
+	const prefix = "LTEST_"
+	defer wirefix.Remove(tests.Context, db, prefix)
+	defer itemfix.Remove(tests.Context, db, prefix)
+	defer live.Delete(tests.Context, db, prefix+"thread")
+	defer db.ExecuteMGO(tests.Context, prefix+"thread", func(c *mgo.Collection) error {
+		return c.DropCollection()
+	})
+
+	store, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to create a new Cayley graph : %v", tests.Failed, err)
+	}
+
+	rel := relationship.Relationship{
+		SubjectTypes: []string{prefix + "comment"},
+		Predicate:    prefix + "on",
+		ObjectTypes:  []string{prefix + "asset"},
+	}
+	if err := relationship.Upsert(tests.Context, db, &rel); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the relationship : %s", tests.Failed, err)
+	}
+
+	v := view.View{
+		Name:       prefix + "thread",
+		Collection: item.Collection,
+		StartType:  prefix + "asset",
+		Path:       view.Path{{Level: 1, Direction: "in", Predicate: prefix + "on", Tag: "comment"}},
+	}
+	if err := view.Upsert(tests.Context, db, &v); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the view : %s", tests.Failed, err)
+	}
+
+	p := pattern.Pattern{
+		Type: prefix + "comment",
+		Inferences: []pattern.Inference{
+			{RelIDField: "asset", Predicate: prefix + "on", Direction: "out"},
+		},
+	}
+	if err := pattern.Upsert(tests.Context, db, &p); err != nil {
+		t.Fatalf("\t%s\tShould be able to upsert the pattern : %s", tests.Failed, err)
+	}
+
+	// write stores the comment on the asset and updates the graph and the
+	// live views.
+	write := func(id, asset string, stale map[string]interface{}) map[string]interface{} {
+		it := item.Item{
+			ID:      id,
+			Type:    prefix + "comment",
+			Version: 1,
+			Data:    map[string]interface{}{"asset": asset},
+		}
+		if err := item.Upsert(tests.Context, db, &it); err != nil {
+			t.Fatalf("\t%s\tShould be able to upsert the item : %s", tests.Failed, err)
+		}
+
+		itMap := map[string]interface{}{
+			"item_id": it.ID,
+			"type":    it.Type,
+			"data":    it.Data,
+		}
+
+		items := []map[string]interface{}{itMap}
+		if stale != nil {
+			if err := wire.RemoveFromGraph(tests.Context, db, store, stale); err != nil {
+				t.Fatalf("\t%s\tShould be able to remove the item from the graph : %s", tests.Failed, err)
+			}
+			items = append(items, stale)
+		}
+
+		if err := wire.AddToGraph(tests.Context, db, store, itMap); err != nil {
+			t.Fatalf("\t%s\tShould be able to add the item to the graph : %s", tests.Failed, err)
+		}
+
+		if err := wire.SyncLive(tests.Context, db, store, items...); err != nil {
+			t.Fatalf("\t%s\tShould be able to update the live views : %s", tests.Failed, err)
+		}
+
+		return itMap
+	}
+
+	// saved returns the ids of the items in the collection of the live view.
+	saved := func() map[string]bool {
+		var docs []bson.M
+		f := func(c *mgo.Collection) error {
+			return c.Find(nil).All(&docs)
+		}
+		if err := db.ExecuteMGO(tests.Context, prefix+"thread", f); err != nil {
+			t.Fatalf("\t%s\tShould be able to query the live view collection : %s", tests.Failed, err)
+		}
+
+		ids := make(map[string]bool)
+		for _, doc := range docs {
+			ids[doc["item_id"].(string)] = true
+		}
+		return ids
+	}
+
+	t.Log("Given the need to keep the items of a view up to date in a collection.")
+	{
+		t.Log("\tWhen a live view is registered")
+		{
+			c1 := write(prefix+"c1", prefix+"a1", nil)
+
+			l := live.Live{
+				Name:       prefix + "thread",
+				View:       v.Name,
+				ItemKey:    prefix + "a1",
+				Collection: prefix + "thread",
+			}
+
+			n, err := wire.UpsertLive(tests.Context, db, store, &l)
+			if err != nil || n != 1 {
+				t.Fatalf("\t%s\tShould be able to register the live view with 1 item : %d : %v", tests.Failed, n, err)
+			}
+			t.Logf("\t%s\tShould be able to register the live view with 1 item.", tests.Success)
+
+			if ids := saved(); len(ids) != 1 || !ids[prefix+"c1"] {
+				t.Fatalf("\t%s\tShould save the items of the view : %v", tests.Failed, ids)
+			}
+			t.Logf("\t%s\tShould save the items of the view.", tests.Success)
+
+			t.Log("\tWhen another live view uses the collection")
+			{
+				other := l
+				other.Name = prefix + "other"
+
+				if _, err := wire.UpsertLive(tests.Context, db, store, &other); err != live.ErrCollectionInUse {
+					t.Fatalf("\t%s\tShould not be able to register the live view : %v", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould not be able to register the live view.", tests.Success)
+			}
+
+			t.Log("\tWhen an item enters the view")
+			{
+				write(prefix+"c2", prefix+"a1", nil)
+
+				if ids := saved(); len(ids) != 2 || !ids[prefix+"c2"] {
+					t.Fatalf("\t%s\tShould save the item that entered the view : %v", tests.Failed, ids)
+				}
+				t.Logf("\t%s\tShould save the item that entered the view.", tests.Success)
+			}
+
+			t.Log("\tWhen an item leaves the view")
+			{
+				write(prefix+"c1", prefix+"a2", c1)
+
+				if ids := saved(); len(ids) != 1 || ids[prefix+"c1"] {
+					t.Fatalf("\t%s\tShould remove the item that left the view : %v", tests.Failed, ids)
+				}
+				t.Logf("\t%s\tShould remove the item that left the view.", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package wire
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/coralproject/shelf/internal/platform/keyset"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrInvalidCursor is returned when a cursor can't be used for the view.
+var ErrInvalidCursor = errors.New("Invalid view cursor")
+
+// viewCursor is the decoded form of a cursor. It identifies the view, item
+// key and sort it was issued for and the sort values of the last item
+// returned.
+type viewCursor struct {
+	View   string        `bson:"v"`
+	Key    string        `bson:"k"`
+	Sort   string        `bson:"o"`
+	Values []interface{} `bson:"s"`
+}
+
+// viewQuery contains the query for reading the items of a view.
+type viewQuery struct {
+	filter bson.M
+	keys   []keyset.Key
+	proj   bson.M
+	skip   int
+	limit  int
+	paged  bool
+}
+
+//==============================================================================
+
+// newViewQuery returns the query reading the items with the ids using the
+// sort, projection and pagination of the view parameters.
+func newViewQuery(viewParams *ViewParams, ids []string) (*viewQuery, error) {
+	if viewParams.Skip < 0 || viewParams.Limit < 0 {
+		return nil, errors.New("Invalid skip or limit")
+	}
+
+	q := viewQuery{
+		filter: bson.M{"item_id": bson.M{"$in": ids}},
+		keys:   keyset.Keys(viewParams.Sort, "item_id"),
+		proj:   projection(viewParams.Fields, viewParams.Sort),
+		skip:   viewParams.Skip,
+		limit:  viewParams.Limit,
+		paged:  viewParams.Limit > 0,
+	}
+
+	if viewParams.Cursor == "" {
+		return &q, nil
+	}
+
+	if !q.paged {
+		return nil, errors.New("View cursor requires a limit")
+	}
+
+	var cursor viewCursor
+	if err := keyset.Decode(viewParams.Cursor, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.View != viewParams.ViewName || cursor.Key != viewParams.ItemKey || cursor.Sort != q.sortKey() {
+		return nil, ErrInvalidCursor
+	}
+
+	after, err := keyset.After(q.keys, cursor.Values)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// The cursor replaces the skip since it already points past the items
+	// that were skipped.
+	q.filter = bson.M{"$and": []bson.M{q.filter, after}}
+	q.skip = 0
+
+	return &q, nil
+}
+
+// sort returns the sort fields of the query.
+func (q *viewQuery) sort() []string {
+	return keyset.Fields(q.keys)
+}
+
+// sortKey returns the sort of the query as kept in the cursor.
+func (q *viewQuery) sortKey() string {
+	return strings.Join(q.sort(), ",")
+}
+
+// fetch returns the number of items to read. A paged query reads one more
+// item to know if there is another page.
+func (q *viewQuery) fetch() int {
+	if q.paged {
+		return q.limit + 1
+	}
+	return q.limit
+}
+
+// page trims the extra item that was read to know if there are more items
+// and returns the cursor for the next page.
+func (q *viewQuery) page(viewParams *ViewParams, items []bson.M) ([]bson.M, string, error) {
+	if !q.paged || len(items) <= q.limit {
+		return items, "", nil
+	}
+
+	items = items[:q.limit]
+
+	next, err := q.cursor(viewParams, items[q.limit-1])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, next, nil
+}
+
+// cursor returns the cursor for the page following the last item.
+func (q *viewQuery) cursor(viewParams *ViewParams, last bson.M) (string, error) {
+	return keyset.Encode(viewCursor{View: viewParams.ViewName, Key: viewParams.ItemKey, Sort: q.sortKey(), Values: keyset.Values(last, q.keys)})
+}
+
+//==============================================================================
+
+// projection returns the projection keeping the data fields of the items.
+// The sort fields are kept so the cursor for the next page can be created.
+// There is no projection when no data fields are provided.
+func projection(fields []string, sort []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	proj := bson.M{"_id": 0}
+	proj["item_id"] = 1
+	proj["type"] = 1
+	proj["version"] = 1
+	for _, fld := range fields {
+		proj["data."+fld] = 1
+	}
+	for _, k := range keyset.Keys(sort, "item_id") {
+		proj[k.Field] = 1
+	}
+
+	return proj
+}
@@ -14,7 +14,10 @@ func init() {
 //==============================================================================
 
 // Inference includes information used to infer a particular relationship
-// within an item.
+// within an item. When a related type is set the ID of the related item is
+// composed with the ID strategy of that type. A strategy with several fields
+// is referred to by a field of the item for each of them, separated by
+// commas and in the same order.
 type Inference struct {
 	RelIDField string `bson:"related_ID_field" json:"related_ID_field" validate:"required,min=2"`
 	RelType    string `bson:"related_type" json:"related_type"`
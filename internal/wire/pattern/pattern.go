@@ -6,12 +6,21 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
-// Collection is the Mongo collection containing pattern metadata.
-const Collection = "patterns"
+// Contains the name of Mongo collections.
+const (
+	Collection        = "patterns"         // Collection containing pattern metadata.
+	CollectionHistory = "patterns_history" // Collection containing the history of each pattern.
+)
+
+func init() {
+	collections.Protect(Collection)
+}
 
 // ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
 var ErrNotFound = errors.New("Set Not found")
@@ -38,6 +47,28 @@ func Upsert(context interface{}, db *db.DB, pattern *Pattern) error {
 		return err
 	}
 
+	// Add this pattern to the beginning of the history. The history record is
+	// created by the upsert if this pattern is new.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"type": pattern.Type}
+		qu := bson.M{
+			"$push": bson.M{
+				"patterns": bson.M{
+					"$each":     []*Pattern{pattern},
+					"$position": 0,
+				},
+			},
+		}
+
+		log.Dev(context, "Upsert", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(qu))
+		_, err := c.Upsert(q, qu)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
 	log.Dev(context, "Upsert", "Completed")
 	return nil
 }
@@ -87,6 +118,57 @@ func GetByType(context interface{}, db *db.DB, itemType string) (*Pattern, error
 	return &pattern, nil
 }
 
+// GetLastHistoryByType gets the last written Pattern within the history.
+func GetLastHistoryByType(context interface{}, db *db.DB, itemType string) (*Pattern, error) {
+	log.Dev(context, "GetLastHistoryByType", "Started : Type[%s]", itemType)
+
+	// Get the newest pattern from the history.
+	var result struct {
+		Patterns []Pattern `bson:"patterns"`
+	}
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"type": itemType}
+		proj := bson.M{"patterns": bson.M{"$slice": 1}}
+		log.Dev(context, "GetLastHistoryByType", "MGO : db.%s.find(%s,%s)", c.Name, mongo.Query(q), mongo.Query(proj))
+		return c.Find(q).Select(proj).One(&result)
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetLastHistoryByType", err, "Completed")
+		return nil, err
+	}
+
+	if len(result.Patterns) == 0 {
+		log.Error(context, "GetLastHistoryByType", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetLastHistoryByType", "Completed")
+	return &result.Patterns[0], nil
+}
+
+// History describes the history kept for patterns so their revisions can
+// be listed, compared and restored by type.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "patterns",
+	Selector: func(itemType string) (bson.M, error) {
+		return bson.M{"type": itemType}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var pattern Pattern
+		if err := raw.Unmarshal(&pattern); err != nil {
+			return nil, err
+		}
+		return &pattern, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*Pattern))
+	},
+}
+
 // Delete removes a pattern from from Mongo.
 func Delete(context interface{}, db *db.DB, itemType string) error {
 	log.Dev(context, "Delete", "Started : Type[%s]", itemType)
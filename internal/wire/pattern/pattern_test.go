@@ -8,6 +8,7 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/platform/history"
 	"github.com/coralproject/shelf/internal/wire/pattern"
 	"github.com/coralproject/shelf/internal/wire/pattern/patternfix"
 )
@@ -155,3 +156,77 @@ func TestGetAll(t *testing.T) {
 		}
 	}
 }
+
+// TestHistory tests if we can list, compare and restore the revisions of a
+// pattern.
+func TestHistory(t *testing.T) {
+	patterns, db := setup(t)
+	defer teardown(t, db)
+
+	t.Log("Given the need to keep the history of a pattern.")
+	{
+		t.Log("\tWhen upserting a pattern twice")
+		{
+			pat := patterns[0]
+			if err := pattern.Upsert(tests.Context, db, &pat); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the pattern : %s", tests.Failed, err)
+			}
+
+			changed := pat
+			changed.Inferences = changed.Inferences[:1]
+			if err := pattern.Upsert(tests.Context, db, &changed); err != nil {
+				t.Fatalf("\t%s\tShould be able to upsert the changed pattern : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to upsert the pattern twice.", tests.Success)
+
+			last, err := pattern.GetLastHistoryByType(tests.Context, db, pat.Type)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to get the last pattern from history : %s", tests.Failed, err)
+			}
+			if !reflect.DeepEqual(changed, *last) {
+				t.Logf("\t%+v", changed)
+				t.Logf("\t%+v", last)
+				t.Fatalf("\t%s\tShould get the changed pattern from history.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get the changed pattern from history.", tests.Success)
+
+			revs, err := history.List(tests.Context, db, pattern.History, pat.Type)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to list the revisions : %s", tests.Failed, err)
+			}
+			if len(revs) != 2 || revs[0].Number != 2 {
+				t.Fatalf("\t%s\tShould have two revisions with the newest first : %+v", tests.Failed, revs)
+			}
+			t.Logf("\t%s\tShould have two revisions with the newest first.", tests.Success)
+
+			changes, err := history.Diff(tests.Context, db, pattern.History, pat.Type, 1, 2)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to compare the revisions : %s", tests.Failed, err)
+			}
+			if len(changes) == 0 || changes[0].Field != "inferences.1.direction" || changes[0].To != nil {
+				t.Fatalf("\t%s\tShould get the removed inferences : %+v", tests.Failed, changes)
+			}
+			t.Logf("\t%s\tShould get the removed inferences.", tests.Success)
+
+			if err := history.Restore(tests.Context, db, pattern.History, pat.Type, 1); err != nil {
+				t.Fatalf("\t%s\tShould be able to restore the first revision : %s", tests.Failed, err)
+			}
+
+			cur, err := pattern.GetByType(tests.Context, db, pat.Type)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to get the pattern by type : %s", tests.Failed, err)
+			}
+			if !reflect.DeepEqual(pat, *cur) {
+				t.Logf("\t%+v", pat)
+				t.Logf("\t%+v", cur)
+				t.Fatalf("\t%s\tShould get back the restored pattern.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get back the restored pattern.", tests.Success)
+
+			if _, err := history.Get(tests.Context, db, pattern.History, pat.Type, 4); err != history.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find a revision that doesn't exist : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find a revision that doesn't exist.", tests.Success)
+		}
+	}
+}
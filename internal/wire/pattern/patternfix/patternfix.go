@@ -71,5 +71,9 @@ func Remove(context interface{}, db *db.DB, prefix string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, pattern.CollectionHistory, f); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -30,3 +30,27 @@ func (r *Relationship) Validate() error {
 	}
 	return nil
 }
+
+// Allows checks the relationship can link a subject and an object of the
+// types. An empty type is not known so it is allowed.
+func (r *Relationship) Allows(subjectType string, objectType string) error {
+	if subjectType != "" && !contains(r.SubjectTypes, subjectType) {
+		return ErrSubjectType
+	}
+
+	if objectType != "" && !contains(r.ObjectTypes, objectType) {
+		return ErrObjectType
+	}
+
+	return nil
+}
+
+// contains reports if the type is in the list of types.
+func contains(types []string, t string) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -6,15 +6,33 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
-// Collection is the Mongo collection containing relationship metadata.
-const Collection = "relationships"
+// Contains the name of Mongo collections.
+const (
+	Collection        = "relationships"         // Collection containing relationship metadata.
+	CollectionHistory = "relationships_history" // Collection containing the history of each relationship.
+)
+
+func init() {
+	collections.Protect(Collection)
+}
 
-// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
-var ErrNotFound = errors.New("Set Not found")
+// Set of error variables.
+var (
+	// ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
+	ErrNotFound = errors.New("Set Not found")
+
+	// ErrSubjectType is returned when a relationship doesn't allow a subject type.
+	ErrSubjectType = errors.New("Subject type not allowed")
+
+	// ErrObjectType is returned when a relationship doesn't allow an object type.
+	ErrObjectType = errors.New("Object type not allowed")
+)
 
 // Upsert upserts a relationship to the collection of currently utilized relationships.
 func Upsert(context interface{}, db *db.DB, rel *Relationship) error {
@@ -38,6 +56,28 @@ func Upsert(context interface{}, db *db.DB, rel *Relationship) error {
 		return err
 	}
 
+	// Add this relationship to the beginning of the history. The history record is
+	// created by the upsert if this relationship is new.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"predicate": rel.Predicate}
+		qu := bson.M{
+			"$push": bson.M{
+				"relationships": bson.M{
+					"$each":     []*Relationship{rel},
+					"$position": 0,
+				},
+			},
+		}
+
+		log.Dev(context, "Upsert", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(qu))
+		_, err := c.Upsert(q, qu)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
 	log.Dev(context, "Upsert", "Completed")
 	return nil
 }
@@ -87,6 +127,57 @@ func GetByPredicate(context interface{}, db *db.DB, predicate string) (*Relation
 	return &rel, nil
 }
 
+// GetLastHistoryByPredicate gets the last written Relationship within the history.
+func GetLastHistoryByPredicate(context interface{}, db *db.DB, predicate string) (*Relationship, error) {
+	log.Dev(context, "GetLastHistoryByPredicate", "Started : Predicate[%s]", predicate)
+
+	// Get the newest relationship from the history.
+	var result struct {
+		Relationships []Relationship `bson:"relationships"`
+	}
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"predicate": predicate}
+		proj := bson.M{"relationships": bson.M{"$slice": 1}}
+		log.Dev(context, "GetLastHistoryByPredicate", "MGO : db.%s.find(%s,%s)", c.Name, mongo.Query(q), mongo.Query(proj))
+		return c.Find(q).Select(proj).One(&result)
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetLastHistoryByPredicate", err, "Completed")
+		return nil, err
+	}
+
+	if len(result.Relationships) == 0 {
+		log.Error(context, "GetLastHistoryByPredicate", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetLastHistoryByPredicate", "Completed")
+	return &result.Relationships[0], nil
+}
+
+// History describes the history kept for relationships so their revisions can
+// be listed, compared and restored by predicate.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "relationships",
+	Selector: func(predicate string) (bson.M, error) {
+		return bson.M{"predicate": predicate}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var rel Relationship
+		if err := raw.Unmarshal(&rel); err != nil {
+			return nil, err
+		}
+		return &rel, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*Relationship))
+	},
+}
+
 // Delete removes a relationship from from Mongo.
 func Delete(context interface{}, db *db.DB, predicate string) error {
 	log.Dev(context, "Delete", "Started : Predicate[%s]", predicate)
@@ -3,12 +3,14 @@ package wire
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/log"
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
 	"github.com/cayleygraph/cayley/quad"
+	"github.com/coralproject/shelf/internal/sponge/idstrategy"
 	"github.com/coralproject/shelf/internal/wire/pattern"
 	validator "gopkg.in/bluesuncorp/validator.v8"
 )
@@ -47,12 +49,14 @@ func (q *QuadParam) Validate() error {
 	return nil
 }
 
-// AddToGraph adds relationships as quads into the cayley graph.
+// AddToGraph adds relationships as quads into the cayley graph. Relationships
+// that violate the relationship metadata fail in strict mode and are skipped
+// otherwise.
 func AddToGraph(context interface{}, db *db.DB, store *cayley.Handle, item map[string]interface{}) error {
 	log.Dev(context, "AddToGraph", "Started : %v", item)
 
 	// Infer the relationships in the item.
-	quadParams, err := inferRelationships(context, db, item)
+	quadParams, err := inferRelationships(context, db, item, true)
 	if err != nil {
 		log.Error(context, "AddToGraph", err, "Completed")
 		return err
@@ -85,14 +89,23 @@ func AddToGraph(context interface{}, db *db.DB, store *cayley.Handle, item map[s
 	return nil
 }
 
-// RemoveFromGraph removes relationship quads from the cayley graph.
+// RemoveFromGraph removes relationship quads from the cayley graph. Every
+// relationship the pattern infers is removed, including the ones that were
+// skipped when the item was added or that the relationship metadata no
+// longer allows, so quads that don't exist are ignored.
 func RemoveFromGraph(context interface{}, db *db.DB, store *cayley.Handle, item map[string]interface{}) error {
 	log.Dev(context, "RemoveFromGraph", "Started : %v", item)
 
 	// Infer the relationships in the item.
-	quadParams, err := inferRelationships(context, db, item)
+	quadParams, err := inferRelationships(context, db, item, false)
 	if err != nil {
-		log.Error(context, "AddToGraph", err, "Completed")
+		log.Error(context, "RemoveFromGraph", err, "Completed")
+		return err
+	}
+
+	qw, err := newWriter(store)
+	if err != nil {
+		log.Error(context, "RemoveFromGraph", err, "Completed")
 		return err
 	}
 
@@ -112,7 +125,7 @@ func RemoveFromGraph(context interface{}, db *db.DB, store *cayley.Handle, item
 	}
 
 	// Apply the transaction.
-	if err := store.ApplyTransaction(tx); err != nil {
+	if err := qw.ApplyTransaction(tx); err != nil {
 		log.Error(context, "RemoveFromGraph", err, "Completed")
 		return err
 	}
@@ -122,8 +135,9 @@ func RemoveFromGraph(context interface{}, db *db.DB, store *cayley.Handle, item
 }
 
 // inferRelationships infers realtionships based on patterns corresponding to
-// a type of item.
-func inferRelationships(context interface{}, db *db.DB, itemIn map[string]interface{}) ([]QuadParam, error) {
+// a type of item. When enforce is set the relationships are checked against
+// the relationship metadata.
+func inferRelationships(context interface{}, db *db.DB, itemIn map[string]interface{}, enforce bool) ([]QuadParam, error) {
 
 	// Parse the item.
 	item, err := itemParse(itemIn)
@@ -140,51 +154,95 @@ func inferRelationships(context interface{}, db *db.DB, itemIn map[string]interf
 		return nil, nil
 	}
 
+	// Get the strategies used to compose the IDs of related items.
+	ids, err := strategies(context, db)
+	if err != nil {
+		return nil, err
+	}
+
+	if enforce {
+		rels, err := relationships(context, db)
+		if err != nil {
+			return nil, err
+		}
+		return constrain(context, item, p, ids, rels)
+	}
+
+	return infer(item, p, ids), nil
+}
+
+// infer infers the relationships of a parsed item using the pattern.
+func infer(item parsedItem, p *pattern.Pattern, ids idStrategies) []QuadParam {
+
 	// Loop over inferences in the pattern.
 	var qps []QuadParam
 	for _, inf := range p.Inferences {
+		if qp, ok := inferOne(item, inf, ids); ok {
+			qps = append(qps, qp)
+		}
+	}
 
-		// Check for the relevant field in the item.
-		if relID, ok := item.itemData[inf.RelIDField]; ok {
-
-			// If the rel field is empty, do not create the quad.
-			if relID == "" {
-				continue
-			}
-
-			// If we are using source ids and rel types, compose the id.
-			if inf.RelType != "" {
-				relID = fmt.Sprintf("%s_%v", inf.RelType, relID)
-			}
-
-			// Add the relationship parameters.
-			switch inf.Direction {
-			case inString:
-				qp := QuadParam{
-					Subject:   relID,
-					Predicate: inf.Predicate,
-					Object:    item.itemID,
-				}
-				qps = append(qps, qp)
-			case outString:
-				qp := QuadParam{
-					Subject:   item.itemID,
-					Predicate: inf.Predicate,
-					Object:    relID,
-				}
-				qps = append(qps, qp)
-			}
+	return qps
+}
+
+// inferOne infers the relationship of a parsed item for a single inference.
+// It reports false when the item doesn't have the relationship.
+func inferOne(item parsedItem, inf pattern.Inference, ids idStrategies) (QuadParam, bool) {
+
+	// Check for the relevant fields in the item. A related type whose ID
+	// strategy is composed of several fields is referred to by a field for
+	// each of them, separated by commas.
+	fields := strings.Split(inf.RelIDField, ",")
+	values := make([]interface{}, len(fields))
+	for i, f := range fields {
+
+		// If the rel field is missing or empty, do not create the quad.
+		v, ok := idstrategy.Lookup(item.itemData, strings.TrimSpace(f))
+		if !ok {
+			return QuadParam{}, false
+		}
+		values[i] = v
+	}
+
+	// If we are using source ids and rel types, compose the id with the
+	// strategy of the related type.
+	var relID string
+	if inf.RelType != "" {
+		var err error
+		if relID, err = ids.forType(inf.RelType).IDFromValues(values); err != nil {
+			return QuadParam{}, false
+		}
+	} else {
+		if len(values) != 1 {
+			return QuadParam{}, false
 		}
+		relID = fmt.Sprintf("%v", values[0])
 	}
 
-	return qps, nil
+	// Add the relationship parameters.
+	switch inf.Direction {
+	case inString:
+		return QuadParam{
+			Subject:   relID,
+			Predicate: inf.Predicate,
+			Object:    item.itemID,
+		}, true
+	case outString:
+		return QuadParam{
+			Subject:   item.itemID,
+			Predicate: inf.Predicate,
+			Object:    relID,
+		}, true
+	}
+
+	return QuadParam{}, false
 }
 
 // parsedItem contains the structure of the item.
 type parsedItem struct {
 	itemID   string
 	itemType string
-	itemData map[string]string
+	itemData map[string]interface{}
 }
 
 // itemParse parses a general map[string]interface{} into a parsedItem value,
@@ -232,17 +290,16 @@ func itemParse(itemIn map[string]interface{}) (parsedItem, error) {
 		return parsedItem{}, ErrItemData
 	}
 
-	itemData := make(map[string]string)
-	for k, v := range dataMap {
-
-		vString, ok := v.(string)
-		if !ok || vString == "" {
-			continue
+	// The data must have a value related IDs can be composed from.
+	var hasValue bool
+	for k := range dataMap {
+		if _, ok := idstrategy.Lookup(dataMap, k); ok {
+			hasValue = true
+			break
 		}
-		itemData[k] = vString
 	}
 
-	if len(itemData) == 0 {
+	if !hasValue {
 		return parsedItem{}, ErrItemData
 	}
 
@@ -250,7 +307,35 @@ func itemParse(itemIn map[string]interface{}) (parsedItem, error) {
 	itemOut := parsedItem{
 		itemID:   itemID,
 		itemType: itemType,
-		itemData: itemData,
+		itemData: dataMap,
 	}
 	return itemOut, nil
 }
+
+// idStrategies contains the ID strategies of the item types by type.
+type idStrategies map[string]idstrategy.Strategy
+
+// strategies returns the registered ID strategies by type.
+func strategies(context interface{}, db *db.DB) (idStrategies, error) {
+	ss, err := idstrategy.GetAll(context, db)
+	if err != nil && err != idstrategy.ErrNotFound {
+		return nil, err
+	}
+
+	ids := make(idStrategies, len(ss))
+	for _, s := range ss {
+		ids[s.Type] = s
+	}
+
+	return ids, nil
+}
+
+// forType returns the ID strategy of the type or the default strategy when
+// none is registered.
+func (ids idStrategies) forType(itemType string) *idstrategy.Strategy {
+	if s, exists := ids[itemType]; exists {
+		return &s
+	}
+
+	return idstrategy.Default(itemType)
+}
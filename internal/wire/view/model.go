@@ -17,13 +17,31 @@ func init() {
 
 //==============================================================================
 
+// Set of values for joining the branches of a path segment.
+const (
+	JoinAnd = "and" // Items reached through every branch.
+	JoinOr  = "or"  // Items reached through any branch.
+)
+
+// MaxDepth is the largest number of times a path segment can follow its
+// predicate.
+const MaxDepth = 10
+
 // PathSegment contains metadata about a segment of a path,
-// which path partially defines a View.
+// which path partially defines a View. A segment either follows a predicate
+// or joins the items reached through its branches. Each branch is a path that
+// starts from the items reached by the previous segment.
 type PathSegment struct {
-	Level     int    `bson:"level" json:"level" validate:"required,min=1"`
-	Direction string `bson:"direction" json:"direction" validate:"required,min=2"`
-	Predicate string `bson:"predicate" json:"predicate" validate:"required,min=1"`
-	Tag       string `bson:"tag,omitempty" json:"tag,omitempty"`
+	Level     int      `bson:"level" json:"level" validate:"required,min=1"`
+	Direction string   `bson:"direction" json:"direction"`
+	Predicate string   `bson:"predicate" json:"predicate"`
+	Tag       string   `bson:"tag,omitempty" json:"tag,omitempty"`
+	Types     []string `bson:"types,omitempty" json:"types,omitempty"`                  // Item types the segment must end on.
+	Optional  bool     `bson:"optional,omitempty" json:"optional,omitempty"`            // Items can also skip the segment.
+	Depth     int      `bson:"depth,omitempty" json:"depth,omitempty" validate:"min=0"` // Follow the predicate 1 to Depth times.
+	Limit     int      `bson:"limit,omitempty" json:"limit,omitempty" validate:"min=0"` // Most items kept after the segment.
+	Join      string   `bson:"join,omitempty" json:"join,omitempty"`                    // How the branches are joined, and or or.
+	Branches  []Path   `bson:"branches,omitempty" json:"branches,omitempty"`            // Paths joined by the segment.
 }
 
 // Path is a slice of PathSegment.
@@ -34,6 +52,75 @@ func (ps *PathSegment) Validate() error {
 	if err := validate.Struct(ps); err != nil {
 		return err
 	}
+
+	if ps.Depth > MaxDepth {
+		return fmt.Errorf("Path segment depth can't be more than %d", MaxDepth)
+	}
+
+	// A segment joining branches doesn't follow a predicate itself.
+	if len(ps.Branches) > 0 {
+		if ps.Predicate != "" || ps.Direction != "" {
+			return fmt.Errorf("Path segment with branches can't include a predicate")
+		}
+
+		if ps.Depth != 0 || ps.Optional {
+			return fmt.Errorf("Path segment with branches can't include a depth or be optional")
+		}
+
+		switch ps.Join {
+		case JoinAnd, JoinOr:
+		default:
+			return fmt.Errorf("Path segment includes undefined join")
+		}
+
+		for _, branch := range ps.Branches {
+			if err := branch.Validate(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if ps.Join != "" {
+		return fmt.Errorf("Path segment without branches can't include a join")
+	}
+
+	if ps.Predicate == "" {
+		return fmt.Errorf("Path segment includes no predicate")
+	}
+
+	// Ensure that the Direction value is either "in" or "out."
+	switch ps.Direction {
+	case "in", "out":
+	default:
+		return fmt.Errorf("Path segment includes undefined direction")
+	}
+
+	return nil
+}
+
+// Validate checks the segments of the Path value for consistency and that
+// their levels run from 1 without gaps.
+func (slice Path) Validate() error {
+	if len(slice) == 0 {
+		return fmt.Errorf("Path includes no segments")
+	}
+
+	levels := make(map[int]bool, len(slice))
+	for _, segment := range slice {
+		if err := segment.Validate(); err != nil {
+			return err
+		}
+		levels[segment.Level] = true
+	}
+
+	for level := 1; level <= len(slice); level++ {
+		if !levels[level] {
+			return fmt.Errorf("Invalid view path level, missing level %d", level)
+		}
+	}
+
 	return nil
 }
 
@@ -69,20 +156,5 @@ func (v *View) Validate() error {
 	}
 
 	// Validate each of the PathSegment values in the View.
-	for _, segment := range v.Path {
-
-		// Validate the PathSegment using the validator.
-		if err := segment.Validate(); err != nil {
-			return err
-		}
-
-		// Ensure that the Direction value is either "in" or "out."
-		switch segment.Direction {
-		case "in", "out":
-			continue
-		default:
-			return fmt.Errorf("Path segment includes undefined direction")
-		}
-	}
-	return nil
+	return v.Path.Validate()
 }
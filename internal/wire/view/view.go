@@ -6,12 +6,21 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/platform/history"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
-// Collection is the Mongo collection containing view metadata.
-const Collection = "views"
+// Contains the name of Mongo collections.
+const (
+	Collection        = "views"         // Collection containing view metadata.
+	CollectionHistory = "views_history" // Collection containing the history of each view.
+)
+
+func init() {
+	collections.Protect(Collection)
+}
 
 // ErrNotFound is an error variable thrown when no results are returned from a Mongo query.
 var ErrNotFound = errors.New("Set Not found")
@@ -38,6 +47,28 @@ func Upsert(context interface{}, db *db.DB, view *View) error {
 		return err
 	}
 
+	// Add this view to the beginning of the history. The history record is
+	// created by the upsert if this view is new.
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"name": view.Name}
+		qu := bson.M{
+			"$push": bson.M{
+				"views": bson.M{
+					"$each":     []*View{view},
+					"$position": 0,
+				},
+			},
+		}
+
+		log.Dev(context, "Upsert", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(qu))
+		_, err := c.Upsert(q, qu)
+		return err
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
 	log.Dev(context, "Upsert", "Completed")
 	return nil
 }
@@ -87,6 +118,57 @@ func GetByName(context interface{}, db *db.DB, name string) (*View, error) {
 	return &view, nil
 }
 
+// GetLastHistoryByName gets the last written View within the history.
+func GetLastHistoryByName(context interface{}, db *db.DB, name string) (*View, error) {
+	log.Dev(context, "GetLastHistoryByName", "Started : Name[%s]", name)
+
+	// Get the newest view from the history.
+	var result struct {
+		Views []View `bson:"views"`
+	}
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": name}
+		proj := bson.M{"views": bson.M{"$slice": 1}}
+		log.Dev(context, "GetLastHistoryByName", "MGO : db.%s.find(%s,%s)", c.Name, mongo.Query(q), mongo.Query(proj))
+		return c.Find(q).Select(proj).One(&result)
+	}
+	if err := db.ExecuteMGO(context, CollectionHistory, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+		log.Error(context, "GetLastHistoryByName", err, "Completed")
+		return nil, err
+	}
+
+	if len(result.Views) == 0 {
+		log.Error(context, "GetLastHistoryByName", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetLastHistoryByName", "Completed")
+	return &result.Views[0], nil
+}
+
+// History describes the history kept for views so their revisions can
+// be listed, compared and restored by name.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "views",
+	Selector: func(name string) (bson.M, error) {
+		return bson.M{"name": name}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var view View
+		if err := raw.Unmarshal(&view); err != nil {
+			return nil, err
+		}
+		return &view, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*View))
+	},
+}
+
 // Delete removes a view from from Mongo.
 func Delete(context interface{}, db *db.DB, name string) error {
 	log.Dev(context, "Delete", "Started : Name[%s]", name)
@@ -155,3 +155,72 @@ func TestGetAll(t *testing.T) {
 		}
 	}
 }
+
+// TestValidatePath tests if view paths with branches, depths and limits are
+// validated.
+func TestValidatePath(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	seg := func(level int) view.PathSegment {
+		return view.PathSegment{Level: level, Direction: "in", Predicate: "on"}
+	}
+
+	branches := view.PathSegment{
+		Level:    1,
+		Join:     view.JoinAnd,
+		Branches: []view.Path{{seg(1)}, {seg(1), seg(2)}},
+	}
+
+	deep := seg(1)
+	deep.Depth = view.MaxDepth + 1
+
+	noJoin := branches
+	noJoin.Join = ""
+
+	withPredicate := branches
+	withPredicate.Predicate = "on"
+
+	badBranch := branches
+	badBranch.Branches = []view.Path{{seg(2)}}
+
+	deepBranches := branches
+	deepBranches.Depth = 2
+
+	optionalBranches := branches
+	optionalBranches.Optional = true
+
+	paths := []struct {
+		name  string
+		path  view.Path
+		valid bool
+	}{
+		{"branches", view.Path{branches, seg(2)}, true},
+		{"depth over the maximum", view.Path{deep}, false},
+		{"branches without a join", view.Path{noJoin}, false},
+		{"branches with a predicate", view.Path{withPredicate}, false},
+		{"branch with a missing level", view.Path{badBranch}, false},
+		{"branches with a depth", view.Path{deepBranches}, false},
+		{"optional branches", view.Path{optionalBranches}, false},
+		{"missing level", view.Path{seg(1), seg(3)}, false},
+	}
+
+	t.Log("Given the need to validate view paths.")
+	{
+		for _, p := range paths {
+			t.Logf("\tWhen validating a path with %s", p.name)
+			{
+				v := view.View{Name: "VTEST_view", Collection: "items", StartType: "coral_asset", Path: p.path}
+				err := v.Validate()
+
+				if p.valid && err != nil {
+					t.Fatalf("\t%s\tShould be valid : %s", tests.Failed, err)
+				}
+				if !p.valid && err == nil {
+					t.Fatalf("\t%s\tShould be invalid.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould be valid : %v", tests.Success, p.valid)
+			}
+		}
+	}
+}
@@ -56,5 +56,9 @@ func Remove(context interface{}, db *db.DB, pattern string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, view.CollectionHistory, f); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -10,6 +10,7 @@ import (
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
 	"github.com/cayleygraph/cayley"
 	"github.com/cayleygraph/cayley/graph"
@@ -33,9 +34,11 @@ const (
 	outString = "out"
 )
 
-// Result represents what a user will receive after generating a view.
+// Result represents what a user will receive after generating a view. Next
+// is the cursor for reading the next page of items when there are more.
 type Result struct {
 	Results interface{} `json:"results"`
+	Next    string      `json:"next,omitempty"`
 }
 
 // errResult returns a Result value with an error message.
@@ -47,12 +50,22 @@ func errResult(err error) *Result {
 	return &result
 }
 
-// ViewParams represents how the View will be generated and persisted.
+// ViewParams represents how the View will be generated and persisted. Sort
+// contains item fields like data.created and a field starting with - is
+// sorted in descending order. Fields contains the data fields to return for
+// each item. A Limit pages the items and the Next cursor of the result reads
+// the following page when provided as the Cursor.
 type ViewParams struct {
-	ViewName          string `json:"view_name"`
-	ItemKey           string `json:"item_key"`
-	ResultsCollection string `json:"results_collection"`
-	BufferLimit       int    `json:"buffer_limit"`
+	ViewName          string   `json:"view_name"`
+	ItemKey           string   `json:"item_key"`
+	ResultsCollection string   `json:"results_collection"`
+	BufferLimit       int      `json:"buffer_limit"`
+	Sort              []string `json:"sort,omitempty"`   // Item fields to sort by.
+	Fields            []string `json:"fields,omitempty"` // Data fields to return.
+	Skip              int      `json:"skip,omitempty"`   // Number of items to skip.
+	Limit             int      `json:"limit,omitempty"`  // Number of items per page.
+	Cursor            string   `json:"cursor,omitempty"` // Cursor of the page to read.
+	Count             bool     `json:"count,omitempty"`  // Only count the items.
 }
 
 //==============================================================================
@@ -75,14 +88,39 @@ func Execute(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, viewPara
 	}
 
 	// Translate the view path into a graph query path.
-	graphPath, err := viewPathToGraphPath(v, viewParams.ItemKey, graphDB)
+	graphPath, filters, err := viewPathToGraphPath(v, viewParams.ItemKey, graphDB)
 	if err != nil {
 		log.Error(context, "Execute", err, "Completed")
 		return errResult(err), err
 	}
 
 	// Retrieve the item IDs for the view.
-	ids, err := viewIDs(v, graphPath, graphDB)
+	ids, err := viewIDs(context, mgoDB, v, graphPath, filters, graphDB)
+	if err != nil {
+		log.Error(context, "Execute", err, "Completed")
+		return errResult(err), err
+	}
+
+	// Only count the items in the view, if asked for.
+	if viewParams.Count {
+		if viewParams.ResultsCollection != "" {
+			err := errors.New("View count can't be saved to a results collection")
+			log.Error(context, "Execute", err, "Completed")
+			return errResult(err), err
+		}
+
+		n, err := viewCount(context, mgoDB, v, ids)
+		if err != nil {
+			log.Error(context, "Execute", err, "Completed")
+			return errResult(err), err
+		}
+
+		log.Dev(context, "Execute", "Completed : Count[%d]", n)
+		return &Result{Results: bson.M{"number_of_results": n}}, nil
+	}
+
+	// Prepare the query for the page of items in the view.
+	q, err := newViewQuery(viewParams, ids)
 	if err != nil {
 		log.Error(context, "Execute", err, "Completed")
 		return errResult(err), err
@@ -90,24 +128,34 @@ func Execute(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, viewPara
 
 	// Persist the items in the view, if an output Collection is provided.
 	if viewParams.ResultsCollection != "" {
-		if err := viewSave(context, mgoDB, v, viewParams, ids); err != nil {
+		n, next, err := viewSave(context, mgoDB, v, viewParams, q)
+		if err != nil {
 			log.Error(context, "Execute", err, "Completed")
 			return errResult(err), err
 		}
 		result := Result{
-			Results: bson.M{"number_of_results": len(ids)},
+			Results: bson.M{"number_of_results": n},
+			Next:    next,
 		}
 		return &result, nil
 	}
 
 	// Otherwise, gather the items in the view.
-	items, err := viewItems(context, mgoDB, v, ids)
+	items, err := viewItems(context, mgoDB, v, q)
+	if err != nil {
+		log.Error(context, "Execute", err, "Completed")
+		return errResult(err), err
+	}
+
+	items, next, err := q.page(viewParams, items)
 	if err != nil {
 		log.Error(context, "Execute", err, "Completed")
 		return errResult(err), err
 	}
+
 	result := Result{
 		Results: items,
+		Next:    next,
 	}
 
 	log.Dev(context, "Execute", "Completed")
@@ -116,21 +164,37 @@ func Execute(context interface{}, mgoDB *db.DB, graphDB *cayley.Handle, viewPara
 
 //==============================================================================
 
-// validateStartType verifies the start type of a view path.
+// validateStartType verifies the start type of a view path against the
+// relationships of the first segment. The first segments of the branches are
+// used when the first segment joins branches.
 func validateStartType(context interface{}, db *db.DB, v *view.View) error {
+	return validatePathStart(context, db, v.StartType, v.Path)
+}
+
+// validatePathStart verifies the start type against the relationship of the
+// first segment of the path.
+func validatePathStart(context interface{}, db *db.DB, startType string, p view.Path) error {
 
-	// Extract the first level relationship predicate.
-	var firstRel string
-	var firstDir string
-	for _, segment := range v.Path {
+	// Extract the first level segment.
+	var first view.PathSegment
+	for _, segment := range p {
 		if segment.Level == 1 {
-			firstRel = segment.Predicate
-			firstDir = segment.Direction
+			first = segment
+		}
+	}
+
+	// Every branch starts from the start type.
+	if len(first.Branches) > 0 {
+		for _, branch := range first.Branches {
+			if err := validatePathStart(context, db, startType, branch); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
 	// Get the relationship metadata.
-	rel, err := relationship.GetByPredicate(context, db, firstRel)
+	rel, err := relationship.GetByPredicate(context, db, first.Predicate)
 	if err != nil {
 		return err
 	}
@@ -138,7 +202,7 @@ func validateStartType(context interface{}, db *db.DB, v *view.View) error {
 	// Get the relevant item types based on the direction of the
 	// first relationship in the path.
 	var itemTypes []string
-	switch firstDir {
+	switch first.Direction {
 	case outString:
 		itemTypes = rel.SubjectTypes
 	case inString:
@@ -147,105 +211,328 @@ func validateStartType(context interface{}, db *db.DB, v *view.View) error {
 
 	// Validate the starting type provided in the view.
 	for _, itemType := range itemTypes {
-		if itemType == v.StartType {
+		if itemType == startType {
 			return nil
 		}
 	}
 
-	return fmt.Errorf("Start type %s does not match relationship subject types %v", v.StartType, itemTypes)
+	return fmt.Errorf("Start type %s does not match relationship subject types %v", startType, itemTypes)
 }
 
 // viewPathToGraphPath translates the path in a view into a "path"
-// utilized in graph queries.
-func viewPathToGraphPath(v *view.View, key string, graphDB *cayley.Handle) (*path.Path, error) {
+// utilized in graph queries. The items reached by the segments with types
+// are filtered once the results are read by viewIDs using the filters.
+func viewPathToGraphPath(v *view.View, key string, graphDB *cayley.Handle) (*path.Path, []typeFilter, error) {
+	var filters []typeFilter
+
+	build, err := pathBuilder(v.Path, &filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return build(cayley.StartPath(graphDB, quad.String(key))), filters, nil
+}
+
+// pathBuild builds the graph path for a view path continuing from the path
+// of the items it starts from. The path it continues from is not changed so
+// it can be continued more than once.
+type pathBuild func(from *path.Path) *path.Path
+
+// typeTag is the prefix of the tags of the segments with types.
+const typeTag = "_types_"
+
+// typeFilter contains the types the items reached by a segment must be of.
+// The items are tagged so the results can be filtered once they are read.
+type typeFilter struct {
+	tag   string
+	types []string
+	limit int
+}
+
+// pathBuilder returns the function that builds the graph path for the view
+// path. The filters of the segments with types are added to filters.
+func pathBuilder(p view.Path, filters *[]typeFilter) (pathBuild, error) {
 
 	// Sort the view Path value.
-	sort.Sort(v.Path)
+	sort.Sort(p)
 
 	// Loop over the path segments translating the path.
-	var graphPath *path.Path
+	builds := make([]pathBuild, 0, len(p))
 	level := 1
-	for _, segment := range v.Path {
+	for _, segment := range p {
 
 		// Check that the level is the level we expect (i.e., that the levels
 		// are in order)
 		if level != segment.Level {
 			err := fmt.Errorf("Invalid view path level, expected %d but seeing %d", level, segment.Level)
-			return graphPath, err
+			return nil, err
 		}
 
-		// Initialize the path, if we are on level 1.
-		if level == 1 {
+		build, err := segmentBuilder(segment, filters)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, build)
 
-			// Add the first level relationship.
-			switch segment.Direction {
-			case inString:
-				graphPath = cayley.StartPath(graphDB, quad.String(key)).In(quad.String(segment.Predicate))
-			case outString:
-				graphPath = cayley.StartPath(graphDB, quad.String(key)).Out(quad.String(segment.Predicate))
-			}
+		level++
+	}
 
-			// Add the tag, if present.
-			if segment.Tag != "" {
-				graphPath = graphPath.Clone().Tag(segment.Tag)
-			}
+	build := func(from *path.Path) *path.Path {
+		for _, b := range builds {
+			from = b(from)
+		}
+		return from
+	}
 
-			level++
-			continue
+	return build, nil
+}
+
+// segmentBuilder returns the function that builds the graph path for the
+// path segment. The path it continues from is cloned for each branch and
+// depth so the segments before it are only built once.
+func segmentBuilder(segment view.PathSegment, filters *[]typeFilter) (pathBuild, error) {
+
+	// Build the branches ahead of time so their errors are returned.
+	branches := make([]pathBuild, len(segment.Branches))
+	for i, branch := range segment.Branches {
+		var err error
+		if branches[i], err = pathBuilder(branch, filters); err != nil {
+			return nil, err
 		}
+	}
+
+	// The items of the types the segment must end on are kept once the
+	// results are read, which also applies the limit of the segment.
+	var typesTag string
+	if len(segment.Types) > 0 {
+		typesTag = fmt.Sprintf("%s%d", typeTag, len(*filters))
+		*filters = append(*filters, typeFilter{
+			tag:   typesTag,
+			types: segment.Types,
+			limit: segment.Limit,
+		})
+	}
 
-		// Add the relationship.
+	// hop adds the relationship to the path.
+	hop := func(graphPath *path.Path) *path.Path {
 		switch segment.Direction {
 		case inString:
-			graphPath = graphPath.Clone().In(quad.String(segment.Predicate))
+			return graphPath.In(quad.String(segment.Predicate))
 		case outString:
-			graphPath = graphPath.Clone().Out(quad.String(segment.Predicate))
+			return graphPath.Out(quad.String(segment.Predicate))
+		}
+		return graphPath
+	}
+
+	minDepth, maxDepth := 1, 1
+	if segment.Depth > 1 {
+		maxDepth = segment.Depth
+	}
+	if segment.Optional {
+		minDepth = 0
+	}
+
+	build := func(from *path.Path) *path.Path {
+		var graphPath *path.Path
+
+		switch {
+
+		// Join the items reached through the branches.
+		case len(branches) > 0:
+			graphPath = branches[0](from.Clone())
+			for _, branch := range branches[1:] {
+				switch segment.Join {
+				case view.JoinAnd:
+					graphPath = graphPath.And(branch(from.Clone()))
+				case view.JoinOr:
+					graphPath = graphPath.Or(branch(from.Clone()))
+				}
+			}
+
+		// Follow the relationship the range of times.
+		default:
+			for depth := minDepth; depth <= maxDepth; depth++ {
+				next := from.Clone()
+				for i := 0; i < depth; i++ {
+					next = hop(next)
+				}
+
+				if graphPath == nil {
+					graphPath = next
+					continue
+				}
+				graphPath = graphPath.Or(next)
+			}
+		}
+
+		// Tag the items that must be of the types, if present.
+		if typesTag != "" {
+			graphPath = graphPath.Tag(typesTag)
 		}
 
 		// Add the tag, if present.
 		if segment.Tag != "" {
-			graphPath = graphPath.Clone().Tag(segment.Tag)
+			graphPath = graphPath.Tag(segment.Tag)
 		}
 
-		level++
+		// Limit the items, if a limit is present.
+		if segment.Limit > 0 && typesTag == "" {
+			graphPath = graphPath.Limit(int64(segment.Limit))
+		}
+
+		return graphPath
 	}
 
-	return graphPath, nil
+	return build, nil
 }
 
-// viewIDs retrieves the item IDs associated with the view.
-func viewIDs(v *view.View, path *path.Path, graphDB *cayley.Handle) ([]string, error) {
+// filterTypes removes the results whose item reached by a segment with types
+// is not of the types. Only the first items up to the limit of the segment
+// are kept. Results without the tag of a segment reached the end of the path
+// through another branch and are kept.
+func filterTypes(context interface{}, db *db.DB, filters []typeFilter, results []map[string]string) ([]map[string]string, error) {
+
+	// Get the types of the items reached by the segments.
+	found := make(map[string]bool)
+	var ids []string
+	for _, result := range results {
+		for _, f := range filters {
+			if id, ok := result[f.tag]; ok && !found[id] {
+				found[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	types, err := itemTypes(context, db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range filters {
+		allowed := make(map[string]bool, len(f.types))
+		for _, t := range f.types {
+			allowed[t] = true
+		}
+
+		kept := make(map[string]bool)
+		j := 0
+		for _, result := range results {
+			if id, ok := result[f.tag]; ok {
+				if !allowed[types[id]] {
+					continue
+				}
+
+				if !kept[id] {
+					if f.limit > 0 && len(kept) == f.limit {
+						continue
+					}
+					kept[id] = true
+				}
+			}
+
+			results[j] = result
+			j++
+		}
+		results = results[:j]
+	}
+
+	return results, nil
+}
+
+// itemTypes retrieves the types of the items by item ID. Nodes that are not
+// items have no type.
+func itemTypes(context interface{}, db *db.DB, ids []string) (map[string]string, error) {
+	types := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return types, nil
+	}
+
+	var items []item.Item
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"item_id": bson.M{"$in": ids}}
+		log.Dev(context, "itemTypes", "MGO : db.%s.find({\"item_id\": {\"$in\": [%d ids]}})", c.Name, len(ids))
+		return c.Find(q).Select(bson.M{"item_id": 1, "type": 1}).All(&items)
+	}
+
+	if err := db.ExecuteMGO(context, item.Collection, f); err != nil {
+		return nil, err
+	}
+
+	for _, it := range items {
+		types[it.ID] = it.Type
+	}
+
+	return types, nil
+}
+
+// pathTags returns the tags in the view path including the tags in the
+// branches of its segments.
+func pathTags(p view.Path) []string {
+	var tags []string
+	for _, segment := range p {
+		if segment.Tag != "" {
+			tags = append(tags, segment.Tag)
+		}
+		for _, branch := range segment.Branches {
+			tags = append(tags, pathTags(branch)...)
+		}
+	}
+	return tags
+}
+
+// viewIDs retrieves the item IDs associated with the view. The results are
+// filtered by the types of the segments first.
+func viewIDs(context interface{}, mgoDB *db.DB, v *view.View, path *path.Path, filters []typeFilter, graphDB *cayley.Handle) ([]string, error) {
 
 	// Build the Cayley iterator.
 	it := path.BuildIterator()
 	it, _ = it.Optimize()
 	defer it.Close()
 
-	// Extract any tags in the View value.
-	var viewTags []string
-	for _, segment := range v.Path {
-		if segment.Tag != "" {
-			viewTags = append(viewTags, segment.Tag)
-		}
+	// Extract any tags in the View value plus the tags of the segments
+	// with types.
+	viewTags := pathTags(v.Path)
+	tags := append([]string(nil), viewTags...)
+	for _, f := range filters {
+		tags = append(tags, f.tag)
 	}
 
-	// Retrieve the end path and tagged item IDs.
-	var ids []string
+	// Retrieve the tagged item IDs of each result.
+	var results []map[string]string
 	for it.Next() {
 
 		// Tag the results.
 		resultTags := make(map[string]graph.Value)
 		it.TagResults(resultTags)
 
-		// Extract the tagged item IDs.
-		for _, tag := range viewTags {
+		result := make(map[string]string, len(tags))
+		for _, tag := range tags {
 			if t, ok := resultTags[tag]; ok {
-				ids = append(ids, quad.NativeOf(graphDB.NameOf(t)).(string))
+				result[tag] = quad.NativeOf(graphDB.NameOf(t)).(string)
 			}
 		}
+		results = append(results, result)
 	}
 	if it.Err() != nil {
-		return ids, it.Err()
+		return nil, it.Err()
+	}
+
+	if len(filters) > 0 {
+		var err error
+		if results, err = filterTypes(context, mgoDB, filters, results); err != nil {
+			return nil, err
+		}
+	}
+
+	// Extract the tagged item IDs.
+	var ids []string
+	for _, result := range results {
+		for _, tag := range viewTags {
+			if id, ok := result[tag]; ok {
+				ids = append(ids, id)
+			}
+		}
 	}
 
 	// Remove duplicates.
@@ -264,67 +551,96 @@ func viewIDs(v *view.View, path *path.Path, graphDB *cayley.Handle) ([]string, e
 }
 
 // viewSave retrieve items for a view and saves those items to a new collection.
-func viewSave(context interface{}, mgoDB *db.DB, v *view.View, viewParams *ViewParams, ids []string) error {
+// It returns the number of items saved and the cursor for the next page.
+func viewSave(context interface{}, mgoDB *db.DB, v *view.View, viewParams *ViewParams, q *viewQuery) (int, string, error) {
 
 	// Determine the buffer limit that will be used for saving this view.
 	if viewParams.BufferLimit != 0 {
 		bufferLimit = viewParams.BufferLimit
 	}
 
+	// The _id of the items is not saved so items can be saved again.
+	proj := q.proj
+	if proj == nil {
+		proj = bson.M{"_id": 0}
+	}
+
 	// Form the query.
-	q := bson.M{"item_id": bson.M{"$in": ids}}
-	results, err := mgoDB.BatchedQueryMGO(context, v.Collection, q)
+	col, err := mgoDB.CollectionMGO(context, v.Collection)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
+	results := col.Find(q.filter).Sort(q.sort()...).Skip(q.skip).Limit(q.fetch()).Select(proj).Iter()
 
 	// Set up a Bulk upsert.
 	tx, err := mgoDB.BulkOperationMGO(context, viewParams.ResultsCollection)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 
-	// Iterate over the view items.
-	var queuedDocs int
-	var result item.Item
-	for results.Next(&result) {
+	// Iterate over the view items. A paged query reads an extra item to know
+	// if there is another page.
+	var queuedDocs, saved int
+	var last bson.M
+	var more bool
+	for {
+		var result bson.M
+		if !results.Next(&result) {
+			break
+		}
+
+		if q.paged && saved == q.limit {
+			more = true
+			break
+		}
 
 		// Queue the upsert of the result.
-		tx.Upsert(bson.M{"item_id": result.ID}, result)
+		tx.Upsert(bson.M{"item_id": result["item_id"]}, result)
 		queuedDocs++
+		saved++
+		last = result
 
 		// If the queued documents for upsert have reached the buffer limit,
 		// run the bulk upsert and re-initialize the bulk operation.
 		if queuedDocs >= bufferLimit {
 			if _, err := tx.Run(); err != nil {
-				return err
+				return 0, "", err
 			}
 			tx, err = mgoDB.BulkOperationMGO(context, viewParams.ResultsCollection)
 			if err != nil {
-				return err
+				return 0, "", err
 			}
 			queuedDocs = 0
 		}
 	}
 	if err := results.Close(); err != nil {
-		return err
+		return 0, "", err
 	}
 
 	// Run the bulk operation for any remaining queued documents.
 	if _, err := tx.Run(); err != nil {
-		return err
+		return 0, "", err
 	}
 
-	return nil
+	// Create the cursor when there is another page.
+	var next string
+	if more {
+		if next, err = q.cursor(viewParams, last); err != nil {
+			return 0, "", err
+		}
+	}
+
+	return saved, next, nil
 }
 
-// viewItems retrieves the items corresponding to the provided list of item IDs.
-func viewItems(context interface{}, db *db.DB, v *view.View, ids []string) ([]bson.M, error) {
+// viewItems retrieves the page of items corresponding to the query.
+func viewItems(context interface{}, db *db.DB, v *view.View, q *viewQuery) ([]bson.M, error) {
 
 	// Form the query.
 	var results []bson.M
 	f := func(c *mgo.Collection) error {
-		return c.Find(bson.M{"item_id": bson.M{"$in": ids}}).All(&results)
+		log.Dev(context, "viewItems", "MGO : db.%s.find(%s,%s).sort(%v).skip(%d).limit(%d)", c.Name, mongo.Query(q.filter), mongo.Query(q.proj), q.sort(), q.skip, q.fetch())
+		return c.Find(q.filter).Sort(q.sort()...).Skip(q.skip).Limit(q.fetch()).Select(q.proj).All(&results)
 	}
 
 	// Execute the query.
@@ -337,3 +653,22 @@ func viewItems(context interface{}, db *db.DB, v *view.View, ids []string) ([]bs
 
 	return results, nil
 }
+
+// viewCount counts the items corresponding to the provided list of item IDs.
+func viewCount(context interface{}, db *db.DB, v *view.View, ids []string) (int, error) {
+	var n int
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"item_id": bson.M{"$in": ids}}
+		log.Dev(context, "viewCount", "MGO : db.%s.find(%s).count()", c.Name, mongo.Query(q))
+
+		var err error
+		n, err = c.Find(q).Count()
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, v.Collection, f); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -241,6 +241,141 @@ func TestExecuteView(t *testing.T) {
 	}
 }
 
+// TestExecuteJoinedView tests the generation of views with branches, type
+// filters, limits and optional segments.
+func TestExecuteJoinedView(t *testing.T) {
+	db, store := setup(t)
+	defer teardown(t, db)
+
+	views := []struct {
+		name  string
+		count int
+	}{
+		{wirePrefix + "commenters", 3},
+		{wirePrefix + "asset or comments", 4},
+	}
+
+	t.Log("Given the need to generate views with richer paths.")
+	{
+		for _, vw := range views {
+			t.Logf("\tWhen using the view %q with the relationship and item fixtures.", vw.name)
+			{
+				viewParams := wire.ViewParams{
+					ViewName: vw.name,
+					ItemKey:  "ITEST_c1b2bbfe-af9f-4903-8777-bd47c4d5b20a",
+				}
+
+				result, err := wire.Execute(tests.Context, db, store, &viewParams)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to generate the view : %s", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould be able to generate the view", tests.Success)
+
+				items, ok := result.Results.([]bson.M)
+				if !ok || len(items) != vw.count {
+					t.Fatalf("\t%s\tShould be able to get %d items in the view : %d", tests.Failed, vw.count, len(items))
+				}
+				t.Logf("\t%s\tShould be able to get %d items in the view.", tests.Success, vw.count)
+			}
+		}
+	}
+}
+
+// TestExecutePagedView tests the generation of a view one page at a time with
+// a projection of the data fields and the counting of the items in the view.
+func TestExecutePagedView(t *testing.T) {
+	db, store := setup(t)
+	defer teardown(t, db)
+
+	t.Log("Given the need to page through a view.")
+	{
+		t.Log("\tWhen using the view, relationship, and item fixtures.")
+		{
+			viewParams := wire.ViewParams{
+				ViewName: wirePrefix + "thread",
+				ItemKey:  "ITEST_c1b2bbfe-af9f-4903-8777-bd47c4d5b20a",
+				Count:    true,
+			}
+
+			result, err := wire.Execute(tests.Context, db, store, &viewParams)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to count the view : %s", tests.Failed, err)
+			}
+
+			msg, ok := result.Results.(bson.M)
+			if !ok || msg["number_of_results"] != 5 {
+				t.Fatalf("\t%s\tShould count 5 items in the view : %v", tests.Failed, result.Results)
+			}
+			t.Logf("\t%s\tShould count 5 items in the view.", tests.Success)
+
+			viewParams.Count = false
+			viewParams.Fields = []string{"body"}
+			viewParams.Limit = 2
+
+			// Items without the sort field sort before the others.
+			for _, sort := range [][]string{{"-type"}, {"data.missing"}, {"-data.missing"}} {
+				viewParams.Sort = sort
+				viewParams.Cursor = ""
+
+				var pages int
+				ids := make(map[interface{}]bool)
+				for {
+					result, err := wire.Execute(tests.Context, db, store, &viewParams)
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to generate the page : %s", tests.Failed, err)
+					}
+					pages++
+
+					items, ok := result.Results.([]bson.M)
+					if !ok || len(items) > 2 {
+						t.Fatalf("\t%s\tShould get at most 2 items in a page : %v", tests.Failed, result.Results)
+					}
+
+					for _, it := range items {
+						ids[it["item_id"]] = true
+
+						data, _ := it["data"].(bson.M)
+						for fld := range data {
+							if fld != "body" {
+								t.Fatalf("\t%s\tShould only get the body of the data : %v", tests.Failed, data)
+							}
+						}
+					}
+
+					if result.Next == "" {
+						break
+					}
+					viewParams.Cursor = result.Next
+				}
+
+				if pages != 3 || len(ids) != 5 {
+					t.Fatalf("\t%s\tShould get 5 items in 3 pages sorted by %v : %d %d", tests.Failed, sort, len(ids), pages)
+				}
+				t.Logf("\t%s\tShould get 5 items in 3 pages sorted by %v.", tests.Success, sort)
+			}
+
+			t.Log("\tWhen using a cursor with another sort.")
+			{
+				viewParams.Sort = []string{"-type"}
+				viewParams.Cursor = ""
+
+				result, err := wire.Execute(tests.Context, db, store, &viewParams)
+				if err != nil || result.Next == "" {
+					t.Fatalf("\t%s\tShould be able to generate the first page : %v", tests.Failed, err)
+				}
+
+				viewParams.Sort = []string{"type"}
+				viewParams.Cursor = result.Next
+
+				if _, err := wire.Execute(tests.Context, db, store, &viewParams); err != wire.ErrInvalidCursor {
+					t.Fatalf("\t%s\tShould reject the cursor : %v", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould reject the cursor.", tests.Success)
+			}
+		}
+	}
+}
+
 // TestExecuteBackwardsView tests the generation of a view with multiple
 // out direction relationships, opting not to persist the view.
 func TestExecuteBackwardsView(t *testing.T) {
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/ardanlabs/kit/db"
+	"github.com/coralproject/shelf/internal/wire"
 	"github.com/coralproject/shelf/internal/wire/pattern"
 	"github.com/coralproject/shelf/internal/wire/relationship"
 	"github.com/coralproject/shelf/internal/wire/view"
@@ -82,7 +83,7 @@ func Add(context interface{}, db *db.DB, rels []relationship.Relationship, views
 	return nil
 }
 
-// Remove removes relationships, views, and patterns in Mongo that match a given pattern.
+// Remove removes relationships, views, patterns and backfills in Mongo that match a given pattern.
 func Remove(context interface{}, db *db.DB, prefix string) error {
 	f := func(c *mgo.Collection) error {
 		q := bson.M{"predicate": bson.RegEx{Pattern: prefix}}
@@ -94,6 +95,10 @@ func Remove(context interface{}, db *db.DB, prefix string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, relationship.CollectionHistory, f); err != nil {
+		return err
+	}
+
 	f = func(c *mgo.Collection) error {
 		q := bson.M{"name": bson.RegEx{Pattern: prefix}}
 		_, err := c.RemoveAll(q)
@@ -104,6 +109,10 @@ func Remove(context interface{}, db *db.DB, prefix string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, view.CollectionHistory, f); err != nil {
+		return err
+	}
+
 	f = func(c *mgo.Collection) error {
 		q := bson.M{"type": bson.RegEx{Pattern: prefix}}
 		_, err := c.RemoveAll(q)
@@ -114,5 +123,19 @@ func Remove(context interface{}, db *db.DB, prefix string) error {
 		return err
 	}
 
+	if err := db.ExecuteMGO(context, pattern.CollectionHistory, f); err != nil {
+		return err
+	}
+
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"_id": bson.RegEx{Pattern: prefix}}
+		_, err := c.RemoveAll(q)
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, wire.CollectionBackfill, f); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,105 @@
+package xenia
+
+import (
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/audit"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// auditing reports if the executions of sets are recorded.
+var auditing = true
+
+// SetAudit turns the recording of set executions into the audit
+// collection on or off.
+func SetAudit(on bool) {
+	auditing = on
+}
+
+// Call contains the details of who is executing a set and how. Masks names
+// the collection whose masks apply to the results read from a collection
+// when it holds a copy of the documents of another collection.
+type Call struct {
+	Caller string            // Identity of the caller, empty when not known.
+	Page   query.Page        // Page of the results to return.
+	Masks  map[string]string // Collection whose masks apply by the collection read.
+}
+
+//==============================================================================
+
+// auditExec records the execution of the set. Failing to record the
+// execution does not fail the execution so errors are only logged.
+func auditExec(context interface{}, db *db.DB, set *query.Set, vars map[string]string, call Call, started time.Time, status string, queries []audit.Query, errMsg string) {
+	if !auditing {
+		return
+	}
+
+	params := make(map[string]string, len(vars))
+	for k, v := range vars {
+		params[k] = v
+	}
+
+	if queries == nil {
+		queries = []audit.Query{}
+	}
+
+	r := audit.Record{
+		Set:      set.Name,
+		Caller:   call.Caller,
+		Params:   params,
+		Started:  started,
+		Duration: millis(time.Since(started)),
+		Cache:    status,
+		Queries:  queries,
+		Error:    errMsg,
+	}
+
+	if err := audit.Add(context, db, r); err != nil {
+		log.Error(context, "auditExec", err, "Recording execution : Name[%s]", set.Name)
+	}
+}
+
+// resultError returns the error reported by a failed execution.
+func resultError(result *query.Result) string {
+
+	// Failed executions return a document with the error.
+	if doc, ok := result.Results.(bson.M); ok {
+		if msg, ok := doc["error"].(string); ok {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+// queryStats returns the details for each query of the set that ran.
+func queryStats(set *query.Set, outcomes []outcome) []audit.Query {
+	var queries []audit.Query
+	for i, o := range outcomes {
+		if !o.ran {
+			continue
+		}
+
+		aq := audit.Query{
+			Name:     set.Queries[i].Name,
+			Duration: millis(o.duration),
+			Docs:     len(o.result.Docs),
+		}
+
+		if o.err != nil {
+			aq.Error = o.err.Error()
+		}
+
+		queries = append(queries, aq)
+	}
+
+	return queries
+}
+
+// millis returns the duration in whole milliseconds.
+func millis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
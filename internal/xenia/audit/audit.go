@@ -0,0 +1,154 @@
+// Package audit provides the service layer for recording the executions of
+// query sets and reporting the aggregated statistics for a set.
+package audit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Collection contains the name of the audit collection.
+const Collection = "query_audit"
+
+// Set of error variables.
+var (
+	ErrNotFound = errors.New("Audit records not found")
+)
+
+// =============================================================================
+
+// Add inserts the record of a set execution.
+func Add(context interface{}, db *db.DB, r Record) error {
+	log.Dev(context, "Add", "Started : Set[%s]", r.Set)
+
+	r.ID = bson.NewObjectId()
+
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "Add", "MGO : db.%s.insert(%s)", c.Name, mongo.Query(r))
+		return c.Insert(&r)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Add", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Add", "Completed")
+	return nil
+}
+
+// GetBySet retrieves the most recent records for the specified set.
+func GetBySet(context interface{}, db *db.DB, name string, limit int) ([]Record, error) {
+	log.Dev(context, "GetBySet", "Started : Set[%s] Limit[%d]", name, limit)
+
+	var recs []Record
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"set": name}
+		log.Dev(context, "GetBySet", "MGO : db.%s.find(%s).sort([\"-started\"]).limit(%d)", c.Name, mongo.Query(q), limit)
+		return c.Find(q).Sort("-started").Limit(limit).All(&recs)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetBySet", err, "Completed")
+		return nil, err
+	}
+
+	if recs == nil {
+		recs = []Record{}
+	}
+
+	log.Dev(context, "GetBySet", "Completed : Records[%d]", len(recs))
+	return recs, nil
+}
+
+// Stats aggregates the records for the specified set that started on or
+// after the since time. A zero since time includes every record.
+func Stats(context interface{}, db *db.DB, name string, since time.Time) (*SetStats, error) {
+	log.Dev(context, "Stats", "Started : Set[%s] Since[%v]", name, since)
+
+	match := bson.M{"set": name}
+	if !since.IsZero() {
+		match["started"] = bson.M{"$gte": since}
+	}
+
+	set := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":             "$set",
+			"executions":      bson.M{"$sum": 1},
+			"errors":          bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$ifNull": []interface{}{"$error", false}}, 1, 0}}},
+			"cache_hits":      bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$cache", "HIT"}}, 1, 0}}},
+			"callers":         bson.M{"$addToSet": "$caller"},
+			"avg_duration_ms": bson.M{"$avg": "$duration_ms"},
+			"max_duration_ms": bson.M{"$max": "$duration_ms"},
+			"last_run":        bson.M{"$max": "$started"},
+		}},
+	}
+
+	queries := []bson.M{
+		{"$match": match},
+		{"$unwind": "$queries"},
+		{"$group": bson.M{
+			"_id":             "$queries.name",
+			"executions":      bson.M{"$sum": 1},
+			"errors":          bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$ifNull": []interface{}{"$queries.error", false}}, 1, 0}}},
+			"avg_duration_ms": bson.M{"$avg": "$queries.duration_ms"},
+			"max_duration_ms": bson.M{"$max": "$queries.duration_ms"},
+			"avg_docs":        bson.M{"$avg": "$queries.docs"},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	var results []setGroup
+	var qs []QueryStats
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "Stats", "MGO : db.%s.aggregate(%s)", c.Name, mongo.Query(set))
+		if err := c.Pipe(set).All(&results); err != nil {
+			return err
+		}
+
+		log.Dev(context, "Stats", "MGO : db.%s.aggregate(%s)", c.Name, mongo.Query(queries))
+		return c.Pipe(queries).All(&qs)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Stats", err, "Completed")
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		log.Error(context, "Stats", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	if qs == nil {
+		qs = []QueryStats{}
+	}
+
+	st := results[0].SetStats
+	st.Since = since
+	st.Callers = callers(results[0].Callers)
+	st.Queries = qs
+
+	log.Dev(context, "Stats", "Completed : Executions[%d]", st.Executions)
+	return &st, nil
+}
+
+// callers counts the distinct callers ignoring executions where the
+// caller is not known.
+func callers(names []string) int {
+	var n int
+	for _, name := range names {
+		if name != "" {
+			n++
+		}
+	}
+
+	return n
+}
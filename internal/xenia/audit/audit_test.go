@@ -0,0 +1,153 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/audit"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// prefix is what we are looking to delete after the test.
+const prefix = "ATEST_O"
+
+func init() {
+	// Initialize the configuration and logging systems. Plus anything
+	// else the web app layer needs.
+	tests.Init("XENIA")
+
+	// Initialize MongoDB using the `tests.TestSession` as the name of the
+	// master session.
+	cfg := mongo.Config{
+		Host:     cfg.MustString("MONGO_HOST"),
+		AuthDB:   cfg.MustString("MONGO_AUTHDB"),
+		DB:       cfg.MustString("MONGO_DB"),
+		User:     cfg.MustString("MONGO_USER"),
+		Password: cfg.MustString("MONGO_PASS"),
+	}
+	tests.InitMongo(cfg)
+}
+
+//==============================================================================
+
+// setup initializes for each indivdual test.
+func setup(t *testing.T) *db.DB {
+	tests.ResetLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+
+	return db
+}
+
+// teardown deinitializes for each indivdual test.
+func teardown(t *testing.T, db *db.DB) {
+	f := func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"set": bson.RegEx{Pattern: "^" + prefix}})
+		return err
+	}
+
+	if err := db.ExecuteMGO(tests.Context, audit.Collection, f); err != nil {
+		t.Fatalf("%s\tShould be able to remove the records : %v", tests.Failed, err)
+	}
+	t.Logf("%s\tShould be able to remove the test data.", tests.Success)
+
+	db.CloseMGO(tests.Context)
+
+	tests.DisplayLog()
+}
+
+//==============================================================================
+
+// TestStats tests the records of a set are aggregated into statistics.
+func TestStats(t *testing.T) {
+	db := setup(t)
+	defer teardown(t, db)
+
+	name := prefix + "_basic"
+	now := time.Now()
+
+	recs := []audit.Record{
+		{
+			Set:      name,
+			Caller:   "bill",
+			Started:  now.Add(-2 * time.Hour),
+			Duration: 30,
+			Queries:  []audit.Query{{Name: "list", Duration: 20, Docs: 10}, {Name: "count", Duration: 10, Docs: 1}},
+		},
+		{
+			Set:      name,
+			Caller:   "jill",
+			Started:  now.Add(-time.Minute),
+			Duration: 10,
+			Queries:  []audit.Query{{Name: "list", Duration: 10, Docs: 0, Error: "Timed out"}},
+			Error:    "Timed out",
+		},
+		{
+			Set:      name,
+			Caller:   "bill",
+			Started:  now,
+			Duration: 2,
+			Cache:    "HIT",
+			Queries:  []audit.Query{},
+		},
+	}
+
+	t.Log("Given the need to aggregate the executions of a set.")
+	{
+		for _, r := range recs {
+			if err := audit.Add(tests.Context, db, r); err != nil {
+				t.Fatalf("\t%s\tShould be able to add a record : %s", tests.Failed, err)
+			}
+		}
+		t.Logf("\t%s\tShould be able to add the records.", tests.Success)
+
+		t.Log("\tWhen using all the records")
+		{
+			st, err := audit.Stats(tests.Context, db, name, time.Time{})
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to get the stats : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to get the stats.", tests.Success)
+
+			if st.Executions != 3 || st.Errors != 1 || st.CacheHits != 1 || st.Callers != 2 || st.MaxDuration != 30 {
+				t.Fatalf("\t%s\tShould get the set stats : %+v", tests.Failed, st)
+			}
+			t.Logf("\t%s\tShould get the set stats.", tests.Success)
+
+			if len(st.Queries) != 2 || st.Queries[1].Name != "list" || st.Queries[1].Executions != 2 || st.Queries[1].Errors != 1 || st.Queries[1].AvgDocs != 5 {
+				t.Fatalf("\t%s\tShould get the query stats : %+v", tests.Failed, st.Queries)
+			}
+			t.Logf("\t%s\tShould get the query stats.", tests.Success)
+		}
+
+		t.Log("\tWhen using the records of the last hour")
+		{
+			st, err := audit.Stats(tests.Context, db, name, now.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to get the stats : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to get the stats.", tests.Success)
+
+			if st.Executions != 2 || len(st.Queries) != 1 {
+				t.Fatalf("\t%s\tShould only include the recent records : %+v", tests.Failed, st)
+			}
+			t.Logf("\t%s\tShould only include the recent records.", tests.Success)
+		}
+
+		t.Log("\tWhen using a set with no records")
+		{
+			if _, err := audit.Stats(tests.Context, db, prefix+"_missing", time.Time{}); err != audit.ErrNotFound {
+				t.Fatalf("\t%s\tShould get a not found error : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould get a not found error.", tests.Success)
+		}
+	}
+}
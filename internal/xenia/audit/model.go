@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Query contains the details of executing a single query of a set.
+type Query struct {
+	Name     string `bson:"name" json:"name"`                       // Name of the query.
+	Duration int64  `bson:"duration_ms" json:"duration_ms"`         // How long the query took in milliseconds.
+	Docs     int    `bson:"docs" json:"docs"`                       // Number of documents returned.
+	Error    string `bson:"error,omitempty" json:"error,omitempty"` // Error if the query failed.
+}
+
+// Record contains the details of a single execution of a set.
+type Record struct {
+	ID       bson.ObjectId     `bson:"_id,omitempty" json:"id,omitempty"`
+	Set      string            `bson:"set" json:"set"`                           // Name of the set.
+	Caller   string            `bson:"caller,omitempty" json:"caller,omitempty"` // Identity of the caller if known.
+	Params   map[string]string `bson:"params,omitempty" json:"params,omitempty"` // Resolved parameters of the set.
+	Started  time.Time         `bson:"started" json:"started"`                   // When the execution started.
+	Duration int64             `bson:"duration_ms" json:"duration_ms"`           // How long the execution took in milliseconds.
+	Cache    string            `bson:"cache,omitempty" json:"cache,omitempty"`   // Cache status if the set is cached.
+	Queries  []Query           `bson:"queries" json:"queries"`                   // Details for each query that was executed.
+	Error    string            `bson:"error,omitempty" json:"error,omitempty"`   // Error if the execution failed.
+}
+
+//==============================================================================
+
+// QueryStats contains the aggregated details for a query of a set.
+type QueryStats struct {
+	Name        string  `bson:"_id" json:"name"`                        // Name of the query.
+	Executions  int     `bson:"executions" json:"executions"`           // Number of times the query ran.
+	Errors      int     `bson:"errors" json:"errors"`                   // Number of times the query failed.
+	AvgDuration float64 `bson:"avg_duration_ms" json:"avg_duration_ms"` // Average duration in milliseconds.
+	MaxDuration int64   `bson:"max_duration_ms" json:"max_duration_ms"` // Longest duration in milliseconds.
+	AvgDocs     float64 `bson:"avg_docs" json:"avg_docs"`               // Average number of documents returned.
+}
+
+// SetStats contains the aggregated details for the executions of a set.
+type SetStats struct {
+	Set         string       `bson:"_id" json:"set"`                         // Name of the set.
+	Since       time.Time    `bson:"-" json:"since"`                         // Start of the period covered.
+	Executions  int          `bson:"executions" json:"executions"`           // Number of executions.
+	Errors      int          `bson:"errors" json:"errors"`                   // Number of failed executions.
+	CacheHits   int          `bson:"cache_hits" json:"cache_hits"`           // Number of executions served from the cache.
+	Callers     int          `bson:"-" json:"callers"`                       // Number of distinct known callers.
+	AvgDuration float64      `bson:"avg_duration_ms" json:"avg_duration_ms"` // Average duration in milliseconds.
+	MaxDuration int64        `bson:"max_duration_ms" json:"max_duration_ms"` // Longest duration in milliseconds.
+	LastRun     time.Time    `bson:"last_run" json:"last_run"`               // When the set last started.
+	Queries     []QueryStats `bson:"-" json:"queries"`                       // Details for each query of the set.
+}
+
+// setGroup is used to read the aggregated details for a set.
+type setGroup struct {
+	SetStats `bson:",inline"`
+	Callers  []string `bson:"callers"`
+}
@@ -0,0 +1,143 @@
+package xenia
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// Set of cache status values reported by ExecCache.
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+// ExecCache executes the specified query set like ExecCall. If the set has a
+// cache policy the results are served from and saved to the store using a
+// key derived from the set name, the resolved variables and the page. The status
+// reports if the result was a cache hit or miss and is empty when the set
+// is not cached.
+func ExecCache(context interface{}, db *db.DB, store cache.Store, set *query.Set, vars map[string]string, call Call) (*query.Result, string) {
+
+	// Sets that are not cached or want the explain output run as normal.
+	if store == nil || set.Cache == nil || set.Explain {
+		return ExecCall(context, db, set, vars, call), ""
+	}
+
+	log.Dev(context, "ExecCache", "Started : Name[%s]", set.Name)
+
+	started := time.Now()
+
+	// If we have been provided a nil map, make one.
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+
+	// Validate the set and prepare it for execution. This resolves the
+	// default values so they are part of the key.
+	if msg, err := prepareSet(context, db, set, vars); err != nil {
+		result := errResult(context, err, msg)
+		auditExec(context, db, set, vars, call, started, "", nil, resultError(result))
+		return result, ""
+	}
+
+	opts, err := newOptions(set, call)
+	if err != nil {
+		result := errResult(context, err, "Page")
+		auditExec(context, db, set, vars, call, started, "", nil, resultError(result))
+		return result, ""
+	}
+
+	key := cache.Key(set.Name, pageVars(vars, call.Page))
+
+	// Do we have the result in the cache.
+	data, err := store.Get(key)
+	switch err {
+	case nil:
+		result := query.Result{Results: json.RawMessage(data)}
+		auditExec(context, db, set, vars, call, started, CacheHit, nil, resultError(&result))
+
+		log.Dev(context, "ExecCache", "Completed : CACHE : Key[%s]", key)
+		return &result, CacheHit
+
+	case cache.ErrNotFound:
+
+	default:
+		log.Error(context, "ExecCache", err, "Reading cache : Key[%s]", key)
+	}
+
+	result, queries := execQueries(context, db, set, vars, opts)
+	auditExec(context, db, set, vars, call, started, CacheMiss, queries, resultError(result))
+
+	// Only successful executions are cached. Errors return a document.
+	if _, ok := result.Results.([]docs); !ok {
+		log.Dev(context, "ExecCache", "Completed : Not cached")
+		return result, CacheMiss
+	}
+
+	// Validate has already checked the TTL can be parsed.
+	ttl, _ := set.Cache.Duration()
+
+	data, err = json.Marshal(result.Results)
+	if err == nil {
+		err = store.Set(key, data, ttl)
+	}
+
+	if err != nil {
+		log.Error(context, "ExecCache", err, "Saving cache : Key[%s]", key)
+	}
+
+	log.Dev(context, "ExecCache", "Completed : Key[%s] TTL[%s]", key, ttl)
+	return result, CacheMiss
+}
+
+// InvalidateCache removes all the cached results for the specified set.
+func InvalidateCache(context interface{}, store cache.Store, name string) error {
+	log.Dev(context, "InvalidateCache", "Started : Name[%s]", name)
+
+	if err := store.DeletePrefix(cache.Prefix(name)); err != nil {
+		log.Error(context, "InvalidateCache", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "InvalidateCache", "Completed")
+	return nil
+}
+
+// InvalidateAllCache removes the cached results of every set. It is used
+// when metadata shared by the sets changes, like the masks of a collection.
+func InvalidateAllCache(context interface{}, store cache.Store) error {
+	log.Dev(context, "InvalidateAllCache", "Started")
+
+	if err := store.DeletePrefix(cache.PrefixAll()); err != nil {
+		log.Error(context, "InvalidateAllCache", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "InvalidateAllCache", "Completed")
+	return nil
+}
+
+// pageVars returns the variables with the page added so each page of the
+// results is cached under its own key.
+func pageVars(vars map[string]string, page query.Page) map[string]string {
+	if page.Size == 0 {
+		return vars
+	}
+
+	pv := make(map[string]string, len(vars)+2)
+	for k, v := range vars {
+		pv[k] = v
+	}
+
+	// The leading space keeps these apart from the variables of the set.
+	pv[" page_size"] = strconv.Itoa(page.Size)
+	pv[" page_token"] = page.Token
+
+	return pv
+}
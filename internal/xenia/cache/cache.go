@@ -0,0 +1,65 @@
+// Package cache provides support for caching the results of executing
+// query sets. Results are held in a pluggable Store so they can be kept
+// in process or shared between instances using a Redis compatible server.
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Set of error variables.
+var (
+	ErrNotFound = errors.New("Cache entry not found")
+)
+
+// Store declares the behavior required for storing cached results.
+type Store interface {
+
+	// Get returns the value stored for the key or ErrNotFound.
+	Get(key string) ([]byte, error)
+
+	// Set stores the value for the key for the specified duration.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// DeletePrefix removes all the keys starting with the prefix.
+	DeletePrefix(prefix string) error
+}
+
+// =============================================================================
+
+// keyPrefix starts the keys used for all the sets.
+const keyPrefix = "xenia:"
+
+// Prefix returns the prefix of the keys used for the specified set.
+func Prefix(setName string) string {
+	return keyPrefix + setName + ":"
+}
+
+// PrefixAll returns the prefix of the keys used for all the sets.
+func PrefixAll() string {
+	return keyPrefix
+}
+
+// Key returns the key to use for the specified set and variables. The
+// variables are sorted so the same values always produce the same key.
+func Key(setName string, vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha1.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(vars[k]))
+		h.Write([]byte{0})
+	}
+
+	return Prefix(setName) + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package cache_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+)
+
+// TestKey validates keys are stable and unique per set and variables.
+func TestKey(t *testing.T) {
+	t.Log("Given the need to build cache keys.")
+	{
+		t.Log("\tWhen using the same variables in a different order.")
+		{
+			k1 := cache.Key("set", map[string]string{"a": "1", "b": "2"})
+			k2 := cache.Key("set", map[string]string{"b": "2", "a": "1"})
+
+			if k1 != k2 {
+				t.Fatalf("\t%s\tShould get the same key : %s != %s", tests.Failed, k1, k2)
+			}
+			t.Logf("\t%s\tShould get the same key.", tests.Success)
+
+			if !strings.HasPrefix(k1, cache.Prefix("set")) {
+				t.Fatalf("\t%s\tShould have the set prefix : %s", tests.Failed, k1)
+			}
+			t.Logf("\t%s\tShould have the set prefix.", tests.Success)
+		}
+
+		t.Log("\tWhen using different variables.")
+		{
+			k1 := cache.Key("set", map[string]string{"a": "1", "b": "2"})
+			k2 := cache.Key("set", map[string]string{"a": "12"})
+
+			if k1 == k2 {
+				t.Fatalf("\t%s\tShould get different keys.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get different keys.", tests.Success)
+		}
+	}
+}
+
+// TestMemory validates the in process store.
+func TestMemory(t *testing.T) {
+	store := cache.NewMemory(time.Minute)
+
+	t.Log("Given the need to cache values in process.")
+	{
+		t.Log("\tWhen saving and invalidating values.")
+		{
+			k1 := cache.Key("set1", nil)
+			k2 := cache.Key("set2", nil)
+
+			store.Set(k1, []byte("one"), time.Minute)
+			store.Set(k2, []byte("two"), time.Minute)
+
+			v, err := store.Get(k1)
+			if err != nil || string(v) != "one" {
+				t.Fatalf("\t%s\tShould be able to get the value : %q %v", tests.Failed, v, err)
+			}
+			t.Logf("\t%s\tShould be able to get the value.", tests.Success)
+
+			if err := store.DeletePrefix(cache.Prefix("set1")); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete the set : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to delete the set.", tests.Success)
+
+			if _, err := store.Get(k1); err != cache.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find the deleted value : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find the deleted value.", tests.Success)
+
+			if _, err := store.Get(k2); err != nil {
+				t.Fatalf("\t%s\tShould still find the other set : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould still find the other set.", tests.Success)
+
+			if err := store.DeletePrefix(cache.PrefixAll()); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete all the sets : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to delete all the sets.", tests.Success)
+
+			if _, err := store.Get(k2); err != cache.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find the other set : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find the other set.", tests.Success)
+		}
+
+		t.Log("\tWhen a value expires.")
+		{
+			k := cache.Key("set3", nil)
+			store.Set(k, []byte("three"), time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+
+			if _, err := store.Get(k); err != cache.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find the expired value : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find the expired value.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"strings"
+	"time"
+
+	gc "github.com/patrickmn/go-cache"
+)
+
+// memory implements the Store interface using an in process cache.
+type memory struct {
+	c *gc.Cache
+}
+
+// NewMemory returns a Store that keeps the values in process. Expired
+// values are removed every cleanup interval.
+func NewMemory(cleanup time.Duration) Store {
+	return &memory{c: gc.New(gc.NoExpiration, cleanup)}
+}
+
+// Get implements the Store interface.
+func (m *memory) Get(key string) ([]byte, error) {
+	v, found := m.c.Get(key)
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return v.([]byte), nil
+}
+
+// Set implements the Store interface.
+func (m *memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.c.Set(key, value, ttl)
+	return nil
+}
+
+// DeletePrefix implements the Store interface.
+func (m *memory) DeletePrefix(prefix string) error {
+	for key := range m.c.Items() {
+		if strings.HasPrefix(key, prefix) {
+			m.c.Delete(key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// maxIdle is the number of idle connections kept open to the server.
+const maxIdle = 8
+
+// redis implements the Store interface against a Redis compatible server
+// using the RESP protocol.
+type redis struct {
+	addr    string
+	timeout time.Duration
+	idle    chan *redisConn
+}
+
+// redisConn is a single connection to the server.
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedis returns a Store that keeps the values in a Redis compatible
+// server at the specified address. The timeout is applied to every call.
+func NewRedis(addr string, timeout time.Duration) Store {
+	return &redis{
+		addr:    addr,
+		timeout: timeout,
+		idle:    make(chan *redisConn, maxIdle),
+	}
+}
+
+// Get implements the Store interface.
+func (rd *redis) Get(key string) ([]byte, error) {
+	v, err := rd.do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, ErrNotFound
+	}
+
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected reply type %T", v)
+	}
+
+	return b, nil
+}
+
+// Set implements the Store interface.
+func (rd *redis) Set(key string, value []byte, ttl time.Duration) error {
+	ms := strconv.FormatInt(int64(ttl/time.Millisecond), 10)
+	_, err := rd.do("SET", key, string(value), "PX", ms)
+	return err
+}
+
+// DeletePrefix implements the Store interface.
+func (rd *redis) DeletePrefix(prefix string) error {
+	cursor := "0"
+	for {
+		v, err := rd.do("SCAN", cursor, "MATCH", prefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+
+		reply, ok := v.([]interface{})
+		if !ok || len(reply) != 2 {
+			return errors.New("Unexpected reply to SCAN")
+		}
+
+		next, _ := reply[0].([]byte)
+		keys, _ := reply[1].([]interface{})
+
+		if len(keys) > 0 {
+			args := make([]string, len(keys))
+			for i, k := range keys {
+				b, _ := k.([]byte)
+				args[i] = string(b)
+			}
+
+			if _, err := rd.do("DEL", args...); err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// =============================================================================
+
+// do sends the command to the server and returns the reply.
+func (rd *redis) do(cmd string, args ...string) (interface{}, error) {
+	conn, err := rd.get()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(rd.timeout))
+
+	// Write the command as an array of bulk strings.
+	buf := []byte("*" + strconv.Itoa(len(args)+1) + "\r\n")
+	for _, arg := range append([]string{cmd}, args...) {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+
+	if _, err := conn.Write(buf); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	v, err := readReply(conn.r)
+	if err != nil {
+
+		// Server errors leave the connection in a usable state.
+		if _, ok := err.(redisError); !ok {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	rd.put(conn)
+	return v, err
+}
+
+// get returns an idle connection or dials a new one.
+func (rd *redis) get() (*redisConn, error) {
+	select {
+	case conn := <-rd.idle:
+		return conn, nil
+	default:
+	}
+
+	c, err := net.DialTimeout("tcp", rd.addr, rd.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisConn{Conn: c, r: bufio.NewReader(c)}, nil
+}
+
+// put returns the connection to the idle list or closes it if full.
+func (rd *redis) put(conn *redisConn) {
+	select {
+	case rd.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// =============================================================================
+
+// redisError represents an error reply from the server.
+type redisError string
+
+// Error implements the error interface.
+func (re redisError) Error() string {
+	return string(re)
+}
+
+// readReply reads a single RESP reply. Bulk strings are returned as []byte,
+// integers as int64 and arrays as []interface{}. Null replies are nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 {
+		return nil, errors.New("Invalid reply")
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+
+	case '-':
+		return nil, redisError(line[1:])
+
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+
+		return b[:n], nil
+
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, nil
+		}
+
+		vs := make([]interface{}, n)
+		for i := range vs {
+			if vs[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return vs, nil
+
+	default:
+		return nil, fmt.Errorf("Invalid reply type %q", line[0])
+	}
+}
@@ -0,0 +1,87 @@
+package xenia_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/cache"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// TestExecCache tests the results of a set with a cache policy are
+// served from the cache until it is invalidated.
+func TestExecCache(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	t.Log("Given the need to load the test data.")
+	{
+		loadTestData(t, db)
+	}
+
+	defer func() {
+		t.Log("Given the need to unload the test data.")
+		{
+			unloadTestData(t, db)
+		}
+	}()
+
+	store := cache.NewMemory(time.Minute)
+	exp := `{"results":[{"Name":"Basic","Docs":[{"name":"C14 - Pasco County Buoy, FL"}]}]}`
+
+	t.Log("Given the need to cache the results of a set.")
+	{
+		for i, status := range []string{xenia.CacheMiss, xenia.CacheHit} {
+			t.Logf("\tWhen executing the set for the %d time.", i+1)
+			{
+				es := basic()
+				es.set.Cache = &query.Cache{TTL: "1m"}
+
+				result, got := xenia.ExecCache(tests.Context, db, store, es.set, es.vars, xenia.Call{})
+				if got != status {
+					t.Fatalf("\t%s\tShould get a cache %s : %s", tests.Failed, status, got)
+				}
+				t.Logf("\t%s\tShould get a cache %s.", tests.Success, status)
+
+				data, err := json.Marshal(result)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to marshal the result : %s", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould be able to marshal the result.", tests.Success)
+
+				if string(data) != exp {
+					t.Log("Exp:", exp)
+					t.Log("Got:", string(data))
+					t.Fatalf("\t%s\tShould have the correct result.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have the correct result.", tests.Success)
+			}
+		}
+
+		t.Log("\tWhen the cache is invalidated.")
+		{
+			if err := xenia.InvalidateCache(tests.Context, store, "Basic"); err != nil {
+				t.Fatalf("\t%s\tShould be able to invalidate the cache : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to invalidate the cache.", tests.Success)
+
+			es := basic()
+			es.set.Cache = &query.Cache{TTL: "1m"}
+
+			if _, got := xenia.ExecCache(tests.Context, db, store, es.set, es.vars, xenia.Call{}); got != xenia.CacheMiss {
+				t.Fatalf("\t%s\tShould get a cache miss : %s", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould get a cache miss.", tests.Success)
+		}
+	}
+}
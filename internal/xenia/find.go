@@ -0,0 +1,278 @@
+package xenia
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// findSpec contains the options of a find, count or distinct command.
+//
+// {"filter": {}, "projection": {}, "sort": ["-date"], "skip": 0, "limit": 10}
+// {"filter": {}}
+// {"field": "station_id", "filter": {}}
+type findSpec struct {
+	filter     map[string]interface{}
+	projection map[string]interface{}
+	sort       []string
+	skip       int
+	limit      int
+	field      string
+}
+
+// execFind executes the specified find, count or distinct query.
+func execFind(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, opts options) (docs, []map[string]interface{}, error) {
+
+	// The single command is processed for variables like a pipeline.
+	cmds, agg, save, commands, err := buildPipeline(context, q, vars, data)
+	if err != nil {
+		return docs{}, commands, err
+	}
+
+	if len(cmds) != 1 {
+		return docs{}, commands, fmt.Errorf("A %s query requires a single command", q.Type)
+	}
+
+	spec, err := parseFindSpec(cmds[0])
+	if err != nil {
+		return docs{}, commands, err
+	}
+
+	// Do we want the explain output.
+	if opts.explain {
+		var m bson.M
+		f := func(c *mgo.Collection) error {
+			log.Dev(context, "execFind", "MGO Explain :\ndb.%s.%s(%s)", c.Name, q.Type, agg)
+			return findQuery(c, spec).Explain(&m)
+		}
+
+		if err := db.ExecuteMGO(context, q.Collection, f); err != nil {
+			return docs{}, commands, err
+		}
+
+		return docs{Name: q.Name, Docs: []bson.M{m}}, commands, nil
+	}
+
+	// Only finds return documents that can be paged.
+	var pg *paging
+	if q.Type == query.TypeFind {
+		if pg = opts.paging(q); pg != nil {
+			pg.masks = loadMasks(context, db, opts.maskCollection(q.Collection))
+			if spec, err = pg.find(spec); err != nil {
+				return docs{}, commands, err
+			}
+		}
+	}
+
+	// Set the timeout for the session.
+	timeout := queryTimeout(context, q)
+
+	// Build the function for the execution based on the type.
+	var results []bson.M
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "execFind", "MGO Started\ndb.%s.%s(%s)", c.Name, q.Type, agg)
+
+		switch q.Type {
+		case query.TypeCount:
+			n, err := findQuery(c, spec).Count()
+			if err != nil {
+				return err
+			}
+
+			results = []bson.M{{"count": n}}
+			return nil
+
+		case query.TypeDistinct:
+			var values []interface{}
+			if err := c.Find(spec.filter).Distinct(spec.field, &values); err != nil {
+				return err
+			}
+
+			results = make([]bson.M, len(values))
+			for i, v := range values {
+				results[i] = bson.M{"value": v}
+			}
+			return nil
+
+		default:
+			return findQuery(c, spec).All(&results)
+		}
+	}
+
+	if err := execTimeout(context, db, q.Collection, timeout, f); err != nil {
+		return docs{}, commands, err
+	}
+
+	log.Dev(context, "execFind", "Completed")
+
+	// If there were no results, return an empty array.
+	if results == nil {
+		return pg.docs(q.Name, []bson.M{}, ""), commands, nil
+	}
+
+	// Trim the results to the page before the sort key is masked.
+	var next string
+	if pg != nil {
+		if results, next, err = pg.result(context, q, results); err != nil {
+			return docs{}, commands, err
+		}
+	}
+
+	// Perform any masking that is required.
+	switch q.Type {
+	case query.TypeCount:
+
+	case query.TypeDistinct:
+
+		// The values are masked using the mask for the distinct field.
+		fld := spec.field[strings.LastIndex(spec.field, ".")+1:]
+		if msk, exists := loadMasks(context, db, opts.maskCollection(q.Collection))[fld]; exists {
+			for _, doc := range results {
+				if err := applyMask(context, msk, doc, "value"); err != nil {
+					return docs{}, commands, err
+				}
+			}
+		}
+
+	default:
+		if err := processMasks(context, db, opts.maskCollection(q.Collection), results); err != nil {
+			return docs{}, commands, err
+		}
+	}
+
+	// Do we need to save the result.
+	if save != nil {
+		if err := saveResult(context, db, save, results, data); err != nil {
+			return docs{}, commands, err
+		}
+	}
+
+	return pg.docs(q.Name, results, next), commands, nil
+}
+
+// findQuery builds the mgo query for the spec.
+func findQuery(c *mgo.Collection, spec findSpec) *mgo.Query {
+	mq := c.Find(spec.filter)
+
+	if spec.projection != nil {
+		mq = mq.Select(spec.projection)
+	}
+
+	if len(spec.sort) > 0 {
+		mq = mq.Sort(spec.sort...)
+	}
+
+	if spec.skip > 0 {
+		mq = mq.Skip(spec.skip)
+	}
+
+	if spec.limit > 0 {
+		mq = mq.Limit(spec.limit)
+	}
+
+	return mq
+}
+
+// parseFindSpec extracts the options from the command document.
+func parseFindSpec(cmd map[string]interface{}) (findSpec, error) {
+	var spec findSpec
+
+	for key, value := range cmd {
+		var err error
+
+		switch key {
+		case "filter":
+			spec.filter, err = findDoc(key, value)
+
+		case "projection":
+			spec.projection, err = findDoc(key, value)
+
+		case "sort":
+			switch v := value.(type) {
+			case string:
+				spec.sort = []string{v}
+
+			case []interface{}:
+				for _, fld := range v {
+					s, ok := fld.(string)
+					if !ok {
+						return spec, fmt.Errorf("Sort field \"%v\" must be a string", fld)
+					}
+					spec.sort = append(spec.sort, s)
+				}
+
+			default:
+				err = fmt.Errorf("Sort is a %T but must be a string or array", value)
+			}
+
+		case "skip":
+			spec.skip, err = findInt(key, value)
+
+		case "limit":
+			spec.limit, err = findInt(key, value)
+
+		case "field":
+			s, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("Field is a %T but must be a string", value)
+			}
+			spec.field = s
+
+		default:
+			err = fmt.Errorf("Invalid find option %q", key)
+		}
+
+		if err != nil {
+			return spec, err
+		}
+	}
+
+	return spec, nil
+}
+
+// findDoc returns the value as a document.
+func findDoc(key string, value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, nil
+
+	case bson.M:
+		return v, nil
+
+	case nil:
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("Option %q is a %T but must be a document", key, value)
+	}
+}
+
+// findInt returns the numeric value as an int.
+func findInt(key string, value interface{}) (int, error) {
+	var n int
+
+	switch v := value.(type) {
+	case int:
+		n = v
+	case int32:
+		n = int(v)
+	case int64:
+		n = int(v)
+	case float64:
+		n = int(v)
+	default:
+		return 0, fmt.Errorf("Option %q is a %T but must be a number", key, value)
+	}
+
+	if n < 0 {
+		return 0, errors.New("Option " + key + " can't be negative")
+	}
+
+	return n, nil
+}
@@ -0,0 +1,31 @@
+package xenia_test
+
+import (
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/coralproject/shelf/tstdata"
+)
+
+// findInvldOption performs a find query with an unknown option.
+func findInvldOption() execSet {
+	return execSet{
+		fail: true,
+		set: &query.Set{
+			Name:    "Find Invalid Option",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Find",
+					Type:       "find",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"filter": map[string]interface{}{"station_id": "42021"}, "bad": 1},
+					},
+				},
+			},
+		},
+		results: []string{
+			`{"results":{"commands":[{"bad":1,"filter":{"station_id":"42021"}}],"error":"Invalid find option \"bad\""}}`,
+		},
+	}
+}
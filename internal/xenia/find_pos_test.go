@@ -0,0 +1,132 @@
+package xenia_test
+
+import (
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/coralproject/shelf/tstdata"
+)
+
+// basicFind performs a simple find query.
+func basicFind() execSet {
+	return execSet{
+		fail: false,
+		set: &query.Set{
+			Name:    "Basic Find",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Basic Find",
+					Type:       "find",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{
+							"filter":     map[string]interface{}{"station_id": map[string]interface{}{"$in": []string{"42021", "44008"}}},
+							"projection": map[string]interface{}{"_id": 0, "name": 1},
+							"sort":       []interface{}{"-name"},
+							"limit":      "#number:limit",
+						},
+					},
+				},
+			},
+		},
+		vars: map[string]string{"limit": "1"},
+		results: []string{
+			`{"results":[{"Name":"Basic Find","Docs":[{"name":"NANTUCKET 54NM Southeast of Nantucket"}]}]}`,
+		},
+	}
+}
+
+// basicCount performs a simple count query.
+func basicCount() execSet {
+	return execSet{
+		fail: false,
+		set: &query.Set{
+			Name:    "Basic Count",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Basic Count",
+					Type:       "count",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"filter": map[string]interface{}{"station_id": "#string:station_id"}},
+					},
+				},
+			},
+		},
+		vars: map[string]string{"station_id": "42021"},
+		results: []string{
+			`{"results":[{"Name":"Basic Count","Docs":[{"count":1}]}]}`,
+		},
+	}
+}
+
+// basicDistinct performs a simple distinct query.
+func basicDistinct() execSet {
+	return execSet{
+		fail: false,
+		set: &query.Set{
+			Name:    "Basic Distinct",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Basic Distinct",
+					Type:       "distinct",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{
+							"field":  "station_id",
+							"filter": map[string]interface{}{"station_id": map[string]interface{}{"$in": []string{"42021", "44008"}}},
+						},
+					},
+				},
+			},
+		},
+		results: []string{
+			`{"results":[{"Name":"Basic Distinct","Docs":[{"value":"42021"},{"value":"44008"}]}]}`,
+			`{"results":[{"Name":"Basic Distinct","Docs":[{"value":"44008"},{"value":"42021"}]}]}`,
+		},
+	}
+}
+
+// findSaveIn performs a find query where the result is saved and used by
+// a pipeline query.
+func findSaveIn() execSet {
+	return execSet{
+		fail: false,
+		set: &query.Set{
+			Name:    "Find Save In",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Get Ids",
+					Type:       "find",
+					Collection: tstdata.CollectionExecTest,
+					Return:     false,
+					Commands: []map[string]interface{}{
+						{
+							"filter":     map[string]interface{}{"station_id": "42021"},
+							"projection": map[string]interface{}{"_id": 0, "station_id": 1},
+						},
+						{"$save": map[string]interface{}{"$map": "list"}},
+					},
+				},
+				{
+					Name:       "Get Documents",
+					Type:       "pipeline",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"$match": map[string]interface{}{"station_id": map[string]interface{}{"$in": "#data.*:list.station_id"}}},
+						{"$project": map[string]interface{}{"_id": 0, "name": 1}},
+					},
+				},
+			},
+		},
+		results: []string{
+			`{"results":[{"Name":"Get Documents","Docs":[{"name":"C14 - Pasco County Buoy, FL"}]}]}`,
+		},
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/history"
 	gc "github.com/patrickmn/go-cache"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -292,6 +293,34 @@ func GetLastHistoryByName(context interface{}, db *db.DB, collection string, fie
 
 // =============================================================================
 
+// History describes the history kept for query masks so their revisions can
+// be listed, compared and restored. The key is the collection and the field
+// separated by the first dot, so comments.user.email is the user.email field
+// of the comments collection.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "masks",
+	Selector: func(key string) (bson.M, error) {
+		parts := strings.SplitN(key, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, history.ErrInvalidKey
+		}
+		return bson.M{"collection": parts[0], "field": parts[1]}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var msk Mask
+		if err := raw.Unmarshal(&msk); err != nil {
+			return nil, err
+		}
+		return msk, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(Mask))
+	},
+}
+
+// =============================================================================
+
 // Delete is used to remove an existing query mask document.
 func Delete(context interface{}, db *db.DB, collection string, field string) error {
 	log.Dev(context, "Delete", "Started : Collection[%s] Field[%s]", collection, field)
@@ -11,13 +11,17 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// ApplyMasks applies the masks defined for the collection to documents that
+// were read from it without running a query set.
+func ApplyMasks(context interface{}, db *db.DB, collection string, docs []bson.M) error {
+	return processMasks(context, db, collection, docs)
+}
+
 // processMasks reviews the document for fields that are defined to have
 // their values masked.
 func processMasks(context interface{}, db *db.DB, collection string, results []bson.M) error {
-	masks, err := mask.GetByCollection(context, db, collection)
-	if err != nil {
-
-		// If there are no masks to process then great.
+	masks := loadMasks(context, db, collection)
+	if len(masks) == 0 {
 		return nil
 	}
 
@@ -30,6 +34,17 @@ func processMasks(context interface{}, db *db.DB, collection string, results []b
 	return nil
 }
 
+// loadMasks returns the masks defined for the specified collection. A nil
+// map is returned when there are no masks to process.
+func loadMasks(context interface{}, db *db.DB, collection string) map[string]mask.Mask {
+	masks, err := mask.GetByCollection(context, db, collection)
+	if err != nil {
+		return nil
+	}
+
+	return masks
+}
+
 // matchMaskField checks the specificed document against the masks and updated any
 // field values that match based on the configured masking operation.
 func matchMaskField(context interface{}, masks map[string]mask.Mask, doc map[string]interface{}) error {
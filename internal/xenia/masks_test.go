@@ -435,3 +435,41 @@ func fixtures() ([]bson.M, error) {
 
 	return docs, nil
 }
+
+// TestPageMasks tests pages can't be sorted on masked fields.
+func TestPageMasks(t *testing.T) {
+	masks := map[string]mask.Mask{
+		"wind_dir": {"*", "wind_dir", mask.MaskAll},
+	}
+
+	t.Logf("Given the need to keep masked values out of page tokens.")
+	{
+		t.Logf("\tWhen sorting a find on a masked field")
+		{
+			p := paging{size: 2, masks: masks}
+			if _, err := p.find(findSpec{sort: []string{"-condition.wind_dir"}}); err == nil {
+				t.Fatalf("\t%s\tShould not be able to page the results.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be able to page the results.", tests.Success)
+		}
+
+		t.Logf("\tWhen sorting a pipeline on a masked field")
+		{
+			p := paging{size: 2, masks: masks}
+			pipeline := []bson.M{{"$sort": bson.M{"wind_dir": 1}}}
+			if _, err := p.pipeline(pipeline); err == nil {
+				t.Fatalf("\t%s\tShould not be able to page the results.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be able to page the results.", tests.Success)
+		}
+
+		t.Logf("\tWhen sorting on a field that is not masked")
+		{
+			p := paging{size: 2, masks: masks}
+			if _, err := p.find(findSpec{sort: []string{"-name"}}); err != nil {
+				t.Fatalf("\t%s\tShould be able to page the results : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to page the results.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package xenia
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/coralproject/shelf/internal/platform/keyset"
+	"github.com/coralproject/shelf/internal/xenia/mask"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrInvalidToken is returned when a continuation token can't be used.
+var ErrInvalidToken = errors.New("Invalid page token")
+
+// options contains the settings for executing the queries of a set.
+type options struct {
+	set      string
+	explain  bool
+	pageSize int
+	token    *pageToken
+	masks    map[string]string
+}
+
+// pageToken is the decoded form of a continuation token. It identifies the
+// query it was issued for, the sort key of the last document returned and
+// the number of documents returned before the next page.
+type pageToken struct {
+	Set    string        `bson:"s"`
+	Query  string        `bson:"q"`
+	Values []interface{} `bson:"v"`
+	Read   int           `bson:"n,omitempty"`
+}
+
+// paging contains the pagination to apply to a single query. The masks of
+// the collection of the query are set before the page is read.
+type paging struct {
+	set   string
+	size  int
+	keys  []keyset.Key
+	after []interface{}
+	read  int
+	masks map[string]mask.Mask
+}
+
+//==============================================================================
+
+// newOptions validates the page of the call and returns the options for
+// executing the queries of the set.
+func newOptions(set *query.Set, call Call) (options, error) {
+	page := call.Page
+
+	opts := options{
+		set:      set.Name,
+		explain:  set.Explain,
+		pageSize: page.Size,
+		masks:    call.Masks,
+	}
+
+	if page.Size < 0 {
+		return opts, errors.New("Invalid page size")
+	}
+
+	if page.Token == "" {
+		return opts, nil
+	}
+
+	if page.Size == 0 {
+		return opts, errors.New("Page token requires a page size")
+	}
+
+	var token pageToken
+	if err := keyset.Decode(page.Token, &token); err != nil {
+		return opts, ErrInvalidToken
+	}
+
+	if token.Set != set.Name {
+		return opts, ErrInvalidToken
+	}
+
+	opts.token = &token
+	return opts, nil
+}
+
+// paging returns the pagination to apply to the query or nil if the query
+// is not paginated.
+func (opts options) paging(q *query.Query) *paging {
+	if opts.pageSize == 0 || opts.explain || !q.Return {
+		return nil
+	}
+
+	p := paging{set: opts.set, size: opts.pageSize}
+	if opts.token != nil && opts.token.Query == q.Name {
+		p.after = opts.token.Values
+		p.read = opts.token.Read
+	}
+
+	return &p
+}
+
+// maskCollection returns the collection whose masks apply to the results
+// read from the collection.
+func (opts options) maskCollection(collection string) string {
+	if m, exists := opts.masks[collection]; exists {
+		return m
+	}
+
+	return collection
+}
+
+//==============================================================================
+
+// pipelineSortKeys returns the sort keys from the last $sort stage of the
+// pipeline with _id added to break ties. The order of the fields in a
+// $sort document is not preserved so only a single field is supported.
+func pipelineSortKeys(pipeline []bson.M) ([]keyset.Key, error) {
+	var sort []string
+
+	for i := len(pipeline) - 1; i >= 0; i-- {
+		stage, exists := pipeline[i]["$sort"]
+		if !exists {
+			continue
+		}
+
+		doc, err := findDoc("$sort", stage)
+		if err != nil || len(doc) != 1 {
+			return nil, errors.New("Pagination requires the last $sort to use a single field")
+		}
+
+		for fld, dir := range doc {
+			d, err := sortDir(fld, dir)
+			if err != nil {
+				return nil, err
+			}
+			if d < 0 {
+				fld = "-" + fld
+			}
+			sort = append(sort, fld)
+		}
+		break
+	}
+
+	return keyset.Keys(sort, "_id"), nil
+}
+
+// sortDir returns the direction of a $sort field as 1 or -1.
+func sortDir(fld string, dir interface{}) (int, error) {
+	var n float64
+
+	switch v := dir.(type) {
+	case int:
+		n = float64(v)
+	case int32:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	}
+
+	switch n {
+	case 1:
+		return 1, nil
+	case -1:
+		return -1, nil
+	}
+
+	return 0, fmt.Errorf("Invalid sort direction for %q", fld)
+}
+
+// pipeline adds the stages to the pipeline that read the page of documents
+// after the token. The page is read from the output of the pipeline so the
+// sort key is taken from its last $sort stage.
+func (p *paging) pipeline(pipeline []bson.M) ([]bson.M, error) {
+	keys, err := pipelineSortKeys(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	if err := p.checkMasks(); err != nil {
+		return nil, err
+	}
+
+	if p.after != nil {
+		after, err := keyset.After(p.keys, p.after)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+
+		pipeline = append(pipeline, bson.M{"$match": after})
+	}
+
+	// Ask for one more document to know if there is another page.
+	return append(pipeline, bson.M{"$sort": keyset.Doc(p.keys)}, bson.M{"$limit": p.size + 1}), nil
+}
+
+// find updates the spec to read the page of documents after the token. The
+// skip only applies to the first page and the pages stop at the limit of the
+// find, so the last page may be smaller.
+func (p *paging) find(spec findSpec) (findSpec, error) {
+	p.keys = keyset.Keys(spec.sort, "_id")
+
+	if err := p.checkMasks(); err != nil {
+		return spec, err
+	}
+
+	if p.after != nil {
+		after, err := keyset.After(p.keys, p.after)
+		if err != nil {
+			return spec, ErrInvalidToken
+		}
+
+		if spec.filter != nil {
+			after = bson.M{"$and": []interface{}{spec.filter, after}}
+		}
+
+		spec.filter = after
+		spec.skip = 0
+	}
+
+	// Ask for one more document to know if there is another page.
+	spec.sort = keyset.Fields(p.keys)
+	limit := p.size + 1
+
+	if spec.limit > 0 {
+		remaining := spec.limit - p.read
+		if remaining <= 0 {
+			return spec, ErrInvalidToken
+		}
+
+		// There is no page after the one reaching the limit.
+		if remaining < limit {
+			limit = remaining
+			p.size = remaining
+		}
+	}
+
+	spec.limit = limit
+
+	return spec, nil
+}
+
+// checkMasks rejects a page sorted on a field whose value is masked. The
+// token holds the sort values of the last document before it is masked and
+// a token with chosen values would reveal the masked values.
+func (p *paging) checkMasks() error {
+	flds := make([]string, len(p.keys))
+	for i, k := range p.keys {
+		flds[i] = k.Field
+	}
+
+	masked := func(name string) bool {
+		_, exists := p.masks[name]
+		return exists
+	}
+
+	if fld := keyset.Masked(flds, masked); fld != "" {
+		return fmt.Errorf("Pagination can't sort on the masked field %q", fld)
+	}
+
+	return nil
+}
+
+// result trims the extra document that was requested to know if there are
+// more documents and returns the token for the next page.
+func (p *paging) result(context interface{}, q *query.Query, results []bson.M) ([]bson.M, string, error) {
+	if len(results) <= p.size {
+		return results, "", nil
+	}
+
+	results = results[:p.size]
+	last := results[p.size-1]
+
+	values := make([]interface{}, len(p.keys))
+	for i, k := range p.keys {
+		v, err := docFieldLookup(context, last, k.Field)
+		if err != nil {
+			return nil, "", fmt.Errorf("Pagination requires the sort field %q in the results", k.Field)
+		}
+		values[i] = v
+	}
+
+	token, err := keyset.Encode(pageToken{Set: p.set, Query: q.Name, Values: values, Read: p.read + p.size})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return results, token, nil
+}
+
+// docs returns the documents for the query with the page details. It can
+// be called on a nil value for queries that are not paged.
+func (p *paging) docs(name string, results []bson.M, next string) docs {
+	d := docs{Name: name, Docs: results}
+
+	if p != nil {
+		hasMore := next != ""
+		d.Next = next
+		d.HasMore = &hasMore
+	}
+
+	return d
+}
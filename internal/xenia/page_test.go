@@ -0,0 +1,192 @@
+package xenia_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/coralproject/shelf/tstdata"
+)
+
+// pageResult is the result of executing a set for a single page.
+type pageResult struct {
+	Results []struct {
+		Name    string
+		Docs    []map[string]interface{}
+		Next    string `json:"next"`
+		HasMore *bool  `json:"has_more"`
+	} `json:"results"`
+}
+
+// getPageSets returns the sets used to test paging. Each set returns the
+// stations 42021 and 44008 sorted by name in descending order.
+func getPageSets() []*query.Set {
+	filter := map[string]interface{}{"station_id": map[string]interface{}{"$in": []string{"42021", "44008"}}}
+
+	return []*query.Set{
+		{
+			Name:    "Page Find",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Page Find",
+					Type:       "find",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{
+							"filter":     filter,
+							"projection": map[string]interface{}{"name": 1},
+							"sort":       []interface{}{"-name"},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    "Page Pipeline",
+			Enabled: true,
+			Queries: []query.Query{
+				{
+					Name:       "Page Pipeline",
+					Type:       "pipeline",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"$match": filter},
+						{"$project": map[string]interface{}{"name": 1}},
+						{"$sort": map[string]interface{}{"name": -1}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestExecPage tests the results of a set can be read a page at a time
+// using the continuation token.
+func TestExecPage(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	t.Log("Given the need to load the test data.")
+	{
+		loadTestData(t, db)
+	}
+
+	defer func() {
+		t.Log("Given the need to unload the test data.")
+		{
+			unloadTestData(t, db)
+		}
+	}()
+
+	t.Log("Given the need to page through the results of a set.")
+	{
+		for _, set := range getPageSets() {
+			t.Logf("\tWhen using Execute Set %s", set.Name)
+			{
+				var names []string
+				var hasMore []bool
+
+				page := query.Page{Size: 1}
+				for i := 0; i < 3; i++ {
+					data, err := json.Marshal(xenia.ExecPage(tests.Context, db, set, nil, page))
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to marshal the result : %s", tests.Failed, err)
+					}
+
+					var res pageResult
+					if err := json.Unmarshal(data, &res); err != nil || len(res.Results) != 1 {
+						t.Log("Got :", string(data))
+						t.Fatalf("\t%s\tShould be able to unmarshal the result.", tests.Failed)
+					}
+
+					r := res.Results[0]
+					if len(r.Docs) != 1 || r.HasMore == nil {
+						t.Log("Got :", string(data))
+						t.Fatalf("\t%s\tShould get a single document with the page details.", tests.Failed)
+					}
+
+					name, _ := r.Docs[0]["name"].(string)
+					names = append(names, name)
+					hasMore = append(hasMore, *r.HasMore)
+
+					if r.Next == "" {
+						break
+					}
+					page.Token = r.Next
+				}
+				t.Logf("\t%s\tShould be able to read each page.", tests.Success)
+
+				if len(names) != 2 || names[0] != "NANTUCKET 54NM Southeast of Nantucket" || names[0] == names[1] {
+					t.Log("Got :", names)
+					t.Errorf("\t%s\tShould get each document once in order.", tests.Failed)
+				} else {
+					t.Logf("\t%s\tShould get each document once in order.", tests.Success)
+				}
+
+				if len(hasMore) != 2 || !hasMore[0] || hasMore[1] {
+					t.Log("Got :", hasMore)
+					t.Errorf("\t%s\tShould report more documents on the first page only.", tests.Failed)
+				} else {
+					t.Logf("\t%s\tShould report more documents on the first page only.", tests.Success)
+				}
+			}
+		}
+	}
+
+	t.Log("Given the need to page through the results of a find with a limit.")
+	{
+		t.Log("\tWhen using a page size below the limit")
+		{
+			set := getPageSets()[0]
+			set.Queries[0].Commands[0]["limit"] = 1
+
+			data, err := json.Marshal(xenia.ExecPage(tests.Context, db, set, nil, query.Page{Size: 5}))
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to marshal the result : %s", tests.Failed, err)
+			}
+
+			var res pageResult
+			if err := json.Unmarshal(data, &res); err != nil || len(res.Results) != 1 {
+				t.Log("Got :", string(data))
+				t.Fatalf("\t%s\tShould be able to unmarshal the result.", tests.Failed)
+			}
+
+			r := res.Results[0]
+			if len(r.Docs) != 1 || r.Next != "" {
+				t.Log("Got :", string(data))
+				t.Fatalf("\t%s\tShould stop the page at the limit of the find.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould stop the page at the limit of the find.", tests.Success)
+		}
+	}
+
+	t.Log("Given the need to reject an invalid continuation token.")
+	{
+		t.Log("\tWhen using a token that was not issued for the set")
+		{
+			set := getPageSets()[0]
+			result := xenia.ExecPage(tests.Context, db, set, nil, query.Page{Size: 1, Token: "bad"})
+
+			data, _ := json.Marshal(result)
+			if exp := `{"results":{"error":"Invalid page token"}}`; string(data) != exp {
+				t.Log("Got :", string(data))
+				t.Log("Exp :", exp)
+				t.Errorf("\t%s\tShould get an invalid token error.", tests.Failed)
+			} else {
+				t.Logf("\t%s\tShould get an invalid token error.", tests.Success)
+			}
+		}
+	}
+}
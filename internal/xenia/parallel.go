@@ -0,0 +1,360 @@
+package xenia
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// workers is the maximum number of queries of a set executed concurrently.
+var workers = 4
+
+// SetWorkers sets the maximum number of queries of a set that are executed
+// concurrently. A value of 1 executes the independent queries one at a time.
+func SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	workers = n
+}
+
+// outcome contains the result of executing a single query.
+type outcome struct {
+	result   docs
+	commands []map[string]interface{}
+	err      error
+	ran      bool
+	duration time.Duration
+}
+
+// runQueries executes the queries of the set returning an outcome for each
+// query in the same order. Queries that don't depend on the saved results of
+// another query run concurrently. Once a query fails that is not marked to
+// continue, the queries after it that have not started are skipped. Queries
+// that write to a collection always wait for the queries before them that
+// are not marked to continue, so a write never happens after such a query
+// failed, like when the queries run one at a time.
+func runQueries(context interface{}, db *db.DB, set *query.Set, vars map[string]string, opts options) []outcome {
+	deps, saves := queryDeps(set.Queries, vars)
+
+	n := len(set.Queries)
+	outcomes := make([]outcome, n)
+
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	// Hold any data we have been asked to save.
+	data := make(map[string]interface{})
+
+	// Index of the first query that failed and stops the set.
+	stopAt := n
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+
+	wg.Add(n)
+	for i := range set.Queries {
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			// Wait for the queries we depend on to complete.
+			for _, d := range deps[i] {
+				<-done[d]
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Take a copy of the saved data so we can run without holding
+			// the lock. Our dependencies have saved their results.
+			mu.Lock()
+			if i > stopAt {
+				mu.Unlock()
+				return
+			}
+
+			local := make(map[string]interface{}, len(data))
+			for k, v := range data {
+				local[k] = v
+			}
+			mu.Unlock()
+
+			q := set.Queries[i]
+			q.Commands = cloneCommands(q.Commands)
+
+			started := time.Now()
+			result, commands, err := execQuery(context, db, &q, vars, local, opts)
+			duration := time.Since(started)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			outcomes[i] = outcome{result: result, commands: commands, err: err, ran: true, duration: duration}
+
+			if err != nil {
+				if !q.Continue && i < stopAt {
+					stopAt = i
+				}
+				return
+			}
+
+			if name := saves[i]; name != "" {
+				if v, exists := local[name]; exists {
+					data[name] = v
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	log.Dev(context, "runQueries", "Completed : Queries[%d] Workers[%d]", n, workers)
+	return outcomes
+}
+
+// queryDeps returns for each query the index of the queries it must wait
+// for and the name of the map each query saves its results under. A query
+// depends on a prior query that saves to a name it reads with #data, saves
+// to the same name or reads the name it saves to. The same applies to the
+// collections queries save their results into. A query that writes to a
+// collection depends on every prior query that is not marked to continue.
+// The collections are the ones written with $save, $out and $merge and read
+// as the collection of the query or with $lookup and $graphLookup.
+func queryDeps(queries []query.Query, vars map[string]string) ([][]int, []string) {
+	n := len(queries)
+	reads := make([]map[string]bool, n)
+	saves := make([]string, n)
+	colls := make([]map[string]bool, n)
+	writes := make([]map[string]bool, n)
+
+	for i, q := range queries {
+		reads[i] = make(map[string]bool)
+		for _, cmd := range q.Commands {
+			dataReads(cmd, vars, reads[i])
+		}
+
+		colls[i] = map[string]bool{q.Collection: true}
+		writes[i] = make(map[string]bool)
+		stageCollections(q.Commands, vars, colls[i], writes[i])
+
+		st := querySave(q.Commands)
+		saves[i] = st.mapName
+		if st.collection != "" {
+			writes[i][st.collection] = true
+		}
+	}
+
+	deps := make([][]int, n)
+	for i := range queries {
+		for j := 0; j < i; j++ {
+			switch {
+			case len(writes[i]) > 0 && !queries[j].Continue:
+			case saves[j] != "" && reads[i][saves[j]]:
+			case saves[i] != "" && saves[i] == saves[j]:
+			case saves[i] != "" && reads[j][saves[i]]:
+			case shares(writes[j], colls[i]) || shares(writes[j], writes[i]):
+			case shares(writes[i], colls[j]):
+			default:
+				continue
+			}
+
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	return deps, saves
+}
+
+// querySave returns the targets the query saves its results to.
+func querySave(commands []map[string]interface{}) saveTarget {
+	l := len(commands) - 1
+	if l < 0 {
+		return saveTarget{}
+	}
+
+	save, err := findDoc("$save", commands[l]["$save"])
+	if err != nil || save == nil {
+		return saveTarget{}
+	}
+
+	// Invalid targets fail when the query runs.
+	st, _ := parseSave(save)
+	return st
+}
+
+// stageCollections adds the collections the stages of the commands read
+// from and write to. The pipelines of $lookup and $facet stages are walked.
+//
+// {"$out": "totals"}
+// {"$merge": {"into": "totals"}}
+// {"$merge": {"into": {"coll": "totals"}}}
+// {"$lookup": {"from": "users", ...}}
+func stageCollections(commands []map[string]interface{}, vars map[string]string, reads map[string]bool, writes map[string]bool) {
+	for _, cmd := range commands {
+		for stage, value := range cmd {
+			d, _ := value.(map[string]interface{})
+
+			switch stage {
+			case "$out", "$merge":
+				name, _ := value.(string)
+				if into, ok := d["into"].(map[string]interface{}); ok {
+					d = into
+				}
+
+				for _, key := range []string{"into", "coll"} {
+					if s, ok := d[key].(string); ok {
+						name = s
+						break
+					}
+				}
+
+				if name != "" {
+					writes[collectionVar(name, vars)] = true
+				}
+
+			case "$lookup", "$graphLookup":
+				if from, ok := d["from"].(string); ok {
+					reads[collectionVar(from, vars)] = true
+				}
+
+				if sub, ok := d["pipeline"].([]interface{}); ok {
+					stageCollections(subCommands(sub), vars, reads, writes)
+				}
+
+			case "$facet":
+				for _, sub := range d {
+					if stages, ok := sub.([]interface{}); ok {
+						stageCollections(subCommands(stages), vars, reads, writes)
+					}
+				}
+			}
+		}
+	}
+}
+
+// subCommands returns the stages of a pipeline nested in a command.
+func subCommands(stages []interface{}) []map[string]interface{} {
+	cmds := make([]map[string]interface{}, 0, len(stages))
+	for _, stage := range stages {
+		if cmd, ok := stage.(map[string]interface{}); ok {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	return cmds
+}
+
+// collectionVar returns the collection named by a string variable or the
+// name itself when it isn't a variable.
+func collectionVar(name string, vars map[string]string) string {
+	if strings.HasPrefix(name, "#string:") {
+		if v, exists := vars[name[len("#string:"):]]; exists {
+			return v
+		}
+	}
+
+	return name
+}
+
+// shares reports if the two sets of collections have a collection in common.
+func shares(a map[string]bool, b map[string]bool) bool {
+	for name := range a {
+		if b[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataReads walks the document adding the names of the saved results it
+// references with the #data command.
+func dataReads(doc map[string]interface{}, vars map[string]string, reads map[string]bool) {
+	for _, value := range doc {
+		dataReadsValue(value, vars, reads)
+	}
+}
+
+// dataReadsValue checks a single value of a document for #data references.
+func dataReadsValue(value interface{}, vars map[string]string, reads map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		dataReads(v, vars, reads)
+
+	case []interface{}:
+		for _, sub := range v {
+			dataReadsValue(sub, vars, reads)
+		}
+
+	case string:
+
+		// {"field": "#data.0:list.station_id"}
+		if !strings.HasPrefix(v, "#data") {
+			return
+		}
+
+		idx := strings.IndexByte(v, ':')
+		if idx == -1 {
+			return
+		}
+
+		// The lookup can be provided through a variable.
+		lookups := []string{v[idx+1:]}
+		if param, exists := vars[v[idx+1:]]; exists {
+			lookups = append(lookups, param)
+		}
+
+		for _, lookup := range lookups {
+			if idx := strings.IndexByte(lookup, '.'); idx != -1 {
+				reads[lookup[:idx]] = true
+			}
+		}
+	}
+}
+
+// cloneCommands returns a deep copy of the commands so variables can be
+// substituted without touching documents shared with other queries, such
+// as the ones added by pre/post scripts.
+func cloneCommands(commands []map[string]interface{}) []map[string]interface{} {
+	if commands == nil {
+		return nil
+	}
+
+	cmds := make([]map[string]interface{}, len(commands))
+	for i, cmd := range commands {
+		cmds[i] = cloneValue(cmd).(map[string]interface{})
+	}
+
+	return cmds
+}
+
+// cloneValue returns a deep copy of documents and arrays.
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		doc := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			doc[key] = cloneValue(val)
+		}
+		return doc
+
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, val := range v {
+			arr[i] = cloneValue(val)
+		}
+		return arr
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,137 @@
+package xenia
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// TestQueryDeps tests the dependencies between queries are found from
+// their saved results, #data references and the collections queries write
+// and read.
+func TestQueryDeps(t *testing.T) {
+	queries := []query.Query{
+		{
+			Name: "list",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+				{"$save": map[string]interface{}{"$map": "list"}},
+			},
+		},
+		{
+			Name:     "independent",
+			Continue: true,
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "#string:station_id"}},
+			},
+		},
+		{
+			Name: "in",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": map[string]interface{}{"$in": "#data.*:list.station_id"}}},
+			},
+		},
+		{
+			Name:     "variable",
+			Continue: true,
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"$or": []interface{}{
+					map[string]interface{}{"station_id": "#data.0:lookup"},
+				}}},
+			},
+		},
+		{
+			Name: "overwrite",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+				{"$save": map[string]interface{}{"$map": "list"}},
+			},
+		},
+		{
+			Name:       "materialize",
+			Collection: "stations",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+				{"$save": map[string]interface{}{"$collection": "top_stations"}},
+			},
+		},
+		{
+			Name:       "rollup",
+			Collection: "top_stations",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+			},
+		},
+		{
+			Name:       "archive",
+			Collection: "stations",
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+				{"$out": "archived_stations"},
+			},
+		},
+		{
+			Name:       "report",
+			Collection: "archived_stations",
+			Continue:   true,
+			Commands: []map[string]interface{}{
+				{"$match": map[string]interface{}{"station_id": "42021"}},
+			},
+		},
+		{
+			Name:       "joined",
+			Collection: "stations",
+			Continue:   true,
+			Commands: []map[string]interface{}{
+				{"$lookup": map[string]interface{}{"from": "#string:archive", "localField": "station_id", "foreignField": "station_id", "as": "archived"}},
+			},
+		},
+		{
+			Name:       "merge",
+			Collection: "archived_stations",
+			Continue:   true,
+			Commands: []map[string]interface{}{
+				{"$merge": map[string]interface{}{"into": map[string]interface{}{"coll": "stations"}}},
+			},
+		},
+	}
+
+	vars := map[string]string{"lookup": "list.station_id", "archive": "archived_stations"}
+
+	exp := [][]int{
+		nil,
+		nil,
+		{0},
+		{0},
+		{0, 2, 3},
+		{0, 2, 4},
+		{5},
+		{0, 2, 4, 5, 6},
+		{7},
+		{7},
+		{0, 2, 4, 5, 6, 7, 9},
+	}
+
+	t.Log("Given the need to find the dependencies between queries.")
+	{
+		deps, saves := queryDeps(queries, vars)
+
+		for i, q := range queries {
+			t.Logf("\tWhen checking query %q", q.Name)
+			{
+				if !reflect.DeepEqual(deps[i], exp[i]) {
+					t.Errorf("\t%s\tShould depend on %v : got %v", tests.Failed, exp[i], deps[i])
+					continue
+				}
+				t.Logf("\t%s\tShould depend on %v.", tests.Success, exp[i])
+			}
+		}
+
+		if saves[0] != "list" || saves[4] != "list" || saves[1] != "" {
+			t.Fatalf("\t%s\tShould find the saved names : %v", tests.Failed, saves)
+		}
+		t.Logf("\t%s\tShould find the saved names.", tests.Success)
+	}
+}
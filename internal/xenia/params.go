@@ -11,8 +11,32 @@ import (
 	"github.com/coralproject/shelf/internal/xenia/regex"
 )
 
+// ParamError describes why the value of a parameter is invalid.
+type ParamError struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+	Msg   string `json:"error"`
+}
+
+// ParamErrors contains the errors for all the invalid parameters.
+type ParamErrors []ParamError
+
+// Error implements the error interface.
+func (pe ParamErrors) Error() string {
+	errs := make([]string, len(pe))
+	for i, e := range pe {
+		errs[i] = e.Name + ": " + e.Msg
+	}
+
+	return "Invalid parameters : " + strings.Join(errs, ", ")
+}
+
+//==============================================================================
+
 // processParams validates the variables against the query string of parameters.
-// It also loads default values and processes parameter regexes.
+// It also loads default values, coerces the values to the canonical form
+// of their type and processes parameter regexes. Any failures are returned
+// as ParamErrors.
 func processParams(context interface{}, db *db.DB, set *query.Set, vars map[string]string) error {
 
 	// Do we not have parameters.
@@ -20,46 +44,48 @@ func processParams(context interface{}, db *db.DB, set *query.Set, vars map[stri
 		return nil
 	}
 
-	// Do we not have variables, load the default values.
-	if len(vars) == 0 {
-		for _, p := range set.Params {
-			if p.Default != "" {
-				log.Dev(context, "validateParameters", "Adding : Name[%s] Default[%s]", p.Name, p.Default)
-				vars[p.Name] = p.Default
-			}
-		}
-	}
-
-	var errs []string
+	var errs ParamErrors
 
 	// Validate each known parameter is represented in the variable list.
 	for _, p := range set.Params {
-		if _, exists := vars[p.Name]; !exists {
+		value, exists := vars[p.Name]
+		if !exists {
 
 			// The variable was not provided but we have a
 			// default value for this so use it.
-			if p.Default != "" {
-				log.Dev(context, "validateParameters", "Adding : Name[%s] Default[%s]", p.Name, p.Default)
-				vars[p.Name] = p.Default
-			} else {
-
-				// We are missing the parameter.
-				errs = append(errs, "Missing["+p.Name+"]")
+			if p.Default == "" {
+				if !p.Optional {
+					errs = append(errs, ParamError{Name: p.Name, Msg: "Value is required"})
+				}
+				continue
 			}
+
+			log.Dev(context, "validateParameters", "Adding : Name[%s] Default[%s]", p.Name, p.Default)
+			value = p.Default
+		}
+
+		// Check the value against the type and limits.
+		coerced, err := p.Coerce(value)
+		if err != nil {
+			errs = append(errs, ParamError{Name: p.Name, Value: value, Msg: err.Error()})
+			continue
 		}
+		value = coerced
 
 		// Is there a regex to validate against?
 		if p.RegexName != "" {
-			value := vars[p.Name]
 			if err := validateRegex(context, db, value, p.RegexName); err != nil {
-				errs = append(errs, "Invalid["+value+":"+p.RegexName+":"+err.Error()+"]")
+				errs = append(errs, ParamError{Name: p.Name, Value: value, Msg: err.Error()})
+				continue
 			}
 		}
+
+		vars[p.Name] = value
 	}
 
 	// Were there any errors.
 	if errs != nil {
-		return errors.New(strings.Join(errs, ","))
+		return errs
 	}
 
 	return nil
@@ -2,39 +2,119 @@ package xenia
 
 import (
 	"errors"
-	"fmt"
 	"net"
 	"time"
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/metrics"
 	"github.com/coralproject/shelf/internal/xenia/query"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
 // execPipeline executes the sepcified pipeline query.
-func execPipeline(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, explain bool) (docs, []map[string]interface{}, error) {
+func execPipeline(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, opts options) (docs, []map[string]interface{}, error) {
 
 	// I am returning commands as the second return value because if there
 	// is an error I need to send how far we got back to the client. If not,
 	// the user will not understand the error message.
 
+	pipeline, agg, save, commands, err := buildPipeline(context, q, vars, data)
+	if err != nil {
+		return docs{}, commands, err
+	}
+
+	// Do we want the explain output.
+	if opts.explain {
+		m, err := explainPipeline(context, db, q, pipeline, agg)
+		if err != nil {
+			return docs{}, commands, err
+		}
+
+		return docs{Name: q.Name, Docs: []bson.M{m}}, commands, nil
+	}
+
+	// Add the stages to read a single page of the results.
+	pg := opts.paging(q)
+	if pg != nil {
+		pg.masks = loadMasks(context, db, opts.maskCollection(q.Collection))
+
+		n := len(pipeline)
+		if pipeline, err = pg.pipeline(pipeline); err != nil {
+			return docs{}, commands, err
+		}
+
+		for _, stage := range pipeline[n:] {
+			agg += mongo.Query(stage) + ",\n"
+		}
+	}
+
+	// Set the timeout for the session.
+	timeout := queryTimeout(context, q)
+
+	// Build the pipeline function for the execution.
+	var results []bson.M
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "executePipeline", "MGO Started\ndb.%s.aggregate([\n%s])", c.Name, agg)
+		err := c.Pipe(pipeline).All(&results)
+		return err
+	}
+
+	if err := execTimeout(context, db, q.Collection, timeout, f); err != nil {
+		return docs{}, commands, err
+	}
+
+	log.Dev(context, "executePipeline", "Completed")
+
+	// If there were no results, return an empty array.
+	if results == nil {
+		return pg.docs(q.Name, []bson.M{}, ""), commands, nil
+	}
+
+	// Trim the results to the page before the sort key is masked.
+	var next string
+	if pg != nil {
+		if results, next, err = pg.result(context, q, results); err != nil {
+			return docs{}, commands, err
+		}
+	}
+
+	// Perform any masking that is required.
+	if err := processMasks(context, db, opts.maskCollection(q.Collection), results); err != nil {
+		return docs{}, commands, err
+	}
+
+	// Do we need to save the result.
+	if save != nil {
+		if err := saveResult(context, db, save, results, data); err != nil {
+			return docs{}, commands, err
+		}
+	}
+
+	return pg.docs(q.Name, results, next), commands, nil
+}
+
+// buildPipeline performs the variable substitution for the query commands and
+// returns the pipeline to execute. If the last command is the extended $save
+// command it is removed from the pipeline and returned separately.
+func buildPipeline(context interface{}, q *query.Query, vars map[string]string, data map[string]interface{}) ([]bson.M, string, map[string]interface{}, []map[string]interface{}, error) {
+
 	// We need to check to see if the last command is the extended $save command.
 	commands := q.Commands
 	l := len(q.Commands) - 1
 
 	// Validate we have scripts to run.
 	if l < 0 {
-		return docs{}, commands, errors.New("Invalid pipeline script")
+		return nil, "", nil, commands, errors.New("Invalid pipeline script")
 	}
 
 	// If the last command is a $save, capture its value and remove
 	// it from the pipeline.
 	var save map[string]interface{}
 	if v, exists := q.Commands[l]["$save"]; exists {
-		if cmd, ok := v.(map[string]interface{}); ok {
+		if cmd, err := findDoc("$save", v); err == nil {
 			save = cmd
 		}
 
@@ -50,7 +130,7 @@ func execPipeline(context interface{}, db *db.DB, q *query.Query, vars map[strin
 		// Do we have variables to be substitued.
 		if vars != nil {
 			if err := ProcessVariables(context, command, vars, data); err != nil {
-				return docs{}, commands, err
+				return nil, "", nil, commands, err
 			}
 		}
 
@@ -61,25 +141,27 @@ func execPipeline(context interface{}, db *db.DB, q *query.Query, vars map[strin
 		agg += mongo.Query(command) + ",\n"
 	}
 
-	// Do we want the explain output.
-	if explain {
-
-		// Build the pipeline function for the execution for explain.
-		var m bson.M
-		f := func(c *mgo.Collection) error {
-			log.Dev(context, "executePipeline", "MGO Explain :\ndb.%s.aggregate([\n%s])", c.Name, agg)
-			return c.Pipe(pipeline).Explain(&m)
-		}
+	return pipeline, agg, save, commands, nil
+}
 
-		// Execute the pipeline.
-		if err := db.ExecuteMGO(context, q.Collection, f); err != nil {
-			return docs{}, commands, err
-		}
+// explainPipeline returns the explain output for the pipeline.
+func explainPipeline(context interface{}, db *db.DB, q *query.Query, pipeline []bson.M, agg string) (bson.M, error) {
+	var m bson.M
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "executePipeline", "MGO Explain :\ndb.%s.aggregate([\n%s])", c.Name, agg)
+		return c.Pipe(pipeline).Explain(&m)
+	}
 
-		return docs{q.Name, []bson.M{m}}, commands, nil
+	if err := db.ExecuteMGO(context, q.Collection, f); err != nil {
+		return nil, err
 	}
 
-	// Set the default timeout for the session.
+	return m, nil
+}
+
+// queryTimeout returns the timeout configured for the query or the
+// default timeout if none is provided or it can't be parsed.
+func queryTimeout(context interface{}, q *query.Query) time.Duration {
 	timeout := 25 * time.Second
 	if q.Timeout != "" {
 		if d, err := time.ParseDuration(q.Timeout); err != nil {
@@ -90,105 +172,80 @@ func execPipeline(context interface{}, db *db.DB, q *query.Query, vars map[strin
 	}
 
 	log.Dev(context, "executePipeline", "MGO Timeout Set[%s]", timeout)
+	return timeout
+}
 
-	// Build the pipeline function for the execution.
-	var results []bson.M
-	f := func(c *mgo.Collection) error {
-		log.Dev(context, "executePipeline", "MGO Started\ndb.%s.aggregate([\n%s])", c.Name, agg)
-		err := c.Pipe(pipeline).All(&results)
-		return err
-	}
+// execTimeout executes the function against the collection. If the function
+// does not return within the timeout an error is returned.
+func execTimeout(context interface{}, db *db.DB, collection string, timeout time.Duration, f func(*mgo.Collection) error) error {
 
 	// Set the channel to one because we might not be around
 	// waiting for the result on timeouts.
 	wait := make(chan error, 1)
 
-	// Execute the pipeline.
+	// Execute the function.
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Dev(context, "executePipeline", "******> Recovered from timing out")
+				log.Dev(context, "execTimeout", "******> Recovered from timing out")
 			}
-			log.Dev(context, "executePipeline", "MGO Response Complete")
+			log.Dev(context, "execTimeout", "MGO Response Complete")
 		}()
 
-		wait <- db.ExecuteMGOTimeout(context, timeout, q.Collection, f)
+		wait <- db.ExecuteMGOTimeout(context, timeout, collection, f)
 	}()
 
 	// Did any errors occur.
 	select {
 
-	// Wait for the response from executing the pipeline.
+	// Wait for the response from executing the function.
 	case err := <-wait:
 		if err != nil {
 			if _, ok := err.(*net.OpError); ok {
-				log.Error(context, "executePipeline", err, "Timed out Network")
-				return docs{}, commands, errors.New("Completed : Timed out executing commands")
+				metrics.ExecTimeouts.Inc(collection)
+				log.Error(context, "execTimeout", err, "Timed out Network")
+				return errors.New("Completed : Timed out executing commands")
 			}
 
-			log.Error(context, "executePipeline", err, "Completed")
-			return docs{}, commands, err
+			log.Error(context, "execTimeout", err, "Completed")
+			return err
 		}
 
 	// Wait to timeout the entire operation.
 	case <-time.After(timeout):
+		metrics.ExecTimeouts.Inc(collection)
 		err := errors.New("Timedout executing commands")
-		log.Error(context, "executePipeline", err, "Completed : Timed out Processing")
-		return docs{}, commands, err
-	}
-
-	log.Dev(context, "executePipeline", "Completed")
-
-	// If there were no results, return an empty array.
-	if results == nil {
-		return docs{q.Name, []bson.M{}}, commands, nil
-	}
-
-	// Perform any masking that is required.
-	if err := processMasks(context, db, q.Collection, results); err != nil {
-		return docs{}, commands, err
-	}
-
-	// Do we need to save the result.
-	if save != nil {
-		if err := saveResult(context, save, results, data); err != nil {
-			return docs{}, commands, err
-		}
+		log.Error(context, "execTimeout", err, "Completed : Timed out Processing")
+		return err
 	}
 
-	return docs{q.Name, results}, commands, nil
+	return nil
 }
 
 // saveResult processes the $save command for this result.
-func saveResult(context interface{}, save map[string]interface{}, results []bson.M, data map[string]interface{}) error {
+func saveResult(context interface{}, db *db.DB, save map[string]interface{}, results []bson.M, data map[string]interface{}) error {
 
 	// {"$map": "list"}
+	// {"$collection": "top_commenters", "$key": "user_id"}
+	// {"$append": "daily_totals"}
 
-	// Capture the key and value and process the save.
-	for cmd, value := range save {
-		name, ok := value.(string)
-		if !ok {
-			err := fmt.Errorf("Save key \"%v\" is a %T but must be a string", value, value)
-			log.Error(context, "saveResult", err, "Extracting save key")
-			return err
-		}
-
-		switch cmd {
+	st, err := parseSave(save)
+	if err != nil {
+		log.Error(context, "saveResult", err, "Nothing saved")
+		return err
+	}
 
-		// Save the results into the map under the specified key.
-		case "$map":
-			log.Dev(context, "saveResult", "Saving result to map[%s]", name)
-			data[name] = results
-			return nil
+	// Save the results into the map under the specified key.
+	if st.mapName != "" {
+		log.Dev(context, "saveResult", "Saving result to map[%s]", st.mapName)
+		data[st.mapName] = results
+	}
 
-		default:
-			err := fmt.Errorf("Invalid save location %q", cmd)
-			log.Error(context, "saveResult", err, "Nothing saved")
-			return err
-		}
+	// Persist the results into the collection.
+	if st.collection != "" {
+		log.Dev(context, "saveResult", "Saving result to %s[%s]", st.mode, st.collection)
+		return writeResult(context, db, st, results)
 	}
 
-	err := errors.New("Missing save document")
-	log.Error(context, "saveResult", err, "Nothing saved")
-	return err
+	return nil
 }
@@ -20,6 +20,8 @@ func getNegExecSet() []execSet {
 		dataInMalformed(),
 		mongoRegexMalformed1(),
 		mongoRegexMalformed2(),
+		findInvldOption(),
+		paramInvldType(),
 	}
 }
 
@@ -216,7 +218,7 @@ func basicVarRegexFail() execSet {
 			},
 		},
 		results: []string{
-			`{"results":{"error":"Invalid[42021:email:Value \"42021\" does not match \"email\" expression]"}}`,
+			`{"results":{"error":"Invalid parameters : station_id: Value \"42021\" does not match \"email\" expression","params":[{"name":"station_id","value":"42021","error":"Value \"42021\" does not match \"email\" expression"}]}}`,
 		},
 	}
 }
@@ -247,7 +249,7 @@ func basicVarRegexMissing() execSet {
 			},
 		},
 		results: []string{
-			`{"results":{"error":"Invalid[42021:numbers:Regex Not found]"}}`,
+			`{"results":{"error":"Invalid parameters : station_id: Regex Not found","params":[{"name":"station_id","value":"42021","error":"Regex Not found"}]}}`,
 		},
 	}
 }
@@ -314,7 +316,7 @@ func basicMissingVars() execSet {
 			},
 		},
 		results: []string{
-			`{"results":{"error":"Missing[station_id]"}}`,
+			`{"results":{"error":"Invalid parameters : station_id: Value is required","params":[{"name":"station_id","error":"Value is required"}]}}`,
 		},
 	}
 }
@@ -407,3 +409,37 @@ func mongoRegexMalformed2() execSet {
 		},
 	}
 }
+
+// paramInvldType performs a simple query with values that don't match the
+// types of the parameters.
+func paramInvldType() execSet {
+	max := 10.0
+
+	return execSet{
+		fail: true,
+		vars: map[string]string{"limit": "20", "station_id": "abc"},
+		set: &query.Set{
+			Name:    "Param Invalid Type",
+			Enabled: true,
+			Params: []query.Param{
+				{Name: "station_id", Type: query.ParamInt},
+				{Name: "limit", Type: query.ParamInt, Max: &max},
+				{Name: "date", Type: query.ParamDate},
+			},
+			Queries: []query.Query{
+				{
+					Name:       "Typed",
+					Type:       "pipeline",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"$match": map[string]interface{}{"station_id": "#string:station_id"}},
+					},
+				},
+			},
+		},
+		results: []string{
+			`{"results":{"error":"Invalid parameters : station_id: Value is not an integer, limit: Value must be at most 10, date: Value is required","params":[{"name":"station_id","value":"abc","error":"Value is not an integer"},{"name":"limit","value":"20","error":"Value must be at most 10"},{"name":"date","error":"Value is required"}]}}`,
+		},
+	}
+}
@@ -27,6 +27,11 @@ func getPosExecSet() []execSet {
 		withAdjTime(),
 		fieldReplace(),
 		explain(),
+		basicFind(),
+		basicCount(),
+		basicDistinct(),
+		findSaveIn(),
+		paramTyped(),
 	}
 }
 
@@ -288,6 +293,43 @@ func basicParamDefault() execSet {
 	}
 }
 
+// paramTyped performs a simple query with typed parameters.
+func paramTyped() execSet {
+	min, max := 1.0, 10.0
+
+	return execSet{
+		fail: false,
+		vars: map[string]string{"station_id": "42021", "ids": "42021, 44008", "limit": " 2"},
+		set: &query.Set{
+			Name:    "Param Typed",
+			Enabled: true,
+			Params: []query.Param{
+				{Name: "station_id", Type: query.ParamEnum, Enum: []string{"42021", "44008"}},
+				{Name: "ids", Type: query.ParamList, Max: &max},
+				{Name: "limit", Type: query.ParamInt, Min: &min, Max: &max},
+				{Name: "skip", Type: query.ParamInt, Optional: true},
+			},
+			Queries: []query.Query{
+				{
+					Name:       "Typed",
+					Type:       "pipeline",
+					Collection: tstdata.CollectionExecTest,
+					Return:     true,
+					Commands: []map[string]interface{}{
+						{"$match": map[string]interface{}{"station_id": map[string]interface{}{"$in": "#list:ids"}}},
+						{"$sort": map[string]interface{}{"name": 1}},
+						{"$limit": "#number:limit"},
+						{"$project": map[string]interface{}{"_id": 0, "name": 1}},
+					},
+				},
+			},
+		},
+		results: []string{
+			`{"results":[{"Name":"Typed","Docs":[{"name":"C14 - Pasco County Buoy, FL"},{"name":"NANTUCKET 54NM Southeast of Nantucket"}]}]}`,
+		},
+	}
+}
+
 // basicVarRegex performs simple query with variables and regex validation.
 func basicVarRegex() execSet {
 	return execSet{
@@ -0,0 +1,507 @@
+// Package policy provides support for checking a query set against the
+// aggregation stages and collections a caller is allowed to use before the
+// set is executed.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// RoleAll is the name of the role whose rule applies to every caller,
+// including when authentication is off.
+const RoleAll = "*"
+
+// DefStages contains the aggregation stages allowed when the policy does
+// not provide its own list. The $out and $merge stages are controlled by
+// the write grant of the roles.
+var DefStages = []string{
+	"$match", "$project", "$addFields", "$set", "$unset", "$group", "$sort",
+	"$limit", "$skip", "$unwind", "$count", "$sortByCount", "$bucket",
+	"$bucketAuto", "$facet", "$sample", "$replaceRoot", "$lookup",
+	"$graphLookup", "$geoNear", "$redact",
+}
+
+// writeStages contains the stages that write into a collection.
+var writeStages = map[string]bool{
+	"$out":   true,
+	"$merge": true,
+}
+
+// jsOperators contains the operators that execute JavaScript on the server.
+// They are never allowed.
+var jsOperators = map[string]bool{
+	"$where":       true,
+	"$function":    true,
+	"$accumulator": true,
+}
+
+//==============================================================================
+
+// Rule contains what the callers holding a role are allowed to do.
+type Rule struct {
+	Collections []string `json:"collections"` // Collections that can be read. Names ending in * match a prefix.
+	Write       bool     `json:"write"`       // If the $out and $merge stages can be used.
+}
+
+// Policy contains the stages that can be used and the rule for each role.
+type Policy struct {
+	Stages []string        `json:"stages,omitempty"` // Stages that can be used. DefStages when empty.
+	Roles  map[string]Rule `json:"roles"`            // Rules by the name of the role or scope.
+}
+
+// Default returns the policy used when none is configured. Every caller can
+// read any collection that does not belong to Shelf using the default
+// stages and nobody can write.
+func Default() *Policy {
+	return &Policy{
+		Roles: map[string]Rule{
+			RoleAll: {Collections: []string{"*"}},
+		},
+	}
+}
+
+// Load reads the JSON policy from the specified file.
+func Load(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p Policy
+	if err := json.NewDecoder(f).Decode(&p); err != nil {
+		return nil, fmt.Errorf("Decoding policy %s : %v", path, err)
+	}
+
+	return &p, nil
+}
+
+//==============================================================================
+
+// Error describes why a query of a set is not allowed by the policy.
+type Error struct {
+	Query      string `json:"query"`
+	Stage      string `json:"stage,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("Query %q", e.Query)
+
+	if e.Stage != "" {
+		msg += fmt.Sprintf(" stage %q", e.Stage)
+	}
+
+	if e.Collection != "" {
+		msg += fmt.Sprintf(" collection %q", e.Collection)
+	}
+
+	return msg + " : " + e.Reason
+}
+
+//==============================================================================
+
+// grant contains the combined rules of the roles a caller holds.
+type grant struct {
+	collections []string
+	write       bool
+}
+
+// grant combines the rules for the roles plus the rule for every caller.
+func (p *Policy) grant(roles []string) grant {
+	var g grant
+
+	add := func(r Rule) {
+		g.collections = append(g.collections, r.Collections...)
+		g.write = g.write || r.Write
+	}
+
+	if r, exists := p.Roles[RoleAll]; exists {
+		add(r)
+	}
+
+	for _, role := range roles {
+		if role == RoleAll {
+			continue
+		}
+
+		if r, exists := p.Roles[role]; exists {
+			add(r)
+		}
+	}
+
+	return g
+}
+
+// allows reports if the collection can be read. The collections holding
+// xenia's own metadata, such as the sets and masks, are only allowed when
+// they are named exactly.
+func (g grant) allows(collection string) bool {
+	internal := metadata(collection)
+
+	for _, c := range g.collections {
+		if c == collection {
+			return true
+		}
+
+		if internal {
+			continue
+		}
+
+		if strings.HasSuffix(c, "*") && strings.HasPrefix(collection, strings.TrimSuffix(c, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// metadata reports if the collection holds xenia's own metadata.
+func metadata(collection string) bool {
+	return strings.HasPrefix(collection, "query_") || strings.HasPrefix(collection, "system.")
+}
+
+// Check validates each query of the set against the policy for a caller
+// holding the specified roles. The variables the set is executed with are
+// substituted the way the set is executed so the collections and stages
+// that are checked are the ones that are used. The first violation found
+// is returned as an *Error.
+func (p *Policy) Check(set *query.Set, roles []string, vars map[string]string) error {
+	stages := p.Stages
+	if len(stages) == 0 {
+		stages = DefStages
+	}
+
+	c := checker{
+		grant:  p.grant(roles),
+		stages: make(map[string]bool, len(stages)),
+		vars:   make(map[string]string, len(vars)+len(set.Params)),
+	}
+
+	// The defaults of the parameters are used when no value is provided.
+	for _, prm := range set.Params {
+		if prm.Default != "" {
+			c.vars[prm.Name] = prm.Default
+		}
+	}
+
+	for k, v := range vars {
+		c.vars[k] = v
+	}
+
+	for _, s := range stages {
+		c.stages[s] = true
+	}
+
+	for _, q := range set.Queries {
+		if err := c.query(q); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckWrite validates a caller holding the specified roles can write into
+// the collection for the set outside of its queries, like the collection the
+// results of a view are kept in for the set. The violation is returned as an
+// *Error.
+func (p *Policy) CheckWrite(set *query.Set, roles []string, collection string) error {
+	c := checker{grant: p.grant(roles)}
+	return c.write(set.Name, "", collection)
+}
+
+//==============================================================================
+
+// checker validates the queries of a set for a grant.
+type checker struct {
+	grant  grant
+	stages map[string]bool
+	vars   map[string]string
+}
+
+// query validates a single query.
+func (c checker) query(q query.Query) error {
+	if err := c.read(q.Name, "", q.Collection); err != nil {
+		return err
+	}
+
+	pipeline := strings.ToLower(q.Type) == query.TypePipeline
+
+	for _, cmd := range q.Commands {
+
+		// The $save command is handled by xenia and not sent to Mongo.
+		if save, exists := cmd["$save"]; exists {
+			if err := c.save(q.Name, save); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if pipeline {
+			if err := c.stage(q.Name, cmd); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.operators(q.Name, cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// read validates the collection a query or stage reads.
+func (c checker) read(name string, stage string, collection string) error {
+	coll, ok := c.collection(collection)
+	if !ok {
+		return &Error{Query: name, Stage: stage, Collection: collection, Reason: "Collection variable can't be resolved"}
+	}
+
+	if !c.grant.allows(coll) {
+		return &Error{Query: name, Stage: stage, Collection: coll, Reason: "Collection is not allowed"}
+	}
+
+	return nil
+}
+
+// write validates the collection a stage or $save command writes into.
+func (c checker) write(name string, stage string, collection string) error {
+	if !c.grant.write {
+		return &Error{Query: name, Stage: stage, Reason: "Stage requires the write grant"}
+	}
+
+	coll, ok := c.collection(collection)
+	if !ok {
+		return &Error{Query: name, Stage: stage, Collection: collection, Reason: "Collection variable can't be resolved"}
+	}
+
+	if err := collections.Writable(coll); err != nil {
+		return &Error{Query: name, Stage: stage, Collection: coll, Reason: err.Error()}
+	}
+
+	if !c.grant.allows(coll) {
+		return &Error{Query: name, Stage: stage, Collection: coll, Reason: "Collection is not allowed"}
+	}
+
+	return nil
+}
+
+// save validates the collection a $save command saves the results into.
+func (c checker) save(name string, value interface{}) error {
+	d := doc(value)
+
+	for _, mode := range []string{"$collection", "$append"} {
+		if target, exists := d[mode]; exists {
+			coll, _ := target.(string)
+			if err := c.write(name, "$save", coll); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// stage validates a single pipeline stage and any pipelines it contains.
+func (c checker) stage(name string, cmd map[string]interface{}) error {
+	for key, value := range cmd {
+		stage := c.key(key)
+
+		switch {
+		case writeStages[stage]:
+			coll, local := c.stageTarget(value)
+			if !local {
+				return &Error{Query: name, Stage: stage, Reason: "Stage can't write into another database"}
+			}
+
+			if err := c.write(name, stage, coll); err != nil {
+				return err
+			}
+
+		case !c.stages[stage]:
+			return &Error{Query: name, Stage: stage, Reason: "Stage is not allowed"}
+		}
+
+		switch stage {
+		case "$lookup", "$graphLookup":
+			d := c.doc(value)
+
+			from, _ := d["from"].(string)
+			if err := c.read(name, stage, from); err != nil {
+				return err
+			}
+
+			// The pipeline form of $lookup runs stages on the collection.
+			if sub, ok := d["pipeline"].([]interface{}); ok {
+				if err := c.pipeline(name, sub); err != nil {
+					return err
+				}
+			}
+
+		case "$facet":
+			for _, sub := range c.doc(value) {
+				if stages, ok := sub.([]interface{}); ok {
+					if err := c.pipeline(name, stages); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if err := c.operators(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pipeline validates each stage of a pipeline inside a stage.
+func (c checker) pipeline(name string, stages []interface{}) error {
+	for _, s := range stages {
+		cmd := doc(s)
+		if cmd == nil {
+			return &Error{Query: name, Reason: fmt.Sprintf("Stage is a %T but must be a document", s)}
+		}
+
+		if err := c.stage(name, cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// operators walks the value rejecting operators that execute JavaScript.
+func (c checker) operators(name string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}, bson.M:
+		for k, sub := range c.doc(v) {
+			if jsOperators[k] {
+				return &Error{Query: name, Stage: k, Reason: "Operator executes JavaScript and is not allowed"}
+			}
+
+			if err := c.operators(name, sub); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for _, sub := range v {
+			if err := c.operators(name, sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// collection resolves a collection named with a string variable. Collections
+// named with any other variable can't be checked.
+func (c checker) collection(name string) (string, bool) {
+	if name == "" || name[0] != '#' {
+		return name, true
+	}
+
+	if !strings.HasPrefix(name, "#string:") {
+		return "", false
+	}
+
+	v := c.vars[name[len("#string:"):]]
+	return v, v != ""
+}
+
+// key replaces the variables in the parts of a key with their values.
+//
+// {"{field}": 1} becomes {"status": 1}
+func (c checker) key(k string) string {
+	if strings.IndexByte(k, '{') == -1 {
+		return k
+	}
+
+	parts := strings.Split(k, ".")
+	for i, p := range parts {
+		if len(p) > 2 && p[0] == '{' && p[len(p)-1] == '}' {
+			if v, exists := c.vars[p[1:len(p)-1]]; exists {
+				parts[i] = v
+			}
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// doc returns the value as a document with the variables in its keys
+// replaced or nil if it isn't one.
+func (c checker) doc(value interface{}) map[string]interface{} {
+	d := doc(value)
+	if d == nil {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		out[c.key(k)] = v
+	}
+
+	return out
+}
+
+// stageTarget returns the collection a write stage writes into. It reports
+// false when the stage names a database as only the current one can be
+// written into.
+//
+// {"$out": "totals"}
+// {"$out": {"db": "reports", "coll": "totals"}}
+// {"$merge": "totals"}
+// {"$merge": {"into": "totals"}}
+// {"$merge": {"into": {"db": "reports", "coll": "totals"}}}
+func (c checker) stageTarget(value interface{}) (string, bool) {
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+
+	d := c.doc(value)
+	if into := c.doc(d["into"]); into != nil {
+		d = into
+	}
+
+	if _, exists := d["db"]; exists {
+		return "", false
+	}
+
+	for _, key := range []string{"into", "coll"} {
+		if s, ok := d[key].(string); ok {
+			return s, true
+		}
+	}
+
+	return "", true
+}
+
+//==============================================================================
+
+// doc returns the value as a document or nil if it isn't one.
+func doc(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case bson.M:
+		return v
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/policy"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// pipeline returns a set with a single pipeline query for the collection.
+func pipeline(collection string, commands ...map[string]interface{}) *query.Set {
+	return &query.Set{
+		Name: "PTEST_policy",
+		Queries: []query.Query{
+			{Name: "test", Type: query.TypePipeline, Collection: collection, Commands: commands},
+		},
+	}
+}
+
+// TestCheck tests sets are checked against the stages and collections
+// the roles of the caller allow.
+func TestCheck(t *testing.T) {
+	p := policy.Policy{
+		Roles: map[string]policy.Rule{
+			policy.RoleAll: {Collections: []string{"comments", "users_*"}},
+			"analyst":      {Collections: []string{"*"}},
+			"admin":        {Collections: []string{"*", "query_masks"}, Write: true},
+		},
+	}
+
+	sets := []struct {
+		name  string
+		set   *query.Set
+		roles []string
+		vars  map[string]string
+		stage string
+		ok    bool
+	}{
+		{"allowed stages", pipeline("comments", map[string]interface{}{"$match": map[string]interface{}{"status": 1}}, map[string]interface{}{"$limit": 10}), nil, nil, "", true},
+		{"prefix collection", pipeline("users_2016"), nil, nil, "", true},
+		{"collection for role", pipeline("assets"), []string{"analyst"}, nil, "", true},
+		{"collection not allowed", pipeline("assets"), nil, nil, "", false},
+		{"protected collection", pipeline("query_sets"), []string{"analyst"}, nil, "", false},
+		{"protected collection named", pipeline("query_masks"), []string{"admin"}, nil, "", true},
+		{"stage not allowed", pipeline("comments", map[string]interface{}{"$collStats": map[string]interface{}{}}), nil, nil, "$collStats", false},
+		{"out without write", pipeline("comments", map[string]interface{}{"$out": "totals"}), []string{"analyst"}, nil, "$out", false},
+		{"out with write", pipeline("comments", map[string]interface{}{"$out": "totals"}), []string{"admin"}, nil, "", true},
+		{"out into protected", pipeline("comments", map[string]interface{}{"$out": "query_masks"}), []string{"admin"}, nil, "$out", false},
+		{"out into database", pipeline("comments", map[string]interface{}{"$out": map[string]interface{}{"db": "admin", "coll": "totals"}}), []string{"admin"}, nil, "$out", false},
+		{"merge into database", pipeline("comments", map[string]interface{}{"$merge": map[string]interface{}{"into": map[string]interface{}{"db": "admin", "coll": "totals"}}}), []string{"admin"}, nil, "$merge", false},
+		{"out into collection", pipeline("comments", map[string]interface{}{"$out": map[string]interface{}{"coll": "totals"}}), []string{"admin"}, nil, "", true},
+		{"merge without write", pipeline("comments", map[string]interface{}{"$merge": map[string]interface{}{"into": "totals"}}), nil, nil, "$merge", false},
+		{"lookup protected", pipeline("comments", map[string]interface{}{"$lookup": map[string]interface{}{"from": "query_sets", "as": "sets"}}), []string{"analyst"}, nil, "$lookup", false},
+		{"lookup allowed", pipeline("comments", map[string]interface{}{"$lookup": map[string]interface{}{"from": "users_2016", "as": "user"}}), nil, nil, "", true},
+		{"facet stage", pipeline("comments", map[string]interface{}{"$facet": map[string]interface{}{"a": []interface{}{map[string]interface{}{"$out": "x"}}}}), nil, nil, "$out", false},
+		{"javascript", pipeline("comments", map[string]interface{}{"$match": map[string]interface{}{"$where": "sleep(1000)"}}), nil, nil, "$where", false},
+		{"save command", pipeline("comments", map[string]interface{}{"$limit": 1}, map[string]interface{}{"$save": map[string]interface{}{"$map": "list"}}), nil, nil, "", true},
+		{"save without write", pipeline("comments", map[string]interface{}{"$save": map[string]interface{}{"$collection": "totals"}}), []string{"analyst"}, nil, "$save", false},
+		{"save with write", pipeline("comments", map[string]interface{}{"$save": map[string]interface{}{"$append": "totals"}}), []string{"admin"}, nil, "", true},
+		{"save into protected", pipeline("comments", map[string]interface{}{"$save": map[string]interface{}{"$collection": "query_sets"}}), []string{"admin"}, nil, "$save", false},
+		{"collection variable", pipeline("#string:c"), []string{"analyst"}, map[string]string{"c": "query_masks"}, "", false},
+		{"collection variable allowed", pipeline("#string:c"), nil, map[string]string{"c": "comments"}, "", true},
+		{"lookup variable", pipeline("comments", map[string]interface{}{"$lookup": map[string]interface{}{"from": "#string:c", "as": "masks"}}), []string{"analyst"}, map[string]string{"c": "query_masks"}, "$lookup", false},
+		{"lookup data variable", pipeline("comments", map[string]interface{}{"$lookup": map[string]interface{}{"from": "#data.0:coll", "as": "x"}}), []string{"analyst"}, nil, "$lookup", false},
+		{"lookup key variable", pipeline("comments", map[string]interface{}{"$lookup": map[string]interface{}{"{f}": "query_sets", "as": "sets"}}), []string{"analyst"}, map[string]string{"f": "from"}, "$lookup", false},
+		{"javascript key variable", pipeline("comments", map[string]interface{}{"$match": map[string]interface{}{"{op}": "sleep(1000)"}}), nil, map[string]string{"op": "$where"}, "$where", false},
+	}
+
+	t.Log("Given the need to check sets against the policy.")
+	{
+		for _, s := range sets {
+			t.Logf("\tWhen using a set with %s", s.name)
+			{
+				err := p.Check(s.set, s.roles, s.vars)
+				if s.ok {
+					if err != nil {
+						t.Errorf("\t%s\tShould be allowed : %v", tests.Failed, err)
+						continue
+					}
+					t.Logf("\t%s\tShould be allowed.", tests.Success)
+					continue
+				}
+
+				pe, ok := err.(*policy.Error)
+				if !ok {
+					t.Errorf("\t%s\tShould get a policy error : %v", tests.Failed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould get a policy error : %v", tests.Success, pe)
+
+				if pe.Stage != s.stage {
+					t.Errorf("\t%s\tShould name the stage %q : got %q", tests.Failed, s.stage, pe.Stage)
+					continue
+				}
+				t.Logf("\t%s\tShould name the stage %q.", tests.Success, s.stage)
+			}
+		}
+	}
+}
+
+// TestDefault tests the default policy allows reading but not writing.
+func TestDefault(t *testing.T) {
+	p := policy.Default()
+
+	t.Log("Given the need to use the default policy.")
+	{
+		t.Log("\tWhen using a set that reads a collection")
+		{
+			if err := p.Check(pipeline("comments", map[string]interface{}{"$match": map[string]interface{}{}}), nil, nil); err != nil {
+				t.Fatalf("\t%s\tShould be allowed : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be allowed.", tests.Success)
+		}
+
+		t.Log("\tWhen using a set that reads the items")
+		{
+			if err := p.Check(pipeline("items", map[string]interface{}{"$match": map[string]interface{}{}}), nil, nil); err != nil {
+				t.Fatalf("\t%s\tShould be allowed : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be allowed.", tests.Success)
+		}
+
+		t.Log("\tWhen using a set that reads the masks")
+		{
+			if err := p.Check(pipeline("query_masks", map[string]interface{}{"$match": map[string]interface{}{}}), nil, nil); err == nil {
+				t.Fatalf("\t%s\tShould not be allowed.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be allowed.", tests.Success)
+		}
+
+		t.Log("\tWhen using a set that writes a collection")
+		{
+			if err := p.Check(pipeline("comments", map[string]interface{}{"$out": "totals"}), nil, nil); err == nil {
+				t.Fatalf("\t%s\tShould not be allowed.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be allowed.", tests.Success)
+		}
+	}
+}
+
+// TestCheckWrite tests the collection a set keeps the results of a view in is
+// checked against the write grant of the roles of the caller.
+func TestCheckWrite(t *testing.T) {
+	p := policy.Policy{
+		Roles: map[string]policy.Rule{
+			policy.RoleAll: {Collections: []string{"comments"}},
+			"analyst":      {Collections: []string{"*"}},
+			"admin":        {Collections: []string{"*"}, Write: true},
+		},
+	}
+
+	set := pipeline("#string:view")
+
+	t.Log("Given the need to keep the results of a view in a named collection.")
+	{
+		t.Log("\tWhen the caller doesn't hold the write grant")
+		{
+			if err := p.CheckWrite(set, []string{"analyst"}, "thread"); err == nil {
+				t.Fatalf("\t%s\tShould not be allowed.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be allowed.", tests.Success)
+		}
+
+		t.Log("\tWhen the collection is protected")
+		{
+			if err := p.CheckWrite(set, []string{"admin"}, "query_sets"); err == nil {
+				t.Fatalf("\t%s\tShould not be allowed.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not be allowed.", tests.Success)
+		}
+
+		t.Log("\tWhen the caller holds the write grant")
+		{
+			if err := p.CheckWrite(set, []string{"admin"}, "thread"); err != nil {
+				t.Fatalf("\t%s\tShould be allowed : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be allowed.", tests.Success)
+		}
+	}
+}
@@ -2,13 +2,21 @@ package query
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/bluesuncorp/validator.v8"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // Set of query types we expect to receive.
 const (
 	TypePipeline = "pipeline"
+	TypeFind     = "find"
+	TypeCount    = "count"
+	TypeDistinct = "distinct"
 )
 
 //==============================================================================
@@ -46,7 +54,7 @@ type Index struct {
 type Query struct {
 	Name        string                   `bson:"name" json:"name" validate:"required,min=3"`                                 // Unique name per query document.
 	Description string                   `bson:"desc,omitempty" json:"desc,omitempty"`                                       // Description of this specific query.
-	Type        string                   `bson:"type" json:"type" validate:"required,min=8"`                                 // TypePipeline, TypeTemplate
+	Type        string                   `bson:"type" json:"type" validate:"required,min=4"`                                 // TypePipeline, TypeFind, TypeCount, TypeDistinct
 	Collection  string                   `bson:"collection,omitempty" json:"collection,omitempty" validate:"required,min=3"` // Name of the collection to use for processing the query.
 	Timeout     string                   `bson:"timeout,omitempty" json:"timeout,omitempty"`                                 // Provides a timeout for the query if it does not return.
 	Commands    []map[string]interface{} `bson:"commands" json:"commands"`                                                   // Commands to process for the query.
@@ -67,7 +75,25 @@ func (q *Query) Validate() error {
 
 	switch q.Type {
 	case TypePipeline:
-		// Currently this is the only type we have at the moment.
+
+	case TypeFind, TypeCount, TypeDistinct:
+
+		// These types take a single command document with an optional
+		// $save command after it.
+		cmds := q.Commands
+		if _, exists := cmds[len(cmds)-1]["$save"]; exists {
+			cmds = cmds[:len(cmds)-1]
+		}
+
+		if len(cmds) != 1 {
+			return fmt.Errorf("A %s query requires a single command", q.Type)
+		}
+
+		if q.Type == TypeDistinct {
+			if fld, ok := cmds[0]["field"].(string); !ok || fld == "" {
+				return errors.New("A distinct query requires a field")
+			}
+		}
 
 	default:
 		return errors.New("Invalid query type")
@@ -78,12 +104,185 @@ func (q *Query) Validate() error {
 
 //==============================================================================
 
+// Set of parameter types we expect to receive.
+const (
+	ParamString   = "string"
+	ParamInt      = "int"
+	ParamFloat    = "float"
+	ParamBool     = "bool"
+	ParamDate     = "date"
+	ParamObjectID = "objectid"
+	ParamEnum     = "enum"
+	ParamList     = "list"
+)
+
 // Param contains meta-data about a required parameter for the query.
 type Param struct {
-	Name      string `bson:"name" json:"name"`             // Name of the parameter.
-	Desc      string `bson:"desc" json:"desc"`             // Description about the parameter.
-	Default   string `bson:"default" json:"default"`       // Default value for the parameter.
-	RegexName string `bson:"regex_name" json:"regex_name"` // Regular expression name.
+	Name      string   `bson:"name" json:"name"`                             // Name of the parameter.
+	Desc      string   `bson:"desc" json:"desc"`                             // Description about the parameter.
+	Default   string   `bson:"default" json:"default"`                       // Default value for the parameter.
+	RegexName string   `bson:"regex_name" json:"regex_name"`                 // Regular expression name.
+	Type      string   `bson:"type,omitempty" json:"type,omitempty"`         // Type of the value, defaults to ParamString.
+	Optional  bool     `bson:"optional,omitempty" json:"optional,omitempty"` // The parameter is not required when there is no default.
+	Min       *float64 `bson:"min,omitempty" json:"min,omitempty"`           // Minimum value for numbers, length for strings and items for lists.
+	Max       *float64 `bson:"max,omitempty" json:"max,omitempty"`           // Maximum value for numbers, length for strings and items for lists.
+	Enum      []string `bson:"enum,omitempty" json:"enum,omitempty"`         // Set of allowed values for ParamEnum.
+}
+
+// Validate checks the param value for consistency.
+func (p *Param) Validate() error {
+	if p.Name == "" {
+		return errors.New("Parameter name is required")
+	}
+
+	switch p.Type {
+	case "", ParamString, ParamInt, ParamFloat, ParamBool, ParamDate, ParamObjectID, ParamList:
+
+	case ParamEnum:
+		if len(p.Enum) == 0 {
+			return fmt.Errorf("Parameter %q requires enum values", p.Name)
+		}
+
+	default:
+		return fmt.Errorf("Parameter %q has an invalid type %q", p.Name, p.Type)
+	}
+
+	if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+		return fmt.Errorf("Parameter %q has a min greater than the max", p.Name)
+	}
+
+	return nil
+}
+
+// Coerce validates the value against the type and limits of the parameter
+// and returns the value in the canonical form for its type. Dates are
+// returned in the format understood by the #date command and lists are
+// returned as comma separated values.
+func (p *Param) Coerce(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch p.Type {
+	case ParamInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return "", errors.New("Value is not an integer")
+		}
+
+		if err := p.checkRange(float64(i)); err != nil {
+			return "", err
+		}
+
+		return strconv.Itoa(i), nil
+
+	case ParamFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", errors.New("Value is not a number")
+		}
+
+		if err := p.checkRange(f); err != nil {
+			return "", err
+		}
+
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+
+	case ParamBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", errors.New("Value is not a boolean")
+		}
+
+		return strconv.FormatBool(b), nil
+
+	case ParamDate:
+		for _, layout := range []string{"2006-01-02", time.RFC3339Nano, "2006-01-02T15:04:05.999"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format("2006-01-02T15:04:05.000Z"), nil
+			}
+		}
+
+		return "", errors.New("Value is not a date")
+
+	case ParamObjectID:
+		if !bson.IsObjectIdHex(value) {
+			return "", errors.New("Value is not an object id")
+		}
+
+		return value, nil
+
+	case ParamEnum:
+		for _, e := range p.Enum {
+			if e == value {
+				return value, nil
+			}
+		}
+
+		return "", fmt.Errorf("Value must be one of %s", strings.Join(p.Enum, ", "))
+
+	case ParamList:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		if err := p.checkRange(float64(len(items))); err != nil {
+			return "", err
+		}
+
+		return strings.Join(items, ","), nil
+
+	default:
+		if err := p.checkRange(float64(len(value))); err != nil {
+			return "", err
+		}
+
+		return value, nil
+	}
+}
+
+// checkRange validates the number is within the min and max.
+func (p *Param) checkRange(n float64) error {
+	if p.Min != nil && n < *p.Min {
+		return fmt.Errorf("Value must be at least %v", *p.Min)
+	}
+
+	if p.Max != nil && n > *p.Max {
+		return fmt.Errorf("Value must be at most %v", *p.Max)
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// Cache contains the policy for caching the results of a set.
+type Cache struct {
+	TTL string `bson:"ttl" json:"ttl"` // How long results are cached, e.g. "30s".
+}
+
+// Duration returns the TTL of the cache policy.
+func (c *Cache) Duration() (time.Duration, error) {
+	d, err := time.ParseDuration(c.TTL)
+	if err != nil {
+		return 0, err
+	}
+
+	if d <= 0 {
+		return 0, errors.New("Cache TTL must be positive")
+	}
+
+	return d, nil
+}
+
+//==============================================================================
+
+// Page contains the options for reading a single page of the documents
+// returned by the queries of a set.
+type Page struct {
+	Size  int    // Number of documents per page. Zero returns all documents.
+	Token string // Continuation token returned with the previous page.
 }
 
 //==============================================================================
@@ -98,6 +297,7 @@ type Set struct {
 	Queries     []Query `bson:"queries" json:"queries"`                     // Collection of queries.
 	Enabled     bool    `bson:"enabled" json:"enabled"`                     // If the query set is enabled to run.
 	Explain     bool    `bson:"explain" json:"explain"`                     // If we want the explain output.
+	Cache       *Cache  `bson:"cache,omitempty" json:"cache,omitempty"`     // Policy for caching the results.
 }
 
 // Validate checks the set value for consistency.
@@ -106,6 +306,18 @@ func (s *Set) Validate() error {
 		return err
 	}
 
+	for _, p := range s.Params {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.Cache != nil {
+		if _, err := s.Cache.Duration(); err != nil {
+			return err
+		}
+	}
+
 	for _, q := range s.Queries {
 		if err := q.Validate(); err != nil {
 			return err
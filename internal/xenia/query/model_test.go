@@ -0,0 +1,63 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/query"
+)
+
+// TestParamCoerce tests values are validated and coerced based on the
+// type of the parameter.
+func TestParamCoerce(t *testing.T) {
+	min, max := 2.0, 3.0
+
+	params := []struct {
+		param query.Param
+		value string
+		exp   string
+		fail  bool
+	}{
+		{query.Param{Name: "s"}, "bill", "bill", false},
+		{query.Param{Name: "s", Min: &min}, "b", "", true},
+		{query.Param{Name: "i", Type: query.ParamInt}, " 10", "10", false},
+		{query.Param{Name: "i", Type: query.ParamInt}, "ten", "", true},
+		{query.Param{Name: "i", Type: query.ParamInt, Max: &max}, "4", "", true},
+		{query.Param{Name: "f", Type: query.ParamFloat}, "1.50", "1.5", false},
+		{query.Param{Name: "b", Type: query.ParamBool}, "1", "true", false},
+		{query.Param{Name: "b", Type: query.ParamBool}, "yes", "", true},
+		{query.Param{Name: "d", Type: query.ParamDate}, "2013-01-16", "2013-01-16T00:00:00.000Z", false},
+		{query.Param{Name: "d", Type: query.ParamDate}, "2013-01-16T10:00:00+02:00", "2013-01-16T08:00:00.000Z", false},
+		{query.Param{Name: "d", Type: query.ParamDate}, "16/01/2013", "", true},
+		{query.Param{Name: "o", Type: query.ParamObjectID}, "5660bc6e16908cae692e0593", "5660bc6e16908cae692e0593", false},
+		{query.Param{Name: "o", Type: query.ParamObjectID}, "5660bc6e", "", true},
+		{query.Param{Name: "e", Type: query.ParamEnum, Enum: []string{"a", "b"}}, "b", "b", false},
+		{query.Param{Name: "e", Type: query.ParamEnum, Enum: []string{"a", "b"}}, "c", "", true},
+		{query.Param{Name: "l", Type: query.ParamList, Min: &min}, "a, b,,c", "a,b,c", false},
+		{query.Param{Name: "l", Type: query.ParamList, Min: &min}, "a", "", true},
+	}
+
+	t.Log("Given the need to coerce parameter values.")
+	{
+		for _, p := range params {
+			t.Logf("\tWhen using type %q with value %q", p.param.Type, p.value)
+			{
+				v, err := p.param.Coerce(p.value)
+				if p.fail {
+					if err == nil {
+						t.Errorf("\t%s\tShould fail to coerce the value : %q", tests.Failed, v)
+						continue
+					}
+					t.Logf("\t%s\tShould fail to coerce the value : %s", tests.Success, err)
+					continue
+				}
+
+				if err != nil || v != p.exp {
+					t.Errorf("\t%s\tShould coerce the value to %q : %q %v", tests.Failed, p.exp, v, err)
+					continue
+				}
+				t.Logf("\t%s\tShould coerce the value to %q.", tests.Success, p.exp)
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/audit"
 	"github.com/coralproject/shelf/internal/xenia/query"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -46,7 +47,7 @@ func Add(db *db.DB, set *query.Set) error {
 	return nil
 }
 
-// Remove is used to clear out all the test sets from the collection.
+// Remove is used to clear out all the test sets and their audit records.
 // All test documents must start with QSTEST in their name.
 func Remove(db *db.DB, pattern string) error {
 	f := func(c *mgo.Collection) error {
@@ -69,5 +70,15 @@ func Remove(db *db.DB, pattern string) error {
 		return err
 	}
 
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"set": bson.RegEx{Pattern: pattern}}
+		_, err := c.RemoveAll(q)
+		return err
+	}
+
+	if err := db.ExecuteMGO(tests.Context, audit.Collection, f); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/history"
 	gc "github.com/patrickmn/go-cache"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -354,6 +355,31 @@ func GetLastHistoryByName(context interface{}, db *db.DB, name string) (*Set, er
 
 // =============================================================================
 
+// History describes the history kept for sets so their revisions can be
+// listed, compared and restored by name.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "sets",
+	Selector: func(name string) (bson.M, error) {
+		return bson.M{"name": name}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var set Set
+		if err := raw.Unmarshal(&set); err != nil {
+			return nil, err
+		}
+
+		// Fix the set so it can be used for processing.
+		set.PrepareForUse()
+		return &set, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(*Set))
+	},
+}
+
+// =============================================================================
+
 // Delete is used to remove an existing Set document.
 func Delete(context interface{}, db *db.DB, name string) error {
 	log.Dev(context, "Delete", "Started : Name[%s]", name)
@@ -11,6 +11,7 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/history"
 	gc "github.com/patrickmn/go-cache"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -361,6 +362,28 @@ func GetLastHistoryByName(context interface{}, db *db.DB, name string) (Regex, e
 
 // =============================================================================
 
+// History describes the history kept for regexs so their revisions can be
+// listed, compared and restored by name.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "regexs",
+	Selector: func(name string) (bson.M, error) {
+		return bson.M{"name": name}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var rgx Regex
+		if err := raw.Unmarshal(&rgx); err != nil {
+			return nil, err
+		}
+		return rgx, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(Regex))
+	},
+}
+
+// =============================================================================
+
 // Delete is used to remove an existing Regex document.
 func Delete(context interface{}, db *db.DB, name string) error {
 	log.Dev(context, "Delete", "Started : Name[%s]", name)
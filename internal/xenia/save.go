@@ -0,0 +1,237 @@
+package xenia
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Set of save targets that persist the results to a collection.
+const (
+	saveCollection = "$collection"
+	saveAppend     = "$append"
+)
+
+// saveTarget contains the locations a $save command saves the results to.
+//
+// {"$map": "list"}
+// {"$collection": "top_commenters"}
+// {"$collection": "top_commenters", "$key": ["user_id"]}
+// {"$append": "daily_totals", "$map": "totals"}
+type saveTarget struct {
+	mapName    string
+	collection string
+	mode       string
+	key        []string
+}
+
+// parseSave extracts the save targets from the $save document.
+func parseSave(save map[string]interface{}) (saveTarget, error) {
+	var st saveTarget
+
+	if len(save) == 0 {
+		return st, errors.New("Missing save document")
+	}
+
+	for cmd, value := range save {
+
+		// The key can be a single field or a list of fields.
+		if cmd == "$key" {
+			switch v := value.(type) {
+			case string:
+				st.key = []string{v}
+
+			case []interface{}:
+				for _, fld := range v {
+					s, ok := fld.(string)
+					if !ok {
+						return st, fmt.Errorf("Save key field \"%v\" must be a string", fld)
+					}
+					st.key = append(st.key, s)
+				}
+
+			default:
+				return st, fmt.Errorf("Save key is a %T but must be a string or array", value)
+			}
+			continue
+		}
+
+		name, ok := value.(string)
+		if !ok {
+			return st, fmt.Errorf("Save key \"%v\" is a %T but must be a string", value, value)
+		}
+
+		switch cmd {
+		case "$map":
+			st.mapName = name
+
+		case saveCollection, saveAppend:
+			if st.mode != "" {
+				return st, errors.New("Only one of $collection or $append can be used")
+			}
+
+			st.mode = cmd
+			st.collection = name
+
+		default:
+			return st, fmt.Errorf("Invalid save location %q", cmd)
+		}
+	}
+
+	if st.key != nil && st.mode != saveCollection {
+		return st, errors.New("Save key requires $collection")
+	}
+
+	if st.mapName == "" && st.mode == "" {
+		return st, errors.New("Missing save document")
+	}
+
+	return st, nil
+}
+
+// writeResult saves the results into the target collection. By default the
+// content of the collection is atomically replaced by the results. With a key the
+// results are upserted using the key fields and $append inserts the results
+// as new documents.
+func writeResult(context interface{}, db *db.DB, st saveTarget, results []bson.M) error {
+	if err := collections.Writable(st.collection); err != nil {
+		log.Error(context, "writeResult", err, "Checking collection")
+		return err
+	}
+
+	f := func(c *mgo.Collection) error {
+		switch {
+		case st.mode == saveAppend:
+			if len(results) == 0 {
+				return nil
+			}
+
+			// New ids are used so the same results can be appended again.
+			docs := make([]interface{}, len(results))
+			for i, doc := range results {
+				d := withoutID(doc)
+				d["_id"] = bson.NewObjectId()
+				docs[i] = d
+			}
+
+			log.Dev(context, "writeResult", "MGO :\ndb.%s.insert(%d documents)", c.Name, len(docs))
+			return c.Insert(docs...)
+
+		case st.key != nil:
+			if len(results) == 0 {
+				return nil
+			}
+
+			bulk := c.Bulk()
+			for _, doc := range results {
+				selector := make(bson.M, len(st.key))
+				for _, fld := range st.key {
+					v, err := docFieldLookup(context, doc, fld)
+					if err != nil {
+						return fmt.Errorf("Save key field %q missing from result", fld)
+					}
+					selector[fld] = v
+				}
+
+				// The _id of an existing document can't be changed.
+				d := doc
+				if _, exists := selector["_id"]; !exists {
+					d = withoutID(doc)
+				}
+
+				bulk.Upsert(selector, d)
+			}
+
+			log.Dev(context, "writeResult", "MGO :\ndb.%s.upsert(%d documents) key%v", c.Name, len(results), st.key)
+			_, err := bulk.Run()
+			return err
+
+		default:
+			return replace(context, c, results)
+		}
+	}
+
+	if err := db.ExecuteMGO(context, st.collection, f); err != nil {
+		log.Error(context, "writeResult", err, "Saving to collection[%s]", st.collection)
+		return err
+	}
+
+	return nil
+}
+
+// replace replaces the content of the collection with the results. The
+// results are written into a new collection that is renamed over the
+// collection so readers see either the old or the new results. The indexes
+// of the collection are created on the new collection first.
+func replace(context interface{}, c *mgo.Collection, results []bson.M) error {
+	tmp := c.Database.C(c.Name + "_save_" + bson.NewObjectId().Hex())
+
+	log.Dev(context, "replace", "MGO :\ndb.createCollection(%q)", tmp.Name)
+	if err := tmp.Create(&mgo.CollectionInfo{}); err != nil {
+		return err
+	}
+
+	err := func() error {
+		indexes, err := c.Indexes()
+		if err != nil {
+			return err
+		}
+
+		for _, idx := range indexes {
+			if idx.Name == "_id_" {
+				continue
+			}
+
+			if err := tmp.EnsureIndex(idx); err != nil {
+				return err
+			}
+		}
+
+		if len(results) > 0 {
+			docs := make([]interface{}, len(results))
+			for i, doc := range results {
+				docs[i] = doc
+			}
+
+			log.Dev(context, "replace", "MGO :\ndb.%s.insert(%d documents)", tmp.Name, len(docs))
+			if err := tmp.Insert(docs...); err != nil {
+				return err
+			}
+		}
+
+		cmd := bson.D{
+			{Name: "renameCollection", Value: tmp.FullName},
+			{Name: "to", Value: c.FullName},
+			{Name: "dropTarget", Value: true},
+		}
+
+		log.Dev(context, "replace", "MGO :\ndb.%s.renameCollection(%q, true)", tmp.Name, c.Name)
+		return c.Database.Session.Run(cmd, nil)
+	}()
+
+	if err != nil {
+		if derr := tmp.DropCollection(); derr != nil {
+			log.Error(context, "replace", derr, "Dropping collection[%s]", tmp.Name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// withoutID returns a copy of the document without the _id field.
+func withoutID(doc bson.M) bson.M {
+	d := make(bson.M, len(doc))
+	for k, v := range doc {
+		if k != "_id" {
+			d[k] = v
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,167 @@
+package xenia_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/sponge/item"
+	"github.com/coralproject/shelf/internal/sponge/schema"
+	"github.com/coralproject/shelf/internal/wire"
+	"github.com/coralproject/shelf/internal/wire/live"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"github.com/coralproject/shelf/tstdata"
+)
+
+// collectionSave is the collection the tests save results into.
+const collectionSave = "test_xenia_save"
+
+// saveSet returns a set that saves two stations into the collection using
+// the specified save document and counts the documents in the collection.
+func saveSet(save map[string]interface{}) *query.Set {
+	return &query.Set{
+		Name:    "Save Collection",
+		Enabled: true,
+		Queries: []query.Query{
+			{
+				Name:       "Save",
+				Type:       "pipeline",
+				Collection: tstdata.CollectionExecTest,
+				Commands: []map[string]interface{}{
+					{"$match": map[string]interface{}{"station_id": map[string]interface{}{"$in": []string{"42021", "44008"}}}},
+					{"$project": map[string]interface{}{"_id": 0, "station_id": 1, "name": 1}},
+					{"$save": save},
+				},
+			},
+			{
+				Name:       "Count",
+				Type:       "count",
+				Collection: collectionSave,
+				Return:     true,
+				Commands: []map[string]interface{}{
+					{"filter": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+}
+
+// TestSaveCollection tests the results of a query can be saved into a
+// collection and that Shelf's own collections are protected.
+func TestSaveCollection(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	t.Log("Given the need to load the test data.")
+	{
+		loadTestData(t, db)
+	}
+
+	defer func() {
+		t.Log("Given the need to unload the test data.")
+		{
+			unloadTestData(t, db)
+
+			if col, err := db.CollectionMGO(tests.Context, collectionSave); err == nil {
+				col.DropCollection()
+			}
+		}
+	}()
+
+	saves := []struct {
+		name string
+		save map[string]interface{}
+		exp  []string
+	}{
+		{
+			name: "replace",
+			save: map[string]interface{}{"$collection": collectionSave},
+			exp: []string{
+				`{"results":[{"Name":"Count","Docs":[{"count":2}]}]}`,
+				`{"results":[{"Name":"Count","Docs":[{"count":2}]}]}`,
+			},
+		},
+		{
+			name: "upsert",
+			save: map[string]interface{}{"$collection": collectionSave, "$key": "station_id"},
+			exp: []string{
+				`{"results":[{"Name":"Count","Docs":[{"count":2}]}]}`,
+				`{"results":[{"Name":"Count","Docs":[{"count":2}]}]}`,
+			},
+		},
+		{
+			name: "append",
+			save: map[string]interface{}{"$append": collectionSave},
+			exp: []string{
+				`{"results":[{"Name":"Count","Docs":[{"count":4}]}]}`,
+				`{"results":[{"Name":"Count","Docs":[{"count":6}]}]}`,
+			},
+		},
+		{
+			name: "protected",
+			save: map[string]interface{}{"$collection": query.Collection},
+			exp: []string{
+				`{"results":{"commands":[{"$match":{"station_id":{"$in":["42021","44008"]}}},{"$project":{"_id":0,"name":1,"station_id":1}}],"error":"Collection \"query_sets\" is protected and can't be saved to"}}`,
+			},
+		},
+	}
+
+	t.Log("Given the need to save results into a collection.")
+	{
+		for _, sv := range saves {
+			t.Logf("\tWhen using the %s save target", sv.name)
+			{
+				for i, exp := range sv.exp {
+					result := xenia.Exec(tests.Context, db, saveSet(sv.save), nil)
+
+					data, err := json.Marshal(result)
+					if err != nil {
+						t.Fatalf("\t%s\tShould be able to marshal the result : %s", tests.Failed, err)
+					}
+
+					if string(data) != exp {
+						t.Log("Got :", string(data))
+						t.Log("Exp :", exp)
+						t.Errorf("\t%s\tShould have the correct result for execution %d.", tests.Failed, i+1)
+						continue
+					}
+					t.Logf("\t%s\tShould have the correct result for execution %d.", tests.Success, i+1)
+				}
+			}
+		}
+	}
+}
+
+// TestWritable tests the collections owned by the other services are
+// protected, including the ones registered with Protect.
+func TestWritable(t *testing.T) {
+	names := []string{item.Collection, schema.CollectionViolations, live.Collection, wire.CollectionBackfill, "quads", "query_custom", "items_history"}
+
+	t.Log("Given the need to save results into Shelf's own collections.")
+	{
+		for _, name := range names {
+			t.Logf("\tWhen using collection %q", name)
+			{
+				if err := collections.Writable(name); err == nil {
+					t.Errorf("\t%s\tShould not be able to save into the collection.", tests.Failed)
+					continue
+				}
+				t.Logf("\t%s\tShould not be able to save into the collection.", tests.Success)
+			}
+		}
+
+		if err := collections.Writable(collectionSave); err != nil {
+			t.Fatalf("\t%s\tShould be able to save into collection %q : %v", tests.Failed, collectionSave, err)
+		}
+		t.Logf("\t%s\tShould be able to save into collection %q.", tests.Success, collectionSave)
+	}
+}
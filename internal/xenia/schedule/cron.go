@@ -0,0 +1,248 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron contains a parsed cron expression. Expressions have the standard five
+// fields for minute, hour, day of month, month and day of week. Each field
+// supports *, lists, ranges and steps such as "*/15" or "1-5". Months and
+// days of the week can be provided by name. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly are also supported. All times are
+// evaluated in UTC.
+type Cron struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// The day of month and day of week fields match if either does when
+	// both are restricted.
+	domStar bool
+	dowStar bool
+}
+
+// descriptors maps the supported descriptors to their expression.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the range and names of a field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+// Set of fields in the order they appear in an expression.
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCron parses the cron expression.
+func ParseCron(expr string) (Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if d, exists := descriptors[strings.ToLower(expr)]; exists {
+		expr = d
+	}
+
+	flds := strings.Fields(expr)
+	if len(flds) != len(cronFields) {
+		return Cron{}, fmt.Errorf("Cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	var bits [5]uint64
+	for i, fld := range flds {
+		b, err := parseCronField(fld, cronFields[i])
+		if err != nil {
+			return Cron{}, err
+		}
+		bits[i] = b
+	}
+
+	// Sunday can be provided as 0 or 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	c := Cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: flds[2] == "*" || flds[2] == "?",
+		dowStar: flds[4] == "*" || flds[4] == "?",
+	}
+
+	if !c.possible() {
+		return Cron{}, fmt.Errorf("Cron expression %q never matches a date", expr)
+	}
+
+	return c, nil
+}
+
+// monthDays contains the most days each month can have.
+var monthDays = [13]uint{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// possible reports if a day of month of the expression exists in one of its
+// months, so "0 0 30 2 *" is rejected. Every month has each day of the week
+// so the expression is possible when the day of week is restricted.
+func (c Cron) possible() bool {
+	if c.domStar || !c.dowStar {
+		return true
+	}
+
+	for m := uint(1); m <= 12; m++ {
+		if c.month&(1<<m) == 0 {
+			continue
+		}
+
+		// The days up to the last day of the month.
+		if c.dom&(1<<(monthDays[m]+1)-1) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCronField returns the set of values for a field as a bitset.
+func parseCronField(fld string, cf cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(fld, ",") {
+		rng, step := part, 1
+
+		if idx := strings.IndexByte(part, '/'); idx != -1 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("Invalid step %q for %s", part, cf.name)
+			}
+			rng, step = part[:idx], n
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = cf.min, cf.max
+
+			// Sunday is only counted once when stepping over the week.
+			if cf.max == 7 {
+				hi = 6
+			}
+
+		case strings.Contains(rng, "-"):
+			idx := strings.IndexByte(rng, '-')
+
+			var err error
+			if lo, err = cronValue(rng[:idx], cf); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(rng[idx+1:], cf); err != nil {
+				return 0, err
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("Invalid range %q for %s", rng, cf.name)
+			}
+
+		default:
+			var err error
+			if lo, err = cronValue(rng, cf); err != nil {
+				return 0, err
+			}
+
+			hi = lo
+			if step > 1 {
+				hi = cf.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// cronValue returns the value of a number or name for the field.
+func cronValue(s string, cf cronField) (int, error) {
+	for i, name := range cf.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < cf.min || n > cf.max {
+		return 0, fmt.Errorf("Invalid value %q for %s", s, cf.name)
+	}
+
+	return n, nil
+}
+
+// Next returns the first time after t that matches the expression. A zero
+// time is returned if no time matches within the next five years.
+func (c Cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+
+	for t.Before(end) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches checks the day of month and day of week of the time.
+func (c Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,86 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+)
+
+// TestCronNext tests the next time is calculated for cron expressions.
+func TestCronNext(t *testing.T) {
+
+	// Saturday 2016-07-02 10:30 UTC.
+	from := time.Date(2016, 7, 2, 10, 30, 0, 0, time.UTC)
+
+	crons := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2016, 7, 2, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2016, 7, 2, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2016, 7, 3, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2016, 7, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2016, 7, 2, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2016, 7, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2016, 7, 3, 0, 0, 0, 0, time.UTC)},
+		{"30 6 1,15 * *", time.Date(2016, 7, 15, 6, 30, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2016, 7, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Log("Given the need to calculate the next time for cron expressions.")
+	{
+		for _, c := range crons {
+			t.Logf("\tWhen using expression %q", c.expr)
+			{
+				cron, err := schedule.ParseCron(c.expr)
+				if err != nil {
+					t.Errorf("\t%s\tShould be able to parse the expression : %v", tests.Failed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould be able to parse the expression.", tests.Success)
+
+				if next := cron.Next(from); !next.Equal(c.next) {
+					t.Errorf("\t%s\tShould get the next time %v : got %v", tests.Failed, c.next, next)
+					continue
+				}
+				t.Logf("\t%s\tShould get the next time %v.", tests.Success, c.next)
+			}
+		}
+	}
+}
+
+// TestCronInvalid tests invalid cron expressions are rejected.
+func TestCronInvalid(t *testing.T) {
+	exprs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	}
+
+	t.Log("Given the need to reject invalid cron expressions.")
+	{
+		for _, expr := range exprs {
+			t.Logf("\tWhen using expression %q", expr)
+			{
+				if _, err := schedule.ParseCron(expr); err == nil {
+					t.Errorf("\t%s\tShould not be able to parse the expression.", tests.Failed)
+					continue
+				}
+				t.Logf("\t%s\tShould not be able to parse the expression.", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"gopkg.in/bluesuncorp/validator.v8"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// validate is used to perform model field validation.
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New(&validator.Config{TagName: "validate"})
+}
+
+//==============================================================================
+
+// Output contains where the results of a scheduled execution are sent. When
+// no output is provided the results are only recorded as a run.
+type Output struct {
+	Collection string `bson:"collection,omitempty" json:"collection,omitempty"` // Collection each result is inserted into.
+	URL        string `bson:"url,omitempty" json:"url,omitempty"`               // URL each result is posted to. Its host must be allowed.
+}
+
+// Validate checks the output value for consistency.
+func (o *Output) Validate() error {
+	if o.Collection == "" && o.URL == "" {
+		return errors.New("Output requires a collection or url")
+	}
+
+	if o.Collection != "" {
+		if err := collections.Writable(o.Collection); err != nil {
+			return err
+		}
+	}
+
+	if o.URL != "" {
+		if err := checkURL(o.URL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// Schedule contains the details for executing a query set on a schedule.
+type Schedule struct {
+	Name        string            `bson:"name" json:"name" validate:"required,min=3"`       // Unique name of the schedule.
+	Description string            `bson:"desc,omitempty" json:"desc,omitempty"`             // Description of the schedule.
+	Set         string            `bson:"set" json:"set" validate:"required,min=3"`         // Name of the query set to execute.
+	Params      map[string]string `bson:"params,omitempty" json:"params,omitempty"`         // Parameters to execute the set with.
+	Cron        string            `bson:"cron" json:"cron" validate:"required"`             // Cron expression for when the set is executed.
+	Output      *Output           `bson:"output,omitempty" json:"output,omitempty"`         // Where the results are sent.
+	Enabled     bool              `bson:"enabled" json:"enabled"`                           // If the schedule is active.
+	NextRun     time.Time         `bson:"next_run,omitempty" json:"next_run,omitempty"`     // When the set is next executed.
+	LastRun     time.Time         `bson:"last_run,omitempty" json:"last_run,omitempty"`     // When the set was last executed.
+	LastError   string            `bson:"last_error,omitempty" json:"last_error,omitempty"` // Error from the last execution.
+}
+
+// Validate checks the schedule value for consistency.
+func (s *Schedule) Validate() error {
+	if err := validate.Struct(s); err != nil {
+		return err
+	}
+
+	if _, err := ParseCron(s.Cron); err != nil {
+		return err
+	}
+
+	if s.Output != nil {
+		if err := s.Output.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// Run contains the record of a single execution of a schedule.
+type Run struct {
+	ID       bson.ObjectId `bson:"_id,omitempty" json:"id,omitempty"`
+	Schedule string        `bson:"schedule" json:"schedule"`               // Name of the schedule.
+	Set      string        `bson:"set" json:"set"`                         // Name of the query set executed.
+	Owner    string        `bson:"owner" json:"owner"`                     // Identity of the replica that executed the set.
+	Started  time.Time     `bson:"started" json:"started"`                 // When the execution started.
+	Duration int64         `bson:"duration_ms" json:"duration_ms"`         // How long the execution took in milliseconds.
+	Error    string        `bson:"error,omitempty" json:"error,omitempty"` // Error if the execution failed.
+}
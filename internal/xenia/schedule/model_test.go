@@ -0,0 +1,41 @@
+package schedule_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+)
+
+// TestOutputURL tests results can only be posted to the allowed hosts.
+func TestOutputURL(t *testing.T) {
+	schedule.SetOutputHosts([]string{"hooks.example.com", "localhost:8080"})
+	defer schedule.SetOutputHosts(nil)
+
+	urls := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.example.com/results", true},
+		{"http://HOOKS.example.com:9000/results", true},
+		{"http://localhost:8080/results", true},
+		{"http://localhost:9090/results", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"ftp://hooks.example.com/results", false},
+	}
+
+	t.Log("Given the need to restrict where results are posted.")
+	{
+		for _, u := range urls {
+			t.Logf("\tWhen using url %q", u.url)
+			{
+				o := schedule.Output{URL: u.url}
+				if err := o.Validate(); (err == nil) != u.allowed {
+					t.Errorf("\t%s\tShould get allowed %v : %v", tests.Failed, u.allowed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould get allowed %v.", tests.Success, u.allowed)
+			}
+		}
+	}
+}
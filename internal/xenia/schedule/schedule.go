@@ -0,0 +1,310 @@
+// Package schedule provides the service layer for managing the schedules
+// query sets are executed on and the record of each execution.
+package schedule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/log"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Contains the name of Mongo collections.
+const (
+	Collection      = "query_schedules"
+	CollectionRuns  = "query_schedules_runs"
+	CollectionLocks = "query_schedules_locks"
+)
+
+// Set of error variables.
+var (
+	ErrNotFound = errors.New("Schedule Not found")
+)
+
+// =============================================================================
+
+// Upsert is used to create or update an existing Schedule document. The
+// next run is calculated from the cron expression.
+func Upsert(context interface{}, db *db.DB, s Schedule) error {
+	log.Dev(context, "Upsert", "Started : Name[%s]", s.Name)
+
+	// Validate the schedule that is provided.
+	if err := s.Validate(); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	// Validate has already checked the expression can be parsed.
+	c, _ := ParseCron(s.Cron)
+	s.NextRun = c.Next(time.Now())
+
+	// Keep the details of the last run when updating a schedule.
+	if cur, err := GetByName(context, db, s.Name); err == nil {
+		s.LastRun = cur.LastRun
+		s.LastError = cur.LastError
+	}
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": s.Name}
+		log.Dev(context, "Upsert", "MGO : db.%s.upsert(%s, %s)", c.Name, mongo.Query(q), mongo.Query(s))
+		_, err := c.Upsert(q, &s)
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "Upsert", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Upsert", "Completed : NextRun[%v]", s.NextRun)
+	return nil
+}
+
+// =============================================================================
+
+// GetAll retrieves the list of schedules.
+func GetAll(context interface{}, db *db.DB) ([]Schedule, error) {
+	log.Dev(context, "GetAll", "Started")
+
+	var schs []Schedule
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "GetAll", "MGO : db.%s.find({}).sort([\"name\"])", c.Name)
+		return c.Find(nil).Sort("name").All(&schs)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetAll", err, "Completed")
+		return nil, err
+	}
+
+	if schs == nil {
+		log.Error(context, "GetAll", ErrNotFound, "Completed")
+		return nil, ErrNotFound
+	}
+
+	log.Dev(context, "GetAll", "Completed : Schedules[%d]", len(schs))
+	return schs, nil
+}
+
+// GetByName retrieves the document for the specified name.
+func GetByName(context interface{}, db *db.DB, name string) (Schedule, error) {
+	log.Dev(context, "GetByName", "Started : Name[%s]", name)
+
+	var s Schedule
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": name}
+		log.Dev(context, "GetByName", "MGO : db.%s.findOne(%s)", c.Name, mongo.Query(q))
+		return c.Find(q).One(&s)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+
+		log.Error(context, "GetByName", err, "Completed")
+		return Schedule{}, err
+	}
+
+	log.Dev(context, "GetByName", "Completed : Schedule[%+v]", s)
+	return s, nil
+}
+
+// GetDue retrieves the enabled schedules with a next run at or before the
+// specified time.
+func GetDue(context interface{}, db *db.DB, now time.Time) ([]Schedule, error) {
+	log.Dev(context, "GetDue", "Started : Now[%v]", now)
+
+	var schs []Schedule
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"enabled": true, "next_run": bson.M{"$lte": now}}
+		log.Dev(context, "GetDue", "MGO : db.%s.find(%s).sort([\"next_run\"])", c.Name, mongo.Query(q))
+		return c.Find(q).Sort("next_run").All(&schs)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "GetDue", err, "Completed")
+		return nil, err
+	}
+
+	log.Dev(context, "GetDue", "Completed : Schedules[%d]", len(schs))
+	return schs, nil
+}
+
+// GetRuns retrieves the most recent runs for the specified schedule with
+// the newest first.
+func GetRuns(context interface{}, db *db.DB, name string, limit int) ([]Run, error) {
+	log.Dev(context, "GetRuns", "Started : Name[%s] Limit[%d]", name, limit)
+
+	var runs []Run
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"schedule": name}
+		log.Dev(context, "GetRuns", "MGO : db.%s.find(%s).sort([\"-started\"]).limit(%d)", c.Name, mongo.Query(q), limit)
+		return c.Find(q).Sort("-started").Limit(limit).All(&runs)
+	}
+
+	if err := db.ExecuteMGO(context, CollectionRuns, f); err != nil {
+		log.Error(context, "GetRuns", err, "Completed")
+		return nil, err
+	}
+
+	if runs == nil {
+		runs = []Run{}
+	}
+
+	log.Dev(context, "GetRuns", "Completed : Runs[%d]", len(runs))
+	return runs, nil
+}
+
+// =============================================================================
+
+// Advance moves the next run of the schedule forward from the specified
+// time. The update only happens if the next run has not been changed since
+// the schedule was read so a run is only started once.
+func Advance(context interface{}, db *db.DB, s Schedule, now time.Time) (bool, error) {
+	log.Dev(context, "Advance", "Started : Name[%s]", s.Name)
+
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		log.Error(context, "Advance", err, "Completed")
+		return false, err
+	}
+
+	next := c.Next(now)
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": s.Name, "next_run": s.NextRun}
+		u := bson.M{"$set": bson.M{"next_run": next}}
+		log.Dev(context, "Advance", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(u))
+		return c.Update(q, u)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			log.Dev(context, "Advance", "Completed : Already advanced")
+			return false, nil
+		}
+
+		log.Error(context, "Advance", err, "Completed")
+		return false, err
+	}
+
+	log.Dev(context, "Advance", "Completed")
+	return true, nil
+}
+
+// AddRun records the run and updates the last run details of the schedule.
+func AddRun(context interface{}, db *db.DB, run Run) error {
+	log.Dev(context, "AddRun", "Started : Schedule[%s]", run.Schedule)
+
+	if run.ID == "" {
+		run.ID = bson.NewObjectId()
+	}
+
+	f := func(c *mgo.Collection) error {
+		log.Dev(context, "AddRun", "MGO : db.%s.insert(%s)", c.Name, mongo.Query(run))
+		return c.Insert(&run)
+	}
+
+	if err := db.ExecuteMGO(context, CollectionRuns, f); err != nil {
+		log.Error(context, "AddRun", err, "Completed")
+		return err
+	}
+
+	f = func(c *mgo.Collection) error {
+		q := bson.M{"name": run.Schedule}
+		u := bson.M{"$set": bson.M{"last_run": run.Started, "last_error": run.Error}}
+		log.Dev(context, "AddRun", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(u))
+		err := c.Update(q, u)
+		if err == mgo.ErrNotFound {
+
+			// The schedule was removed while it was running.
+			return nil
+		}
+		return err
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		log.Error(context, "AddRun", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "AddRun", "Completed")
+	return nil
+}
+
+// =============================================================================
+
+// Lock acquires or renews the named lock for the owner. The lock is held
+// until it expires after the ttl. It returns false if another owner holds
+// the lock.
+func Lock(context interface{}, db *db.DB, name string, owner string, ttl time.Duration) (bool, error) {
+	log.Dev(context, "Lock", "Started : Name[%s] Owner[%s]", name, owner)
+
+	now := time.Now()
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{
+			"_id": name,
+			"$or": []bson.M{
+				{"owner": owner},
+				{"expires": bson.M{"$lt": now}},
+			},
+		}
+		u := bson.M{"$set": bson.M{"owner": owner, "expires": now.Add(ttl)}}
+
+		log.Dev(context, "Lock", "MGO : db.%s.update(%s, %s)", c.Name, mongo.Query(q), mongo.Query(u))
+		err := c.Update(q, u)
+		if err != mgo.ErrNotFound {
+			return err
+		}
+
+		// The lock does not exist yet or is held by another owner.
+		doc := bson.M{"_id": name, "owner": owner, "expires": now.Add(ttl)}
+		log.Dev(context, "Lock", "MGO : db.%s.insert(%s)", c.Name, mongo.Query(doc))
+		return c.Insert(doc)
+	}
+
+	if err := db.ExecuteMGO(context, CollectionLocks, f); err != nil {
+		if mgo.IsDup(err) {
+			log.Dev(context, "Lock", "Completed : Held by another owner")
+			return false, nil
+		}
+
+		log.Error(context, "Lock", err, "Completed")
+		return false, err
+	}
+
+	log.Dev(context, "Lock", "Completed : Acquired")
+	return true, nil
+}
+
+// =============================================================================
+
+// Delete is used to remove an existing Schedule document. The runs of the
+// schedule are kept.
+func Delete(context interface{}, db *db.DB, name string) error {
+	log.Dev(context, "Delete", "Started : Name[%s]", name)
+
+	f := func(c *mgo.Collection) error {
+		q := bson.M{"name": name}
+		log.Dev(context, "Delete", "MGO : db.%s.remove(%s)", c.Name, mongo.Query(q))
+		return c.Remove(q)
+	}
+
+	if err := db.ExecuteMGO(context, Collection, f); err != nil {
+		if err == mgo.ErrNotFound {
+			err = ErrNotFound
+		}
+
+		log.Error(context, "Delete", err, "Completed")
+		return err
+	}
+
+	log.Dev(context, "Delete", "Completed")
+	return nil
+}
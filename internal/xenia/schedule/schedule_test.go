@@ -0,0 +1,276 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/cfg"
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/db/mongo"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia/schedule"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// prefix is what we are looking to delete after the test.
+const prefix = "STEST_O"
+
+func init() {
+	// Initialize the configuration and logging systems. Plus anything
+	// else the web app layer needs.
+	tests.Init("XENIA")
+
+	// Initialize MongoDB using the `tests.TestSession` as the name of the
+	// master session.
+	cfg := mongo.Config{
+		Host:     cfg.MustString("MONGO_HOST"),
+		AuthDB:   cfg.MustString("MONGO_AUTHDB"),
+		DB:       cfg.MustString("MONGO_DB"),
+		User:     cfg.MustString("MONGO_USER"),
+		Password: cfg.MustString("MONGO_PASS"),
+	}
+	tests.InitMongo(cfg)
+}
+
+//==============================================================================
+
+// setup initializes for each indivdual test.
+func setup(t *testing.T) *db.DB {
+	tests.ResetLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+
+	return db
+}
+
+// teardown deinitializes for each indivdual test.
+func teardown(t *testing.T, db *db.DB) {
+	f := func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"name": bson.RegEx{Pattern: "^" + prefix}})
+		return err
+	}
+
+	if err := db.ExecuteMGO(tests.Context, schedule.Collection, f); err != nil {
+		t.Fatalf("%s\tShould be able to remove the schedules : %v", tests.Failed, err)
+	}
+
+	f = func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"schedule": bson.RegEx{Pattern: "^" + prefix}})
+		return err
+	}
+
+	if err := db.ExecuteMGO(tests.Context, schedule.CollectionRuns, f); err != nil {
+		t.Fatalf("%s\tShould be able to remove the runs : %v", tests.Failed, err)
+	}
+
+	f = func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"_id": bson.RegEx{Pattern: "^" + prefix}})
+		return err
+	}
+
+	if err := db.ExecuteMGO(tests.Context, schedule.CollectionLocks, f); err != nil {
+		t.Fatalf("%s\tShould be able to remove the locks : %v", tests.Failed, err)
+	}
+	t.Logf("%s\tShould be able to remove the test data.", tests.Success)
+
+	db.CloseMGO(tests.Context)
+
+	tests.DisplayLog()
+}
+
+//==============================================================================
+
+// TestUpsertSchedule tests a schedule can be saved, advanced and removed.
+func TestUpsertSchedule(t *testing.T) {
+	db := setup(t)
+	defer teardown(t, db)
+
+	sch1 := schedule.Schedule{
+		Name:    prefix + "_nightly",
+		Set:     "QTEST_O_basic",
+		Params:  map[string]string{"station_id": "42021"},
+		Cron:    "0 2 * * *",
+		Enabled: true,
+	}
+
+	t.Log("Given the need to save a schedule into the database.")
+	{
+		t.Log("\tWhen using a schedule that runs nightly")
+		{
+			if err := schedule.Upsert(tests.Context, db, sch1); err != nil {
+				t.Fatalf("\t%s\tShould be able to create a schedule : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create a schedule.", tests.Success)
+
+			sch2, err := schedule.GetByName(tests.Context, db, sch1.Name)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the schedule : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the schedule.", tests.Success)
+
+			if sch2.NextRun.IsZero() || sch2.NextRun.UTC().Hour() != 2 {
+				t.Fatalf("\t%s\tShould have the next run at 02:00 : %v", tests.Failed, sch2.NextRun)
+			}
+			t.Logf("\t%s\tShould have the next run at 02:00.", tests.Success)
+
+			// Make the schedule due so it can be advanced.
+			sch2.NextRun = time.Now().Add(-time.Minute).Truncate(time.Minute)
+			f := func(c *mgo.Collection) error {
+				return c.Update(bson.M{"name": sch2.Name}, bson.M{"$set": bson.M{"next_run": sch2.NextRun}})
+			}
+			if err := db.ExecuteMGO(tests.Context, schedule.Collection, f); err != nil {
+				t.Fatalf("\t%s\tShould be able to update the next run : %s", tests.Failed, err)
+			}
+
+			due, err := schedule.GetDue(tests.Context, db, time.Now())
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the due schedules : %s", tests.Failed, err)
+			}
+
+			var found bool
+			for _, sch := range due {
+				if sch.Name == sch1.Name {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Fatalf("\t%s\tShould find the schedule is due.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould find the schedule is due.", tests.Success)
+
+			if ok, err := schedule.Advance(tests.Context, db, sch2, time.Now()); err != nil || !ok {
+				t.Fatalf("\t%s\tShould be able to advance the schedule : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to advance the schedule.", tests.Success)
+
+			if ok, err := schedule.Advance(tests.Context, db, sch2, time.Now()); err != nil || ok {
+				t.Fatalf("\t%s\tShould not be able to advance the schedule twice : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to advance the schedule twice.", tests.Success)
+
+			if err := schedule.Delete(tests.Context, db, sch1.Name); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete the schedule : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to delete the schedule.", tests.Success)
+
+			if _, err := schedule.GetByName(tests.Context, db, sch1.Name); err != schedule.ErrNotFound {
+				t.Fatalf("\t%s\tShould not be able to retrieve the schedule : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to retrieve the schedule.", tests.Success)
+		}
+	}
+}
+
+// TestInvalidSchedule tests invalid schedules are rejected.
+func TestInvalidSchedule(t *testing.T) {
+	db := setup(t)
+	defer teardown(t, db)
+
+	schs := []schedule.Schedule{
+		{Name: prefix + "_cron", Set: "QTEST_O_basic", Cron: "* * *"},
+		{Name: prefix + "_set", Cron: "@daily"},
+		{Name: prefix + "_output", Set: "QTEST_O_basic", Cron: "@daily", Output: &schedule.Output{}},
+		{Name: prefix + "_protected", Set: "QTEST_O_basic", Cron: "@daily", Output: &schedule.Output{Collection: "query_sets"}},
+		{Name: prefix + "_url", Set: "QTEST_O_basic", Cron: "@daily", Output: &schedule.Output{URL: "ftp://example.com"}},
+	}
+
+	t.Log("Given the need to reject invalid schedules.")
+	{
+		for _, sch := range schs {
+			t.Logf("\tWhen using schedule %s", sch.Name)
+			{
+				if err := schedule.Upsert(tests.Context, db, sch); err == nil {
+					t.Errorf("\t%s\tShould not be able to save the schedule.", tests.Failed)
+					continue
+				}
+				t.Logf("\t%s\tShould not be able to save the schedule.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestRuns tests a run of a schedule is recorded.
+func TestRuns(t *testing.T) {
+	db := setup(t)
+	defer teardown(t, db)
+
+	sch := schedule.Schedule{
+		Name:    prefix + "_missing",
+		Set:     prefix + "_missing_set",
+		Cron:    "@hourly",
+		Enabled: true,
+	}
+
+	t.Log("Given the need to record the runs of a schedule.")
+	{
+		t.Log("\tWhen executing a schedule for a set that does not exist")
+		{
+			if err := schedule.Upsert(tests.Context, db, sch); err != nil {
+				t.Fatalf("\t%s\tShould be able to create a schedule : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create a schedule.", tests.Success)
+
+			run := schedule.Execute(tests.Context, db, sch, "test")
+			if run.Error == "" {
+				t.Fatalf("\t%s\tShould get an error for the run.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould get an error for the run.", tests.Success)
+
+			runs, err := schedule.GetRuns(tests.Context, db, sch.Name, 10)
+			if err != nil || len(runs) != 1 {
+				t.Fatalf("\t%s\tShould be able to retrieve the run : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to retrieve the run.", tests.Success)
+
+			if runs[0].Error != run.Error || runs[0].Owner != "test" {
+				t.Fatalf("\t%s\tShould get back the same run : %+v", tests.Failed, runs[0])
+			}
+			t.Logf("\t%s\tShould get back the same run.", tests.Success)
+
+			sch2, err := schedule.GetByName(tests.Context, db, sch.Name)
+			if err != nil || sch2.LastError != run.Error {
+				t.Fatalf("\t%s\tShould have the last error on the schedule : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have the last error on the schedule.", tests.Success)
+		}
+	}
+}
+
+// TestLock tests only one owner can hold the scheduler lock.
+func TestLock(t *testing.T) {
+	db := setup(t)
+	defer teardown(t, db)
+
+	name := prefix + "_lock"
+
+	t.Log("Given the need to elect a single scheduler.")
+	{
+		t.Log("\tWhen two owners ask for the lock")
+		{
+			if ok, err := schedule.Lock(tests.Context, db, name, "a", time.Minute); err != nil || !ok {
+				t.Fatalf("\t%s\tShould be able to acquire the lock : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to acquire the lock.", tests.Success)
+
+			if ok, err := schedule.Lock(tests.Context, db, name, "b", time.Minute); err != nil || ok {
+				t.Fatalf("\t%s\tShould not be able to acquire a held lock : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be able to acquire a held lock.", tests.Success)
+
+			if ok, err := schedule.Lock(tests.Context, db, name, "a", -time.Minute); err != nil || !ok {
+				t.Fatalf("\t%s\tShould be able to renew the lock : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to renew the lock.", tests.Success)
+
+			if ok, err := schedule.Lock(tests.Context, db, name, "b", time.Minute); err != nil || !ok {
+				t.Fatalf("\t%s\tShould be able to acquire an expired lock : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to acquire an expired lock.", tests.Success)
+		}
+	}
+}
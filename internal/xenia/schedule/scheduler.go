@@ -0,0 +1,273 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/collections"
+	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/policy"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// lockName is the name of the lock held by the replica running schedules.
+const lockName = "scheduler"
+
+// client is used to post results to an output url.
+var client = http.Client{Timeout: 30 * time.Second}
+
+// execPolicy is the policy sets are checked against before each run.
+var execPolicy = policy.Default()
+
+// SetPolicy sets the policy sets are checked against before each run. The
+// schedule has no caller so only the rule of every caller applies.
+func SetPolicy(p *policy.Policy) {
+	execPolicy = p
+}
+
+// outputHosts contains the hosts results can be posted to.
+var outputHosts map[string]bool
+
+// SetOutputHosts sets the hosts results can be posted to. A host matches
+// the host of an output url with or without its port. Results can't be
+// posted anywhere until hosts are set.
+func SetOutputHosts(hosts []string) {
+	outputHosts = make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			outputHosts[h] = true
+		}
+	}
+}
+
+// checkURL checks results can be posted to the url.
+func checkURL(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("Output url must use http or https")
+	}
+
+	host := strings.ToLower(u.Host)
+	if !outputHosts[host] && !outputHosts[strings.ToLower(u.Hostname())] {
+		return fmt.Errorf("Output url host %q is not allowed", u.Host)
+	}
+
+	return nil
+}
+
+// Scheduler checks for schedules that are due on an interval and executes
+// their sets. Only the replica holding the scheduler lock executes sets so
+// any number of replicas can run a Scheduler.
+type Scheduler struct {
+	session  string
+	owner    string
+	interval time.Duration
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New returns a Scheduler that uses the named Mongo master session and
+// checks for schedules that are due on the interval.
+func New(session string, interval time.Duration) *Scheduler {
+	host, _ := os.Hostname()
+
+	return &Scheduler{
+		session:  session,
+		owner:    fmt.Sprintf("%s:%d:%s", host, os.Getpid(), bson.NewObjectId().Hex()),
+		interval: interval,
+		shutdown: make(chan struct{}),
+	}
+}
+
+// Start begins checking for schedules that are due.
+func (s *Scheduler) Start(context interface{}) {
+	log.Dev(context, "Start", "Started : Owner[%s] Interval[%v]", s.owner, s.interval)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.tick(context)
+
+			case <-s.shutdown:
+				log.Dev(context, "Start", "Completed : Shutdown")
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the schedules being executed to complete and stops
+// checking for schedules that are due.
+func (s *Scheduler) Stop(context interface{}) {
+	log.Dev(context, "Stop", "Started")
+
+	close(s.shutdown)
+	s.wg.Wait()
+
+	log.Dev(context, "Stop", "Completed")
+}
+
+// tick executes the schedules that are due if this replica is the leader.
+func (s *Scheduler) tick(context interface{}) {
+	db, err := db.NewMGO(context, s.session)
+	if err != nil {
+		log.Error(context, "tick", err, "Getting Mongo session")
+		return
+	}
+	defer db.CloseMGO(context)
+
+	// The lock is held for two intervals so it survives a slow tick.
+	leader, err := Lock(context, db, lockName, s.owner, 2*s.interval)
+	if err != nil || !leader {
+		return
+	}
+
+	now := time.Now()
+
+	schs, err := GetDue(context, db, now)
+	if err != nil {
+		return
+	}
+
+	for _, sch := range schs {
+
+		// Move the next run forward first so a run is never started twice.
+		ok, err := Advance(context, db, sch, now)
+		if err != nil || !ok {
+			continue
+		}
+
+		Execute(context, db, sch, s.owner)
+	}
+}
+
+// =============================================================================
+
+// Execute runs the set of the schedule, sends the result to the output and
+// records the run.
+func Execute(context interface{}, db *db.DB, sch Schedule, owner string) Run {
+	log.Dev(context, "Execute", "Started : Schedule[%s] Set[%s]", sch.Name, sch.Set)
+
+	run := Run{
+		ID:       bson.NewObjectId(),
+		Schedule: sch.Name,
+		Set:      sch.Set,
+		Owner:    owner,
+		Started:  time.Now().UTC(),
+	}
+
+	if err := execute(context, db, sch, run.Started); err != nil {
+		run.Error = err.Error()
+	}
+
+	run.Duration = int64(time.Since(run.Started) / time.Millisecond)
+
+	if err := AddRun(context, db, run); err != nil {
+		log.Error(context, "Execute", err, "Recording run")
+	}
+
+	log.Dev(context, "Execute", "Completed : Duration[%dms] Error[%s]", run.Duration, run.Error)
+	return run
+}
+
+// execute runs the set and sends the result to the output.
+func execute(context interface{}, db *db.DB, sch Schedule, started time.Time) error {
+	set, err := query.GetByName(context, db, sch.Set)
+	if err != nil {
+		return err
+	}
+
+	if execPolicy != nil {
+		if err := execPolicy.Check(set, nil, sch.Params); err != nil {
+			return err
+		}
+	}
+
+	// Exec adds defaults to the variables so use a copy.
+	vars := make(map[string]string, len(sch.Params))
+	for k, v := range sch.Params {
+		vars[k] = v
+	}
+
+	// Record the schedule as the caller of the execution.
+	result := xenia.ExecCall(context, db, set, vars, xenia.Call{Caller: "schedule:" + sch.Name})
+
+	// Failed executions return a document with the error.
+	if doc, ok := result.Results.(bson.M); ok {
+		if msg, ok := doc["error"].(string); ok {
+			return errors.New(msg)
+		}
+	}
+
+	if sch.Output == nil {
+		return nil
+	}
+
+	if sch.Output.Collection != "" {
+		if err := collections.Writable(sch.Output.Collection); err != nil {
+			return err
+		}
+
+		f := func(c *mgo.Collection) error {
+			doc := bson.M{
+				"schedule": sch.Name,
+				"set":      sch.Set,
+				"started":  started,
+				"results":  result.Results,
+			}
+
+			log.Dev(context, "execute", "MGO : db.%s.insert({\"schedule\": %q, ...})", c.Name, sch.Name)
+			return c.Insert(doc)
+		}
+
+		if err := db.ExecuteMGO(context, sch.Output.Collection, f); err != nil {
+			return err
+		}
+	}
+
+	if sch.Output.URL != "" {
+		if err := checkURL(sch.Output.URL); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Post(sch.Output.URL, "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("Output url returned status %d", resp.StatusCode)
+		}
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/db/mongo"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/history"
 	gc "github.com/patrickmn/go-cache"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -371,6 +372,31 @@ func GetLastHistoryByName(context interface{}, db *db.DB, name string) (Script,
 
 // =============================================================================
 
+// History describes the history kept for scripts so their revisions can be
+// listed, compared and restored by name.
+var History = history.Kind{
+	Collection: CollectionHistory,
+	Field:      "scripts",
+	Selector: func(name string) (bson.M, error) {
+		return bson.M{"name": name}, nil
+	},
+	Decode: func(raw bson.Raw) (interface{}, error) {
+		var scr Script
+		if err := raw.Unmarshal(&scr); err != nil {
+			return nil, err
+		}
+
+		// Fix the script so it can be used for processing.
+		scr.PrepareForUse()
+		return scr, nil
+	},
+	Restore: func(context interface{}, db *db.DB, v interface{}) error {
+		return Upsert(context, db, v.(Script))
+	},
+}
+
+// =============================================================================
+
 // Delete is used to remove an existing Set document.
 func Delete(context interface{}, db *db.DB, name string) error {
 	log.Dev(context, "Delete", "Started : Name[%s]", name)
@@ -0,0 +1,404 @@
+package xenia
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/platform/metrics"
+	"github.com/coralproject/shelf/internal/xenia/audit"
+	"github.com/coralproject/shelf/internal/xenia/query"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// streamBatchSize is the number of documents requested from Mongo on each
+// round trip of the cursor when streaming results.
+const streamBatchSize = 500
+
+// Streamer receives the documents produced by executing a set as they are
+// read from the cursor instead of having them collected in memory.
+type Streamer interface {
+
+	// Query is called before the documents for a query are written. It is
+	// only called for queries that are marked to return their results.
+	Query(name string) error
+
+	// Doc is called for each document that is returned by the current query.
+	Doc(doc bson.M) error
+
+	// Close is called once the execution is complete. If the execution
+	// failed, err contains the reason.
+	Close(err error) error
+}
+
+// writeError wraps errors returned by the Streamer so they can be told apart
+// from errors executing the queries.
+type writeError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (we writeError) Error() string {
+	return we.err.Error()
+}
+
+//==============================================================================
+
+// ExecStream executes the specified query set and streams the documents of
+// each returned query to the Streamer. Execution errors are reported through
+// the Streamer's Close method. An error is returned only if writing to the
+// Streamer failed. Streamed results are not paged so the page of the call
+// is ignored.
+func ExecStream(context interface{}, db *db.DB, set *query.Set, vars map[string]string, call Call, s Streamer) error {
+	log.Dev(context, "ExecStream", "Started : Name[%s] Caller[%s]", set.Name, call.Caller)
+
+	started := time.Now()
+
+	// If we have been provided a nil map, make one.
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+
+	// Validate the set and prepare it for execution.
+	if msg, err := prepareSet(context, db, set, vars); err != nil {
+		auditExec(context, db, set, vars, call, started, "", nil, err.Error())
+
+		log.Error(context, "ExecStream", err, "Completed : %s", msg)
+		return s.Close(err)
+	}
+
+	// Details of each query that ran for the audit record.
+	var queries []audit.Query
+
+	// Hold any data we have been asked to save.
+	data := make(map[string]interface{})
+
+	// Streamed results are not paged.
+	opts := options{set: set.Name, explain: set.Explain, masks: call.Masks}
+
+	// Iterate over the set of queries.
+	for _, q := range set.Queries {
+		var n int
+		var err error
+
+		qStarted := time.Now()
+
+		// Pipelines and finds are read from a cursor. Everything else
+		// returns a small result that is written once executed.
+		typ := strings.ToLower(q.Type)
+		if !set.Explain && (typ == query.TypePipeline || typ == query.TypeFind) {
+			n, err = streamCursor(context, db, &q, vars, data, opts, s)
+		} else {
+			n, err = streamResult(context, db, &q, vars, data, opts, s)
+		}
+
+		aq := audit.Query{
+			Name:     q.Name,
+			Duration: millis(time.Since(qStarted)),
+			Docs:     n,
+		}
+
+		if err != nil {
+			aq.Error = err.Error()
+		}
+
+		queries = append(queries, aq)
+
+		if err != nil {
+
+			// We can't continue if the results can't be written.
+			if we, ok := err.(writeError); ok {
+				auditExec(context, db, set, vars, call, started, "", queries, we.err.Error())
+
+				log.Error(context, "ExecStream", we.err, "Completed : Writing results")
+				return we.err
+			}
+
+			// Were we told to continue to the next one.
+			if q.Continue {
+				continue
+			}
+
+			auditExec(context, db, set, vars, call, started, "", queries, err.Error())
+
+			log.Error(context, "ExecStream", err, "Completed : Executing Result")
+			return s.Close(err)
+		}
+	}
+
+	auditExec(context, db, set, vars, call, started, "", queries, "")
+
+	log.Dev(context, "ExecStream", "Completed")
+	return s.Close(nil)
+}
+
+// streamResult executes the specified query and writes the documents of
+// the result to the Streamer. The number of documents in the result is
+// returned.
+func streamResult(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, opts options, s Streamer) (int, error) {
+	result, _, err := execQuery(context, db, q, vars, data, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	n := len(result.Docs)
+	if !q.Return {
+		return n, nil
+	}
+
+	if err := s.Query(q.Name); err != nil {
+		return n, writeError{err}
+	}
+
+	for _, doc := range result.Docs {
+		if err := s.Doc(doc); err != nil {
+			return n, writeError{err}
+		}
+	}
+
+	return n, nil
+}
+
+// streamCursor executes the specified pipeline or find query using a cursor
+// and writes each document to the Streamer once masking has been applied.
+// The number of documents read from the cursor is returned.
+func streamCursor(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, opts options, s Streamer) (int, error) {
+	cmds, agg, save, _, err := buildPipeline(context, q, vars, data)
+	if err != nil {
+		return 0, err
+	}
+
+	// Build the function that opens the cursor for the query type.
+	var iter func(c *mgo.Collection) *mgo.Iter
+	switch strings.ToLower(q.Type) {
+	case query.TypeFind:
+		if len(cmds) != 1 {
+			return 0, fmt.Errorf("A %s query requires a single command", q.Type)
+		}
+
+		spec, err := parseFindSpec(cmds[0])
+		if err != nil {
+			return 0, err
+		}
+
+		iter = func(c *mgo.Collection) *mgo.Iter {
+			log.Dev(context, "streamCursor", "MGO Started\ndb.%s.find(%s)", c.Name, agg)
+			return findQuery(c, spec).Batch(streamBatchSize).Iter()
+		}
+
+	default:
+		iter = func(c *mgo.Collection) *mgo.Iter {
+			log.Dev(context, "streamCursor", "MGO Started\ndb.%s.aggregate([\n%s])", c.Name, agg)
+			return c.Pipe(cmds).Batch(streamBatchSize).Iter()
+		}
+	}
+
+	// The timeout is applied to each round trip of the cursor since the
+	// entire result can take longer than that to be streamed.
+	timeout := queryTimeout(context, q)
+
+	// Load the masks once for all the documents we will stream.
+	masks := loadMasks(context, db, opts.maskCollection(q.Collection))
+
+	// The results are only kept when we need to save them.
+	var results []bson.M
+	var started bool
+	var n int
+
+	// Build the function for the execution.
+	f := func(c *mgo.Collection) error {
+		iter := iter(c)
+
+		for {
+			var doc bson.M
+			if !iter.Next(&doc) {
+				break
+			}
+			n++
+
+			// Perform any masking that is required.
+			if len(masks) > 0 {
+				if err := matchMaskField(context, masks, doc); err != nil {
+					iter.Close()
+					return err
+				}
+			}
+
+			if save != nil {
+				results = append(results, doc)
+			}
+
+			if !q.Return {
+				continue
+			}
+
+			if !started {
+				if err := s.Query(q.Name); err != nil {
+					iter.Close()
+					return writeError{err}
+				}
+				started = true
+			}
+
+			if err := s.Doc(doc); err != nil {
+				iter.Close()
+				return writeError{err}
+			}
+		}
+
+		return iter.Close()
+	}
+
+	if err := db.ExecuteMGOTimeout(context, timeout, q.Collection, f); err != nil {
+		if _, ok := err.(*net.OpError); ok {
+			metrics.ExecTimeouts.Inc(q.Collection)
+			log.Error(context, "streamCursor", err, "Timed out Network")
+			return n, errors.New("Completed : Timed out executing commands")
+		}
+
+		log.Error(context, "streamCursor", err, "Completed")
+		return n, err
+	}
+
+	// Queries with no documents still report their name.
+	if q.Return && !started {
+		if err := s.Query(q.Name); err != nil {
+			return n, writeError{err}
+		}
+	}
+
+	// Do we need to save the result.
+	if save != nil {
+		if results == nil {
+			results = []bson.M{}
+		}
+
+		if err := saveResult(context, db, save, results, data); err != nil {
+			return n, err
+		}
+	}
+
+	log.Dev(context, "streamCursor", "Completed")
+	return n, nil
+}
+
+//==============================================================================
+
+// jsonStreamer writes the results as a single JSON document with the same
+// layout as a query.Result. Execution errors are added as an error field.
+type jsonStreamer struct {
+	w       io.Writer
+	queries int
+	docs    int
+}
+
+// NewJSONStreamer returns a Streamer that writes the results as a JSON
+// document to the specified writer.
+func NewJSONStreamer(w io.Writer) Streamer {
+	return &jsonStreamer{w: w}
+}
+
+// Query implements the Streamer interface.
+func (js *jsonStreamer) Query(name string) error {
+	n, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+
+	prefix := `]},`
+	if js.queries == 0 {
+		prefix = `{"results":[`
+	}
+
+	js.queries++
+	js.docs = 0
+
+	_, err = io.WriteString(js.w, prefix+`{"Name":`+string(n)+`,"Docs":[`)
+	return err
+}
+
+// Doc implements the Streamer interface.
+func (js *jsonStreamer) Doc(doc bson.M) error {
+	d, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	if js.docs > 0 {
+		if _, err := io.WriteString(js.w, ","); err != nil {
+			return err
+		}
+	}
+	js.docs++
+
+	_, err = js.w.Write(d)
+	return err
+}
+
+// Close implements the Streamer interface.
+func (js *jsonStreamer) Close(err error) error {
+	end := `]}]`
+	if js.queries == 0 {
+		end = `{"results":[]`
+	}
+
+	if err != nil {
+		msg, mErr := json.Marshal(err.Error())
+		if mErr != nil {
+			return mErr
+		}
+		end += `,"error":` + string(msg)
+	}
+
+	_, wErr := io.WriteString(js.w, end+"}\n")
+	return wErr
+}
+
+//==============================================================================
+
+// ndjsonStreamer writes each document as a separate line of JSON with
+// the name of the query it belongs to.
+type ndjsonStreamer struct {
+	enc  *json.Encoder
+	name string
+}
+
+// ndjsonDoc represents a single line written by the ndjsonStreamer.
+type ndjsonDoc struct {
+	Name  string `json:"name,omitempty"`
+	Doc   bson.M `json:"doc,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewNDJSONStreamer returns a Streamer that writes the results as newline
+// delimited JSON to the specified writer.
+func NewNDJSONStreamer(w io.Writer) Streamer {
+	return &ndjsonStreamer{enc: json.NewEncoder(w)}
+}
+
+// Query implements the Streamer interface.
+func (ns *ndjsonStreamer) Query(name string) error {
+	ns.name = name
+	return nil
+}
+
+// Doc implements the Streamer interface.
+func (ns *ndjsonStreamer) Doc(doc bson.M) error {
+	return ns.enc.Encode(ndjsonDoc{Name: ns.name, Doc: doc})
+}
+
+// Close implements the Streamer interface.
+func (ns *ndjsonStreamer) Close(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return ns.enc.Encode(ndjsonDoc{Error: err.Error()})
+}
@@ -0,0 +1,134 @@
+package xenia_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/db"
+	"github.com/ardanlabs/kit/tests"
+	"github.com/coralproject/shelf/internal/xenia"
+)
+
+// TestExecStream tests the streamed execution of Sets produces the same
+// results as the buffered execution.
+func TestExecStream(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	t.Log("Given the need to load the test data.")
+	{
+		loadTestData(t, db)
+	}
+
+	defer func() {
+		t.Log("Given the need to unload the test data.")
+		{
+			unloadTestData(t, db)
+		}
+	}()
+
+	t.Log("Given the need to stream the results of positive mongo tests.")
+	{
+		for _, es := range getPosExecSet() {
+			t.Logf("\tWhen using Execute Set %s", es.set.Name)
+			{
+				var buf bytes.Buffer
+				if err := xenia.ExecStream(tests.Context, db, es.set, es.vars, xenia.Call{}, xenia.NewJSONStreamer(&buf)); err != nil {
+					t.Errorf("\t%s\tShould be able to stream the result : %s", tests.Failed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould be able to stream the result.", tests.Success)
+
+				data := strings.TrimSpace(buf.String())
+
+				var res map[string]interface{}
+				if err := json.Unmarshal([]byte(data), &res); err != nil {
+					t.Errorf("\t%s\tShould be able to unmarshal the result : %s", tests.Failed, err)
+					continue
+				}
+				t.Logf("\t%s\tShould be able to unmarshal the result.", tests.Success)
+
+				var found bool
+				for _, rslt := range es.results {
+					if strings.HasPrefix(rslt, "#find:") {
+						if strings.Contains(data, rslt[6:]) {
+							found = true
+							break
+						}
+						continue
+					}
+
+					if data == rslt {
+						found = true
+						break
+					}
+				}
+
+				if !found {
+					t.Log("Exp:", data)
+					for _, rslt := range es.results {
+						t.Log("Rsl:", rslt)
+					}
+					t.Errorf("\t%s\tShould have the correct result.", tests.Failed)
+					continue
+				}
+				t.Logf("\t%s\tShould have the correct result", tests.Success)
+			}
+		}
+	}
+}
+
+// TestExecStreamNDJSON tests the streamed execution of a Set as newline
+// delimited JSON.
+func TestExecStreamNDJSON(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	db, err := db.NewMGO(tests.Context, tests.TestSession)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to get a Mongo session : %v", tests.Failed, err)
+	}
+	defer db.CloseMGO(tests.Context)
+
+	t.Log("Given the need to load the test data.")
+	{
+		loadTestData(t, db)
+	}
+
+	defer func() {
+		t.Log("Given the need to unload the test data.")
+		{
+			unloadTestData(t, db)
+		}
+	}()
+
+	t.Log("Given the need to stream results as newline delimited JSON.")
+	{
+		es := basic()
+
+		t.Logf("\tWhen using Execute Set %s", es.set.Name)
+		{
+			var buf bytes.Buffer
+			if err := xenia.ExecStream(tests.Context, db, es.set, es.vars, xenia.Call{}, xenia.NewNDJSONStreamer(&buf)); err != nil {
+				t.Fatalf("\t%s\tShould be able to stream the result : %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to stream the result.", tests.Success)
+
+			exp := `{"name":"Basic","doc":{"name":"C14 - Pasco County Buoy, FL"}}`
+			if got := strings.TrimSpace(buf.String()); got != exp {
+				t.Log("Exp:", exp)
+				t.Log("Got:", got)
+				t.Fatalf("\t%s\tShould have the correct result.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have the correct result.", tests.Success)
+		}
+	}
+}
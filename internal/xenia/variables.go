@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/query"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -91,6 +92,38 @@ func ProcessVariables(context interface{}, commands map[string]interface{}, vars
 	return nil
 }
 
+// ProcessCollections replaces the collection of the queries that use a
+// string variable with the value of the variable. Collections can't be
+// substituted with any other type of variable.
+func ProcessCollections(context interface{}, set *query.Set, vars map[string]string) error {
+
+	// Before: {"collection": "#string:view"}  After: {"collection": "view_8fa3"}
+
+	for i := range set.Queries {
+		name := set.Queries[i].Collection
+		if name == "" || name[0] != '#' {
+			continue
+		}
+
+		if !strings.HasPrefix(name, "#string:") {
+			err := fmt.Errorf("Invalid collection variable %q, must be a string", name)
+			log.Error(context, "ProcessCollections", err, "Parsing variable")
+			return err
+		}
+
+		v, exists := vars[name[len("#string:"):]]
+		if !exists || v == "" {
+			err := fmt.Errorf("Collection variable does not exist : %q", name)
+			log.Error(context, "ProcessCollections", err, "Collection variable lookup")
+			return err
+		}
+
+		set.Queries[i].Collection = v
+	}
+
+	return nil
+}
+
 // fldSub appends to the replace map the fields that need to change and what the
 // new field name is.
 func fldSub(context interface{}, key string, vars map[string]string, replace map[string]string) error {
@@ -159,6 +192,18 @@ func valSub(context interface{}, key, variable string, commands map[string]inter
 
 	switch key {
 	case "$in":
+
+		// A list variable can provide the values.
+		if cmd == "list" {
+			v, err := varLookup(context, cmd, vari, vars, results)
+			if err != nil {
+				return err
+			}
+
+			commands[key] = v
+			return nil
+		}
+
 		if len(cmd) != 6 || cmd[0:4] != "data" {
 			err := fmt.Errorf("Invalid $in command %q, missing \"data\" keyword or malformed", cmd)
 			log.Error(context, "varSub", err, "$in command processing")
@@ -189,6 +234,9 @@ func varLookup(context interface{}, cmd, variable string, vars map[string]string
 
 	// {"field": "#cmd:variable"}
 	// Before: {"field": "#number:variable_name"}  		After: {"field": 1234}
+	// Before: {"field": "#float:variable_name"}  		After: {"field": 12.34}
+	// Before: {"field": "#bool:variable_name"}  		After: {"field": true}
+	// Before: {"field": "#list:variable_name"}  		After: {"field": ["a", "b"]}
 	// Before: {"field": "#string:variable_name"}  		After: {"field": "value"}
 	// Before: {"field": "#date:variable_name"}    		After: {"field": time.Time}
 	// Before: {"field": "#objid:variable_name"}   		After: {"field": mgo.ObjectId}
@@ -214,6 +262,15 @@ func varLookup(context interface{}, cmd, variable string, vars map[string]string
 	case "numb":
 		return number(context, param)
 
+	case "floa":
+		return float(context, param)
+
+	case "bool":
+		return boolean(context, param)
+
+	case "list":
+		return list(param), nil
+
 	case "stri":
 		return param, nil
 
@@ -414,6 +471,39 @@ func number(context interface{}, value string) (int, error) {
 	return i, nil
 }
 
+// float is a helper function to convert the value to a float.
+func float(context interface{}, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		err = fmt.Errorf("Parameter %q is not a float", value)
+		log.Error(context, "varLookup", err, "Float conversion")
+		return 0, err
+	}
+	return f, nil
+}
+
+// boolean is a helper function to convert the value to a bool.
+func boolean(context interface{}, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		err = fmt.Errorf("Parameter %q is not a boolean", value)
+		log.Error(context, "varLookup", err, "Bool conversion")
+		return false, err
+	}
+	return b, nil
+}
+
+// list is a helper function to convert comma separated values into an array.
+func list(value string) []interface{} {
+	items := []interface{}{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // isoDate is a helper function to convert the internal extension for dates
 // into a BSON date. We convert the following string
 func isoDate(context interface{}, value string) (time.Time, error) {
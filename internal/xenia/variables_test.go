@@ -7,6 +7,7 @@ import (
 
 	"github.com/ardanlabs/kit/tests"
 	"github.com/coralproject/shelf/internal/xenia"
+	"github.com/coralproject/shelf/internal/xenia/query"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -56,6 +57,24 @@ func TestPreProcessing(t *testing.T) {
 			map[string]string{"value": "10"},
 			map[string]interface{}{"field_name": 10},
 		},
+		{
+			false,
+			map[string]interface{}{"field_name": "#float:value"},
+			map[string]string{"value": "10.5"},
+			map[string]interface{}{"field_name": 10.5},
+		},
+		{
+			false,
+			map[string]interface{}{"field_name": "#bool:value"},
+			map[string]string{"value": "true"},
+			map[string]interface{}{"field_name": true},
+		},
+		{
+			false,
+			map[string]interface{}{"field_name": map[string]interface{}{"$in": "#list:value"}},
+			map[string]string{"value": "a, b"},
+			map[string]interface{}{"field_name": map[string]interface{}{"$in": []interface{}{"a", "b"}}},
+		},
 		{
 			false,
 			map[string]interface{}{"field_name": "#date:value"},
@@ -167,6 +186,48 @@ func TestPreProcessing(t *testing.T) {
 	}
 }
 
+// TestProcessCollections tests the collections of queries can be provided
+// by string variables.
+func TestProcessCollections(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	set := query.Set{
+		Queries: []query.Query{
+			{Name: "View", Collection: "#string:view"},
+			{Name: "Users", Collection: "users"},
+		},
+	}
+
+	t.Log("Given the need to provide the collection of a query in a variable.")
+	{
+		t.Log("\tWhen the variable is provided")
+		{
+			vars := map[string]string{"view": "view_1234"}
+			if err := xenia.ProcessCollections(tests.Context, &set, vars); err != nil {
+				t.Fatalf("\t%s\tShould be able to process the collections : %v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to process the collections.", tests.Success)
+
+			if set.Queries[0].Collection != "view_1234" || set.Queries[1].Collection != "users" {
+				t.Fatalf("\t%s\tShould only replace the collection variable : %v", tests.Failed, set.Queries)
+			}
+			t.Logf("\t%s\tShould only replace the collection variable.", tests.Success)
+		}
+
+		t.Log("\tWhen the variable is not provided or not a string")
+		{
+			for _, name := range []string{"#string:view", "#number:view"} {
+				set.Queries[0].Collection = name
+				if err := xenia.ProcessCollections(tests.Context, &set, nil); err == nil {
+					t.Fatalf("\t%s\tShould not be able to process the collection %q.", tests.Failed, name)
+				}
+				t.Logf("\t%s\tShould not be able to process the collection %q.", tests.Success, name)
+			}
+		}
+	}
+}
+
 // compareTime compares two bson maps for equivalence. This is based
 // on a percent of difference since we are dealing with time.
 func compareTime(t1 time.Time, t2 time.Time) bool {
@@ -5,9 +5,11 @@ package xenia
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/ardanlabs/kit/db"
 	"github.com/ardanlabs/kit/log"
+	"github.com/coralproject/shelf/internal/xenia/audit"
 	"github.com/coralproject/shelf/internal/xenia/query"
 	"github.com/coralproject/shelf/internal/xenia/script"
 	"gopkg.in/mgo.v2/bson"
@@ -16,8 +18,10 @@ import (
 // docs represents what a user will receive after
 // excuting a successful set.
 type docs struct {
-	Name string
-	Docs []bson.M
+	Name    string   `bson:"name"`
+	Docs    []bson.M `bson:"docs"`
+	Next    string   `json:"next,omitempty" bson:"next,omitempty"`
+	HasMore *bool    `json:"has_more,omitempty" bson:"has_more,omitempty"`
 }
 
 // emptyResult is for returning empty runs.
@@ -27,50 +31,63 @@ var emptyResult []docs
 
 // Exec executes the specified query set by name.
 func Exec(context interface{}, db *db.DB, set *query.Set, vars map[string]string) *query.Result {
-	log.Dev(context, "Exec", "Started : Name[%s]", set.Name)
+	return ExecCall(context, db, set, vars, Call{})
+}
 
-	// Validate the set that is provided.
-	if err := set.Validate(); err != nil {
-		return errResult(context, err, "Validated")
-	}
+// ExecPage executes the specified query set by name returning a single page
+// of documents for each returned query. The next token reported for a query
+// is provided in the page to read the documents that follow.
+func ExecPage(context interface{}, db *db.DB, set *query.Set, vars map[string]string, page query.Page) *query.Result {
+	return ExecCall(context, db, set, vars, Call{Page: page})
+}
 
-	// Is the rule enabled.
-	if !set.Enabled {
-		return errResult(context, errors.New("Set disabled"), "Enabled")
-	}
+// ExecCall executes the specified query set by name for the caller. The
+// execution is recorded in the audit collection with the caller, the
+// resolved variables and the details of each query.
+func ExecCall(context interface{}, db *db.DB, set *query.Set, vars map[string]string, call Call) *query.Result {
+	log.Dev(context, "Exec", "Started : Name[%s] Page[%d] Caller[%s]", set.Name, call.Page.Size, call.Caller)
+
+	started := time.Now()
 
 	// If we have been provided a nil map, make one.
 	if vars == nil {
 		vars = make(map[string]string)
 	}
 
-	// Did we get everything we need. Also load defaults.
-	if err := processParams(context, db, set, vars); err != nil {
-		return errResult(context, err, "Process parameters")
+	// Validate the set and prepare it for execution.
+	if msg, err := prepareSet(context, db, set, vars); err != nil {
+		result := errResult(context, err, msg)
+		auditExec(context, db, set, vars, call, started, "", nil, resultError(result))
+		return result
 	}
 
-	// Load the pre/post scripts.
-	if err := loadPrePostScripts(context, db, set); err != nil {
-		return errResult(context, err, "Loading Pre/Post scripts")
+	opts, err := newOptions(set, call)
+	if err != nil {
+		result := errResult(context, err, "Page")
+		auditExec(context, db, set, vars, call, started, "", nil, resultError(result))
+		return result
 	}
 
-	// Hold any data we have been asked to save.
-	data := make(map[string]interface{})
+	result, queries := execQueries(context, db, set, vars, opts)
+	auditExec(context, db, set, vars, call, started, "", queries, resultError(result))
+
+	return result
+}
+
+// execQueries runs the queries of a set that has been prepared. The details
+// of each query that ran are returned for the audit record.
+func execQueries(context interface{}, db *db.DB, set *query.Set, vars map[string]string, opts options) (*query.Result, []audit.Query) {
+
+	// Run the queries, executing the independent ones concurrently.
+	outcomes := runQueries(context, db, set, vars, opts)
+	queries := queryStats(set, outcomes)
 
 	// Final results of running the set of queries.
 	var results []docs
 
-	// Iterate over the set of queries.
-	for _, q := range set.Queries {
-		var result docs
-		var commands []map[string]interface{}
-		var err error
-
-		// We only have pipeline right now.
-		switch strings.ToLower(q.Type) {
-		case "pipeline":
-			result, commands, err = execPipeline(context, db, &q, vars, data, set.Explain)
-		}
+	// Iterate over the outcomes in the order of the queries.
+	for i, q := range set.Queries {
+		result, commands, err := outcomes[i].result, outcomes[i].commands, outcomes[i].err
 
 		// Was there an error processing the query.
 		if err != nil {
@@ -86,7 +103,7 @@ func Exec(context interface{}, db *db.DB, set *query.Set, vars map[string]string
 			}
 
 			log.Error(context, "errResult", err, "Completed : Executing Result")
-			return &r
+			return &r, queries
 		}
 
 		// Append these results to the final set.
@@ -101,13 +118,67 @@ func Exec(context interface{}, db *db.DB, set *query.Set, vars map[string]string
 	}
 
 	log.Dev(context, "Exec", "Completed")
-	return &r
+	return &r, queries
+}
+
+// execQuery executes a single query based on its type.
+func execQuery(context interface{}, db *db.DB, q *query.Query, vars map[string]string, data map[string]interface{}, opts options) (docs, []map[string]interface{}, error) {
+
+	switch strings.ToLower(q.Type) {
+	case query.TypePipeline:
+		return execPipeline(context, db, q, vars, data, opts)
+
+	case query.TypeFind, query.TypeCount, query.TypeDistinct:
+		return execFind(context, db, q, vars, data, opts)
+	}
+
+	return docs{}, q.Commands, nil
+}
+
+// prepareSet validates the set, processes the parameters against the
+// variables and loads the pre/post scripts. On error a message describing
+// the step that failed is returned for logging.
+func prepareSet(context interface{}, db *db.DB, set *query.Set, vars map[string]string) (string, error) {
+
+	// Validate the set that is provided.
+	if err := set.Validate(); err != nil {
+		return "Validated", err
+	}
+
+	// Is the rule enabled.
+	if !set.Enabled {
+		return "Enabled", errors.New("Set disabled")
+	}
+
+	// Did we get everything we need. Also load defaults.
+	if err := processParams(context, db, set, vars); err != nil {
+		return "Process parameters", err
+	}
+
+	// Resolve the collections the queries name with a variable.
+	if err := ProcessCollections(context, set, vars); err != nil {
+		return "Process collections", err
+	}
+
+	// Load the pre/post scripts.
+	if err := loadPrePostScripts(context, db, set); err != nil {
+		return "Loading Pre/Post scripts", err
+	}
+
+	return "", nil
 }
 
 // errResult creates a result value with the error.
 func errResult(context interface{}, err error, msg string) *query.Result {
+	doc := bson.M{"error": err.Error()}
+
+	// Report the details for each invalid parameter.
+	if pe, ok := err.(ParamErrors); ok {
+		doc["params"] = pe
+	}
+
 	r := query.Result{
-		Results: bson.M{"error": err.Error()},
+		Results: doc,
 	}
 
 	log.Error(context, "errResult", err, "Completed : %s", msg)